@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// bench times the benchmark suite's operations against every circle backend, replica count and tree depth
+// it covers, and prints a comparison table. The shared ring flags are ignored: the cases set their own.
+func bench(args []string) error {
+	var s settings
+	fs := s.flagSet("bench", "")
+	keys := fs.Int("keys", 10000, "number of keys in each benchmarked tree")
+	ops := fs.String("ops", "", "comma-separated operations to time (all by default)")
+	fs.Parse(args)
+
+	known := make(map[string]bool)
+	for _, op := range ringtree.BenchOps {
+		known[op.Name] = true
+	}
+	selected := make(map[string]bool)
+	for _, op := range strings.Split(*ops, ",") {
+		if op == "" {
+			continue
+		}
+		if !known[op] {
+			return fmt.Errorf("unknown operation %q", op)
+		}
+		selected[op] = true
+	}
+
+	fmt.Printf("Benchmarks (%d keys per tree):\n", *keys)
+	fmt.Println("----------------------------")
+	fmt.Printf("%-12s %-32s %-12s %-12s %-12s\n", "Operation", "Case", "ns/op", "B/op", "allocs/op")
+	for _, op := range ringtree.BenchOps {
+		if len(selected) > 0 && !selected[op.Name] {
+			continue
+		}
+		for _, c := range ringtree.BenchCases() {
+			var err error
+			result := testing.Benchmark(func(b *testing.B) {
+				rt, stored, buildErr := c.Build(*keys)
+				if buildErr != nil {
+					err = buildErr
+					return
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err = op.Run(rt, stored, i); err != nil {
+						return
+					}
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("%s on %s: %v", op.Name, c.Name(), err)
+			}
+			fmt.Printf("%-12s %-32s %-12d %-12d %-12d\n", op.Name, c.Name(), result.NsPerOp(), result.AllocedBytesPerOp(), result.AllocsPerOp())
+		}
+	}
+	fmt.Println("----------------------------")
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// churn runs a workload of key inserts and lookups while nodes join and leave, and prints the keys remapped,
+// the depth of the tree over the run and the operation latencies.
+func churn(args []string) error {
+	var s settings
+	fs := s.flagSet("churn", "")
+	ops := fs.Int("ops", 100000, "key inserts and lookups to run")
+	lookups := fs.Float64("lookups", 0.5, "share of the operations that look up an inserted key")
+	join := fs.Float64("join", 0.0005, "mean node joins per operation")
+	leave := fs.Float64("leave", 0.0002, "mean node leaves per operation")
+	spec := fs.String("workload", "uniform", "keys to insert: uniform, zipf[:s[:n]], hotspot[:prefix[:fraction]] or sequential")
+	fs.Parse(args)
+
+	opts, err := s.options()
+	if err != nil {
+		return err
+	}
+	keys, err := parseWorkload(*spec, *ops, s.rng())
+	if err != nil {
+		return err
+	}
+	report, err := ringtree.Simulate(ringtree.SimConfig{
+		MaxCount:    s.d,
+		Threshold:   s.tau,
+		Operations:  *ops,
+		LookupRatio: *lookups,
+		JoinRate:    *join,
+		LeaveRate:   *leave,
+		Keys:        keys,
+		Seed:        s.seed,
+		Options:     opts,
+	})
+	if err != nil {
+		return err
+	}
+	printSimReport(os.Stdout, report)
+	return nil
+}
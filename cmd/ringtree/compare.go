@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// compare runs the same seeded keys and scaling plan against a flat ring, a bounded-load ring and a ring
+// tree, and prints their key movement and load spread side by side.
+func compare(args []string) error {
+	var s settings
+	fs := s.flagSet("compare", "")
+	keys := fs.Int("keys", 100000, "number of random keys to insert")
+	plan := fs.String("plan", "10:50:5", "scaling plan, as from:to:steps")
+	epsilon := fs.Float64("epsilon", 0.25, "slack of the bounded-load ring over the mean load")
+	fs.Parse(args)
+
+	opts, err := s.options()
+	if err != nil {
+		return err
+	}
+	var from, to, steps int
+	if _, err := fmt.Sscanf(*plan, "%d:%d:%d", &from, &to, &steps); err != nil {
+		return fmt.Errorf("invalid scaling plan %q: %v", *plan, err)
+	}
+	report, err := ringtree.Compare(ringtree.CompareConfig{
+		Keys:     *keys,
+		Plan:     ringtree.GrowthPlan(from, to, steps),
+		MaxCount: s.d,
+		Epsilon:  *epsilon,
+		Seed:     s.seed,
+		Options:  opts,
+	})
+	if err != nil {
+		return err
+	}
+	printComparisonReport(os.Stdout, report)
+	return nil
+}
@@ -0,0 +1,301 @@
+// Command ringtree manages a ring tree kept in a snapshot file between invocations, and simulates key
+// placement in one.
+//
+// Usage:
+//
+//	ringtree <command> [flags] [arguments]
+//
+// Every command takes -state, the snapshot file (ringtree.json by default), and the ring options -tau, -d,
+// -replicas and -circle. Options are not stored in the snapshot and apply to the invocation they are given
+// to.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// command is one subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"insert-node", "add a node to the root ring", insertNode},
+		{"insert-key", "insert keys into the tree", insertKey},
+		{"lookup", "print the node holding each key", lookup},
+		{"stats", "print hierarchy and load statistics", stats},
+		{"simulate", "insert random keys into a fresh tree and print its statistics", simulate},
+		{"churn", "simulate key traffic while nodes join and leave", churn},
+		{"compare", "compare a flat ring, a bounded-load ring and a ring tree over a scaling plan", compare},
+		{"bench", "time key and node operations across circle backends, replica counts and tree depths", bench},
+		{"export-dot", "write the tree as a Graphviz DOT graph", exportDOT},
+		{"shell", "open an interactive prompt over the stored tree", shell},
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatalf("ringtree %s: %v", cmd.name, err)
+			}
+			return
+		}
+	}
+	if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "-help" {
+		fmt.Fprintf(os.Stderr, "ringtree: unknown command %q\n", os.Args[1])
+	}
+	usage()
+	os.Exit(2)
+}
+
+// usage lists the commands.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ringtree <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'ringtree <command> -h' for the flags of a command.\n")
+}
+
+// settings are the flags shared by every command.
+type settings struct {
+	state    string
+	tau      int
+	d        int
+	replicas int
+	circle   string
+	compress bool
+	verbose  bool
+	sample   int
+	slow     time.Duration
+	seed     int64
+}
+
+// flagSet returns the flag set of a command with the shared flags registered on it.
+func (s *settings) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ringtree %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&s.state, "state", "ringtree.json", "snapshot file holding the tree between invocations")
+	fs.IntVar(&s.tau, "tau", 100, "threshold τ: max keys per node before it splits")
+	fs.IntVar(&s.d, "d", 7, "maximum number of nodes on the root ring")
+	fs.IntVar(&s.replicas, "replicas", ringtree.DefaultConfig(0).Replicas, "virtual nodes per physical node")
+	fs.StringVar(&s.circle, "circle", "rbtree", "vnode storage: rbtree, array, or adaptive[:N] to migrate past N vnodes")
+	fs.BoolVar(&s.compress, "gzip", false, "gzip the state file when saving it")
+	fs.BoolVar(&s.verbose, "v", false, "log every ring operation to stdout")
+	fs.IntVar(&s.sample, "log-sample", 1, "with -v, log one of every n operation log lines")
+	fs.DurationVar(&s.slow, "slow", 0, "log operations taking at least this long to stderr (0 disables)")
+	fs.Int64Var(&s.seed, "seed", 0, "seed of generated node IDs and simulated keys, for reproducible runs (random by default)")
+	return fs
+}
+
+// options returns the ring options the flags select.
+func (s *settings) options() ([]ringtree.Option, error) {
+	opts := []ringtree.Option{ringtree.WithReplicas(s.replicas)}
+	switch kind, arg, _ := strings.Cut(s.circle, ":"); kind {
+	case "rbtree":
+		opts = append(opts, ringtree.WithArrayCircle(false))
+	case "array":
+		opts = append(opts, ringtree.WithArrayCircle(true))
+	case "adaptive":
+		threshold := 256
+		if arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid adaptive circle threshold %q", arg)
+			}
+			threshold = n
+		}
+		opts = append(opts, ringtree.WithAdaptiveCircle(threshold))
+	default:
+		return nil, fmt.Errorf("unknown circle type %q", s.circle)
+	}
+	if s.seed != 0 {
+		opts = append(opts, ringtree.WithRandSource(rand.NewSource(s.seed)))
+	}
+	if s.verbose {
+		opts = append(opts, ringtree.WithLogger(log.New(os.Stdout, "", log.LstdFlags)), ringtree.WithLogSampling(s.sample))
+	}
+	if s.slow > 0 {
+		opts = append(opts, ringtree.WithSlowLog(s.slow, log.New(os.Stderr, "", log.LstdFlags)))
+	}
+	return opts, nil
+}
+
+// rng returns a random source seeded by -seed, or nil to draw from crypto/rand.
+func (s *settings) rng() *rand.Rand {
+	if s.seed == 0 {
+		return nil
+	}
+	return rand.New(rand.NewSource(s.seed))
+}
+
+// load reads the tree from the state file, or returns an empty tree if there is none yet.
+func (s *settings) load() (*ringtree.Ring, error) {
+	opts, err := s.options()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.state)
+	if errors.Is(err, os.ErrNotExist) {
+		return ringtree.New(s.d, opts...), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ringtree.ReadSnapshot(f, opts...)
+}
+
+// save writes the tree to the state file with a checksum, replacing it only once the snapshot is complete.
+func (s *settings) save(rt *ringtree.Ring) error {
+	opts := []ringtree.SnapshotOption{ringtree.WithChecksum(ringtree.ChecksumSHA256)}
+	if s.compress {
+		opts = append(opts, ringtree.WithCompression("gzip"))
+	}
+	f, err := os.CreateTemp(filepath.Dir(s.state), filepath.Base(s.state)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := rt.WriteSnapshot(f, opts...); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.state)
+}
+
+// insertNode adds a node with threshold τ to the root ring.
+func insertNode(args []string) error {
+	var s settings
+	fs := s.flagSet("insert-node", "")
+	id := fs.String("id", "", "ID of the node (random by default)")
+	fs.Parse(args)
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	node := ringtree.NewNode(*id, s.tau)
+	if err := rt.InsertNode(node); err != nil {
+		return err
+	}
+	fmt.Println(node.ID())
+	return s.save(rt)
+}
+
+// insertKey inserts the keys given as arguments.
+func insertKey(args []string) error {
+	var s settings
+	fs := s.flagSet("insert-key", "key...")
+	fs.Parse(args)
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, key := range fs.Args() {
+		if err := rt.InsertKey(key); err != nil {
+			return fmt.Errorf("key %s: %v", key, err)
+		}
+	}
+	return s.save(rt)
+}
+
+// lookup prints the node holding each key given as an argument.
+func lookup(args []string) error {
+	var s settings
+	fs := s.flagSet("lookup", "key...")
+	fs.Parse(args)
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, key := range fs.Args() {
+		nodeID, err := rt.Lookup(key)
+		if err != nil {
+			return fmt.Errorf("key %s: %v", key, err)
+		}
+		fmt.Printf("%s\t%s\n", key, nodeID)
+	}
+	return nil
+}
+
+// stats prints the statistics of the stored tree.
+func stats(args []string) error {
+	var s settings
+	fs := s.flagSet("stats", "")
+	loads := fs.Bool("loads", false, "also print the load of every ring")
+	vnodes := fs.Int("vnodes", 0, "also print the `n` hottest and coldest vnodes of every node")
+	fs.Parse(args)
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	if *loads {
+		printLoadDetails(os.Stdout, rt)
+	}
+	if *vnodes > 0 {
+		printVNodeLoads(os.Stdout, rt, *vnodes)
+	}
+	printHierarchyDetails(os.Stdout, rt)
+	printSystemVariance(os.Stdout, rt)
+	printImbalance(os.Stdout, rt)
+	return nil
+}
+
+// exportDOT writes the stored tree as a Graphviz DOT graph.
+func exportDOT(args []string) error {
+	var s settings
+	fs := s.flagSet("export-dot", "")
+	out := fs.String("o", "", "file to write the graph to (stdout by default)")
+	vnodes := fs.Bool("vnodes", false, "list every vnode of a node with the keys it holds")
+	fs.Parse(args)
+	opts := ringtree.DOTOptions{VNodes: *vnodes}
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return rt.ToDOT(os.Stdout, opts)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := rt.ToDOT(f, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// printLoadDetails prints the loads of every ring, with their mean, variance and standard deviation.
+func printLoadDetails(w io.Writer, rt *ringtree.Ring) {
+	loadDetails := rt.GetTotalLoads()
+	for _, loadInfo := range loadDetails {
+		fmt.Fprintf(w, "RingID: %s, Level: %d\n", loadInfo.ID, loadInfo.Level)
+		fmt.Fprintf(w, "Node Loads: %v\n", loadInfo.Loads)
+		fmt.Fprintf(w, "Total Load: %d\n", loadInfo.Total)
+		fmt.Fprintf(w, "Mean: %.2f\n", loadInfo.Mean)
+		fmt.Fprintf(w, "Variance: %.2f\n", loadInfo.Variance)
+		fmt.Fprintf(w, "Standard Deviation: %.2f\n", loadInfo.Stdev)
+		fmt.Fprintln(w, "----------------------------")
+	}
+}
+
+// printVNodeLoads prints the spread of keys over the vnodes of every node, with its top hottest and
+// coldest vnodes.
+func printVNodeLoads(w io.Writer, rt *ringtree.Ring, top int) {
+	format := func(vnodes []ringtree.VNodeLoad) string {
+		parts := make([]string, len(vnodes))
+		for i, vnode := range vnodes {
+			parts[i] = fmt.Sprintf("%d:%d", vnode.Hash, vnode.Keys)
+		}
+		return strings.Join(parts, " ")
+	}
+	for _, node := range rt.VNodeReport(top) {
+		fmt.Fprintf(w, "Node: %s, Ring: %s, Level: %d\n", node.NodeID, node.RingID, node.Level)
+		fmt.Fprintf(w, "Keys: %d, Mean per VNode: %.2f, Standard Deviation: %.2f, Peak/Mean: %.2f\n", node.Keys, node.Mean, node.Stdev, node.Imbalance.PeakToMean)
+		fmt.Fprintf(w, "Hottest: %s\n", format(node.Hottest))
+		fmt.Fprintf(w, "Coldest: %s\n", format(node.Coldest))
+		fmt.Fprintln(w, "----------------------------")
+	}
+}
+
+// printSystemVariance prints the system-wide variance and standard deviation for all nodes.
+func printSystemVariance(w io.Writer, rt *ringtree.Ring) {
+	loads, totalMean, totalVariance, totalStdDev := rt.GetSystemVariance()
+	fmt.Fprintf(w, "All Node Loads: %v\n", loads)
+	fmt.Fprintf(w, "Num nodes: %d\n", len(loads))
+	fmt.Fprintln(w, "----------------------------")
+	fmt.Fprintf(w, "Total Mean: %.2f\n", totalMean)
+	fmt.Fprintf(w, "Total Variance: %.2f\n", totalVariance)
+	fmt.Fprintf(w, "Total Standard Deviation: %.2f\n", totalStdDev)
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printImbalance prints the peak-to-mean ratio, Gini coefficient and Jain's fairness index of the node
+// loads, system-wide and at each level.
+func printImbalance(w io.Writer, rt *ringtree.Ring) {
+	system, levels := rt.GetImbalance()
+	line := func(label string, imbalance ringtree.Imbalance) {
+		fmt.Fprintf(w, "%s: peak/mean %.3f, Gini %.3f, Jain %.3f\n", label, imbalance.PeakToMean, imbalance.Gini, imbalance.Jain)
+	}
+	order := make([]int, 0, len(levels))
+	for level := range levels {
+		order = append(order, level)
+	}
+	sort.Ints(order)
+	for _, level := range order {
+		line(fmt.Sprintf("Level %d", level), levels[level])
+	}
+	line("System", system)
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printUniformity prints the chi-square and Kolmogorov–Smirnov tests of the node loads against a uniform
+// spread of the keys.
+func printUniformity(w io.Writer, rt *ringtree.Ring) {
+	system := ringtree.EvaluateUniformity(rt).System
+	fmt.Fprintf(w, "Chi-square: %.2f (%d degrees of freedom), p = %.4g\n", system.ChiSquare, system.DegreesOfFreedom, system.ChiSquareP)
+	fmt.Fprintf(w, "Kolmogorov-Smirnov: D = %.4f, p = %.4g\n", system.KS, system.KSP)
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printHierarchyDetails prints the depth of the hierarchy, number of nodes, and number of rings at each level.
+func printHierarchyDetails(w io.Writer, rt *ringtree.Ring) {
+	// Get the hierarchy information
+	maxDepth, levelInfo, numKeys, numNodes := rt.GetHierarchyInfo()
+	// Loop through each level to print its details
+	for i := 0; i <= maxDepth; i++ {
+		if info, ok := levelInfo[i]; ok {
+			fmt.Fprintf(w, "Level: %d\n", info.Level)
+			fmt.Fprintf(w, "Number of Nodes: %d\n", info.NodeCount)
+			fmt.Fprintf(w, "Number of Rings: %d\n", info.RingCount)
+			fmt.Fprintln(w, "----------------------------")
+		}
+	}
+	fmt.Fprintf(w, "Total Depth of Hierarchy: %d\n", maxDepth)
+	fmt.Fprintln(w, "----------------------------")
+
+	fmt.Fprintf(w, "Total Number of Nodes: %d\n", numNodes)
+	fmt.Fprintf(w, "Total Number of Keys: %d\n", numKeys)
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printRemapStats prints how often keys were remapped, against the moves consistent hashing expects.
+func printRemapStats(w io.Writer, rt *ringtree.Ring) {
+	// Calculate stats from the remap results
+	_, total, avgRemapped, avgRatio := rt.Stats().RemapStats()
+
+	fmt.Fprintf(w, "Total Times Keys Remapped: %d\n", total)
+	fmt.Fprintf(w, "Average Remapped per Valid Entry: %.2f\n", avgRemapped)
+	fmt.Fprintf(w, "Average Ratio (Actual/Expected): %.2f\n", avgRatio)
+	fmt.Fprintln(w, "----------------------------")
+
+	/* Print each remap entry for detailed insights
+	fmt.Fprintln(w, "Detailed Remap Information:")
+	for i, remap := range remaps {
+		for actual, expected := range remap {
+			if actual == 0 {
+				continue // Skip entries with 0 actual remaps
+			}
+			fmt.Fprintf(w, "Entry %d - Actual: %d, Expected: %d\n", i+1, actual, expected)
+		}
+	}
+	fmt.Fprintln(w, "----------------------------")*/
+}
+
+// printOperationTimeStats prints the mean, variance and standard deviation of the time taken by each operation.
+func printOperationTimeStats(w io.Writer, rt *ringtree.Ring) {
+	stats := rt.Stats().TimeStats()
+
+	fmt.Fprintln(w, "Operation Time Statistics:")
+	fmt.Fprintln(w, "-----------------------------------------------------")
+	fmt.Fprintf(w, "%-20s %-15s %-15s %-15s\n", "Operation", "Mean (µs)", "Variance", "StdDev")
+
+	for operation, stat := range stats {
+		fmt.Fprintf(w, "%-20s %-15.2f %-15.2f %-15.2f\n", operation, stat["Mean"], stat["Variance"], stat["Stdev"])
+	}
+	fmt.Fprintln(w, "-----------------------------------------------------")
+}
+
+// printLatencyPercentiles prints the tail latency of each operation, overall and on each level.
+func printLatencyPercentiles(w io.Writer, rt *ringtree.Ring) {
+	stats := rt.Stats()
+	levels := stats.LevelPercentiles()
+	operations := make([]string, 0, len(levels))
+	for operation := range levels {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	fmt.Fprintln(w, "Operation Latency Percentiles:")
+	fmt.Fprintln(w, "--------------------------------------------------------------------------------------")
+	fmt.Fprintf(w, "%-20s %-7s %-9s %-12s %-12s %-12s %-12s\n", "Operation", "Level", "Count", "p50", "p90", "p99", "p999")
+	overall := stats.Percentiles()
+	for _, operation := range operations {
+		p := overall[operation]
+		fmt.Fprintf(w, "%-20s %-7s %-9d %-12v %-12v %-12v %-12v\n", operation, "all", p.Count, p.P50, p.P90, p.P99, p.P999)
+		byLevel := levels[operation]
+		if len(byLevel) < 2 {
+			continue
+		}
+		depths := make([]int, 0, len(byLevel))
+		for level := range byLevel {
+			depths = append(depths, level)
+		}
+		sort.Ints(depths)
+		for _, level := range depths {
+			p := byLevel[level]
+			fmt.Fprintf(w, "%-20s %-7d %-9d %-12v %-12v %-12v %-12v\n", "", level, p.Count, p.P50, p.P90, p.P99, p.P999)
+		}
+	}
+	fmt.Fprintln(w, "--------------------------------------------------------------------------------------")
+}
+
+// printStabilityReport prints the keys moved at each step of a scaling plan in the ring tree and on a flat ring.
+func printStabilityReport(w io.Writer, report *ringtree.StabilityReport) {
+	fmt.Fprintf(w, "Ownership Stability (%d keys):\n", report.Keys)
+	fmt.Fprintln(w, "-----------------------------------------------------------------------")
+	fmt.Fprintf(w, "%-6s %-8s %-12s %-12s %-14s %-14s\n", "Step", "Nodes", "Tree Moved", "Flat Moved", "Tree Total", "Flat Total")
+	for i, step := range report.Steps {
+		fmt.Fprintf(w, "%-6d %-8d %-12d %-12d %-14d %-14d\n", i+1, step.Nodes, step.TreeMoved, step.FlatMoved, step.TreeTotal, step.FlatTotal)
+	}
+	fmt.Fprintln(w, "-----------------------------------------------------------------------")
+	fmt.Fprintf(w, "Nodes receiving keys: %d (tree), %d (flat)\n", len(report.TreeByNode), len(report.FlatByNode))
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printComparisonReport prints the keys moved and the load spread of a flat ring, a bounded-load ring and a
+// ring tree after each step of a scaling plan.
+func printComparisonReport(w io.Writer, report *ringtree.ComparisonReport) {
+	fmt.Fprintf(w, "Placement Comparison (%d keys, bounded-load ε %.2f):\n", report.Keys, report.Epsilon)
+	fmt.Fprintln(w, "-----------------------------------------------------------------------")
+	fmt.Fprintf(w, "%-6s %-8s %-12s %-12s %-12s\n", "Step", "Nodes", "Flat Moved", "Bound Moved", "Tree Moved")
+	for i, step := range report.Steps {
+		fmt.Fprintf(w, "%-6d %-8d %-12d %-12d %-12d\n", i, step.Target, step.Flat.Moved, step.Bounded.Moved, step.Tree.Moved)
+	}
+	fmt.Fprintf(w, "%-15s %-12d %-12d %-12d\n", "Total", report.FlatMoved, report.BoundedMoved, report.TreeMoved)
+	fmt.Fprintln(w, "-----------------------------------------------------------------------")
+	fmt.Fprintf(w, "%-6s %-8s %-12s %-12s %-12s %-8s %-8s %-8s\n", "Step", "Nodes", "Flat Var", "Bound Var", "Tree Var", "Flat Pk", "Bound Pk", "Tree Pk")
+	for i, step := range report.Steps {
+		fmt.Fprintf(w, "%-6d %-8d %-12.2f %-12.2f %-12.2f %-8.2f %-8.2f %-8.2f\n", i, step.Target,
+			step.Flat.Variance, step.Bounded.Variance, step.Tree.Variance, step.Flat.Peak, step.Bounded.Peak, step.Tree.Peak)
+	}
+	fmt.Fprintln(w, "----------------------------")
+}
+
+// printSimReport prints the outcome of a churn simulation: the operations run, the keys remapped by
+// membership changes, the shape of the tree over the run and the latency of each operation.
+func printSimReport(w io.Writer, report *ringtree.SimReport) {
+	fmt.Fprintf(w, "Churn Simulation (%d operations: %d inserts, %d lookups):\n", report.Operations, report.Inserts, report.Lookups)
+	fmt.Fprintf(w, "Joins: %d, Leaves: %d, Final Nodes: %d, Final Keys: %d\n", report.Joins, report.Leaves, report.Nodes, report.Keys)
+	fmt.Fprintf(w, "Keys Remapped: %d (%d by churn, %.2f per join or leave)\n", report.Remapped, report.ChurnRemapped, report.PerChange)
+	fmt.Fprintln(w, "----------------------------")
+	fmt.Fprintf(w, "%-12s %-8s %-8s %-10s %-10s\n", "Operation", "Depth", "Nodes", "Keys", "Remapped")
+	for _, s := range report.Shape {
+		fmt.Fprintf(w, "%-12d %-8d %-8d %-10d %-10d\n", s.Operation, s.Depth, s.Nodes, s.Keys, s.Remapped)
+	}
+	fmt.Fprintf(w, "Max Depth: %d\n", report.MaxDepth)
+	fmt.Fprintln(w, "----------------------------")
+	operations := make([]string, 0, len(report.Latency))
+	for operation := range report.Latency {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	fmt.Fprintf(w, "%-20s %-9s %-12s %-12s %-12s\n", "Operation", "Count", "p50", "p99", "Max")
+	for _, operation := range operations {
+		p := report.Latency[operation]
+		fmt.Fprintf(w, "%-20s %-9d %-12v %-12v %-12v\n", operation, p.Count, p.P50, p.P99, p.Max)
+	}
+	fmt.Fprintln(w, "----------------------------")
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+const shellHelp = `Commands:
+  insert-node [id] [threshold]   add a node to the root ring (threshold τ by default)
+  remove-node id                 remove a node anywhere in the tree
+  insert-key key...              insert keys
+  remove-key key...              remove keys
+  remove-prefix prefix           remove every key starting with prefix in one batch
+  lookup key...                  print the node holding each key
+  split id                       replace a node with a subring
+  collapse id                    replace a subring with a single node
+  scale-down n [apply]           pick n nodes to remove with least key movement; apply removes them
+  tree                           print the tree
+  stats                          print hierarchy and load statistics
+  save                           write the tree to the state file
+  exit                           save and leave the shell
+  quit                           leave the shell without saving
+`
+
+// shell loads the stored tree and runs an interactive prompt over it, printing the tree after every
+// mutation.
+func shell(args []string) error {
+	var s settings
+	fs := s.flagSet("shell", "")
+	fs.Parse(args)
+
+	rt, err := s.load()
+	if err != nil {
+		return err
+	}
+	sh := &shellSession{settings: &s, rt: rt, out: os.Stdout}
+	fmt.Fprintf(sh.out, "Loaded %s: %d nodes, %d keys. Type help for commands.\n", s.state, rt.Stats().Nodes(), rt.Stats().Keys())
+	return sh.run(os.Stdin)
+}
+
+// shellSession is the state of an interactive shell.
+type shellSession struct {
+	settings *settings
+	rt       *ringtree.Ring
+	out      io.Writer
+	modified bool
+}
+
+// run reads commands until exit, quit or the end of the input, which saves like exit.
+func (sh *shellSession) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(sh.out, "ringtree> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(sh.out)
+			break
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "exit":
+			return sh.save()
+		case "quit":
+			return nil
+		}
+		if err := sh.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintf(sh.out, "error: %v\n", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return sh.save()
+}
+
+// exec runs one command, printing the tree if it changed it.
+func (sh *shellSession) exec(name string, args []string) error {
+	rt := sh.rt
+	mutated := true
+	switch name {
+	case "help":
+		fmt.Fprint(sh.out, shellHelp)
+		return nil
+	case "insert-node":
+		id, threshold := "", sh.settings.tau
+		if len(args) > 0 {
+			id = args[0]
+		}
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid threshold %q", args[1])
+			}
+			threshold = n
+		}
+		if err := rt.InsertNode(ringtree.NewNode(id, threshold)); err != nil {
+			return err
+		}
+	case "remove-node":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: remove-node id")
+		}
+		node, ring, err := rt.LocateNode(args[0])
+		if err != nil {
+			return err
+		}
+		if err := ring.RemoveNode(node); err != nil {
+			return err
+		}
+	case "insert-key", "remove-key":
+		for _, key := range args {
+			var err error
+			if name == "insert-key" {
+				err = rt.InsertKey(key)
+			} else {
+				err = rt.RemoveKey(key)
+			}
+			if err != nil {
+				return fmt.Errorf("key %s: %v", key, err)
+			}
+		}
+	case "remove-prefix":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: remove-prefix prefix")
+		}
+		results, err := rt.RemoveKeysByPrefix(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(sh.out, "Removed %d keys.\n", len(results))
+	case "split":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: split id")
+		}
+		if _, err := rt.Split(args[0]); err != nil {
+			return err
+		}
+	case "collapse":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: collapse id")
+		}
+		if _, err := rt.Collapse(args[0]); err != nil {
+			return err
+		}
+	case "scale-down":
+		if len(args) < 1 || len(args) > 2 || len(args) == 2 && args[1] != "apply" {
+			return fmt.Errorf("usage: scale-down n [apply]")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid node count %q", args[0])
+		}
+		victims, moved := rt.PlanScaleDown(n)
+		if len(victims) == 0 {
+			return fmt.Errorf("no node can be removed")
+		}
+		fmt.Fprintf(sh.out, "Remove %s, moving about %d keys.\n", strings.Join(victims, ", "), moved)
+		if len(args) == 1 {
+			mutated = false
+			break
+		}
+		if _, err := rt.ScaleDown(n); err != nil {
+			return err
+		}
+	case "lookup":
+		mutated = false
+		for _, key := range args {
+			nodeID, err := rt.Lookup(key)
+			if err != nil {
+				return fmt.Errorf("key %s: %v", key, err)
+			}
+			fmt.Fprintf(sh.out, "%s\t%s\n", key, nodeID)
+		}
+	case "tree":
+		mutated = false
+		printTree(sh.out, rt)
+	case "stats":
+		mutated = false
+		printHierarchyDetails(sh.out, rt)
+		printSystemVariance(sh.out, rt)
+	case "save":
+		return sh.save()
+	default:
+		return fmt.Errorf("unknown command %q, type help for commands", name)
+	}
+	if mutated {
+		sh.modified = true
+		printTree(sh.out, rt)
+	}
+	return nil
+}
+
+// save writes the tree to the state file if the session changed it.
+func (sh *shellSession) save() error {
+	if !sh.modified {
+		return nil
+	}
+	if err := sh.settings.save(sh.rt); err != nil {
+		return err
+	}
+	sh.modified = false
+	fmt.Fprintf(sh.out, "Saved %s.\n", sh.settings.state)
+	return nil
+}
+
+// printTree prints the tree with every ring's members indented below it, nodes showing their load against
+// their threshold.
+func printTree(w io.Writer, rt *ringtree.Ring) {
+	fmt.Fprintf(w, "%s (level %d)\n", rt.ID(), rt.Level())
+	printMembers(w, rt, "")
+}
+
+// printMembers prints the members of a ring below it, and their members in turn.
+func printMembers(w io.Writer, ring *ringtree.Ring, prefix string) {
+	infos := ring.MemberInfo()
+	for i, info := range infos {
+		branch, indent := "├── ", "│   "
+		if i == len(infos)-1 {
+			branch, indent = "└── ", "    "
+		}
+		switch info.Kind {
+		case ringtree.KindRing:
+			fmt.Fprintf(w, "%s%s%s (subring, level %d, %d keys)\n", prefix, branch, info.ID, info.Level, info.Keys)
+			if member, ok := ring.Member(info.ID); ok {
+				printMembers(w, member.(*ringtree.Ring), prefix+indent)
+			}
+		case ringtree.KindNode:
+			member, ok := ring.Member(info.ID)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s %d/%d %s\n", prefix, branch, info.ID, info.Load, info.Threshold, member.(*ringtree.Node).State())
+		default:
+			fmt.Fprintf(w, "%s%s%s (%s, %d keys)\n", prefix, branch, info.ID, info.Kind, info.Keys)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// simulate inserts random keys into a fresh tree and prints its statistics, or compares the key movement of
+// a scaling plan.
+func simulate(args []string) error {
+	var s settings
+	fs := s.flagSet("simulate", "")
+	keys := fs.Int("keys", 100000, "number of random keys to insert")
+	plan := fs.String("plan", "", "compare key movement of a scaling plan, as from:to:steps (e.g. 10:50:5)")
+	flat := fs.Bool("flat", false, "insert into a flat ring of d nodes instead of a ring tree")
+	spec := fs.String("workload", "uniform", "keys to insert: uniform, zipf[:s[:n]], hotspot[:prefix[:fraction]] or sequential")
+	remove := fs.Bool("remove", true, "remove the first 500 keys after inserting")
+	save := fs.Bool("save", false, "store the simulated tree in the state file")
+	export := fs.String("export", "", "also write the statistics to a .csv or .json file")
+	seriesPath := fs.String("series", "", "record node and ring loads over the run to a .csv or .json file")
+	every := fs.Int("every", 1000, "operations between two samples recorded with -series")
+	memProfile := fs.String("memprofile", "", "write a heap profile to this file after the simulation")
+	fs.Parse(args)
+
+	opts, err := s.options()
+	if err != nil {
+		return err
+	}
+	if *plan != "" {
+		return SimulateScalingPlan(*plan, *keys, s.d, opts)
+	}
+
+	rng := s.rng()
+	gen, err := parseWorkload(*spec, *keys, rng)
+	if err != nil {
+		return err
+	}
+	var rec *series
+	if *seriesPath != "" {
+		if *every < 1 {
+			return fmt.Errorf("invalid sampling interval %d", *every)
+		}
+		rec = &series{every: *every}
+	}
+	var rt *ringtree.Ring
+	if *flat {
+		fmt.Println("\nInserting keys into Flat Ring...")
+		rt, err = SimulateInsertionsFlat(*keys, s.tau, s.d, gen, rng, opts, rec)
+	} else {
+		fmt.Println("\nInserting keys into RingTree...")
+		rt, err = SimulateInsertions(*keys, s.tau, s.d, gen, rng, *remove, opts, rec)
+	}
+	if err != nil {
+		return err
+	}
+	if rec != nil && rec.recorder != nil {
+		if err := writeStats(*seriesPath, rec.recorder.Export); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\n--- Stats ---")
+	printHierarchyDetails(os.Stdout, rt)
+	printSystemVariance(os.Stdout, rt)
+	printImbalance(os.Stdout, rt)
+	printUniformity(os.Stdout, rt)
+	printRemapStats(os.Stdout, rt)
+	printOperationTimeStats(os.Stdout, rt)
+	printLatencyPercentiles(os.Stdout, rt)
+	if *export != "" {
+		if err := writeStats(*export, rt.ExportStats); err != nil {
+			return err
+		}
+	}
+	if *memProfile != "" {
+		logMemoryUsage(os.Stderr, "simulate")
+		if err := memoryProfile(*memProfile); err != nil {
+			return err
+		}
+	}
+	if *save {
+		return s.save(rt)
+	}
+	return nil
+}
+
+// parseWorkload returns the key generator a -workload flag selects, drawing from rng if it is not nil.
+// Zipfian keys are drawn from n keys, by default as many as the keys to insert.
+func parseWorkload(spec string, keys int, rng *rand.Rand) (workload.Generator, error) {
+	parts := strings.Split(spec, ":")
+	arg := func(i int, fallback string) string {
+		if i < len(parts) && parts[i] != "" {
+			return parts[i]
+		}
+		return fallback
+	}
+	switch parts[0] {
+	case "uniform":
+		return workload.Uniform(rng, 20), nil
+	case "zipf":
+		s, err := strconv.ParseFloat(arg(1, "1"), 64)
+		if err != nil || s < 0 {
+			return nil, fmt.Errorf("invalid Zipf exponent %q", arg(1, ""))
+		}
+		n, err := strconv.Atoi(arg(2, strconv.Itoa(max(keys, 1))))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid Zipf key space %q", arg(2, ""))
+		}
+		return workload.Zipf(rng, "key", s, n), nil
+	case "hotspot":
+		fraction, err := strconv.ParseFloat(arg(2, "0.5"), 64)
+		if err != nil || fraction < 0 || fraction > 1 {
+			return nil, fmt.Errorf("invalid hotspot fraction %q", arg(2, ""))
+		}
+		return workload.Hotspot(rng, arg(1, "hot/"), fraction, workload.Uniform(rng, 20)), nil
+	case "sequential":
+		return workload.Sequential("key", 0), nil
+	default:
+		return nil, fmt.Errorf("unknown workload %q", spec)
+	}
+}
+
+// writeStats writes statistics to a file with export, as CSV or JSON by the extension of the file.
+func writeStats(path string, export func(io.Writer, ringtree.Format) error) error {
+	format := ringtree.FormatJSON
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		format = ringtree.FormatCSV
+	case ".json":
+	default:
+		return fmt.Errorf("unknown export format %q, want .csv or .json", ext)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// memoryProfile writes a heap profile of the process to a file.
+func memoryProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create memory profile: %v", err)
+	}
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write memory profile: %v", err)
+	}
+	return f.Close()
+}
+
+// logMemoryUsage prints the memory allocated by the process after an operation.
+func logMemoryUsage(w io.Writer, operation string) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	fmt.Fprintf(w, "%s: Alloc = %v KB, TotalAlloc = %v KB, Sys = %v KB, NumGC = %v\n",
+		operation, m.Alloc/1024, m.TotalAlloc/1024, m.Sys/1024, m.NumGC)
+}
+
+// series samples the loads of a simulated tree every few operations.
+type series struct {
+	recorder *ringtree.Recorder
+	every    int
+	ops      int
+}
+
+// step counts an operation on rt, and samples its loads when one is due. A nil series does nothing.
+func (s *series) step(rt *ringtree.Ring) {
+	if s == nil {
+		return
+	}
+	if s.recorder == nil {
+		s.recorder = ringtree.NewRecorder(rt, 100000)
+	}
+	s.ops++
+	if s.ops%s.every == 0 {
+		s.recorder.Sample()
+	}
+}
+
+// SimulateScalingPlan reports the keys moved by a from:to:steps scaling plan in a ring tree and a flat ring.
+func SimulateScalingPlan(spec string, numKeys, d int, opts []ringtree.Option) error {
+	var from, to, steps int
+	if _, err := fmt.Sscanf(spec, "%d:%d:%d", &from, &to, &steps); err != nil {
+		return fmt.Errorf("invalid scaling plan %q: %v", spec, err)
+	}
+	report, err := ringtree.SimulateScalingPlan(ringtree.GrowthPlan(from, to, steps), numKeys, d, opts...)
+	if err != nil {
+		return fmt.Errorf("error simulating scaling plan: %v", err)
+	}
+	printStabilityReport(os.Stdout, report)
+	return nil
+}
+
+// SimulateInsertionsFlat inserts keys into a flat consistent hashing ring. The compare command scales a
+// flat ring alongside a ring tree.
+func SimulateInsertionsFlat(numKeys, τ, d int, gen workload.Generator, rng *rand.Rand, opts []ringtree.Option, rec *series) (*ringtree.Ring, error) {
+	rt := ringtree.New(1439, opts...) // Initialize a flat ring with capacity numKeys
+
+	for i := 0; i < d; i++ {
+		node := ringtree.NewNode(ringtree.NodeID(rng), τ) // Keep the threshold large to prevent splitting
+		rt.InsertNode(node)
+	}
+
+	for i := 0; i < numKeys; i++ {
+		key := gen.Next()
+		err := rt.InsertKey(key)
+		if errors.Is(err, ringtree.ErrKeyExists) {
+			continue // Skewed workloads repeat keys
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error inserting key: %v", err)
+		}
+		rec.step(rt)
+	}
+	return rt, nil
+}
+
+// SimulateInsertions simulates the insertion of keys into a hierarchical RingTree structure
+func SimulateInsertions(numKeys, τ, d int, gen workload.Generator, rng *rand.Rand, remove bool, opts []ringtree.Option, rec *series) (*ringtree.Ring, error) {
+	rt := ringtree.New(d, opts...)                    // Start with an empty RingTree
+	node := ringtree.NewNode(ringtree.NodeID(rng), τ) // Set a reasonable threshold for splitting
+	rt.InsertNode(node)
+
+	var keys []string
+
+	for i := 0; i < d; i++ {
+		node := ringtree.NewNode(ringtree.NodeID(rng), τ) // Keep the threshold large to prevent splitting
+		rt.InsertNode(node)
+	}
+
+	for i := 0; i < numKeys; i++ {
+		key := gen.Next()
+		err := rt.InsertKey(key)
+		if errors.Is(err, ringtree.ErrKeyExists) {
+			continue // Skewed workloads repeat keys
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error inserting key: %v", err)
+		}
+		keys = append(keys, key)
+		rec.step(rt)
+	}
+
+	if remove {
+		fmt.Printf("\n\nRemoving...\n\n")
+
+		for i := 0; i < 500 && i < len(keys); i++ {
+			err := rt.RemoveKey(keys[i])
+			if err != nil {
+				return nil, fmt.Errorf("error removing key: %v", err)
+			}
+			rec.step(rt)
+		}
+	}
+
+	return rt, nil
+}
@@ -0,0 +1,60 @@
+package ringtree
+
+import "sync"
+
+// Aggregate maps every physical node below the ring with mapFn and combines the results with reduceFn,
+// working through the subrings in parallel. Results are combined in a fixed order, by ring and then by
+// member ID, so reduceFn need only be associative. An empty tree aggregates to the zero value of T.
+//
+// mapFn runs under the read lock of the node's ring, and may run concurrently for nodes of different rings;
+// it must not modify the tree.
+//
+//	keys := ringtree.Aggregate(rt, func(n *ringtree.Node) int { return n.Load() }, func(a, b int) int { return a + b })
+func Aggregate[T any](r *Ring, mapFn func(*Node) T, reduceFn func(T, T) T) T {
+	result, _ := aggregate(r, mapFn, reduceFn)
+	return result
+}
+
+// aggregate aggregates the nodes below the ring, and reports whether it found any.
+func aggregate[T any](r *Ring, mapFn func(*Node) T, reduceFn func(T, T) T) (T, bool) {
+	members := r.sortedMembers()
+
+	// Each subring is aggregated in its own goroutine, into the slot of its position among the members
+	type partial struct {
+		value T
+		ok    bool
+	}
+	partials := make([]partial, len(members))
+	var wg sync.WaitGroup
+	for i, member := range members {
+		if subring, ok := member.(*Ring); ok {
+			wg.Add(1)
+			go func(i int, subring *Ring) {
+				defer wg.Done()
+				partials[i].value, partials[i].ok = aggregate(subring, mapFn, reduceFn)
+			}(i, subring)
+		}
+	}
+
+	r.RLock()
+	for i, member := range members {
+		if node, ok := member.(*Node); ok && r.members[node.id] == node {
+			partials[i] = partial{value: mapFn(node), ok: true}
+		}
+	}
+	r.RUnlock()
+	wg.Wait()
+
+	var result T
+	found := false
+	for _, p := range partials {
+		switch {
+		case !p.ok:
+		case !found:
+			result, found = p.value, true
+		default:
+			result = reduceFn(result, p.value)
+		}
+	}
+	return result, found
+}
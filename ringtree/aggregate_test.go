@@ -0,0 +1,40 @@
+package ringtree
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	sum := func(a, b int) int { return a + b }
+	if got := Aggregate(New(3), func(n *Node) int { return n.load }, sum); got != 0 {
+		t.Errorf("expected an empty tree to aggregate to 0, got %d", got)
+	}
+
+	rt, _ := buildTree(t, 3, []string{"A"}, 5, 200)
+	if rt.GetDepth() < 1 {
+		t.Fatal("expected the tree to have subrings")
+	}
+	ids := rt.NodeIDs()
+	checkNum(Aggregate(rt, func(n *Node) int { return n.load }, sum), 200, t)
+	checkNum(Aggregate(rt, func(*Node) int { return 1 }, sum), len(ids), t)
+	heaviest := Aggregate(rt, func(n *Node) int { return n.load }, func(a, b int) int { return max(a, b) })
+	loads, _, _, _ := rt.GetSystemVariance()
+	sort.Ints(loads)
+	checkNum(heaviest, loads[len(loads)-1], t)
+
+	// Results are combined in the same order each time, so even a non-commutative reduce is repeatable
+	concat := func(a, b string) string { return a + "," + b }
+	first := Aggregate(rt, func(n *Node) string { return n.id }, concat)
+	for i := 0; i < 5; i++ {
+		if got := Aggregate(rt, func(n *Node) string { return n.id }, concat); got != first {
+			t.Fatalf("got %q, then %q", first, got)
+		}
+	}
+	got := strings.Split(first, ",")
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(ids, ",") {
+		t.Errorf("expected every node once, got %v", got)
+	}
+}
@@ -0,0 +1,309 @@
+package ringtree
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Gossip message types of the anti-entropy exchange.
+const (
+	DigestMessage = "topology.digest" // Carries a Digest; answered with the receiver's Digest
+	PullMessage   = "topology.pull"   // Carries the puller's Digest; answered with a DeltaMessage
+	DeltaMessage  = "topology.delta"  // Carries the rings that differ from the puller's Digest
+)
+
+// ErrUnknownMessage is returned by Exchange for a message type it does not answer.
+var ErrUnknownMessage = errors.New("unknown message type")
+
+// Digest summarizes the topology of a tree: its epoch and a hash of every ring's vnodes and members.
+// Replicas with equal digests route every key alike.
+type Digest struct {
+	Epoch uint64            `json:"epoch"`
+	Rings map[string]uint64 `json:"rings"`
+}
+
+// Equal reports whether two digests describe the same topology.
+func (d Digest) Equal(other Digest) bool {
+	if len(d.Rings) != len(other.Rings) {
+		return false
+	}
+	for id, h := range d.Rings {
+		if oh, ok := other.Rings[id]; !ok || oh != h {
+			return false
+		}
+	}
+	return true
+}
+
+// behind reports whether a replica with digest d should pull from one with digest other: replicas order
+// their topologies by epoch, then by hash, so exactly one of two differing replicas pulls.
+func (d Digest) behind(other Digest) bool {
+	if d.Equal(other) {
+		return false
+	}
+	if d.Epoch != other.Epoch {
+		return d.Epoch < other.Epoch
+	}
+	return d.fingerprint() < other.fingerprint()
+}
+
+// fingerprint hashes the whole digest.
+func (d Digest) fingerprint() uint64 {
+	ids := make([]string, 0, len(d.Rings))
+	for id := range d.Rings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	h := fnv.New64a()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		binary.Write(h, binary.BigEndian, d.Rings[id])
+	}
+	return h.Sum64()
+}
+
+// delta is the payload of a DeltaMessage.
+type delta struct {
+	Epoch uint64     `json:"epoch"`
+	Rings []ringFile `json:"rings"`
+}
+
+// Replica is another copy of the tree that anti-entropy exchanges gossip messages with. A *Ring is a Replica
+// of itself; replicas in other processes are reached through a transport.
+type Replica interface {
+	// Exchange sends a message to the replica and returns its answer.
+	Exchange(msg Message) (Message, error)
+}
+
+// Digest returns the topology digest of the tree.
+func (r *Ring) Digest() Digest {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.root().digest()
+}
+
+// digest computes the topology digest of the tree below the ring (assuming the tree's writer lock is held).
+func (r *Ring) digest() Digest {
+	d := Digest{Epoch: r.Epoch(), Rings: make(map[string]uint64)}
+	queue := []*Ring{r}
+	for len(queue) > 0 {
+		ring := queue[0]
+		queue = queue[1:]
+		ring.RLock()
+		d.Rings[ring.id] = ring.topologyHash()
+		for _, member := range ring.members {
+			if subring, ok := member.(*Ring); ok {
+				queue = append(queue, subring)
+			}
+		}
+		ring.RUnlock()
+	}
+	return d
+}
+
+// topologyHash hashes the ring's capacity, vnodes and members, not the keys they hold (assuming the mutex
+// is already locked).
+func (r *Ring) topologyHash() uint64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.BigEndian, int64(r.maxCount))
+	for _, vNode := range circleVNodes(r.circle) {
+		binary.Write(h, binary.BigEndian, vNode.hash)
+		h.Write([]byte(vNode.nodeID))
+		h.Write([]byte{0})
+	}
+
+	ids := make([]string, 0, len(r.members))
+	for id := range r.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		member := r.members[id]
+		binary.Write(h, binary.BigEndian, int64(member.Kind()))
+		if node, ok := member.(*Node); ok {
+			binary.Write(h, binary.BigEndian, int64(node.threshold))
+			binary.Write(h, binary.BigEndian, int64(node.state))
+		}
+	}
+	return h.Sum64()
+}
+
+// Exchange answers an anti-entropy message from another replica, making the ring a Replica of its own tree.
+func (r *Ring) Exchange(msg Message) (Message, error) {
+	var remote Digest
+	if err := json.Unmarshal(msg.Payload, &remote); err != nil {
+		return Message{}, fmt.Errorf("error decoding digest: %v", err)
+	}
+
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	root := r.root()
+	switch msg.Type {
+	case DigestMessage:
+		return root.message(DigestMessage, root.digest())
+	case PullMessage:
+		d := delta{Epoch: root.Epoch()}
+		var err error
+		d.Rings, err = root.deltaFrom(remote)
+		if err != nil {
+			return Message{}, err
+		}
+		return root.message(DeltaMessage, d)
+	default:
+		return Message{}, ErrUnknownMessage
+	}
+}
+
+// deltaFrom returns the highest rings whose topology differs from a remote digest, with everything below
+// them (assuming the tree's writer lock is held).
+func (r *Ring) deltaFrom(remote Digest) ([]ringFile, error) {
+	r.RLock()
+	h := r.topologyHash()
+	r.RUnlock()
+	if remote.Rings[r.id] != h {
+		file, err := r.encode()
+		if err != nil {
+			return nil, err
+		}
+		return []ringFile{file}, nil
+	}
+
+	var files []ringFile
+	for _, subring := range r.subrings() {
+		sub, err := subring.deltaFrom(remote)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// SyncWith runs one anti-entropy round with a replica: the two exchange digests, and if the ring's tree is
+// behind it pulls the rings that differ. A pulled ring replaces the local one with everything below it,
+// keys included. Reports whether anything was pulled.
+func (r *Ring) SyncWith(peer Replica) (bool, error) {
+	local := r.Digest()
+	msg, err := r.message(DigestMessage, local)
+	if err != nil {
+		return false, err
+	}
+	answer, err := peer.Exchange(msg)
+	if err != nil {
+		return false, err
+	}
+	var remote Digest
+	if err := json.Unmarshal(answer.Payload, &remote); err != nil {
+		return false, fmt.Errorf("error decoding digest: %v", err)
+	}
+	if !local.behind(remote) {
+		return false, nil
+	}
+
+	if msg, err = r.message(PullMessage, local); err != nil {
+		return false, err
+	}
+	if answer, err = peer.Exchange(msg); err != nil {
+		return false, err
+	}
+	var d delta
+	if err := json.Unmarshal(answer.Payload, &d); err != nil {
+		return false, fmt.Errorf("error decoding delta: %v", err)
+	}
+	return true, r.applyDelta(d)
+}
+
+// RunAntiEntropy runs an anti-entropy round with a randomly chosen replica every interval until ctx is
+// done. Failed rounds are logged and retried with the next replica.
+func (r *Ring) RunAntiEntropy(ctx context.Context, interval time.Duration, peers ...Replica) error {
+	if len(peers) == 0 {
+		return errors.New("no replicas to exchange digests with")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			peer := peers[rand.Intn(len(peers))]
+			if pulled, err := r.SyncWith(peer); err != nil {
+				r.logf("Anti-entropy round failed: %v.\n", err)
+			} else if pulled {
+				r.logf("Pulled topology at epoch %d.\n", r.Epoch())
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// applyDelta replaces the rings of a delta in the local tree and adopts the remote epoch.
+func (r *Ring) applyDelta(d delta) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.settleRemaps()
+	root := r.root()
+	for _, file := range d.Rings {
+		ring := root.findRing(file.ID)
+		if ring == nil {
+			return fmt.Errorf("delta replaces unknown ring %s", file.ID)
+		}
+		if err := ring.adopt(file); err != nil {
+			return err
+		}
+	}
+	root.hub.mu.Lock()
+	root.hub.epoch = d.Epoch
+	root.hub.mu.Unlock()
+	return nil
+}
+
+// findRing searches the tree below the ring for a ring with the given ID.
+func (r *Ring) findRing(id string) *Ring {
+	if r.id == id {
+		return r
+	}
+	for _, subring := range r.subrings() {
+		if ring := subring.findRing(id); ring != nil {
+			return ring
+		}
+	}
+	return nil
+}
+
+// adopt replaces the ring's members and everything below them with a ring pulled from a replica (assuming
+// the tree's writer lock is held and no ring lock is).
+func (r *Ring) adopt(file ringFile) error {
+	r.Lock()
+	r.forEachRingNode(func(node *Node, _ *Ring) {
+		for _, keys := range node.keys {
+			for key := range keys {
+				r.index.delete(key)
+				r.countKey(key, -1)
+			}
+		}
+		r.stats.numNodes.add(-1)
+	})
+	r.members = make(map[string]Member)
+	r.circle = r.config.newCircle()
+	r.maxCount = file.MaxCount
+	r.Unlock()
+	return r.decode(file)
+}
+
+// message wraps an anti-entropy payload in a gossip envelope from the tree's root.
+func (r *Ring) message(msgType string, payload interface{}) (Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Version: gossipVersion, Type: msgType, Origin: r.root().id, Seq: r.gossip.seq.Add(1), Payload: data}, nil
+}
@@ -0,0 +1,69 @@
+package ringtree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSyncWith(t *testing.T) {
+	a := New(4)
+	a.InsertNode(NewNode("A", 1000))
+	a.InsertNode(NewNode("B", 1000))
+	for i := 0; i < 300; i++ {
+		key, _ := GenerateRandomString(20)
+		a.InsertKey(key)
+	}
+
+	var buf bytes.Buffer
+	if err := a.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Digest().Equal(a.Digest()) || b.Epoch() != a.Epoch() {
+		t.Fatal("expected a restored replica to have the same digest and epoch")
+	}
+
+	// Change a subring only, so the delta carries that subring alone
+	if _, err := a.Split("A"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Split("B"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.InsertNode(NewNode("C", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if pulled, err := a.SyncWith(b); err != nil || pulled {
+		t.Fatalf("replica ahead: got pulled %v (%v), want nothing pulled", pulled, err)
+	}
+	pulled, err := b.SyncWith(a)
+	if err != nil || !pulled {
+		t.Fatalf("replica behind: got pulled %v (%v), want a pull", pulled, err)
+	}
+	if !b.Digest().Equal(a.Digest()) || b.Epoch() != a.Epoch() {
+		t.Errorf("after the pull the replicas differ: %v and %v", b.Digest(), a.Digest())
+	}
+	if b.Stats().Nodes() != a.Stats().Nodes() || b.Stats().Keys() != a.Stats().Keys() {
+		t.Errorf("got %d nodes and %d keys, want %d and %d", b.Stats().Nodes(), b.Stats().Keys(), a.Stats().Nodes(), a.Stats().Keys())
+	}
+	keys, _ := a.KeysForNode("C")
+	for _, key := range keys {
+		if owner, err := b.Lookup(key); err != nil || owner != "C" {
+			t.Errorf("key %s: got owner %s (%v) on the replica, want C", key, owner, err)
+		}
+	}
+
+	// A change inside one subring is pulled as that subring alone
+	a.members["B"].(*Ring).InsertNode(NewNode("E", 1000))
+	files, err := a.deltaFrom(b.Digest())
+	if err != nil || len(files) != 1 || files[0].ID != "B" {
+		t.Fatalf("got delta %v (%v), want subring B alone", files, err)
+	}
+	b.SyncWith(a)
+	if !b.Digest().Equal(a.Digest()) {
+		t.Error("replicas differ after the second pull")
+	}
+}
@@ -0,0 +1,216 @@
+package ringtree
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// joinBatch collects node joins arriving within the configured batch window.
+type joinBatch struct {
+	mu      sync.Mutex
+	pending []*Node
+	timer   *time.Timer
+	err     error // Error from the last asynchronous flush
+}
+
+// EnqueueNode schedules a node to join the ring. Joins arriving within the batch window of each other are
+// applied together with a single remap pass and a single Watch batch. Without a batch window the node is
+// inserted immediately.
+func (r *Ring) EnqueueNode(node *Node) error {
+	if r.config.BatchWindow <= 0 {
+		return r.InsertNode(node)
+	}
+
+	b := r.batch
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pending := range b.pending {
+		if pending.id == node.id {
+			return errors.New("node is already queued")
+		}
+	}
+	b.pending = append(b.pending, node)
+
+	// Restart the debounce window on every arrival
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(r.config.BatchWindow, func() {
+		if err := r.Flush(); err != nil {
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+		}
+	})
+	return nil
+}
+
+// Flush applies all queued node joins immediately and returns the error of the last asynchronous flush, if any,
+// joined with the error of applying them.
+func (r *Ring) Flush() error {
+	b := r.batch
+	b.mu.Lock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	nodes := b.pending
+	b.pending = nil
+	err := b.err
+	b.err = nil
+	b.mu.Unlock()
+
+	if len(nodes) == 0 {
+		return err
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return errors.Join(err, r.insertNodesOp(nodes))
+}
+
+// Pending returns the number of node joins waiting for the batch window to close.
+func (r *Ring) Pending() int {
+	r.batch.mu.Lock()
+	defer r.batch.mu.Unlock()
+	return len(r.batch.pending)
+}
+
+// insertNodesOp inserts several nodes as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) insertNodesOp(nodes []*Node) error {
+	r.beginOp()
+	op := Op{Type: OpInsertNodes}
+	for _, node := range nodes {
+		op.Nodes = append(op.Nodes, node.id)
+		op.Thresholds = append(op.Thresholds, node.threshold)
+		r.config.startWarmUp(node)
+	}
+	return r.logOp(op, r.insertNodes(nodes))
+}
+
+// insertNodes adds several physical nodes at once, placing all of their vnodes before remapping keys in one pass.
+func (r *Ring) insertNodes(nodes []*Node) error {
+	r.settleRemaps()
+	defer r.timeTrack(time.Now(), "InsertNodes", "to insert "+strconv.Itoa(len(nodes))+" nodes on level "+strconv.Itoa(r.level))
+	r.hub.begin()
+	defer r.hub.end()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	if len(r.members)+len(r.reserved)+len(nodes) > r.maxCount {
+		return ErrRingAtCapacity
+	}
+	for _, node := range nodes {
+		if r.members[node.id] != nil || r.reserved[node.id] != nil {
+			return ErrNodeExists
+		}
+	}
+
+	// Place every vnode of every node before moving any keys
+	hadKeys := r.Size() > 0 && !r.IsEmpty()
+	var vNodes []VNode
+	for _, node := range nodes {
+		vNodes = append(vNodes, r.admitNode(node)...)
+	}
+	newVNodes := make(map[uint32]*Node)
+	for _, vNode := range r.circle.InsertBatch(vNodes) {
+		node := r.members[vNode.nodeID].(*Node)
+		node.keys[vNode.hash] = make(keySet)
+		newVNodes[vNode.hash] = node
+	}
+	r.logf("Placed %d virtual nodes for %d nodes on ring %s.\n", len(newVNodes), len(nodes), r.id)
+
+	if hadKeys || r.hasSubrings() {
+		r.remapBatch(newVNodes)
+	}
+
+	for _, node := range nodes {
+		if err := r.enforceSpread(node); err != nil {
+			return err
+		}
+	}
+
+	r.stats.numNodes.add(len(nodes))
+	for _, node := range nodes {
+		r.emit(Event{Type: NodeAdded, RingID: r.id, NodeID: node.id, Level: r.level, Remapped: r.stats.remapped.get()})
+	}
+	r.stats.calculateRemapComplexity()
+	return nil
+}
+
+// remapBatch moves keys from the vnodes succeeding a set of newly placed vnodes, checking each key only once.
+func (r *Ring) remapBatch(newVNodes map[uint32]*Node) {
+	budget := r.config.RemapBudget
+	// Collect the existing vnodes that lost part of their arc to the new vnodes
+	successors := make(map[uint32]string)
+	for vNodeHash := range newVNodes {
+		it := r.circle.Iter(vNodeHash)
+		next, nextID := it.Next()
+		for i := 0; i < r.circle.Size() && newVNodes[next] != nil; i++ {
+			next, nextID = it.Next()
+		}
+		if newVNodes[next] == nil {
+			successors[next] = nextID
+		}
+	}
+
+	for nextVNodeHash, nextID := range successors {
+		switch next := r.members[nextID].(type) {
+		case *Node:
+			for key, keyHash := range next.keys[nextVNodeHash] {
+				owner, _ := r.circle.FindClosest(keyHash)
+				if newNode, ok := newVNodes[owner]; ok && r.pinAllows(key, r, newNode) {
+					r.remapOrDefer(key, keyHash, next, r, nextVNodeHash, newNode, owner, &budget)
+				}
+			}
+		case *Ring:
+			next.Lock()
+			next.forEachRingNode(func(node *Node, ring *Ring) {
+				for vNodeHash, keyHashMap := range node.keys {
+					for key := range keyHashMap {
+						keyHash := r.config.keyHash(key, r.level)
+						owner, _ := r.circle.FindClosest(keyHash)
+						if newNode, ok := newVNodes[owner]; ok && r.pinAllows(key, r, newNode) {
+							r.remapOrDefer(key, keyHash, node, ring, vNodeHash, newNode, owner, &budget)
+						}
+					}
+				}
+			})
+			next.Unlock()
+		}
+	}
+}
+
+// hasSubrings reports whether any member of the ring is a subring (assuming mutex is already locked).
+func (r *Ring) hasSubrings() bool {
+	for _, member := range r.members {
+		if _, ok := member.(*Ring); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachNode calls fn for every physical node in the ring and its subrings, locking each subring while its
+// nodes are visited (assuming mutex is already locked).
+func (r *Ring) forEachNode(fn func(node *Node)) {
+	r.forEachRingNode(func(node *Node, _ *Ring) {
+		fn(node)
+	})
+}
+
+// forEachRingNode is forEachNode, also passing the ring each node is a member of.
+func (r *Ring) forEachRingNode(fn func(node *Node, ring *Ring)) {
+	for _, member := range r.members {
+		switch member := member.(type) {
+		case *Node:
+			fn(member, r)
+		case *Ring:
+			member.Lock()
+			member.forEachRingNode(fn)
+			member.Unlock()
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package ringtree
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEnqueueNodeBatchesJoins(t *testing.T) {
+	rt := New(5, WithBatchWindow(time.Hour))
+	rt.InsertNode(NewNode("", 1000))
+
+	var keys []string
+	for i := 0; i < 500; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+
+	events, cancel := rt.Watch(4)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		if err := rt.EnqueueNode(NewNode("", 1000)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	checkNum(rt.Pending(), 3, t)
+	checkNum(rt.Size(), 1, t)
+
+	if err := rt.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing joins: %v", err)
+	}
+	checkNum(rt.Pending(), 0, t)
+	checkNum(rt.Size(), 4, t)
+	checkNum(rt.circle.Size(), 4*defaultReplicas, t)
+
+	select {
+	case batch := <-events:
+		checkNum(len(batch), 3, t)
+		for _, event := range batch {
+			if event.Type != NodeAdded {
+				t.Errorf("expected NodeAdded event, got %s", event.Type)
+			}
+		}
+	default:
+		t.Fatalf("expected a single batch of join events")
+	}
+
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after batched join, got error: %v", key, err)
+		}
+	}
+}
+
+func TestEnqueueNodeFlushesAfterWindow(t *testing.T) {
+	rt := New(5, WithBatchWindow(10*time.Millisecond))
+	rt.InsertNode(NewNode("", 10))
+	rt.EnqueueNode(NewNode("", 10))
+	rt.EnqueueNode(NewNode("", 10))
+
+	size := 0
+	deadline := time.Now().Add(time.Second)
+	for size < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rt.RLock()
+		size = rt.Size()
+		rt.RUnlock()
+	}
+	checkNum(size, 3, t)
+}
+
+func TestFlushReportsAsynchronousError(t *testing.T) {
+	rt := New(5, WithBatchWindow(100*time.Millisecond))
+	rt.InsertNode(NewNode("A", 10))
+	rt.EnqueueNode(NewNode("A", 10))
+
+	// Wait for the window to close on the failing join
+	failed := false
+	deadline := time.Now().Add(time.Second)
+	for !failed && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		rt.batch.mu.Lock()
+		failed = rt.batch.err != nil
+		rt.batch.mu.Unlock()
+	}
+	if !failed {
+		t.Fatal("expected the asynchronous flush to fail")
+	}
+
+	// A later flush with queued joins applies them and still reports the earlier failure
+	rt.EnqueueNode(NewNode("B", 10))
+	if err := rt.Flush(); !errors.Is(err, ErrNodeExists) {
+		t.Errorf("expected the asynchronous flush error, got %v", err)
+	}
+	checkNum(rt.Size(), 2, t)
+	if err := rt.Flush(); err != nil {
+		t.Errorf("expected the error to be reported once, got %v", err)
+	}
+}
+
+func TestBatchedJoinMatchesInsertNode(t *testing.T) {
+	opts := []Option{WithMinDwell(time.Hour), WithWarmUp(0.25, time.Hour)}
+	inserted := New(5, opts...)
+	batched := New(5, append(opts, WithBatchWindow(time.Hour))...)
+	for _, rt := range []*Ring{inserted, batched} {
+		rt.InsertNode(NewNode("A", 1000))
+		for i := 0; i < 200; i++ {
+			rt.InsertKey("key-" + strconv.Itoa(i))
+		}
+	}
+
+	for _, id := range []string{"B", "C"} {
+		if err := inserted.InsertNode(NewNode(id, 1000)); err != nil {
+			t.Fatalf("unexpected error inserting %s: %v", id, err)
+		}
+		if err := batched.EnqueueNode(NewNode(id, 1000)); err != nil {
+			t.Fatalf("unexpected error enqueueing %s: %v", id, err)
+		}
+	}
+	if err := batched.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing joins: %v", err)
+	}
+
+	// Batched nodes start their warm-up ramp and dwell period like inserted ones, on the same vnodes
+	for _, id := range []string{"B", "C"} {
+		want, got := inserted.members[id].(*Node), batched.members[id].(*Node)
+		if !got.warming() || !got.dwelling(time.Hour) {
+			t.Errorf("expected batched node %s to be warming up and dwelling", id)
+		}
+		checkNum(len(got.keys), len(want.keys), t)
+		checkNum(got.load, want.load, t)
+	}
+	if !batched.Digest().Equal(inserted.Digest()) {
+		t.Error("expected batched joins to place the same vnodes as InsertNode")
+	}
+}
@@ -0,0 +1,114 @@
+package ringtree
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// benchMaxCount is the number of members per ring of the trees built for benchmarks, small enough that a
+// few nodes reach the deeper levels.
+const benchMaxCount = 4
+
+// benchThreshold is the threshold of benchmark nodes, high enough that inserting keys never splits one.
+const benchThreshold = 1 << 30
+
+// BenchCase is one configuration of the benchmark suite: a circle backend, a vnode count and a tree depth.
+type BenchCase struct {
+	Circle   string // "rbtree" or "array"
+	Replicas int    // Vnodes per member
+	Depth    int    // Levels of subrings below the root
+}
+
+// Name returns the case as a benchmark name, e.g. "rbtree/replicas=20/depth=2".
+func (c BenchCase) Name() string {
+	return fmt.Sprintf("%s/replicas=%d/depth=%d", c.Circle, c.Replicas, c.Depth)
+}
+
+// BenchCases returns every combination of circle backend, replica count and tree depth the suite covers.
+func BenchCases() []BenchCase {
+	var cases []BenchCase
+	for _, circle := range []string{"rbtree", "array"} {
+		for _, replicas := range []int{defaultReplicas, 8 * defaultReplicas} {
+			for _, depth := range []int{0, 2} {
+				cases = append(cases, BenchCase{Circle: circle, Replicas: replicas, Depth: depth})
+			}
+		}
+	}
+	return cases
+}
+
+// Build returns a tree of the case's depth holding the given number of seeded random keys, and the keys.
+// Nodes are added to the shallowest ring with a free slot and split when every ring is full, so the deepest
+// ring always has a free slot left.
+func (c BenchCase) Build(keys int) (*Ring, []string, error) {
+	rt := New(benchMaxCount, WithArrayCircle(c.Circle == "array"), WithReplicas(c.Replicas), WithRandSource(rand.NewSource(1)))
+	for i := 0; rt.Size() < benchMaxCount-1 || rt.GetDepth() < c.Depth; i++ {
+		if err := growTree(rt, "node"+strconv.Itoa(i), benchThreshold); err != nil {
+			return nil, nil, err
+		}
+	}
+	gen := workload.Uniform(rand.New(rand.NewSource(1)), 20)
+	inserted := make([]string, 0, keys)
+	for len(inserted) < keys {
+		key := gen.Next()
+		if err := rt.InsertKey(key); err != nil {
+			return nil, nil, err
+		}
+		inserted = append(inserted, key)
+	}
+	return rt, inserted, nil
+}
+
+// BenchOp is one operation of the benchmark suite, run once per iteration i against a tree built by a case.
+type BenchOp struct {
+	Name string
+	Run  func(rt *Ring, keys []string, i int) error
+}
+
+// BenchOps are the operations the suite times: inserting a new key, looking up a stored key, and inserting
+// a node into the shallowest ring with a free slot. The inserted node is removed again in the same
+// iteration so the tree keeps its shape, which times the keys it takes over and hands back as well.
+var BenchOps = []BenchOp{
+	{"InsertKey", func(rt *Ring, keys []string, i int) error {
+		return rt.InsertKey("bench-" + strconv.Itoa(i))
+	}},
+	{"Lookup", func(rt *Ring, keys []string, i int) error {
+		_, err := rt.Lookup(keys[i%len(keys)])
+		return err
+	}},
+	{"InsertNode", func(rt *Ring, keys []string, i int) error {
+		ring := rt.freeRing()
+		if ring == nil {
+			return ErrRingAtCapacity
+		}
+		node := NewNode("bench-node", benchThreshold)
+		if err := ring.InsertNode(node); err != nil {
+			return err
+		}
+		return ring.RemoveNode(node)
+	}},
+}
+
+// freeRing returns the shallowest ring of the tree with a free slot, or nil if every ring is full.
+func (r *Ring) freeRing() *Ring {
+	queue := []*Ring{r}
+	for len(queue) > 0 {
+		ring := queue[0]
+		queue = queue[1:]
+		ring.RLock()
+		if len(ring.members) < ring.maxCount {
+			ring.RUnlock()
+			return ring
+		}
+		for _, member := range ring.members {
+			if subring, ok := member.(*Ring); ok {
+				queue = append(queue, subring)
+			}
+		}
+		ring.RUnlock()
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ringtree
+
+import (
+	"runtime"
+	"testing"
+)
+
+// benchKeys is the number of keys in the trees the benchmarks run against.
+const benchKeys = 10000
+
+// benchOp runs the named operation of the suite against a tree built by every case.
+func benchOp(b *testing.B, name string) {
+	var op BenchOp
+	for _, o := range BenchOps {
+		if o.Name == name {
+			op = o
+		}
+	}
+	for _, c := range BenchCases() {
+		b.Run(c.Name(), func(b *testing.B) {
+			rt, keys, err := c.Build(benchKeys)
+			if err != nil {
+				b.Fatalf("expected the tree to be built, got error: %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := op.Run(rt, keys, i); err != nil {
+					b.Fatalf("%s failed at iteration %d: %v", name, i, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkInsertKey(b *testing.B) {
+	benchOp(b, "InsertKey")
+}
+
+func BenchmarkLookup(b *testing.B) {
+	benchOp(b, "Lookup")
+}
+
+func BenchmarkInsertNode(b *testing.B) {
+	benchOp(b, "InsertNode")
+}
+
+// BenchmarkKeyStorage reports the heap a tree retains per stored key, and the allocations made storing them.
+func BenchmarkKeyStorage(b *testing.B) {
+	c := BenchCase{Circle: "rbtree", Replicas: defaultReplicas}
+	var before, after runtime.MemStats
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		rt, _, err := c.Build(100000)
+		if err != nil {
+			b.Fatalf("expected the tree to be built, got error: %v", err)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/100000, "B/key")
+		runtime.KeepAlive(rt)
+	}
+}
+
+func TestBenchCases(t *testing.T) {
+	for _, c := range BenchCases() {
+		rt, keys, err := c.Build(100)
+		if err != nil {
+			t.Fatalf("%s: expected the tree to be built, got error: %v", c.Name(), err)
+		}
+		checkNum(rt.GetDepth(), c.Depth, t)
+		checkNum(len(keys), 100, t)
+		for _, op := range BenchOps {
+			for i := 0; i < 3; i++ {
+				if err := op.Run(rt, keys, i); err != nil {
+					t.Errorf("%s: expected %s to succeed, got error: %v", c.Name(), op.Name, err)
+				}
+			}
+		}
+		checkValid(rt, t)
+	}
+}
@@ -0,0 +1,74 @@
+package ringtree
+
+import "sort"
+
+// Transfer is a set of keys a joining node took over from one node, whose values it fetches from that node
+// before it serves them.
+type Transfer struct {
+	From string   // Node that held the keys before the join
+	Keys []string // Keys taken over, sorted
+}
+
+// joinState records the node each key moved off while a node joins, so JoinNode can tell the joining node
+// where to fetch its keys from (guarded by the tree's writer lock).
+type joinState struct {
+	node *Node
+	from map[string]string
+}
+
+// record notes the node a key moved off, if a node is joining. Only the first move of a key counts: the
+// node it came from first still holds its value.
+func (j *joinState) record(key string, from *Node) {
+	if j.node == nil || from == j.node {
+		return
+	}
+	if _, ok := j.from[key]; !ok {
+		j.from[key] = from.id
+	}
+}
+
+// JoinNode inserts a node that still has to fetch the keys it takes over, and returns them grouped by the
+// node each came from, in order of node ID. The node is inserted Joining: new keys are written to it, but
+// reads of its keys go to their replicas until SetNodeState marks it Up, once the transfers are done.
+// Deferred remaps are settled first, so every key the node takes over is included.
+func (r *Ring) JoinNode(node *Node) ([]Transfer, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	node.state = Joining
+	r.joins.node, r.joins.from = node, make(map[string]string)
+	defer func() {
+		r.joins.node, r.joins.from = nil, nil
+	}()
+	if err := r.insertNodeOp(node); err != nil {
+		return nil, err
+	}
+	r.settleRemaps()
+	return r.transfers(node.id), nil
+}
+
+// transfers groups the keys the joining node holds by the node they moved off (assuming the tree's writer
+// lock is held). Keys written to the node directly have nothing to fetch and are left out.
+func (r *Ring) transfers(nodeID string) []Transfer {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return nil // Split away while joining; its keys went to the subring's new nodes
+	}
+	bySource := make(map[string][]string)
+	ring.RLock()
+	for _, keys := range node.keys {
+		for key := range keys {
+			if from, ok := r.joins.from[key]; ok {
+				bySource[from] = append(bySource[from], key)
+			}
+		}
+	}
+	ring.RUnlock()
+
+	transfers := make([]Transfer, 0, len(bySource))
+	for from, keys := range bySource {
+		sort.Strings(keys)
+		transfers = append(transfers, Transfer{From: from, Keys: keys})
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].From < transfers[j].From })
+	return transfers
+}
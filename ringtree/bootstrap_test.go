@@ -0,0 +1,84 @@
+package ringtree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestJoinNode(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"eager":    {WithReplication(2)},
+		"deferred": {WithReplication(2), WithRemapBudget(5)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rt := New(5, opts...)
+			for _, id := range []string{"A", "B", "C"} {
+				rt.InsertNode(NewNode(id, 1000))
+			}
+			var keys []string
+			for i := 0; i < 300; i++ {
+				key, _ := GenerateRandomString(20)
+				keys = append(keys, key)
+				rt.InsertKey(key)
+			}
+			before := owners(rt, keys)
+
+			transfers, err := rt.JoinNode(NewNode("D", 1000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := mustNode(t, rt, "D")
+			checkNum(int(d.State()), int(Joining), t)
+
+			// Every key D took over is in the transfer from the node that held it
+			var held []string
+			d.Keys()(func(key string) bool {
+				held = append(held, key)
+				return true
+			})
+			var fetched []string
+			for _, transfer := range transfers {
+				if !sort.StringsAreSorted(transfer.Keys) {
+					t.Errorf("expected the keys from %s sorted", transfer.From)
+				}
+				for _, key := range transfer.Keys {
+					if before[key] != transfer.From {
+						t.Errorf("expected key %s from %s, got %s", key, before[key], transfer.From)
+					}
+					fetched = append(fetched, key)
+				}
+			}
+			if len(held) == 0 {
+				t.Fatal("expected D to take over keys")
+			}
+			sort.Strings(held)
+			sort.Strings(fetched)
+			if len(held) != len(fetched) {
+				t.Fatalf("expected transfers of the %d keys D holds, got %d", len(held), len(fetched))
+			}
+
+			// Until D is Up its keys are read from their replicas, while new keys may be written to it
+			for _, key := range held {
+				if owner, err := rt.Lookup(key); err != nil || owner == "D" {
+					t.Errorf("expected key %s to be read from a replica while D joins, got %s (%v)", key, owner, err)
+				}
+			}
+			if !d.acceptsWrites() {
+				t.Error("expected a Joining node to accept writes")
+			}
+			if err := rt.SetNodeState("D", Up); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range held {
+				if owner, _ := rt.Lookup(key); owner != "D" {
+					t.Errorf("expected key %s to be read from D once Up, got %s", key, owner)
+				}
+			}
+			checkValid(rt, t)
+
+			if _, err := rt.JoinNode(NewNode("D", 1000)); err != ErrNodeExists {
+				t.Errorf("expected ErrNodeExists, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package ringtree
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// splitWidth returns how many nodes a subring replacing node needs to absorb the node's load plus the
+// incoming load without splitting again, at least the configured seed count, and the threshold to give each
+// of them. When the subring cannot hold that many nodes and the load exceeds their full capacity, the
+// thresholds are raised in proportion.
+func (r *Ring) splitWidth(node *Node, incoming int) (int, int) {
+	threshold := r.config.childThreshold(r.level, node.threshold)
+	capacity := int(r.high * float64(threshold))
+	if capacity < 1 {
+		capacity = 1
+	}
+	perChild := capacity - int(r.config.Headroom*float64(threshold))
+	if perChild < 1 {
+		perChild = 1
+	}
+
+	total := node.load + incoming
+	children := (total + perChild - 1) / perChild
+	if seed := r.config.seedNodes(); children < seed {
+		children = seed
+	}
+	if children > r.maxCount {
+		children = r.maxCount
+	}
+	if children*capacity < total {
+		threshold = (threshold*total + children*capacity - 1) / (children * capacity)
+	}
+	return children, threshold
+}
+
+// InsertKeys inserts several keys at once. A node on a full ring that the keys would push past its threshold
+// is replaced by a single subring sized to the combined load, instead of being split two nodes at a time
+// level after level.
+func (r *Ring) InsertKeys(keys ...string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.insertKeysOp(keys)
+}
+
+// insertKeysOp inserts several keys as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) insertKeysOp(keys []string) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpInsertKeys, Keys: keys}, r.insertKeys(keys))
+}
+
+// insertKeys inserts several keys at once (assuming the tree's writer lock is held).
+func (r *Ring) insertKeys(keys []string) error {
+	// Group the incoming load by the node each key lands on
+	incoming := make(map[*Node]int)
+	parents := make(map[*Node]*Ring)
+	var nodes []*Node
+	for _, key := range keys {
+		node, parent, _, _, err := r.FindNode(key)
+		if err != nil {
+			return err
+		}
+		if _, ok := incoming[node]; !ok {
+			nodes = append(nodes, node)
+		}
+		incoming[node] += r.config.cost(key, nil)
+		parents[node] = parent
+	}
+
+	// Split in ID order, so a replay hands the same generated IDs to the same subrings
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	for _, node := range nodes {
+		load, parent := incoming[node], parents[node]
+		parent.RLock()
+		overflow := parent.Size() >= parent.maxCount && node.load+load > int(parent.high*float64(node.threshold)) &&
+			!node.dwelling(r.config.MinDwell) && (r.config.MaxDepth <= 0 || parent.level < r.config.MaxDepth)
+		parent.RUnlock()
+		if !overflow || r.deferChange(node) {
+			continue
+		}
+		r.logf("Adding size-tiered subring for node %s (Incoming load: %d).\n", node.id, load)
+		if _, err := parent.splitNode(node, load); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range keys {
+		if err := r.insertKey(key, r.config.cost(key, nil), false); err != nil {
+			return err
+		}
+		r.wal.applied = true
+	}
+	return nil
+}
+
+// LoadKeys inserts keys in bulk and is safe to call alongside other writers. Keys are hashed and partitioned
+// by the vnode they land on by parallelism worker goroutines; a parallelism below one uses GOMAXPROCS
+// workers. Inserts may restructure the tree, so the partitions are then inserted one after another in vnode
+// order, each under a single hold of the tree's writer lock, and the same keys always build the same tree.
+// Other writers get the lock between partitions. The first error stops the remaining partitions and is
+// returned.
+func (r *Ring) LoadKeys(keys []string, parallelism int) error {
+	if parallelism < 1 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	// Partition the keys by destination vnode, one chunk of keys per worker
+	chunks := make([]map[uint32][]string, parallelism)
+	size := (len(keys) + parallelism - 1) / parallelism
+	var wg sync.WaitGroup
+	for w := range chunks {
+		chunks[w] = make(map[uint32][]string)
+		batch := keys[min(w*size, len(keys)):min((w+1)*size, len(keys))]
+		wg.Add(1)
+		go func(batch []string, partitions map[uint32][]string) {
+			defer wg.Done()
+			r.RLock()
+			defer r.RUnlock()
+			for _, key := range batch {
+				vNodeHash, _ := r.circle.FindClosest(r.config.keyHash(key, r.level))
+				partitions[vNodeHash] = append(partitions[vNodeHash], key)
+			}
+		}(batch, chunks[w])
+	}
+	wg.Wait()
+
+	// Chunks are merged in worker order, so each partition keeps the keys in the order they were given
+	partitions := make(map[uint32][]string)
+	for _, chunk := range chunks {
+		for vNodeHash, batch := range chunk {
+			partitions[vNodeHash] = append(partitions[vNodeHash], batch...)
+		}
+	}
+	vNodeHashes := make([]uint32, 0, len(partitions))
+	for vNodeHash := range partitions {
+		vNodeHashes = append(vNodeHashes, vNodeHash)
+	}
+	sort.Slice(vNodeHashes, func(i, j int) bool { return vNodeHashes[i] < vNodeHashes[j] })
+
+	for _, vNodeHash := range vNodeHashes {
+		if err := r.loadPartition(partitions[vNodeHash]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPartition inserts the keys of one partition under a single hold of the tree's writer lock, logged as
+// one operation.
+func (r *Ring) loadPartition(keys []string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.loadPartitionOp(keys)
+}
+
+// loadPartitionOp inserts the keys of one partition as one logged operation (assuming the tree's writer
+// lock is held).
+func (r *Ring) loadPartitionOp(keys []string) error {
+	r.beginOp()
+	var err error
+	for _, key := range keys {
+		if err = r.insertKey(key, r.config.cost(key, nil), false); err != nil {
+			break
+		}
+		r.wal.applied = true
+	}
+	return r.logOp(Op{Type: OpLoadKeys, Keys: keys}, err)
+}
+
+// KeyResult is the outcome for one key of a batch operation.
+type KeyResult struct {
+	Key string
+	Err error // Nil if the operation succeeded for the key
+}
+
+// RemoveKeys removes several keys at once and returns the outcome for each, in order: ErrKeyNotFound for a
+// key the tree does not hold, including a repeat of a key already removed by the batch. Keys are grouped by
+// the node holding them and removed under one hold of its ring's lock, and underloaded subring nodes are
+// only removed once the whole batch is out, so deleting many keys does not collapse and rebuild subrings
+// along the way. The error reports a failure handling the underloaded nodes or logging the batch.
+func (r *Ring) RemoveKeys(keys []string) ([]KeyResult, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.removeKeys(keys)
+}
+
+// RemoveKeysByPrefix removes every key held below the ring that starts with prefix, such as all the keys
+// of one tenant, as a batch like RemoveKeys. Results are returned in key order. Keys held by custom members
+// are not found.
+func (r *Ring) RemoveKeysByPrefix(prefix string) ([]KeyResult, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	var keys []string
+	r.Lock()
+	r.forEachNode(func(node *Node) {
+		for _, keySet := range node.keys {
+			for key := range keySet {
+				if strings.HasPrefix(key, prefix) {
+					keys = append(keys, key)
+				}
+			}
+		}
+	})
+	r.Unlock()
+	sort.Strings(keys)
+	return r.removeKeys(keys)
+}
+
+// removeKeys removes a batch of keys (assuming the tree's writer lock is held).
+func (r *Ring) removeKeys(keys []string) ([]KeyResult, error) {
+	r.beginOp()
+	span := r.traceOp("RemoveKeys")
+	type heldKey struct {
+		i         int
+		vNodeHash uint32
+	}
+	results := make([]KeyResult, len(keys))
+	held := make(map[*Node][]heldKey)
+	parents := make(map[*Node]*Ring)
+	for i, key := range keys {
+		results[i].Key = key
+		r.settleKey(key)
+		node, parent, vNodeHash, err := r.locateKey(key)
+		var custom customRoute
+		if errors.As(err, &custom) {
+			writer, werr := custom.writer()
+			if werr == nil {
+				werr = writer.RemoveKey(key)
+			}
+			results[i].Err = werr
+			continue
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		held[node] = append(held[node], heldKey{i, vNodeHash})
+		parents[node] = parent
+	}
+
+	// Remove the keys of each node under one hold of its ring's lock
+	nodes := make([]*Node, 0, len(held))
+	for node, group := range held {
+		parent := parents[node]
+		parent.Lock()
+		for _, k := range group {
+			if _, ok := node.keys[k.vNodeHash][keys[k.i]]; !ok {
+				results[k.i].Err = ErrKeyNotFound
+				continue
+			}
+			r.dropKey(node, k.vNodeHash, keys[k.i])
+		}
+		parent.Unlock()
+		nodes = append(nodes, node)
+	}
+	var removed []string
+	for _, result := range results {
+		if result.Err == nil {
+			removed = append(removed, result.Key)
+			r.heat.forget(result.Key)
+			r.spreads.set(result.Key, 0)
+		}
+	}
+	r.logf("Removed %d of %d keys from %d nodes.\n", len(removed), len(keys), len(nodes))
+
+	// Only then remove the nodes left underloaded, in ID order so a replay removes them the same way
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	var err error
+	for _, node := range nodes {
+		// An earlier removal or merge may have taken the node or its subring out of the tree
+		if current, parent := r.findMember(node.id); current == node {
+			if err = r.relieveUnderflow(node, parent); err != nil {
+				break
+			}
+		}
+	}
+	span.end(err)
+	if len(removed) == 0 {
+		return results, err
+	}
+	r.wal.applied = true
+	return results, r.logOp(Op{Type: OpRemoveKeys, Keys: removed}, err)
+}
@@ -0,0 +1,277 @@
+package ringtree
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInsertKeysSizedSplit(t *testing.T) {
+	rt := New(2, WithCapacitySchedule([]int{2, 16}))
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+	}
+	if err := rt.InsertKeys(keys...); err != nil {
+		t.Fatalf("expected keys to be inserted, got error: %v", err)
+	}
+
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+
+	// Each overloaded node is replaced by one wide subring rather than a chain of two-node subrings
+	wide := false
+	for _, member := range rt.members {
+		if subring, ok := member.(*Ring); ok && len(subring.members) > 2 {
+			wide = true
+		}
+	}
+	if !wide {
+		t.Errorf("expected a subring with more than two nodes")
+	}
+}
+
+func TestSplitWidth(t *testing.T) {
+	rt := New(4)
+	node := NewNode("A", 10)
+	node.load = 10
+
+	children, threshold := rt.splitWidth(node, 1)
+	checkNum(children, 2, t)
+	checkNum(threshold, 10, t)
+
+	children, threshold = rt.splitWidth(node, 30)
+	checkNum(children, 4, t)
+	checkNum(threshold, 10, t)
+
+	// Capped at the ring's capacity, with thresholds raised to compensate
+	children, threshold = rt.splitWidth(node, 70)
+	checkNum(children, 4, t)
+	checkNum(threshold, 20, t)
+}
+
+func TestSeedNodesAndThresholdScale(t *testing.T) {
+	var levels []int
+	halve := func(level, threshold int) int {
+		levels = append(levels, level)
+		return threshold / 2
+	}
+	rt := New(2, WithSeedNodes(3), WithThresholdScale(halve), WithCapacitySchedule([]int{2, 8}))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	for i := 0; i < 50; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+	checkNum(len(subring.members), 3, t)
+	for _, member := range subring.members {
+		checkNum(member.(*Node).threshold, 50, t)
+	}
+	checkNum(levels[len(levels)-1], 1, t)
+
+	// Explicit seed policies scale thresholds too
+	rt.SetSplitPolicy(SeedSplit(2))
+	subring, _ = rt.Split("B")
+	for _, member := range subring.members {
+		checkNum(member.(*Node).threshold, 50, t)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	var keys []string
+	for i := 0; i < 2000; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+	}
+
+	// Load while single keys are written alongside it
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 50; i++ {
+			key, _ := GenerateRandomString(21)
+			if err := rt.InsertKey(key); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	if err := rt.LoadKeys(keys, 8); err != nil {
+		t.Fatalf("expected keys to be loaded, got error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected concurrent insert to succeed, got error: %v", err)
+	}
+
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+	checkNum(rt.Stats().Keys(), len(keys)+50, t)
+	checkNum(rt.MemberStats().Keys, len(keys)+50, t)
+}
+
+func TestLoadKeysDeterministic(t *testing.T) {
+	var keys []string
+	for i := 0; i < 2000; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+	}
+	load := func() *Ring {
+		rt := New(4, WithRandSource(rand.NewSource(1)))
+		rt.InsertNode(NewNode("A", 100))
+		rt.InsertNode(NewNode("B", 100))
+		if err := rt.LoadKeys(keys, 8); err != nil {
+			t.Fatalf("expected keys to be loaded, got error: %v", err)
+		}
+		return rt
+	}
+
+	// The same keys build the same tree, however the workers are scheduled
+	first, second := load(), load()
+	checkNum(second.Stats().Nodes(), first.Stats().Nodes(), t)
+	for _, key := range keys {
+		want, _ := first.Lookup(key)
+		if got, _ := second.Lookup(key); got != want {
+			t.Errorf("expected key %s on %s, got %s", key, want, got)
+		}
+	}
+}
+
+func TestLoadKeysDuplicate(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	if err := rt.LoadKeys([]string{"a", "b", "a"}, 2); err != ErrKeyExists {
+		t.Errorf("expected ErrKeyExists for a duplicate key, got %v", err)
+	}
+}
+
+// fillTenants inserts 2000 keys of tenant t1 and 200 of tenant t2, enough to split a small node A, and
+// returns the t1 keys ordered by the node holding them.
+func fillTenants(t *testing.T, rt *Ring) []string {
+	t.Helper()
+	var keys []string
+	for i := 0; i < 2000; i++ {
+		key := "t1/" + strconv.Itoa(i)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	for i := 0; i < 200; i++ {
+		rt.InsertKey("t2/" + strconv.Itoa(i))
+	}
+	if rt.GetDepth() == 0 {
+		t.Fatal("expected node A to have split")
+	}
+	owned := owners(rt, keys)
+	sort.SliceStable(keys, func(i, j int) bool { return owned[keys[i]] < owned[keys[j]] })
+	return keys
+}
+
+func TestRemoveKeys(t *testing.T) {
+	rt, _ := buildTree(t, 4, []string{"A"}, 400, 0, WithRandSource(rand.NewSource(1)))
+	rt.InsertNode(NewNode("B", 100000))
+	keys := fillTenants(t, rt)
+	batch := append([]string{"missing", keys[0]}, keys...)
+	results, err := rt.RemoveKeys(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(results), len(batch), t)
+	for i, result := range results {
+		if result.Key != batch[i] {
+			t.Errorf("expected result %d for key %s, got %s", i, batch[i], result.Key)
+		}
+		// The repeat of keys[0] finds it already removed
+		if wantErr := i == 0 || i == 2; (result.Err != nil) != wantErr || wantErr && result.Err != ErrKeyNotFound {
+			t.Errorf("unexpected result for key %s: %v", result.Key, result.Err)
+		}
+	}
+	checkNum(rt.stats.numKeys.get(), 200, t)
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != ErrKeyNotFound {
+			t.Fatalf("expected key %s to be removed, got %v", key, err)
+		}
+	}
+	checkValid(rt, t)
+}
+
+func TestRemoveKeysDefersUnderflow(t *testing.T) {
+	// Removing a node's keys one at a time removes the node while it still holds some, moving keys that are
+	// about to be deleted; the batch moves only the keys that stay
+	single, _ := buildTree(t, 4, []string{"A"}, 400, 0, WithRandSource(rand.NewSource(1)))
+	single.InsertNode(NewNode("B", 100000))
+	keys := fillTenants(t, single)
+	before := single.Counters().Remapped
+	for _, key := range keys {
+		single.RemoveKey(key)
+	}
+	oneByOne := single.Counters().Remapped - before
+
+	rt, _ := buildTree(t, 4, []string{"A"}, 400, 0, WithRandSource(rand.NewSource(1)))
+	rt.InsertNode(NewNode("B", 100000))
+	fillTenants(t, rt)
+	before = rt.Counters().Remapped
+	if _, err := rt.RemoveKeys(keys); err != nil {
+		t.Fatal(err)
+	}
+	if batched := rt.Counters().Remapped - before; batched >= oneByOne {
+		t.Errorf("expected the batch to move fewer keys than %d one by one, got %d", oneByOne, batched)
+	}
+	checkNum(rt.stats.numKeys.get(), single.stats.numKeys.get(), t)
+	checkValid(rt, t)
+}
+
+func TestRemoveKeysByPrefix(t *testing.T) {
+	rt, _ := buildTree(t, 4, []string{"A"}, 400, 0, WithRandSource(rand.NewSource(1)))
+	rt.InsertNode(NewNode("B", 100000))
+	fillTenants(t, rt)
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := shadow.RemoveKeysByPrefix("t1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(results), 2000, t)
+	for i, result := range results {
+		if result.Err != nil || !strings.HasPrefix(result.Key, "t1/") || i > 0 && result.Key <= results[i-1].Key {
+			t.Fatalf("expected the t1 keys in order, removed, got %+v at %d", result, i)
+		}
+	}
+	checkNum(shadow.stats.numKeys.get(), 200, t)
+	if _, err := shadow.Lookup("t2/7"); err != nil {
+		t.Errorf("expected other tenants' keys to stay, got %v", err)
+	}
+
+	// The batch is logged as one operation, so replaying it defers underflow the same way
+	if plan := shadow.Plan(); len(plan) != 1 || plan[0].Type != OpRemoveKeys || len(plan[0].Keys) != 2000 {
+		t.Fatalf("expected one logged batch of 2000 keys, got %+v", plan)
+	}
+	if err := shadow.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(shadow.Ring, rt); !d.Unchanged() {
+		t.Errorf("expected the replayed batch to match, got %+v", d)
+	}
+}
@@ -0,0 +1,160 @@
+package ringtree
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files of a tree opened with Open.
+const (
+	snapshotFileName = "snapshot.json"
+	walFileName      = "wal.log"
+)
+
+// WithCheckpoints makes a tree opened with Open checkpoint after every ops logged operations, or on the
+// first operation logged interval after the last checkpoint: it writes a full snapshot and truncates the
+// log, bounding both the log's size and the replay on the next Open. Either trigger may be 0.
+func WithCheckpoints(ops int, interval time.Duration) Option {
+	return func(c *Config) {
+		if ops >= 0 && interval >= 0 {
+			c.SnapshotEvery, c.SnapshotInterval = ops, interval
+		}
+	}
+}
+
+// Open restores the tree kept in dir from its snapshot and the tail of its write-ahead log, and keeps
+// logging to dir: every operation WithWAL covers is appended to the log, replacing any WAL given in opts.
+// An empty or missing dir starts a tree with maxCount members on the root ring; otherwise opts must match
+// those the tree was created with. The restored tree is checkpointed at once if its log had any entries.
+func Open(dir string, maxCount int, opts ...Option) (*Ring, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var r *Ring
+	f, err := os.Open(filepath.Join(dir, snapshotFileName))
+	switch {
+	case err == nil:
+		r, err = ReadSnapshot(f, opts...)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		r = New(maxCount, opts...)
+	default:
+		return nil, err
+	}
+
+	walPath := filepath.Join(dir, walFileName)
+	replayed := false
+	if data, err := os.ReadFile(walPath); err == nil {
+		if err := r.ReplayWAL(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		replayed = len(data) > 0
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		return nil, err
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.config.WAL = wal
+	r.wal.dir = dir
+	r.wal.checked = time.Now()
+	if replayed {
+		// Compacting also drops a final entry torn by a crash, which later appends would follow
+		if err := r.checkpoint(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Checkpoint writes a snapshot of a tree opened with Open and truncates its log.
+func (r *Ring) Checkpoint() error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	if r.wal.dir == "" {
+		return errors.New("tree was not opened from a directory")
+	}
+	return r.checkpoint()
+}
+
+// Close stops the tree's lease janitor and closes its write-ahead log, if it can be closed. Operations must
+// not be applied afterwards.
+func (r *Ring) Close() error {
+	r.leases.close() // Before taking the writer lock, which the janitor takes on every check
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	if closer, ok := r.config.WAL.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// checkpointDue reports whether a tree opened with Open should checkpoint (assuming the tree's writer lock
+// is held).
+func (r *Ring) checkpointDue() bool {
+	s := r.wal
+	if s.dir == "" {
+		return false
+	}
+	if every := r.config.SnapshotEvery; every > 0 && s.sinceCheck >= every {
+		return true
+	}
+	interval := r.config.SnapshotInterval
+	return interval > 0 && time.Since(s.checked) >= interval
+}
+
+// checkpoint atomically replaces the snapshot in the tree's directory and then truncates the log. A crash
+// between the two leaves entries the snapshot already includes, which the next replay skips (assuming the
+// tree's writer lock is held).
+func (r *Ring) checkpoint() error {
+	file, err := r.snapshotFile()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.wal.dir, snapshotFileName)
+	if err := writeFileAtomic(path, file); err != nil {
+		return err
+	}
+	if t, ok := r.config.WAL.(interface{ Truncate() error }); ok {
+		if err := t.Truncate(); err != nil {
+			return err
+		}
+	}
+	r.wal.sinceCheck = 0
+	r.wal.checked = time.Now()
+	r.logf("Checkpointed tree at operation %d.\n", file.Seq)
+	return nil
+}
+
+// writeFileAtomic writes a checksummed snapshot to a temporary file, syncs it and renames it over path.
+func writeFileAtomic(path string, file treeFile) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := encodeSnapshot(tmp, file, WithChecksum(ChecksumSHA256)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
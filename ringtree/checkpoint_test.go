@@ -0,0 +1,83 @@
+package ringtree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	rt, err := Open(dir, 4, WithCheckpoints(50, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.InsertNode(NewNode("A", 40))
+	rt.InsertNode(NewNode("B", 40))
+	var keys []string
+	for i := 0; i < 120; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err != nil {
+		t.Fatalf("expected a checkpoint snapshot: %v", err)
+	}
+	if n := countLines(t, filepath.Join(dir, walFileName)); n >= 50 {
+		t.Fatalf("got %d log entries, want the log truncated by checkpoints", n)
+	}
+	if err := rt.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash while appending leaves a torn final entry
+	f, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":999,"type":"insert-k`)
+	f.Close()
+
+	restored, err := Open(dir, 4, WithCheckpoints(50, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if !restored.Digest().Equal(rt.Digest()) {
+		t.Fatal("expected the restored tree to have the same topology")
+	}
+	for _, key := range keys {
+		want, _ := rt.Lookup(key)
+		if got, err := restored.Lookup(key); err != nil || got != want {
+			t.Fatalf("%s: got %q (%v) after Open, want %q", key, got, err, want)
+		}
+	}
+	if n := countLines(t, filepath.Join(dir, walFileName)); n != 0 {
+		t.Fatalf("got %d log entries after Open, want the log compacted", n)
+	}
+	if err := restored.InsertKey("after-restore"); err != nil {
+		t.Fatal(err)
+	}
+	if restored.wal.seq <= rt.wal.seq {
+		t.Fatalf("got sequence %d after a new operation, want past %d", restored.wal.seq, rt.wal.seq)
+	}
+}
+
+// countLines returns the number of lines in a file.
+func countLines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		n++
+	}
+	return n
+}
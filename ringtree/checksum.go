@@ -0,0 +1,147 @@
+package ringtree
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// checksum returns the secondary hash of a key stored under the given placement hash. It uses FNV-1a so that
+// it is independent of the Murmur3 placement hash, and covers the stored hash so that either can be checked.
+func checksum(key string, keyHash uint32) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], keyHash)
+	h.Write(b[:])
+	return h.Sum32()
+}
+
+// setChecksum records the secondary hash of a key stored under the given placement hash.
+func (n *Node) setChecksum(key string, keyHash uint32) {
+	if n.checksums == nil {
+		n.checksums = make(map[string]uint32)
+	}
+	n.checksums[key] = checksum(key, keyHash)
+}
+
+// storedHash returns the placement hash the node stores for a key, and false when the node does not hold it.
+func (n *Node) storedHash(key string) (uint32, bool) {
+	for _, keys := range n.keys {
+		if keyHash, ok := keys[key]; ok {
+			return keyHash, true
+		}
+	}
+	return 0, false
+}
+
+// verifyKey checks a key stored on a node of the ring under the given placement hash, recording a failure on
+// mismatch. The stored hash must be the key hashed on the ring's level, and the secondary hash must match
+// both; a key without a secondary hash fails.
+func (r *Ring) verifyKey(node *Node, key string, keyHash uint32) bool {
+	if !r.config.Checksums {
+		return true
+	}
+	if keyHash != r.config.keyHash(key, r.level) {
+		r.stats.corrupted.add(1)
+		r.logf("Placement hash mismatch for key %s on node %s.\n", key, node.id)
+		return false
+	}
+	return r.checksumMatches(node, key, keyHash)
+}
+
+// verifyHeld checks a key held by a node of the ring, looking up the hash it is stored under.
+func (r *Ring) verifyHeld(node *Node, key string) bool {
+	if !r.config.Checksums {
+		return true
+	}
+	keyHash, _ := node.storedHash(key)
+	return r.verifyKey(node, key, keyHash)
+}
+
+// verifyNode checks every key of a node of the ring before a migration moves them, so a corrupted key
+// aborts the migration instead of being rehashed into a valid one (assuming mutex is already locked).
+func (r *Ring) verifyNode(node *Node) error {
+	if !r.config.Checksums {
+		return nil
+	}
+	for _, keys := range node.keys {
+		for key, keyHash := range keys {
+			if !r.verifyKey(node, key, keyHash) {
+				return ErrChecksumMismatch
+			}
+		}
+	}
+	return nil
+}
+
+// verifyRing checks every key in the ring and its subrings before a migration moves them (assuming mutex is
+// already locked).
+func (r *Ring) verifyRing() error {
+	if !r.config.Checksums {
+		return nil
+	}
+	var err error
+	r.forEachRingNode(func(node *Node, ring *Ring) {
+		if err == nil {
+			err = ring.verifyNode(node)
+		}
+	})
+	return err
+}
+
+// checksumMatches checks the secondary hash of a key against the key and the hash it is stored under,
+// recording a failure on mismatch. Unlike verifyKey it does not need the level of the node's ring, which
+// callers moving keys across rings may not hold.
+func (r *Ring) checksumMatches(node *Node, key string, keyHash uint32) bool {
+	if !r.config.Checksums {
+		return true
+	}
+	if sum, ok := node.checksums[key]; ok && sum == checksum(key, keyHash) {
+		return true
+	}
+	r.stats.corrupted.add(1)
+	r.logf("Checksum mismatch for key %s on node %s.\n", key, node.id)
+	return false
+}
+
+// rehashChecksum re-records the secondary hash of a key that stays on its node but is hashed on a new level.
+func (r *Ring) rehashChecksum(node *Node, key string, oldHash, keyHash uint32) error {
+	if !r.checksumMatches(node, key, oldHash) {
+		return ErrChecksumMismatch
+	}
+	if r.config.Checksums {
+		node.setChecksum(key, keyHash)
+	}
+	return nil
+}
+
+// carryChecksum records the secondary hash of a key moved onto a new node under its new placement hash.
+func (r *Ring) carryChecksum(key string, keyHash uint32, oldNode *Node, newNode *Node) {
+	if !r.config.Checksums {
+		return
+	}
+	delete(oldNode.checksums, key)
+	newNode.setChecksum(key, keyHash)
+}
+
+// VerifyChecksums checks every key in the tree against its placement hash and secondary hash and returns the
+// corrupted keys.
+func (r *Ring) VerifyChecksums() []string {
+	var corrupted []string
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.RLock()
+	defer r.RUnlock()
+	r.forEachRingNode(func(node *Node, ring *Ring) {
+		for _, keys := range node.keys {
+			for key, keyHash := range keys {
+				if !ring.verifyKey(node, key, keyHash) {
+					corrupted = append(corrupted, key)
+				}
+			}
+		}
+	})
+	sort.Strings(corrupted)
+	return corrupted
+}
@@ -0,0 +1,118 @@
+package ringtree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestChecksumDetectsCorruption(t *testing.T) {
+	rt, keys := buildTree(t, 5, []string{"A"}, 100, 3, WithChecksums(true))
+	node := mustNode(t, rt, "A")
+	if _, err := rt.Lookup(keys[0]); err != nil {
+		t.Fatalf("expected %s to be found, got error: %v", keys[0], err)
+	}
+
+	// Corrupt the stored checksum of a key
+	node.checksums[keys[1]] ^= 0xffff
+
+	if _, err := rt.Lookup(keys[1]); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	corrupted := rt.VerifyChecksums()
+	if len(corrupted) != 1 || corrupted[0] != keys[1] {
+		t.Errorf("expected %s to be reported as corrupted, got %v", keys[1], corrupted)
+	}
+
+	// The corrupted key is not moved to another node
+	rt.InsertNode(NewNode("B", 100))
+	if len(rt.VerifyChecksums()) != 1 {
+		t.Errorf("expected corruption to remain detectable after remapping")
+	}
+	if _, ok := node.storedHash(keys[1]); !ok {
+		t.Errorf("expected the corrupted key to stay on its node")
+	}
+	if rt.Stats().ChecksumFailures() == 0 {
+		t.Errorf("expected checksum failures to be counted")
+	}
+}
+
+func TestChecksumDetectsCorruptedKey(t *testing.T) {
+	rt, keys := buildTree(t, 5, []string{"A"}, 100, 3, WithChecksums(true))
+	node := mustNode(t, rt, "A")
+
+	// Corrupt a key in place, keeping the hash and checksum it was stored with
+	corruptedKey := keys[1] + "x"
+	for _, stored := range node.keys {
+		if keyHash, ok := stored[keys[1]]; ok {
+			delete(stored, keys[1])
+			stored[corruptedKey] = keyHash
+		}
+	}
+
+	corrupted := rt.VerifyChecksums()
+	if len(corrupted) != 1 || corrupted[0] != corruptedKey {
+		t.Errorf("expected %s to be reported as corrupted, got %v", corruptedKey, corrupted)
+	}
+}
+
+func TestChecksumDetectsCorruptedHash(t *testing.T) {
+	rt, keys := buildTree(t, 5, []string{"A"}, 100, 3, WithChecksums(true))
+	node := mustNode(t, rt, "A")
+
+	// Corrupt the stored placement hash of a key
+	for _, stored := range node.keys {
+		if _, ok := stored[keys[1]]; ok {
+			stored[keys[1]] ^= 1
+		}
+	}
+
+	if _, err := rt.Lookup(keys[1]); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	corrupted := rt.VerifyChecksums()
+	if len(corrupted) != 1 || corrupted[0] != keys[1] {
+		t.Errorf("expected %s to be reported as corrupted, got %v", keys[1], corrupted)
+	}
+
+	// Removing the node would move the corrupted key, so the removal is aborted
+	rt.InsertNode(NewNode("B", 100))
+	if err := rt.RemoveNode(node); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected the removal to fail with a checksum mismatch, got %v", err)
+	}
+	if _, ok := node.storedHash(keys[1]); !ok {
+		t.Errorf("expected the corrupted key to stay on its node")
+	}
+}
+
+func TestChecksumMissingEntryFails(t *testing.T) {
+	rt, keys := buildTree(t, 5, []string{"A"}, 100, 3, WithChecksums(true))
+	delete(mustNode(t, rt, "A").checksums, keys[2])
+
+	if _, err := rt.Lookup(keys[2]); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected a key without a checksum to fail, got %v", err)
+	}
+}
+
+func TestChecksumSnapshot(t *testing.T) {
+	rt, keys := buildTree(t, 5, []string{"A"}, 100, 3, WithChecksums(true))
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("expected snapshot to be written, got error: %v", err)
+	}
+	snapshot := buf.Bytes()
+
+	restored, err := ReadSnapshot(bytes.NewReader(snapshot), WithChecksums(true))
+	if err != nil {
+		t.Fatalf("expected snapshot to be read, got error: %v", err)
+	}
+	if corrupted := restored.VerifyChecksums(); len(corrupted) != 0 {
+		t.Errorf("expected restored keys to pass their checksums, got %v", corrupted)
+	}
+
+	// A key corrupted in the snapshot no longer matches its checksum; keys are written before checksums
+	tampered := bytes.Replace(snapshot, []byte(`"`+keys[1]+`"`), []byte(`"`+keys[1]+`x"`), 1)
+	if _, err := ReadSnapshot(bytes.NewReader(tampered)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected a corrupted snapshot key to fail, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package ringtree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// SimConfig describes a churn simulation: a workload of key inserts and lookups running while nodes join
+// and leave the tree. Zero fields take the defaults noted.
+type SimConfig struct {
+	MaxCount    int                // Members per ring (7)
+	Threshold   int                // Threshold τ of every node (100)
+	Nodes       int                // Nodes on the root ring at the start (MaxCount)
+	MinNodes    int                // Nodes below which nodes stop leaving (1)
+	Operations  int                // Key inserts and lookups to run (10000)
+	LookupRatio float64            // Share of the operations that are lookups of inserted keys (0)
+	JoinRate    float64            // Mean node joins per operation, as a Poisson process
+	LeaveRate   float64            // Mean node leaves per operation, as a Poisson process
+	Keys        workload.Generator // Keys to insert (uniformly random)
+	Seed        int64              // Seed of the churn, lookups, default keys and generated node IDs (the current time)
+	SampleEvery int                // Operations between two samples of the tree's shape (Operations/100)
+	Options     []Option           // Options of the simulated tree
+}
+
+// ShapeSample is the shape of the tree at one point of a simulation.
+type ShapeSample struct {
+	Operation int // Operations run before the sample
+	Depth     int
+	Nodes     int
+	Keys      int
+	Remapped  int // Keys remapped so far
+}
+
+// SimReport is the outcome of a churn simulation.
+type SimReport struct {
+	Operations    int
+	Inserts       int
+	Lookups       int
+	Joins         int
+	Leaves        int
+	Nodes         int                    // Nodes at the end
+	Keys          int                    // Keys at the end
+	MaxDepth      int                    // Deepest the tree grew
+	Remapped      int                    // Keys remapped by every membership change, splits included
+	ChurnRemapped int                    // Keys remapped by joins and leaves
+	PerChange     float64                // Keys remapped per join or leave
+	Shape         []ShapeSample          // Shape of the tree over the run
+	Latency       map[string]Percentiles // Latency of each operation
+}
+
+// Simulate runs a churn simulation and reports the keys remapped, how the depth of the tree evolved and the
+// latency of its operations. Joins go to the shallowest ring with a free slot, splitting the most loaded
+// node when every ring is full; leaves remove a node chosen at random.
+func Simulate(cfg SimConfig) (*SimReport, error) {
+	cfg = cfg.withDefaults()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	rt := New(cfg.MaxCount, append([]Option{WithRandSource(rand.NewSource(cfg.Seed))}, cfg.Options...)...)
+	for i := 0; i < cfg.Nodes; i++ {
+		if err := rt.InsertNode(NewNode("node"+strconv.Itoa(i), cfg.Threshold)); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &SimReport{Latency: make(map[string]Percentiles)}
+	sample := func(op int) {
+		c := rt.Counters()
+		report.Shape = append(report.Shape, ShapeSample{Operation: op, Depth: c.Depth, Nodes: c.Nodes, Keys: c.Keys, Remapped: c.Remapped})
+		report.MaxDepth = max(report.MaxDepth, c.Depth)
+	}
+	churn := func(change func() error) error {
+		before := rt.Counters().Remapped
+		if err := change(); err != nil {
+			return err
+		}
+		report.ChurnRemapped += rt.Counters().Remapped - before
+		return nil
+	}
+
+	// Joins and leaves arrive after exponentially distributed gaps, measured in operations
+	gap := func(rate float64) float64 {
+		if rate <= 0 {
+			return math.Inf(1)
+		}
+		return rng.ExpFloat64() / rate
+	}
+	nextJoin, nextLeave := gap(cfg.JoinRate), gap(cfg.LeaveRate)
+	joined := 0
+
+	var keys []string
+	sample(0)
+	for op := 0; op < cfg.Operations; op++ {
+		for nextJoin <= float64(op) {
+			id := "join" + strconv.Itoa(joined)
+			joined++
+			if err := churn(func() error { return growTree(rt, id, cfg.Threshold) }); err != nil {
+				return nil, err
+			}
+			report.Joins++
+			nextJoin += gap(cfg.JoinRate)
+		}
+		for nextLeave <= float64(op) {
+			if rt.Stats().Nodes() > cfg.MinNodes {
+				if err := churn(func() error { return removeRandomNode(rt, rng) }); err != nil {
+					return nil, err
+				}
+				report.Leaves++
+			}
+			nextLeave += gap(cfg.LeaveRate)
+		}
+
+		if len(keys) > 0 && rng.Float64() < cfg.LookupRatio {
+			if _, err := rt.Lookup(keys[rng.Intn(len(keys))]); err != nil {
+				return nil, err
+			}
+			report.Lookups++
+		} else {
+			key := cfg.Keys.Next()
+			switch err := rt.InsertKey(key); {
+			case err == nil:
+				keys = append(keys, key)
+				report.Inserts++
+			case !errors.Is(err, ErrKeyExists): // Skewed workloads repeat keys, which count as operations
+				return nil, err
+			}
+		}
+		report.Operations++
+		if (op+1)%cfg.SampleEvery == 0 {
+			sample(op + 1)
+		}
+	}
+
+	c := rt.Counters()
+	report.Nodes, report.Keys, report.Remapped = c.Nodes, c.Keys, c.Remapped
+	if changes := report.Joins + report.Leaves; changes > 0 {
+		report.PerChange = float64(report.ChurnRemapped) / float64(changes)
+	}
+	for operation, p := range rt.Stats().Percentiles() {
+		report.Latency[operation] = p
+	}
+	return report, nil
+}
+
+// withDefaults fills in the zero fields of the config.
+func (cfg SimConfig) withDefaults() SimConfig {
+	if cfg.MaxCount < 2 {
+		cfg.MaxCount = 7
+	}
+	if cfg.Threshold < 1 {
+		cfg.Threshold = 100
+	}
+	if cfg.Nodes < 1 {
+		cfg.Nodes = cfg.MaxCount
+	}
+	if cfg.MinNodes < 1 {
+		cfg.MinNodes = 1
+	}
+	if cfg.Operations < 1 {
+		cfg.Operations = 10000
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	if cfg.Keys == nil {
+		cfg.Keys = workload.Uniform(rand.New(rand.NewSource(cfg.Seed)), 20)
+	}
+	if cfg.SampleEvery < 1 {
+		cfg.SampleEvery = max(cfg.Operations/100, 1)
+	}
+	return cfg
+}
+
+// removeRandomNode removes a physical node of the tree chosen at random.
+func removeRandomNode(rt *Ring, rng *rand.Rand) error {
+	type held struct {
+		node *Node
+		ring *Ring
+	}
+	var nodes []held
+	rt.writer.Lock()
+	var visit func(ring *Ring)
+	visit = func(ring *Ring) {
+		for _, member := range ring.members {
+			switch member := member.(type) {
+			case *Node:
+				nodes = append(nodes, held{member, ring})
+			case *Ring:
+				visit(member)
+			}
+		}
+	}
+	visit(rt)
+	rt.writer.Unlock()
+	if len(nodes) == 0 {
+		return ErrRingEmpty
+	}
+
+	// Sorted so the choice does not depend on map order
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].node.id < nodes[j].node.id })
+	chosen := nodes[rng.Intn(len(nodes))]
+	return chosen.ring.RemoveNode(chosen.node)
+}
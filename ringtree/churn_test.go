@@ -0,0 +1,73 @@
+package ringtree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+func TestSimulate(t *testing.T) {
+	report, err := Simulate(SimConfig{
+		MaxCount:    4,
+		Threshold:   50,
+		Operations:  3000,
+		LookupRatio: 0.3,
+		JoinRate:    0.004,
+		LeaveRate:   0.002,
+		Seed:        7,
+		SampleEvery: 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Operations != 3000 || report.Inserts+report.Lookups != 3000 || report.Lookups == 0 {
+		t.Errorf("got %d operations, %d inserts and %d lookups, want 3000 split between both", report.Operations, report.Inserts, report.Lookups)
+	}
+	if report.Keys != report.Inserts {
+		t.Errorf("got %d keys after %d inserts", report.Keys, report.Inserts)
+	}
+	if report.Joins == 0 || report.Leaves == 0 {
+		t.Errorf("got %d joins and %d leaves, want both", report.Joins, report.Leaves)
+	}
+	if len(report.Shape) != 7 || report.Shape[6].Operation != 3000 || report.Shape[6].Keys != report.Keys {
+		t.Errorf("got shape %+v, want a sample every 500 operations", report.Shape)
+	}
+	if report.ChurnRemapped > report.Remapped || report.MaxDepth < report.Shape[6].Depth {
+		t.Errorf("got %d churn remaps of %d and max depth %d", report.ChurnRemapped, report.Remapped, report.MaxDepth)
+	}
+	if report.Latency["InsertKey"].Count == 0 || report.Latency["Lookup"].Count == 0 {
+		t.Errorf("expected insert and lookup latencies, got %v", report.Latency)
+	}
+}
+
+func TestSimulateRepeatedKeys(t *testing.T) {
+	report, err := Simulate(SimConfig{
+		Operations: 1000,
+		Threshold:  1000,
+		Keys:       workload.Zipf(rand.New(rand.NewSource(1)), "key", 1.2, 100),
+		Seed:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Operations != 1000 || report.Inserts > 100 || report.Joins != 0 {
+		t.Errorf("got %d operations, %d inserts and %d joins, want 1000, at most 100 and none", report.Operations, report.Inserts, report.Joins)
+	}
+}
+
+func TestSimulateDeterministic(t *testing.T) {
+	cfg := SimConfig{MaxCount: 4, Threshold: 30, Operations: 2000, LookupRatio: 0.2, JoinRate: 0.003, LeaveRate: 0.003, Seed: 42}
+	first, err := Simulate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Simulate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first.Shape, second.Shape) || first.Remapped != second.Remapped || first.Leaves != second.Leaves {
+		t.Errorf("runs with the same seed differ:\n%+v\n%+v", first.Shape, second.Shape)
+	}
+}
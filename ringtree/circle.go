@@ -1,17 +1,30 @@
 package ringtree
 
-import "sort"
+import (
+	"math"
+	"sort"
+)
 
 // Circle interface defines the methods required for vNode storage and retrieval.
 type Circle interface {
 	Insert(vNodeHash uint32, nodeID string) bool
+	InsertBatch(vNodes []VNode) []VNode
 	FindClosest(vNodeHash uint32) (uint32, string)
 	FindNextClosest(vNodeHash uint32) (uint32, string)
+	FindPrevClosest(vNodeHash uint32) (uint32, string)
+	Range(from, to uint32) []VNode
+	Iter(vNodeHash uint32) Iterator
 	Delete(vNodeHash uint32) bool
 	Size() int
 	Sort()
 }
 
+// Iterator walks the vnodes of a circle in hash order, wrapping around at the top of the hash space. It is
+// invalidated by any change to the circle.
+type Iterator interface {
+	Next() (uint32, string) // Returns the next vnode, or 0 and "" if the circle is empty
+}
+
 // RBTreeCircle implements the Circle interface using a red-black tree.
 type RBTreeCircle struct {
 	tree *redBlackTree
@@ -26,6 +39,16 @@ type VNode struct {
 	nodeID string
 }
 
+// Hash returns the position of the vnode on the circle.
+func (v VNode) Hash() uint32 {
+	return v.hash
+}
+
+// NodeID returns the ID of the member owning the vnode.
+func (v VNode) NodeID() string {
+	return v.nodeID
+}
+
 // Creates a New Circle with array or red-black tree.
 func NewCircle(useArray bool) Circle {
 	if useArray {
@@ -43,12 +66,41 @@ func NewCircle(useArray bool) Circle {
 func (rb *RBTreeCircle) Insert(vNodeHash uint32, nodeID string) bool {
 	return rb.tree.Insert(vNodeHash, nodeID)
 }
+func (rb *RBTreeCircle) InsertBatch(vNodes []VNode) []VNode {
+	var inserted []VNode
+	for _, vNode := range vNodes {
+		if rb.tree.Insert(vNode.hash, vNode.nodeID) {
+			inserted = append(inserted, vNode)
+		}
+	}
+	return inserted
+}
 func (rb *RBTreeCircle) FindClosest(vNodeHash uint32) (uint32, string) {
 	return rb.tree.FindClosest(vNodeHash)
 }
 func (rb *RBTreeCircle) FindNextClosest(vNodeHash uint32) (uint32, string) {
 	return rb.tree.FindNextClosest(vNodeHash)
 }
+func (rb *RBTreeCircle) FindPrevClosest(vNodeHash uint32) (uint32, string) {
+	return rb.tree.FindPrevClosest(vNodeHash)
+}
+func (rb *RBTreeCircle) Range(from, to uint32) []VNode {
+	var vNodes []VNode
+	collect := func(n *redBlackNode) {
+		vNodes = append(vNodes, VNode{hash: n.key, nodeID: n.value})
+	}
+	if from <= to {
+		rb.tree.Range(from, to, collect)
+	} else {
+		// Wrap around the top of the hash space
+		rb.tree.Range(from, math.MaxUint32, collect)
+		rb.tree.Range(0, to, collect)
+	}
+	return vNodes
+}
+func (rb *RBTreeCircle) Iter(vNodeHash uint32) Iterator {
+	return &rbTreeIterator{it: rb.tree.iterAfter(vNodeHash)}
+}
 func (rbt *RBTreeCircle) Delete(vNodeHash uint32) bool {
 	return rbt.tree.Delete(vNodeHash)
 }
@@ -77,6 +129,25 @@ func (ac *ArrayCircle) Insert(vNodeHash uint32, nodeID string) bool {
 	return true
 }
 
+// InsertBatch inserts several vnodes into an already sorted array and sorts it once, skipping duplicates.
+func (ac *ArrayCircle) InsertBatch(vNodes []VNode) []VNode {
+	seen := make(map[uint32]bool, len(vNodes))
+	var inserted []VNode
+	for _, vNode := range vNodes {
+		idx := sort.Search(len(ac.vNodes), func(i int) bool {
+			return ac.vNodes[i].hash >= vNode.hash
+		})
+		if seen[vNode.hash] || (idx < len(ac.vNodes) && ac.vNodes[idx].hash == vNode.hash) {
+			continue // Duplicate vnode
+		}
+		seen[vNode.hash] = true
+		inserted = append(inserted, vNode)
+	}
+	ac.vNodes = append(ac.vNodes, inserted...)
+	ac.Sort()
+	return inserted
+}
+
 func (ac *ArrayCircle) FindClosest(vNodeHash uint32) (uint32, string) {
 	if len(ac.vNodes) == 0 {
 		return 0, ""
@@ -107,6 +178,42 @@ func (ac *ArrayCircle) FindNextClosest(vNodeHash uint32) (uint32, string) {
 	return ac.vNodes[0].hash, ac.vNodes[0].nodeID
 }
 
+func (ac *ArrayCircle) FindPrevClosest(vNodeHash uint32) (uint32, string) {
+	if len(ac.vNodes) == 0 {
+		return 0, ""
+	}
+	// Binary search for the first vnode at or after the hash; the one before it is the predecessor
+	idx := sort.Search(len(ac.vNodes), func(i int) bool {
+		return ac.vNodes[i].hash >= vNodeHash
+	})
+	if idx > 0 {
+		return ac.vNodes[idx-1].hash, ac.vNodes[idx-1].nodeID
+	}
+	// Wrap around to the last vnode
+	last := ac.vNodes[len(ac.vNodes)-1]
+	return last.hash, last.nodeID
+}
+
+func (ac *ArrayCircle) Range(from, to uint32) []VNode {
+	between := func(lo, hi uint32) []VNode {
+		start := sort.Search(len(ac.vNodes), func(i int) bool { return ac.vNodes[i].hash >= lo })
+		end := sort.Search(len(ac.vNodes), func(i int) bool { return ac.vNodes[i].hash > hi })
+		return append([]VNode(nil), ac.vNodes[start:end]...)
+	}
+	if from <= to {
+		return between(from, to)
+	}
+	// Wrap around the top of the hash space
+	return append(between(from, math.MaxUint32), between(0, to)...)
+}
+
+func (ac *ArrayCircle) Iter(vNodeHash uint32) Iterator {
+	idx := sort.Search(len(ac.vNodes), func(i int) bool {
+		return ac.vNodes[i].hash > vNodeHash
+	})
+	return &arrayIterator{circle: ac, idx: idx}
+}
+
 func (ac *ArrayCircle) Delete(vNodeHash uint32) bool {
 	for i, vnode := range ac.vNodes {
 		if vnode.hash == vNodeHash {
@@ -126,3 +233,92 @@ func (ac *ArrayCircle) Sort() {
 		return ac.vNodes[i].hash < ac.vNodes[j].hash
 	})
 }
+
+// AdaptiveCircle implements the Circle interface with an array while the circle is small and migrates to a
+// red-black tree once it grows past a threshold, and back again once it shrinks below half of it.
+type AdaptiveCircle struct {
+	Circle
+	threshold int
+}
+
+// Creates a New AdaptiveCircle that switches to a red-black tree past threshold vnodes.
+func NewAdaptiveCircle(threshold int) *AdaptiveCircle {
+	return &AdaptiveCircle{Circle: NewCircle(true), threshold: threshold}
+}
+
+// Adaptive API.
+func (ad *AdaptiveCircle) Insert(vNodeHash uint32, nodeID string) bool {
+	if !ad.Circle.Insert(vNodeHash, nodeID) {
+		return false
+	}
+	if _, isArray := ad.Circle.(*ArrayCircle); isArray && ad.Circle.Size() > ad.threshold {
+		ad.migrate(false)
+	}
+	return true
+}
+
+func (ad *AdaptiveCircle) InsertBatch(vNodes []VNode) []VNode {
+	inserted := ad.Circle.InsertBatch(vNodes)
+	if _, isArray := ad.Circle.(*ArrayCircle); isArray && ad.Circle.Size() > ad.threshold {
+		ad.migrate(false)
+	}
+	return inserted
+}
+
+func (ad *AdaptiveCircle) Delete(vNodeHash uint32) bool {
+	if !ad.Circle.Delete(vNodeHash) {
+		return false
+	}
+	if _, isTree := ad.Circle.(*RBTreeCircle); isTree && ad.Circle.Size() < ad.threshold/2 {
+		ad.migrate(true)
+	}
+	return true
+}
+
+// Backend returns the circle currently holding the vnodes.
+func (ad *AdaptiveCircle) Backend() Circle {
+	return ad.Circle
+}
+
+// migrate moves every vnode into a new array or red-black tree circle.
+func (ad *AdaptiveCircle) migrate(useArray bool) {
+	vNodes := circleVNodes(ad.Circle)
+	next := NewCircle(useArray)
+	for _, vNode := range vNodes {
+		next.Insert(vNode.hash, vNode.nodeID)
+	}
+	next.Sort()
+	ad.Circle = next
+}
+
+// rbTreeIterator iterates an RBTreeCircle.
+type rbTreeIterator struct {
+	it *redBlackIterator
+}
+
+func (ri *rbTreeIterator) Next() (uint32, string) {
+	n := ri.it.next()
+	if n == nil {
+		return 0, ""
+	}
+	return n.key, n.value
+}
+
+// arrayIterator iterates an ArrayCircle.
+type arrayIterator struct {
+	circle *ArrayCircle
+	idx    int
+}
+
+func (ai *arrayIterator) Next() (uint32, string) {
+	vNodes := ai.circle.vNodes
+	if len(vNodes) == 0 {
+		return 0, ""
+	}
+	if ai.idx >= len(vNodes) {
+		ai.idx = 0 // Wrap around to the first vnode
+	}
+	vNode := vNodes[ai.idx]
+	ai.idx++
+	return vNode.hash, vNode.nodeID
+}
@@ -0,0 +1,151 @@
+package ringtree
+
+import "testing"
+
+func TestAdaptiveCircle(t *testing.T) {
+	adaptive := NewAdaptiveCircle(32)
+	reference := NewCircle(true)
+	for i := 0; i < 100; i++ {
+		vNodeHash := hash("node", i)
+		adaptive.Insert(vNodeHash, "node")
+		reference.Insert(vNodeHash, "node")
+		adaptive.Sort()
+		reference.Sort()
+	}
+	if _, ok := adaptive.Backend().(*RBTreeCircle); !ok {
+		t.Errorf("expected adaptive circle to migrate to a red-black tree")
+	}
+
+	check := func() {
+		checkNum(adaptive.Size(), reference.Size(), t)
+		for i := 0; i < 200; i++ {
+			probe := hash("probe", i)
+			got, _ := adaptive.FindClosest(probe)
+			want, _ := reference.FindClosest(probe)
+			if got != want {
+				t.Fatalf("expected closest vnode %d for %d, got %d", want, probe, got)
+			}
+			got, _ = adaptive.FindNextClosest(probe)
+			want, _ = reference.FindNextClosest(probe)
+			if got != want {
+				t.Fatalf("expected next closest vnode %d for %d, got %d", want, probe, got)
+			}
+		}
+	}
+	check()
+
+	// Shrinking below half the threshold migrates back to an array
+	for i := 0; i < 90; i++ {
+		vNodeHash := hash("node", i)
+		adaptive.Delete(vNodeHash)
+		reference.Delete(vNodeHash)
+	}
+	if _, ok := adaptive.Backend().(*ArrayCircle); !ok {
+		t.Errorf("expected adaptive circle to migrate back to an array")
+	}
+	check()
+}
+
+func TestAdaptiveCircleRing(t *testing.T) {
+	rt := New(8, WithAdaptiveCircle(50))
+	for i := 0; i < 4; i++ {
+		rt.InsertNode(NewNode("", 100))
+	}
+	if _, ok := rt.circle.(*AdaptiveCircle).Backend().(*RBTreeCircle); !ok {
+		t.Errorf("expected the root circle to migrate to a red-black tree")
+	}
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+}
+
+func TestCirclePrevAndRange(t *testing.T) {
+	for _, useArray := range []bool{true, false} {
+		c := NewCircle(useArray)
+		for _, h := range []uint32{100, 200, 300, 400} {
+			c.Insert(h, "node")
+		}
+		c.Sort()
+
+		prev, _ := c.FindPrevClosest(250)
+		checkNum(int(prev), 200, t)
+		prev, _ = c.FindPrevClosest(200)
+		checkNum(int(prev), 100, t)
+		prev, _ = c.FindPrevClosest(50) // Wraps around to the last vnode
+		checkNum(int(prev), 400, t)
+
+		checkRange := func(from, to uint32, expected ...uint32) {
+			vNodes := c.Range(from, to)
+			checkNum(len(vNodes), len(expected), t)
+			for i := range expected {
+				if i < len(vNodes) {
+					checkNum(int(vNodes[i].Hash()), int(expected[i]), t)
+				}
+			}
+		}
+		checkRange(150, 300, 200, 300)
+		checkRange(350, 150, 400, 100) // Wraps around the top of the hash space
+		checkRange(410, 420)
+	}
+}
+
+func TestInArc(t *testing.T) {
+	if !inArc(150, 100, 200) || inArc(100, 100, 200) || !inArc(200, 100, 200) {
+		t.Errorf("expected (100, 200] to hold 150 and 200 but not 100")
+	}
+	if !inArc(50, 300, 100) || !inArc(400, 300, 100) || inArc(200, 300, 100) {
+		t.Errorf("expected the wrapping arc (300, 100] to hold 50 and 400 but not 200")
+	}
+	if !inArc(7, 5, 5) {
+		t.Errorf("expected an arc from a vnode to itself to cover the circle")
+	}
+}
+
+func TestCircleInsertBatch(t *testing.T) {
+	for _, c := range []Circle{NewCircle(true), NewCircle(false), NewAdaptiveCircle(4)} {
+		c.Insert(300, "A")
+		c.Sort()
+
+		batch := []VNode{{hash: 500, nodeID: "B"}, {hash: 100, nodeID: "B"}, {hash: 300, nodeID: "B"}, {hash: 100, nodeID: "B"}, {hash: 200, nodeID: "B"}}
+		inserted := c.InsertBatch(batch)
+		checkNum(len(inserted), 3, t) // Duplicates within the batch and of existing vnodes are skipped
+		checkNum(c.Size(), 4, t)
+
+		expected := []uint32{100, 200, 300, 500}
+		for i, vNode := range circleVNodes(c) {
+			checkNum(int(vNode.hash), int(expected[i]), t)
+		}
+		if _, owner := c.FindClosest(300); owner != "A" {
+			t.Errorf("expected existing vnode to keep its owner, got %s", owner)
+		}
+	}
+}
+
+func TestCircleIter(t *testing.T) {
+	for _, useArray := range []bool{true, false} {
+		c := NewCircle(useArray)
+		if _, id := c.Iter(0).Next(); id != "" {
+			t.Errorf("expected an empty circle to yield nothing, got %s", id)
+		}
+		for i := 0; i < 50; i++ {
+			c.Insert(hash("node", i), "node")
+		}
+		c.Sort()
+
+		// Two full laps match repeated successor searches, including the wrap around
+		start := hash("probe", 0)
+		it := c.Iter(start)
+		expected := start
+		for i := 0; i < 2*c.Size(); i++ {
+			expected, _ = c.FindNextClosest(expected)
+			got, _ := it.Next()
+			if got != expected {
+				t.Fatalf("expected vnode %d at step %d, got %d", expected, i, got)
+			}
+		}
+	}
+}
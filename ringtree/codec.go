@@ -0,0 +1,236 @@
+package ringtree
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+// ErrSnapshotCorrupt is returned by ReadSnapshot when a snapshot's checksum does not match its contents.
+var ErrSnapshotCorrupt = errors.New("snapshot is corrupt")
+
+// snapshotMagic starts every snapshot written with compression or a checksum. Plain snapshots start with
+// the JSON object instead.
+const snapshotMagic = "RTSNAP"
+
+// Checksum selects the integrity check appended to a snapshot.
+type Checksum byte
+
+const (
+	NoChecksum     Checksum = iota
+	ChecksumCRC32           // Detects accidental corruption cheaply
+	ChecksumSHA256          // Detects any change to the snapshot
+)
+
+// newHash returns the hash computing the checksum, or nil for NoChecksum.
+func (c Checksum) newHash() (gohash.Hash, error) {
+	switch c {
+	case NoChecksum:
+		return nil, nil
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case ChecksumSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot checksum %d", c)
+	}
+}
+
+// Compressor compresses snapshots. Gzip is registered by default; other algorithms are added with
+// RegisterCompressor, for example zstd through github.com/klauspost/compress/zstd:
+//
+//	type zstdCompressor struct{}
+//
+//	func (zstdCompressor) Name() string { return "zstd" }
+//	func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+//	func (zstdCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+//		d, err := zstd.NewReader(r)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return d.IOReadCloser(), nil
+//	}
+type Compressor interface {
+	// Name identifies the algorithm in the snapshot header.
+	Name() string
+	// Compress returns a writer compressing into w, flushed by Close.
+	Compress(w io.Writer) (io.WriteCloser, error)
+	// Decompress returns a reader decompressing r.
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+var compressors = struct {
+	byName map[string]Compressor
+	sync.RWMutex
+}{byName: map[string]Compressor{"gzip": gzipCompressor{}}}
+
+// RegisterCompressor makes a compressor available to WithCompression and ReadSnapshot under its name.
+func RegisterCompressor(c Compressor) {
+	compressors.Lock()
+	defer compressors.Unlock()
+	compressors.byName[c.Name()] = c
+}
+
+// compressor returns the registered compressor with the given name.
+func compressor(name string) (Compressor, error) {
+	compressors.RLock()
+	defer compressors.RUnlock()
+	c, ok := compressors.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown snapshot compression %q", name)
+	}
+	return c, nil
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) Name() string { return "gzip" }
+
+func (gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+
+func (gzipCompressor) Decompress(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+// snapshotConfig holds the encoding chosen by snapshot options.
+type snapshotConfig struct {
+	compression string
+	checksum    Checksum
+}
+
+// SnapshotOption configures how WriteSnapshot encodes a snapshot.
+type SnapshotOption func(*snapshotConfig)
+
+// WithCompression compresses the snapshot with the registered compressor of the given name, such as "gzip".
+func WithCompression(name string) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.compression = name
+	}
+}
+
+// WithChecksum appends a checksum of the snapshot, which ReadSnapshot verifies before restoring it.
+func WithChecksum(checksum Checksum) SnapshotOption {
+	return func(c *snapshotConfig) {
+		c.checksum = checksum
+	}
+}
+
+// encodeSnapshot writes a snapshot file as plain JSON, or, with options, as a header naming the compression
+// and checksum, the compressed JSON, and the checksum of everything before it.
+func encodeSnapshot(w io.Writer, file treeFile, opts ...SnapshotOption) error {
+	var config snapshotConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.compression == "" && config.checksum == NoChecksum {
+		return json.NewEncoder(w).Encode(file)
+	}
+
+	h, err := config.checksum.newHash()
+	if err != nil {
+		return err
+	}
+	out := w
+	if h != nil {
+		out = io.MultiWriter(w, h)
+	}
+	header := append([]byte(snapshotMagic), byte(config.checksum), byte(len(config.compression)))
+	header = append(header, config.compression...)
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+
+	body := io.WriteCloser(nopCloser{out})
+	if config.compression != "" {
+		c, err := compressor(config.compression)
+		if err != nil {
+			return err
+		}
+		if body, err = c.Compress(out); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(body).Encode(file); err != nil {
+		return err
+	}
+	if err := body.Close(); err != nil {
+		return err
+	}
+	if h != nil {
+		_, err = w.Write(h.Sum(nil))
+	}
+	return err
+}
+
+// decodeSnapshot reads a snapshot file written by encodeSnapshot, verifying its checksum.
+func decodeSnapshot(rd io.Reader) (treeFile, error) {
+	var file treeFile
+	br := bufio.NewReader(rd)
+	if magic, _ := br.Peek(len(snapshotMagic)); string(magic) != snapshotMagic {
+		if err := json.NewDecoder(br).Decode(&file); err != nil {
+			return treeFile{}, fmt.Errorf("error decoding snapshot: %v", err)
+		}
+		return file, nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return treeFile{}, err
+	}
+	if len(data) < len(snapshotMagic)+2 {
+		return treeFile{}, ErrSnapshotCorrupt
+	}
+	checksum := Checksum(data[len(snapshotMagic)])
+	nameEnd := len(snapshotMagic) + 2 + int(data[len(snapshotMagic)+1])
+	if nameEnd > len(data) {
+		return treeFile{}, ErrSnapshotCorrupt
+	}
+	name := string(data[len(snapshotMagic)+2 : nameEnd])
+
+	h, err := checksum.newHash()
+	if err != nil {
+		return treeFile{}, err
+	}
+	if h != nil {
+		end := len(data) - h.Size()
+		if end < nameEnd {
+			return treeFile{}, ErrSnapshotCorrupt
+		}
+		h.Write(data[:end])
+		if !bytes.Equal(h.Sum(nil), data[end:]) {
+			return treeFile{}, ErrSnapshotCorrupt
+		}
+		data = data[:end]
+	}
+
+	var body io.Reader = bytes.NewReader(data[nameEnd:])
+	if name != "" {
+		c, err := compressor(name)
+		if err != nil {
+			return treeFile{}, err
+		}
+		rc, err := c.Decompress(body)
+		if err != nil {
+			return treeFile{}, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		defer rc.Close()
+		body = rc
+	}
+	if err := json.NewDecoder(body).Decode(&file); err != nil {
+		return treeFile{}, fmt.Errorf("error decoding snapshot: %v", err)
+	}
+	return file, nil
+}
+
+// nopCloser adds a no-op Close to a writer.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,62 @@
+package ringtree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSnapshotEncoding(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	for i := 0; i < 200; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	var plain bytes.Buffer
+	if err := rt.WriteSnapshot(&plain); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, opts := range map[string][]SnapshotOption{
+		"plain":       nil,
+		"crc32":       {WithChecksum(ChecksumCRC32)},
+		"gzip":        {WithCompression("gzip")},
+		"gzip+sha256": {WithCompression("gzip"), WithChecksum(ChecksumSHA256)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := rt.WriteSnapshot(&buf, opts...); err != nil {
+				t.Fatal(err)
+			}
+			if name == "gzip" && buf.Len() >= plain.Len() {
+				t.Fatalf("got %d compressed bytes, want fewer than %d", buf.Len(), plain.Len())
+			}
+			data := buf.Bytes()
+			restored, err := ReadSnapshot(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !restored.Digest().Equal(rt.Digest()) {
+				t.Fatal("expected the restored tree to have the same topology")
+			}
+			if len(opts) == 0 || name == "gzip" {
+				return
+			}
+
+			corrupt := append([]byte(nil), data...)
+			corrupt[len(corrupt)/2] ^= 0xff
+			if _, err := ReadSnapshot(bytes.NewReader(corrupt)); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("got %v for a flipped byte, want ErrSnapshotCorrupt", err)
+			}
+			if _, err := ReadSnapshot(bytes.NewReader(data[:len(data)-3])); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("got %v for a truncated snapshot, want ErrSnapshotCorrupt", err)
+			}
+		})
+	}
+
+	if err := rt.WriteSnapshot(&bytes.Buffer{}, WithCompression("lz4")); err == nil {
+		t.Fatal("expected an unregistered compressor to be rejected")
+	}
+}
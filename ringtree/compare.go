@@ -0,0 +1,253 @@
+package ringtree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// CompareConfig describes a comparison of a flat ring, a ring with bounded loads and a ring tree under the
+// same workload. Zero fields take the defaults noted.
+type CompareConfig struct {
+	Keys      int         // Keys inserted before the plan is applied (10000)
+	Plan      ScalingPlan // Node counts to scale through; the first is the starting size (GrowthPlan(10, 20, 5))
+	MaxCount  int         // Members per ring of the ring tree (7)
+	Threshold int         // Threshold τ of the ring tree's nodes (high enough that only the plan splits)
+	Epsilon   float64     // Slack of the bounded-load ring: no node holds more than (1+ε) times the mean (0.25)
+	Seed      int64       // Seed of the keys and of the ring tree's generated node IDs (the current time)
+	Options   []Option    // Options of the flat ring and the ring tree
+}
+
+// SystemStep is the state of one system after a step of the plan.
+type SystemStep struct {
+	Nodes    int     // Physical nodes
+	Moved    int     // Keys whose owner changed during the step
+	Variance float64 // Variance of the node loads
+	Peak     float64 // Highest node load over the mean
+}
+
+// ComparisonStep compares the systems after one step of the plan; the first step is the initial insert.
+type ComparisonStep struct {
+	Target  int // Node count the plan called for
+	Flat    SystemStep
+	Bounded SystemStep
+	Tree    SystemStep
+}
+
+// ComparisonReport holds the side-by-side key movement and load spread of the three systems.
+type ComparisonReport struct {
+	Keys         int
+	Epsilon      float64
+	Steps        []ComparisonStep
+	FlatMoved    int // Keys moved on the flat ring over the whole plan
+	BoundedMoved int // Keys moved on the bounded-load ring over the whole plan
+	TreeMoved    int // Keys moved in the ring tree over the whole plan
+}
+
+// Compare inserts the same seeded keys into a flat consistent hashing ring, a consistent hashing ring with
+// bounded loads, and a ring tree, then scales all three through the plan and reports the keys each moves
+// and the spread of their node loads after every step. The flat and bounded rings grow and shrink one node
+// at a time; the ring tree grows onto the shallowest ring with a free slot, splitting its most loaded node
+// when every ring is full, and all shrink by removing their least loaded node.
+func Compare(cfg CompareConfig) (*ComparisonReport, error) {
+	cfg = cfg.withDefaults()
+	largest := 0
+	for _, n := range cfg.Plan {
+		largest = max(largest, n)
+	}
+
+	flat := New(largest, cfg.Options...)
+	tree := New(cfg.MaxCount, append([]Option{WithRandSource(rand.NewSource(cfg.Seed))}, cfg.Options...)...)
+	bounded := newBoundedRing(flat.config, cfg.Epsilon)
+	next := 0
+	grow := func() error {
+		id := "node" + strconv.Itoa(next)
+		next++
+		if err := growTree(tree, id, cfg.Threshold); err != nil {
+			return err
+		}
+		bounded.add(id)
+		return flat.InsertNode(NewNode(id, cfg.Keys+1))
+	}
+	for i := 0; i < cfg.Plan[0]; i++ {
+		if err := grow(); err != nil {
+			return nil, err
+		}
+	}
+
+	gen := workload.Uniform(rand.New(rand.NewSource(cfg.Seed)), 20)
+	keys := make([]string, 0, cfg.Keys)
+	for len(keys) < cfg.Keys {
+		key := gen.Next()
+		if err := tree.InsertKey(key); err != nil {
+			return nil, err
+		}
+		if err := flat.InsertKey(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	bounded.place(keys)
+
+	report := &ComparisonReport{Keys: cfg.Keys, Epsilon: cfg.Epsilon}
+	flatOwners, treeOwners, boundedOwners := owners(flat, keys), owners(tree, keys), bounded.owners()
+	step := func(target int) {
+		flatAfter, treeAfter, boundedAfter := owners(flat, keys), owners(tree, keys), bounded.owners()
+		s := ComparisonStep{
+			Target:  target,
+			Flat:    systemStep(flat.nodeLoads(), moved(flatOwners, flatAfter, map[string]int{})),
+			Bounded: systemStep(bounded.nodeLoads(), moved(boundedOwners, boundedAfter, map[string]int{})),
+			Tree:    systemStep(tree.nodeLoads(), moved(treeOwners, treeAfter, map[string]int{})),
+		}
+		flatOwners, treeOwners, boundedOwners = flatAfter, treeAfter, boundedAfter
+		report.FlatMoved += s.Flat.Moved
+		report.BoundedMoved += s.Bounded.Moved
+		report.TreeMoved += s.Tree.Moved
+		report.Steps = append(report.Steps, s)
+	}
+	step(cfg.Plan[0])
+
+	for _, target := range cfg.Plan[1:] {
+		for flat.Size() < target {
+			if err := grow(); err != nil {
+				return nil, err
+			}
+		}
+		for flat.Size() > target && flat.Size() > 1 {
+			if err := shrink(tree); err != nil {
+				return nil, err
+			}
+			if err := shrink(flat); err != nil {
+				return nil, err
+			}
+			bounded.shrink()
+		}
+		bounded.place(keys)
+		step(target)
+	}
+	return report, nil
+}
+
+// withDefaults fills in the zero fields of the config.
+func (cfg CompareConfig) withDefaults() CompareConfig {
+	if cfg.Keys < 1 {
+		cfg.Keys = 10000
+	}
+	if len(cfg.Plan) == 0 || cfg.Plan[0] < 1 {
+		cfg.Plan = GrowthPlan(10, 20, 5)
+	}
+	if cfg.MaxCount < 2 {
+		cfg.MaxCount = 7
+	}
+	if cfg.Threshold < 1 {
+		cfg.Threshold = cfg.Keys + 1
+	}
+	if cfg.Epsilon <= 0 {
+		cfg.Epsilon = 0.25
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return cfg
+}
+
+// systemStep summarizes the node loads of a system after a step.
+func systemStep(loads []int, moved int) SystemStep {
+	sort.Ints(loads) // Summed in a fixed order, so a seed reproduces the variance exactly
+	_, variance, _ := calculateStats(loads)
+	return SystemStep{Nodes: len(loads), Moved: moved, Variance: variance, Peak: calculateImbalance(loads).PeakToMean}
+}
+
+// nodeLoads returns the load of every physical node of the tree.
+func (r *Ring) nodeLoads() []int {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.root().systemLoads()
+}
+
+// boundedRing is a flat consistent hashing ring with bounded loads (Mirrokni, Thorup and Zadimoghaddam):
+// a key goes to the first node clockwise from its hash whose load is below ⌈(1+ε)·keys/nodes⌉. It only
+// models placement, for comparison, and places every key again on each membership change.
+type boundedRing struct {
+	config  *Config
+	epsilon float64
+	vnodes  []VNode // Sorted by hash
+	loads   map[string]int
+	owner   map[string]string
+	keys    []string
+}
+
+func newBoundedRing(config *Config, epsilon float64) *boundedRing {
+	return &boundedRing{config: config, epsilon: epsilon, loads: make(map[string]int), owner: make(map[string]string)}
+}
+
+// add places a node's vnodes on the ring.
+func (b *boundedRing) add(id string) {
+	b.loads[id] = 0
+	b.vnodes = append(b.vnodes, b.config.vNodes(id)...)
+	sort.Slice(b.vnodes, func(i, j int) bool { return b.vnodes[i].hash < b.vnodes[j].hash })
+}
+
+// shrink removes the least loaded node, the lowest ID among equals.
+func (b *boundedRing) shrink() {
+	lightest := ""
+	for id, load := range b.loads {
+		if lightest == "" || load < b.loads[lightest] || load == b.loads[lightest] && id < lightest {
+			lightest = id
+		}
+	}
+	delete(b.loads, lightest)
+	vnodes := b.vnodes[:0]
+	for _, vnode := range b.vnodes {
+		if vnode.nodeID != lightest {
+			vnodes = append(vnodes, vnode)
+		}
+	}
+	b.vnodes = vnodes
+}
+
+// place assigns every key in order, each to the first node clockwise that is below capacity.
+func (b *boundedRing) place(keys []string) {
+	b.keys = keys
+	for id := range b.loads {
+		b.loads[id] = 0
+	}
+	if len(b.loads) == 0 {
+		return
+	}
+	capacity := int(math.Ceil((1 + b.epsilon) * float64(len(keys)) / float64(len(b.loads))))
+	for _, key := range keys {
+		h := b.config.keyHash(key, 0)
+		i := sort.Search(len(b.vnodes), func(i int) bool { return b.vnodes[i].hash >= h })
+		for n := 0; n < len(b.vnodes); n++ {
+			id := b.vnodes[(i+n)%len(b.vnodes)].nodeID
+			if b.loads[id] < capacity {
+				b.loads[id]++
+				b.owner[key] = id
+				break
+			}
+		}
+	}
+}
+
+// owners returns a copy of the owner of every key.
+func (b *boundedRing) owners() map[string]string {
+	owners := make(map[string]string, len(b.keys))
+	for _, key := range b.keys {
+		owners[key] = b.owner[key]
+	}
+	return owners
+}
+
+// nodeLoads returns the load of every node.
+func (b *boundedRing) nodeLoads() []int {
+	loads := make([]int, 0, len(b.loads))
+	for _, load := range b.loads {
+		loads = append(loads, load)
+	}
+	return loads
+}
@@ -0,0 +1,56 @@
+package ringtree
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	plan := ScalingPlan{8, 12, 16, 10}
+	report, err := Compare(CompareConfig{Keys: 5000, Plan: plan, MaxCount: 4, Epsilon: 0.1, Seed: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Steps) != len(plan) {
+		t.Fatalf("got %d steps, want %d", len(report.Steps), len(plan))
+	}
+	first := report.Steps[0]
+	if first.Flat.Moved != 0 || first.Bounded.Moved != 0 || first.Tree.Moved != 0 {
+		t.Errorf("got moves on the initial insert: %+v", first)
+	}
+	var flat, bounded, tree int
+	for i, step := range report.Steps {
+		if step.Target != plan[i] || step.Flat.Nodes != plan[i] || step.Bounded.Nodes != plan[i] || step.Tree.Nodes != plan[i] {
+			t.Errorf("step %d: got %+v, want %d nodes in each system", i, step, plan[i])
+		}
+		capacity := math.Ceil(1.1 * 5000 / float64(plan[i]))
+		if peak := step.Bounded.Peak * 5000 / float64(plan[i]); peak > capacity+1e-6 {
+			t.Errorf("step %d: bounded-load ring peaks at %.0f keys, above capacity %.0f", i, peak, capacity)
+		}
+		flat += step.Flat.Moved
+		bounded += step.Bounded.Moved
+		tree += step.Tree.Moved
+	}
+	if flat != report.FlatMoved || bounded != report.BoundedMoved || tree != report.TreeMoved {
+		t.Errorf("got totals %d, %d and %d, want %d, %d and %d", report.FlatMoved, report.BoundedMoved, report.TreeMoved, flat, bounded, tree)
+	}
+	if report.FlatMoved == 0 || report.TreeMoved == 0 {
+		t.Errorf("expected keys to move while scaling, got %+v", report)
+	}
+}
+
+func TestCompareDeterministic(t *testing.T) {
+	cfg := CompareConfig{Keys: 2000, Plan: GrowthPlan(5, 15, 2), MaxCount: 4, Seed: 11}
+	a, err := Compare(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Compare(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave different reports:\n%+v\n%+v", a, b)
+	}
+}
@@ -0,0 +1,224 @@
+package ringtree
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentMutations(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 50))
+	rt.InsertNode(NewNode("B", 50))
+
+	const writers, perWriter = 4, 300
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter*2)
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				key := "key-" + strconv.Itoa(w) + "-" + strconv.Itoa(i)
+				if err := rt.InsertKey(key); err != nil {
+					errs <- err
+					continue
+				}
+				if _, err := rt.Lookup(key); err != nil {
+					errs <- err
+				}
+				// Remove every other key again so removals interleave with splits
+				if i%2 == 1 {
+					if err := rt.RemoveKey(key); err != nil {
+						errs <- err
+					}
+				}
+			}
+		}(w)
+	}
+
+	// Add nodes while keys are being written
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 2; i++ {
+			if err := rt.InsertNode(NewNode("extra-"+strconv.Itoa(i), 50)); err != nil && err != ErrRingAtCapacity {
+				errs <- err
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("unexpected error during concurrent mutations: %v", err)
+	}
+
+	expected := writers * perWriter / 2
+	checkNum(rt.Stats().Keys(), expected, t)
+	checkNum(rt.MemberStats().Keys, expected, t)
+	checkValid(rt, t)
+}
+
+func TestConcurrentLookupsDuringCollapse(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+		keys = append(keys, key)
+	}
+
+	// Readers walk the tree while removals shrink and collapse subrings
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				rt.MemberStats()
+				rt.VerifyChecksums()
+			}
+		}()
+	}
+
+	for _, key := range keys[:90] {
+		if err := rt.RemoveKey(key); err != nil {
+			t.Errorf("expected key %s to be removed, got error: %v", key, err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	for _, key := range keys[90:] {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Errorf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+	checkNum(rt.Stats().Keys(), 10, t)
+}
+
+func TestConcurrentStats(t *testing.T) {
+	rt := New(4, WithChecksums(true))
+	rt.InsertNode(NewNode("A", 20))
+
+	// Statistics are read without the writer lock while keys and nodes change, as a metrics scraper would
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 2; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				s := rt.Stats()
+				_ = s.Keys() + s.Nodes() + s.ChecksumFailures()
+				s.RemapStats()
+				s.TimeStats()
+				s.Percentiles()
+				s.Window(time.Minute)
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < 200; i++ {
+				key := "key-" + strconv.Itoa(w) + "-" + strconv.Itoa(i)
+				if err := rt.InsertKey(key); err != nil {
+					t.Errorf("inserting %s: %v", key, err)
+				}
+				if _, err := rt.Lookup(key); err != nil {
+					t.Errorf("looking up %s: %v", key, err)
+				}
+				if i%3 == 0 {
+					if err := rt.RemoveKey(key); err != nil {
+						t.Errorf("removing %s: %v", key, err)
+					}
+				}
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	checkNum(rt.Stats().Keys(), 4*(200-67), t)
+	if _, remapped, _, _ := rt.Stats().RemapStats(); remapped != rt.Counters().Remapped {
+		t.Errorf("got %d keys remapped by operations, want the %d counted", remapped, rt.Counters().Remapped)
+	}
+}
+
+func TestConcurrentLoadReaders(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 20))
+
+	// The load and hierarchy readers walk every ring while keys and nodes change
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 2; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				rt.GetDepth()
+				rt.GetLoads()
+				rt.GetTotalLoads()
+				rt.GetSystemVariance()
+				rt.GetImbalance()
+				rt.GetHierarchyInfo()
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		writers.Add(1)
+		go func(w int) {
+			defer writers.Done()
+			for i := 0; i < 200; i++ {
+				key := "key-" + strconv.Itoa(w) + "-" + strconv.Itoa(i)
+				if err := rt.InsertKey(key); err != nil {
+					t.Errorf("inserting %s: %v", key, err)
+				}
+				if i%2 == 0 {
+					if err := rt.RemoveKey(key); err != nil {
+						t.Errorf("removing %s: %v", key, err)
+					}
+				}
+			}
+		}(w)
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+
+	loads, _, _, _ := rt.GetSystemVariance()
+	checkNum(sum(loads), 4*100, t)
+	checkValid(rt, t)
+}
@@ -0,0 +1,425 @@
+package ringtree
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Config holds the tunables shared by a ring and all of its subrings.
+type Config struct {
+	MaxCount       int                 // Max members on the root ring
+	BranchFactor   int                 // Multiplier applied to a parent's maxCount for its subrings
+	Schedule       []int               // Max members per level; the last entry repeats for deeper levels
+	CapacityFunc   func(level int) int // Max members per level, takes precedence over Schedule
+	MaxDepth       int                 // Deepest level subrings may be created on (0 means unlimited)
+	Replicas       int                 // Number of virtual nodes per physical node
+	UseArray       bool                // Store vnodes in a sorted array instead of a red-black tree
+	AdaptiveCircle int                 // Vnode count past which an array circle migrates to a red-black tree (0 disables)
+	Logger         *log.Logger         // Destination for operation logs (nil discards them)
+	LogSampling    int                 // Write one of every this many operation log lines (0 or 1 writes all)
+	LogRate        int                 // Most operation log lines written per second (0 is unlimited)
+	SlowThreshold  time.Duration       // Latency at which an operation is written to the slow log (0 disables it)
+	SlowLogger     *log.Logger         // Destination of the slow log (nil writes it to Logger)
+	BatchWindow    time.Duration       // Debounce window for coalescing node joins (0 disables batching)
+	Headroom       float64             // Fraction of each node's threshold that rebalancing leaves free
+	RebalanceSkew  float64             // Per-node load, relative to the tree's mean, past which Rebalance relieves a subring
+	VNodeFactor    float64             // Node load, relative to its ring's mean, past which AdjustVNodes changes its vnodes (1 or below disables)
+	VNodeRounds    int                 // Consecutive AdjustVNodes rounds a node's load must stay past VNodeFactor
+	WarmUpFraction float64             // Share of its vnodes a newly inserted node starts with
+	WarmUp         time.Duration       // Time over which a new node ramps up to all of its vnodes (0 disables the ramp)
+	SeedNodes      int                 // Fewest nodes a split seeds its subring with (below 2 means 2)
+	ThresholdScale ThresholdScale      // Threshold of the nodes a split seeds on each level (nil inherits the split node's)
+	LoadFunc       LoadFunc            // Measures the load of a key (nil counts every key as one unit)
+	LevelSalt      LevelSalt           // Salts keys per level before hashing (nil uses DefaultLevelSalt)
+	KeyExtractor   KeyExtractor        // Selects the part of each key that is hashed (nil hashes the whole key)
+
+	HighWatermark float64       // Fraction of a node's threshold at which it overflows
+	LowWatermark  float64       // Fraction of a node's threshold at or below which a subring node is removed
+	MinDwell      time.Duration // Minimum time between structural changes on the same node
+
+	CheckpointInterval int           // Keys a split moves before briefly releasing the ring's lock (0 holds it throughout)
+	LockFreeReads      bool          // Route lookups through published snapshots instead of waiting on membership changes
+	AutoSplit          bool          // Make room for a node joining a full ring by splitting its most loaded member
+	SplitPolicy        SplitPolicy   // How overloaded nodes are split (nil sizes a subring for the node's load)
+	KeyIndex           bool          // Keep a root-level index of every key's node for constant-time lookups
+	RemapBudget        int           // Keys a node join moves eagerly; the rest move on their next access (0 moves all)
+	HeatHalfLife       time.Duration // Time in which the access count of a key halves (0 disables heat tracking)
+	RequestHalfLife    time.Duration // Time in which the read count of a node halves (0 disables request load tracking)
+
+	SpreadTolerance float64 // Allowed relative deviation of a node's arc share from an even share (0 disables)
+	SiblingMerge    bool    // Merge an underloaded subring node into an adjacent sibling instead of removing it
+	Checksums       bool    // Store a secondary hash of every key and verify it on lookup and migration
+
+	Replication      int          // Copies of each key held on distinct nodes (0 or 1 disables replicated mode)
+	ReadStrategy     ReadStrategy // Replica that serves each read in replicated mode (nil reads from the owner)
+	KeyEventSampling int          // Emit one KeyMoved event per this many key moves (0 disables key events)
+	Quorum           int          // Observers that must confirm a failure before a node is removed automatically
+	Observers        []Observer   // Observers consulted before automatic node removal
+
+	GossipFanout int // Members a ring forwards each gossip message to (0 forwards to all)
+	GossipTTL    int // Ring hops a gossip message travels before it is dropped (0 uses 16)
+
+	LeaseDuration time.Duration // Time a heartbeat keeps a node Up before it is marked Down (0 disables leases)
+	LeaseGrace    time.Duration // Time a node stays Down on an expired lease before it is drained (0 never drains)
+
+	WAL              WAL           // Log that every key, node, split and collapse operation is appended to (nil disables logging)
+	SnapshotEvery    int           // Logged operations after which a tree opened with Open checkpoints (0 disables)
+	SnapshotInterval time.Duration // Time after which a tree opened with Open checkpoints on its next operation (0 disables)
+	History          int           // Epochs of topology retained for AtEpoch (0 retains none)
+
+	Tracer Tracer // Starts a span for every key, node, split and collapse operation (nil disables tracing)
+
+	RandSource rand.Source // Source of the IDs of nodes the tree creates, for reproducible runs (nil uses crypto/rand)
+}
+
+// LoadFunc returns the load a key contributes to its node, in caller-defined units such as bytes.
+type LoadFunc func(key string, value []byte) int
+
+// ThresholdScale returns the threshold of a node seeded by a split on the given level, from the threshold of
+// the node being split.
+type ThresholdScale func(level int, threshold int) int
+
+// Option configures a ring tree at construction time.
+type Option func(*Config)
+
+const (
+	defaultBranchFactor = 1  // Multiplier applied to a parent's maxCount for its subrings
+	defaultReplicas     = 20 // Virtual nodes per physical node
+)
+
+// DefaultConfig returns the configuration of a tree whose root holds maxCount members, built without
+// options.
+func DefaultConfig(maxCount int) Config {
+	return *defaultConfig(maxCount)
+}
+
+// defaultConfig returns the configuration used when no options are given.
+func defaultConfig(maxCount int) *Config {
+	return &Config{
+		MaxCount:      maxCount,
+		BranchFactor:  defaultBranchFactor,
+		Replicas:      defaultReplicas,
+		HighWatermark: 1.0,
+		LowWatermark:  0.1,
+
+		CheckpointInterval: defaultCheckpointInterval,
+	}
+}
+
+// WithReplicas sets the number of virtual nodes placed for each physical node.
+func WithReplicas(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.Replicas = n
+		}
+	}
+}
+
+// WithArrayCircle stores vnodes in a sorted array instead of a red-black tree.
+func WithArrayCircle(useArray bool) Option {
+	return func(c *Config) {
+		c.UseArray = useArray
+	}
+}
+
+// WithAdaptiveCircle starts every ring with an array circle and migrates it to a red-black tree once it holds
+// more than threshold vnodes, and back once it shrinks below half of that. It takes precedence over
+// WithArrayCircle.
+func WithAdaptiveCircle(threshold int) Option {
+	return func(c *Config) {
+		c.AdaptiveCircle = threshold
+	}
+}
+
+// WithLogger writes operation logs to l. By default nothing is logged.
+func WithLogger(l *log.Logger) Option {
+	return func(c *Config) {
+		c.Logger = l
+	}
+}
+
+// WithBranchFactor multiplies a ring's maxCount by factor for each level of subrings.
+func WithBranchFactor(factor int) Option {
+	return func(c *Config) {
+		if factor > 0 {
+			c.BranchFactor = factor
+		}
+	}
+}
+
+// WithCapacitySchedule sets the max members of each level, e.g. []int{4, 8, 16}. Levels past the end of the
+// schedule use its last entry. The schedule is recorded in the ring's Config.
+func WithCapacitySchedule(schedule []int) Option {
+	return func(c *Config) {
+		c.Schedule = append([]int(nil), schedule...)
+	}
+}
+
+// WithCapacityFunc computes the max members of each level with fn.
+func WithCapacityFunc(fn func(level int) int) Option {
+	return func(c *Config) {
+		c.CapacityFunc = fn
+	}
+}
+
+// WithMaxDepth stops nesting subrings below level d. Overloaded nodes on a full ring at that depth have
+// their threshold raised instead of being split, keeping lookup depth bounded.
+func WithMaxDepth(d int) Option {
+	return func(c *Config) {
+		c.MaxDepth = d
+	}
+}
+
+// WithBatchWindow coalesces node joins arriving within d of each other into a single remap and Watch batch.
+func WithBatchWindow(d time.Duration) Option {
+	return func(c *Config) {
+		c.BatchWindow = d
+	}
+}
+
+// WithHeadroom reserves a fraction of every node's threshold that splits, collapses and removals will not
+// fill, so organic growth after a rebalance does not immediately trigger another split.
+func WithHeadroom(fraction float64) Option {
+	return func(c *Config) {
+		if fraction < 0 {
+			fraction = 0
+		}
+		if fraction >= 1 {
+			fraction = 0.99
+		}
+		c.Headroom = fraction
+	}
+}
+
+// WithSeedNodes seeds every subring created by a split with at least n nodes, so a split spreads a heavily
+// skewed node's load at once instead of re-overflowing.
+func WithSeedNodes(n int) Option {
+	return func(c *Config) {
+		c.SeedNodes = n
+	}
+}
+
+// WithThresholdScale sets the threshold of nodes seeded by a split on each level, for example
+// func(level, t int) int { return t / 2 } to halve thresholds with every level.
+func WithThresholdScale(fn ThresholdScale) Option {
+	return func(c *Config) {
+		c.ThresholdScale = fn
+	}
+}
+
+// WithLoadFunc measures node load, thresholds and split decisions in the units returned by fn instead of
+// key counts.
+func WithLoadFunc(fn LoadFunc) Option {
+	return func(c *Config) {
+		c.LoadFunc = fn
+	}
+}
+
+// WithWatermarks sets the default split (high) and removal (low) watermarks as fractions of node thresholds.
+func WithWatermarks(high, low float64) Option {
+	return func(c *Config) {
+		if high > 0 && low >= 0 && low < high {
+			c.HighWatermark, c.LowWatermark = high, low
+		}
+	}
+}
+
+// WithMinDwell prevents a node from being split or removed within d of its last structural change.
+func WithMinDwell(d time.Duration) Option {
+	return func(c *Config) {
+		c.MinDwell = d
+	}
+}
+
+// WithSpreadTolerance adds or removes salted vnodes after a node joins until its share of the ring is within
+// the given relative tolerance of an even share, bounding worst-case imbalance.
+func WithSpreadTolerance(tolerance float64) Option {
+	return func(c *Config) {
+		c.SpreadTolerance = tolerance
+	}
+}
+
+// WithSiblingMerge merges an underloaded subring node into an adjacent low-load sibling, reducing the node
+// count without collapsing the whole subring.
+func WithSiblingMerge(enabled bool) Option {
+	return func(c *Config) {
+		c.SiblingMerge = enabled
+	}
+}
+
+// WithChecksums stores a secondary hash of every key and validates it whenever the key is looked up or
+// migrated, detecting keys corrupted in memory or in transit.
+func WithChecksums(enabled bool) Option {
+	return func(c *Config) {
+		c.Checksums = enabled
+	}
+}
+
+// WithCheckpointInterval releases a ring's lock every n keys moved while splitting a node, so concurrent
+// lookups are not starved during very large splits. Zero holds the lock for the whole split.
+func WithCheckpointInterval(n int) Option {
+	return func(c *Config) {
+		if n >= 0 {
+			c.CheckpointInterval = n
+		}
+	}
+}
+
+// newCircle returns an empty circle of the configured kind.
+func (c *Config) newCircle() Circle {
+	if c.AdaptiveCircle > 0 {
+		return NewAdaptiveCircle(c.AdaptiveCircle)
+	}
+	return NewCircle(c.UseArray)
+}
+
+// vNodes returns the vnodes placed for a member with the given ID.
+func (c *Config) vNodes(id string) []VNode {
+	vNodes := make([]VNode, 0, c.Replicas)
+	for i := 0; i < c.Replicas; i++ {
+		vNodes = append(vNodes, VNode{hash: hash(id, i), nodeID: id})
+	}
+	return vNodes
+}
+
+// WithLockFreeReads routes Lookup through an immutable snapshot of each ring's circle, swapped atomically
+// after every membership change. A lookup that would wait on a change in progress returns the key's owner
+// from before the change instead of blocking. If the change is on the ring holding the key, the owner's
+// hold of the key cannot be checked, and the owner is returned with ErrUnverifiedOwner.
+func WithLockFreeReads(enabled bool) Option {
+	return func(c *Config) {
+		c.LockFreeReads = enabled
+	}
+}
+
+// capacity returns the max members of a ring on the given level, whose parent holds parentMax members.
+func (c *Config) capacity(level int, parentMax int) int {
+	capacity := parentMax * c.BranchFactor
+	switch {
+	case c.CapacityFunc != nil:
+		capacity = c.CapacityFunc(level)
+	case len(c.Schedule) > level:
+		capacity = c.Schedule[level]
+	case len(c.Schedule) > 0:
+		capacity = c.Schedule[len(c.Schedule)-1]
+	}
+	if capacity < 2 {
+		capacity = 2
+	}
+	return capacity
+}
+
+// WithRemovalQuorum requires quorum of the observers to confirm a node failure before AutoRemoveNode
+// removes it.
+func WithRemovalQuorum(quorum int, observers ...Observer) Option {
+	return func(c *Config) {
+		c.Quorum = quorum
+		c.Observers = append(c.Observers, observers...)
+	}
+}
+
+// WithReplication enables replicated mode, where each key is held by n distinct nodes and the next replica
+// is promoted when its primary goes Down.
+func WithReplication(n int) Option {
+	return func(c *Config) {
+		c.Replication = n
+	}
+}
+
+// WithKeyEvents emits a KeyMoved Watch event for one in every n key moves. Each event carries the exact
+// number of moves since the previous one, so aggregate counts stay exact while subscribers see 1/n of the
+// traffic.
+func WithKeyEvents(n int) Option {
+	return func(c *Config) {
+		c.KeyEventSampling = n
+	}
+}
+
+// seedNodes returns the fewest nodes a split seeds its subring with.
+func (c *Config) seedNodes() int {
+	if c.SeedNodes < 2 {
+		return 2
+	}
+	return c.SeedNodes
+}
+
+// childThreshold returns the threshold of a node seeded by a split on the given level.
+func (c *Config) childThreshold(level int, threshold int) int {
+	if c.ThresholdScale == nil {
+		return threshold
+	}
+	if scaled := c.ThresholdScale(level, threshold); scaled > 0 {
+		return scaled
+	}
+	return 1
+}
+
+// replication returns the number of replicas per key.
+func (c *Config) replication() int {
+	if c.Replication < 1 {
+		return 1
+	}
+	return c.Replication
+}
+
+// cost returns the load of a key under the configured LoadFunc.
+func (c *Config) cost(key string, value []byte) int {
+	if c.LoadFunc == nil {
+		return 1
+	}
+	if cost := c.LoadFunc(key, value); cost > 0 {
+		return cost
+	}
+	return 1
+}
+
+// Config returns the configuration shared by the ring tree.
+func (r *Ring) Config() Config {
+	return *r.config
+}
+
+// MaxCount returns the max number of members on this ring.
+func (r *Ring) MaxCount() int {
+	return r.maxCount
+}
+
+// SetWatermarks overrides the split and removal watermarks for this ring only.
+func (r *Ring) SetWatermarks(high, low float64) error {
+	if high <= 0 || low < 0 || low >= high {
+		return errors.New("watermarks must satisfy 0 <= low < high")
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.high, r.low = high, low
+	return nil
+}
+
+// Watermarks returns the split and removal watermarks of this ring.
+func (r *Ring) Watermarks() (float64, float64) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.high, r.low
+}
+
+// logging reports whether a logger is configured. Calls to logf on the key path check it first, so their
+// arguments are not boxed for a logger that would discard them.
+func (r *Ring) logging() bool {
+	return r.config != nil && r.config.Logger != nil
+}
+
+// logf writes an operation log line to the configured logger.
+func (r *Ring) logf(format string, args ...interface{}) {
+	if !r.logging() {
+		return
+	}
+	ok, suppressed := r.logs.allow(r.config)
+	if !ok {
+		return
+	}
+	if suppressed > 0 {
+		r.config.Logger.Printf("Suppressed %d log lines.\n", suppressed)
+	}
+	r.config.Logger.Printf(format, args...)
+}
@@ -0,0 +1,317 @@
+// Package dashboard serves a live, auto-refreshing view of a ring tree for demos and for debugging its
+// splitting behavior: the tree layout with a load bar for every node, the depth, and the load variance
+// over time. The view is driven by the tree's Watch event stream, so splits and collapses show up as they
+// happen.
+//
+// Routes:
+//
+//	GET /          The dashboard page
+//	GET /state     The tree layout, with the load and threshold of every node
+//	GET /history   Depth, key count and load variance sampled over time
+//	GET /events    Topology events as a server-sent event stream
+package dashboard
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+//go:embed dashboard.html
+var page []byte
+
+// DefaultHistory is the number of samples kept for /history unless WithHistory says otherwise.
+const DefaultHistory = 600
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithHistory sets the number of samples kept for the variance-over-time chart.
+func WithHistory(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.limit = n
+		}
+	}
+}
+
+// WithInterval sets how often the page polls /state between topology events, and the least time between
+// two samples taken by polling. Samples taken on topology events are always recorded.
+func WithInterval(d time.Duration) Option {
+	return func(h *Handler) {
+		if d > 0 {
+			h.interval = d
+		}
+	}
+}
+
+// Handler serves the dashboard of one ring tree. Close it to stop following the tree's events.
+type Handler struct {
+	ring     *ringtree.Ring
+	mux      *http.ServeMux
+	limit    int
+	interval time.Duration
+	cancel   func()
+	done     chan struct{}
+
+	mu      sync.Mutex
+	history []Sample // Oldest first, at most limit samples
+}
+
+// New returns a dashboard for the tree rooted at ring, and starts sampling the tree on every topology
+// change. Mount it with http.StripPrefix to serve it below a path.
+func New(ring *ringtree.Ring, opts ...Option) *Handler {
+	h := &Handler{ring: ring, mux: http.NewServeMux(), limit: DefaultHistory, interval: time.Second, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("/", h.index)
+	h.mux.HandleFunc("/state", h.state)
+	h.mux.HandleFunc("/history", h.historyHandler)
+	h.mux.HandleFunc("/events", h.events)
+
+	events, cancel := ring.Watch(16)
+	h.cancel = cancel
+	h.record(h.layout())
+	go h.follow(events)
+	return h
+}
+
+// ServeHTTP dispatches a request to its endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Close stops sampling the tree. Open event streams are not affected.
+func (h *Handler) Close() {
+	h.cancel()
+	<-h.done
+}
+
+// Node describes a ring member in the tree layout.
+type Node struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Level     int    `json:"level"`
+	Keys      int    `json:"keys"`
+	Load      int    `json:"load"`
+	Threshold int    `json:"threshold,omitempty"`
+	State     string `json:"state,omitempty"`
+	Members   []Node `json:"members,omitempty"`
+}
+
+// State is the response of /state.
+type State struct {
+	Time     time.Time `json:"time"`
+	Epoch    uint64    `json:"epoch"`
+	Depth    int       `json:"depth"`
+	Nodes    int       `json:"nodes"`
+	Keys     int       `json:"keys"`
+	Mean     float64   `json:"mean"`     // Mean load of the physical nodes
+	Variance float64   `json:"variance"` // Load variance of the physical nodes
+	Root     Node      `json:"root"`
+}
+
+// Sample is one point of the /history series.
+type Sample struct {
+	Time     time.Time `json:"time"`
+	Epoch    uint64    `json:"epoch"`
+	Depth    int       `json:"depth"`
+	Nodes    int       `json:"nodes"`
+	Keys     int       `json:"keys"`
+	Variance float64   `json:"variance"`
+}
+
+// Event is a topology event as sent on /events.
+type Event struct {
+	Type     string    `json:"type"`
+	RingID   string    `json:"ring"`
+	NodeID   string    `json:"node"`
+	Level    int       `json:"level"`
+	Remapped int       `json:"remapped,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	From     string    `json:"from,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// index serves the dashboard page.
+func (h *Handler) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(bytes.Replace(page, []byte("{{interval}}"), []byte(strconv.FormatInt(h.interval.Milliseconds(), 10)), 1))
+}
+
+// state reports the current layout of the tree, and samples it if the last sample is older than the
+// polling interval.
+func (h *Handler) state(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	state := h.layout()
+	h.mu.Lock()
+	stale := len(h.history) == 0 || state.Time.Sub(h.history[len(h.history)-1].Time) >= h.interval
+	h.mu.Unlock()
+	if stale {
+		h.record(state)
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// historyHandler reports the recorded samples, oldest first.
+func (h *Handler) historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.History())
+}
+
+// events streams the tree's topology events to the client until it disconnects.
+func (h *Handler) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	batches, cancel := h.ring.Watch(16)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case batch := <-batches:
+			events := make([]Event, len(batch))
+			for i, e := range batch {
+				events[i] = Event{Type: e.Type.String(), RingID: e.RingID, NodeID: e.NodeID, Level: e.Level, Remapped: e.Remapped, Key: e.Key, From: e.From, Time: e.Time}
+			}
+			data, _ := json.Marshal(events)
+			if _, err := w.Write(append(append([]byte("data: "), data...), '\n', '\n')); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// History returns the recorded samples, oldest first.
+func (h *Handler) History() []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Sample(nil), h.history...)
+}
+
+// follow samples the tree after every batch of topology events until the subscription is cancelled.
+func (h *Handler) follow(events <-chan []ringtree.Event) {
+	defer close(h.done)
+	for range events {
+		h.record(h.layout())
+	}
+}
+
+// record appends a sample of the state to the history, dropping the oldest sample once it is full.
+func (h *Handler) record(state State) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.history = append(h.history, Sample{Time: state.Time, Epoch: state.Epoch, Depth: state.Depth, Nodes: state.Nodes, Keys: state.Keys, Variance: state.Variance})
+	if len(h.history) > h.limit {
+		h.history = append(h.history[:0], h.history[len(h.history)-h.limit:]...)
+	}
+}
+
+// layout walks the tree and gathers its layout with the load statistics of its physical nodes.
+func (h *Handler) layout() State {
+	state := State{Time: time.Now(), Epoch: h.ring.Epoch()}
+	var loads []int
+	var walk func(ring *ringtree.Ring, member ringtree.Member) Node
+	walk = func(ring *ringtree.Ring, member ringtree.Member) Node {
+		stats := member.MemberStats()
+		n := Node{ID: member.ID(), Kind: member.Kind().String(), Level: ring.Level(), Keys: stats.Keys, Load: stats.Load}
+		switch member := member.(type) {
+		case *ringtree.Node:
+			n.Threshold = member.Threshold()
+			n.State = member.State().String()
+			loads = append(loads, stats.Load)
+		case *ringtree.Ring:
+			n.Level = member.Level()
+			if n.Level > state.Depth {
+				state.Depth = n.Level
+			}
+			ids := member.Members()
+			sort.Strings(ids)
+			for _, id := range ids {
+				if child, ok := member.Member(id); ok {
+					n.Members = append(n.Members, walk(member, child))
+				}
+			}
+		}
+		return n
+	}
+	state.Root = walk(h.ring, h.ring)
+	state.Root.Kind = "root"
+	state.Nodes = len(loads)
+	state.Keys = state.Root.Keys
+	state.Mean, state.Variance = meanVariance(loads)
+	return state
+}
+
+// meanVariance returns the mean and population variance of the loads, or zeros if there are none.
+func meanVariance(loads []int) (float64, float64) {
+	if len(loads) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, load := range loads {
+		sum += float64(load)
+	}
+	mean := sum / float64(len(loads))
+	var squares float64
+	for _, load := range loads {
+		squares += math.Pow(float64(load)-mean, 2)
+	}
+	return mean, squares / float64(len(loads))
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// methodNotAllowed rejects a request made with an unsupported method.
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
@@ -0,0 +1,149 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+func TestDashboard(t *testing.T) {
+	ring := ringtree.New(4)
+	if err := ring.InsertNode(ringtree.NewNode("A", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	dashboard := New(ring, WithInterval(time.Hour))
+	defer dashboard.Close()
+	server := httptest.NewServer(dashboard)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "const interval = 3600000;") {
+		t.Errorf("GET /: page does not carry the polling interval")
+	}
+
+	if err := ring.InsertNode(ringtree.NewNode("B", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := ring.InsertKey("key" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var state State
+	get(t, server.URL+"/state", http.StatusOK, &state)
+	if state.Nodes != 2 || state.Keys != 10 || state.Depth != 0 {
+		t.Errorf("GET /state: got %d nodes, %d keys at depth %d, want 2, 10 and 0", state.Nodes, state.Keys, state.Depth)
+	}
+	if len(state.Root.Members) != 2 || state.Root.Members[0].ID != "A" || state.Root.Members[0].Threshold != 1000 {
+		t.Errorf("GET /state: got root members %+v, want nodes A and B", state.Root.Members)
+	}
+	if state.Mean != 5 {
+		t.Errorf("GET /state: got mean load %v, want 5", state.Mean)
+	}
+
+	// The insert of B is sampled by the watcher; polling /state within the interval is not.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var history []Sample
+		get(t, server.URL+"/history", http.StatusOK, &history)
+		if len(history) == 2 && history[0].Nodes == 1 && history[1].Nodes == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET /history: got %+v, want samples of 1 and 2 nodes", history)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err = http.Post(server.URL+"/state", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /state: got status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestDashboardEvents(t *testing.T) {
+	ring := ringtree.New(4)
+	dashboard := New(ring)
+	defer dashboard.Close()
+	server := httptest.NewServer(dashboard)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("GET /events: got content type %q", ct)
+	}
+	if err := ring.InsertNode(ringtree.NewNode("A", 1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &events); err != nil {
+		t.Fatalf("decoding %q: %v", line, err)
+	}
+	if len(events) != 1 || events[0].Type != "NodeAdded" || events[0].NodeID != "A" {
+		t.Errorf("GET /events: got %+v, want NodeAdded for A", events)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	ring := ringtree.New(8)
+	dashboard := New(ring, WithHistory(3))
+	for _, id := range []string{"A", "B", "C", "D", "E"} {
+		if err := ring.InsertNode(ringtree.NewNode(id, 1000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dashboard.Close()
+
+	history := dashboard.History()
+	if len(history) > 3 {
+		t.Fatalf("got %d samples, want at most 3", len(history))
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].Epoch < history[i-1].Epoch {
+			t.Errorf("samples out of order: %+v", history)
+		}
+	}
+}
+
+// get fetches a URL, checks its status and decodes its JSON body into v.
+func get(t *testing.T, url string, code int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != code {
+		t.Fatalf("GET %s: got status %d, want %d", url, resp.StatusCode, code)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
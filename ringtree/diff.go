@@ -0,0 +1,104 @@
+package ringtree
+
+import "sort"
+
+// TopologyDiff is the difference between the topology and key placement of two trees, such as a tree
+// before and after an upgrade or a tree and its restored snapshot.
+type TopologyDiff struct {
+	AddedNodes        []string // Physical nodes only in the second tree, sorted
+	RemovedNodes      []string // Physical nodes only in the first tree, sorted
+	CreatedSubrings   []string // Subrings only in the second tree, sorted
+	CollapsedSubrings []string // Subrings only in the first tree, sorted
+	KeysKept          int      // Keys on the same node in both trees
+	KeysMoved         int      // Keys on a different node in the second tree
+	KeysAdded         int      // Keys only in the second tree
+	KeysRemoved       int      // Keys only in the first tree
+}
+
+// Unchanged reports whether both trees have the same nodes and subrings, with every key on the same node.
+func (d TopologyDiff) Unchanged() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.CreatedSubrings) == 0 &&
+		len(d.CollapsedSubrings) == 0 && d.KeysMoved == 0 && d.KeysAdded == 0 && d.KeysRemoved == 0
+}
+
+// placement is the nodes, subrings and key owners of a tree.
+type placement struct {
+	nodes    map[string]bool
+	subrings map[string]bool
+	owners   map[string]string // Node holding each key
+}
+
+// Diff compares the topology and key placement of two trees: the nodes and subrings only one of them has,
+// and how many keys stayed on their node, moved to another, or are held by only one tree. Keys are compared
+// where they are stored, so keys held by custom members are not counted. Each tree is read under its own
+// writer lock in turn, so the trees can be changed between the two reads.
+func Diff(a, b *Ring) TopologyDiff {
+	before, after := a.placement(), b.placement()
+	d := TopologyDiff{
+		AddedNodes:        missing(after.nodes, before.nodes),
+		RemovedNodes:      missing(before.nodes, after.nodes),
+		CreatedSubrings:   missing(after.subrings, before.subrings),
+		CollapsedSubrings: missing(before.subrings, after.subrings),
+	}
+	for key, owner := range before.owners {
+		switch now, ok := after.owners[key]; {
+		case !ok:
+			d.KeysRemoved++
+		case now == owner:
+			d.KeysKept++
+		default:
+			d.KeysMoved++
+		}
+	}
+	for key := range after.owners {
+		if _, ok := before.owners[key]; !ok {
+			d.KeysAdded++
+		}
+	}
+	return d
+}
+
+// placement returns the nodes, subrings and key owners of the whole tree.
+func (r *Ring) placement() placement {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	p := placement{nodes: make(map[string]bool), subrings: make(map[string]bool), owners: make(map[string]string)}
+	root := r.root()
+	root.RLock()
+	defer root.RUnlock()
+	root.collectPlacement(p)
+	return p
+}
+
+// collectPlacement adds the ring's members and everything below them to a placement (assuming the ring's
+// mutex is held).
+func (r *Ring) collectPlacement(p placement) {
+	for id, member := range r.members {
+		switch member := member.(type) {
+		case *Node:
+			p.nodes[id] = true
+			for _, keys := range member.keys {
+				for key := range keys {
+					p.owners[key] = id
+				}
+			}
+		case *Ring:
+			p.subrings[id] = true
+			member.RLock()
+			member.collectPlacement(p)
+			member.RUnlock()
+		}
+	}
+}
+
+// missing returns the sorted IDs in from that are not in other.
+func missing(from, other map[string]bool) []string {
+	var ids []string
+	for id := range from {
+		if !other[id] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,100 @@
+package ringtree
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiffRestore(t *testing.T) {
+	rt := New(4)
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	if _, err := rt.Split("A"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 300; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Diff(rt, restored)
+	if !d.Unchanged() || d.KeysKept != 300 {
+		t.Errorf("expected a restore to keep all 300 keys in place, got %+v", d)
+	}
+	if d := Diff(rt, rt); !d.Unchanged() {
+		t.Errorf("expected a tree to match itself, got %+v", d)
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	rt := New(4)
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := owners(rt, keys)
+
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.RemoveNodeByID("B")
+	rt.InsertNode(NewNode("D", 1000))
+	rt.RemoveKey(keys[0])
+	rt.InsertKey("new key")
+	moved := 0
+	for key, owner := range owners(rt, keys[1:]) {
+		if owner != owned[key] {
+			moved++
+		}
+	}
+
+	d := Diff(before, rt)
+	var children []string
+	subring.RLock()
+	for id := range subring.members {
+		children = append(children, id)
+	}
+	subring.RUnlock()
+	added := append([]string{"D"}, children...)
+	sort.Strings(added)
+	if !reflect.DeepEqual(d.AddedNodes, added) {
+		t.Errorf("expected added nodes %v, got %v", added, d.AddedNodes)
+	}
+	if !reflect.DeepEqual(d.RemovedNodes, []string{"A", "B"}) {
+		t.Errorf("expected nodes A and B removed, got %v", d.RemovedNodes)
+	}
+	if !reflect.DeepEqual(d.CreatedSubrings, []string{"A"}) || len(d.CollapsedSubrings) != 0 {
+		t.Errorf("expected subring A created and none collapsed, got %v and %v", d.CreatedSubrings, d.CollapsedSubrings)
+	}
+	if d.KeysMoved != moved || d.KeysKept != 299-moved || d.KeysAdded != 1 || d.KeysRemoved != 1 {
+		t.Errorf("expected %d keys moved, %d kept, 1 added and 1 removed, got %+v", moved, 299-moved, d)
+	}
+	if d.Unchanged() {
+		t.Error("expected the diff to report changes")
+	}
+}
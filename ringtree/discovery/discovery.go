@@ -0,0 +1,235 @@
+// Package discovery registers ring nodes in a coordination service such as etcd or Consul, keeps the nodes
+// of a ring tree in sync with the registrations, and publishes the topology epoch every router has applied,
+// so operators and routers can tell when all of them share the same view.
+//
+// The topology epoch is the store revision up to which a router has applied registration changes. Each
+// router publishes it with a digest of the registrations it applied; routers publishing the digest of the
+// registrations currently in the store have converged.
+package discovery
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+	"github.com/kagwave/ring-tree/ringtree/membership"
+)
+
+// config holds the settings of a Discovery.
+type config struct {
+	prefix     string
+	membership []membership.Option
+}
+
+// Option configures a Discovery.
+type Option func(*config)
+
+// WithPrefix keeps the registrations under prefix instead of "ringtree/", so several trees can share a
+// store.
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithMembership configures how registrations become nodes, such as the threshold of a node of weight 1.
+func WithMembership(opts ...membership.Option) Option {
+	return func(c *config) {
+		c.membership = append(c.membership, opts...)
+	}
+}
+
+// Discovery keeps a ring tree in sync with the nodes registered in a Store.
+type Discovery struct {
+	store  Store
+	router string
+	config config
+	sync   *membership.Sync
+	view   map[string][]byte // Registrations applied, by node ID
+	epoch  int64
+	mu     sync.Mutex
+}
+
+// epochFile is the encoded form of a router's published epoch.
+type epochFile struct {
+	Epoch int64  `json:"epoch"`
+	View  string `json:"view"`
+}
+
+// New returns a Discovery applying registrations to ring and publishing its epoch as router.
+func New(store Store, ring *ringtree.Ring, router string, opts ...Option) *Discovery {
+	d := &Discovery{store: store, router: router, config: config{prefix: "ringtree/"}, view: make(map[string][]byte)}
+	for _, opt := range opts {
+		opt(&d.config)
+	}
+	d.sync = membership.New(ring, d.config.membership...)
+	return d
+}
+
+// Register advertises a node hosted by this process for as long as the process keeps its session.
+func (d *Discovery) Register(ctx context.Context, nodeID string, meta membership.Meta) error {
+	value, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return d.store.Register(ctx, d.nodesPrefix()+nodeID, value)
+}
+
+// Deregister withdraws a node hosted by this process.
+func (d *Discovery) Deregister(ctx context.Context, nodeID string) error {
+	return d.store.Delete(ctx, d.nodesPrefix()+nodeID)
+}
+
+// Run joins the registered nodes to the ring, then applies registration changes as they happen until ctx
+// is done. A withdrawn or expired registration is handled as a node failure, so the ring's removal quorum
+// applies to it.
+func (d *Discovery) Run(ctx context.Context) error {
+	kvs, revision, err := d.store.List(ctx, d.nodesPrefix())
+	if err != nil {
+		return err
+	}
+	for _, kv := range kvs {
+		d.apply(Event{Type: Put, KV: kv})
+	}
+	if err := d.publish(ctx, revision); err != nil {
+		return err
+	}
+
+	for event := range d.store.Watch(ctx, d.nodesPrefix(), revision) {
+		d.apply(event)
+		if err := d.publish(ctx, event.Revision); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// apply makes the ring follow a registration change.
+func (d *Discovery) apply(event Event) {
+	nodeID := d.nodeID(event.Key)
+	d.mu.Lock()
+	_, known := d.view[nodeID]
+	if event.Type == Delete {
+		delete(d.view, nodeID)
+	} else {
+		d.view[nodeID] = event.Value
+	}
+	d.mu.Unlock()
+
+	switch {
+	case event.Type == Delete:
+		d.sync.Fail(nodeID)
+	case known:
+		d.sync.Update(nodeID, event.Value)
+	default:
+		d.sync.Join(nodeID, event.Value)
+	}
+}
+
+// Epoch returns the topology epoch this router has applied.
+func (d *Discovery) Epoch() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.epoch
+}
+
+// Epochs returns the topology epoch published by every running router.
+func (d *Discovery) Epochs(ctx context.Context) (map[string]int64, error) {
+	files, err := d.published(ctx)
+	if err != nil {
+		return nil, err
+	}
+	epochs := make(map[string]int64, len(files))
+	for router, file := range files {
+		epochs[router] = file.Epoch
+	}
+	return epochs, nil
+}
+
+// Converged reports whether every running router has applied the registrations currently in the store.
+func (d *Discovery) Converged(ctx context.Context) (bool, error) {
+	kvs, _, err := d.store.List(ctx, d.nodesPrefix())
+	if err != nil {
+		return false, err
+	}
+	view := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		view[d.nodeID(kv.Key)] = kv.Value
+	}
+	want := digest(view)
+
+	files, err := d.published(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, file := range files {
+		if file.View != want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// published returns the epoch every running router has published.
+func (d *Discovery) published(ctx context.Context) (map[string]epochFile, error) {
+	kvs, _, err := d.store.List(ctx, d.epochsPrefix())
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]epochFile, len(kvs))
+	for _, kv := range kvs {
+		var file epochFile
+		if err := json.Unmarshal(kv.Value, &file); err != nil {
+			continue
+		}
+		files[strings.TrimPrefix(kv.Key, d.epochsPrefix())] = file
+	}
+	return files, nil
+}
+
+// publish records and advertises the epoch this router has applied.
+func (d *Discovery) publish(ctx context.Context, epoch int64) error {
+	d.mu.Lock()
+	d.epoch = epoch
+	value, err := json.Marshal(epochFile{Epoch: epoch, View: digest(d.view)})
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return d.store.Register(ctx, d.epochsPrefix()+d.router, value)
+}
+
+// digest returns a fingerprint of a set of registrations.
+func digest(view map[string][]byte) string {
+	ids := make([]string, 0, len(view))
+	for id := range view {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	h := fnv.New64a()
+	for _, id := range ids {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		h.Write(view[id])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (d *Discovery) nodesPrefix() string {
+	return d.config.prefix + "nodes/"
+}
+
+func (d *Discovery) epochsPrefix() string {
+	return d.config.prefix + "epochs/"
+}
+
+// nodeID returns the node ID a registration key names.
+func (d *Discovery) nodeID(key string) string {
+	return strings.TrimPrefix(key, d.nodesPrefix())
+}
@@ -0,0 +1,54 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+	"github.com/kagwave/ring-tree/ringtree/membership"
+)
+
+func TestDiscovery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemStore()
+
+	ringA, ringB := ringtree.New(8), ringtree.New(8)
+	a := New(store, ringA, "router-a", WithMembership(membership.WithThreshold(10)))
+	b := New(store, ringB, "router-b", WithMembership(membership.WithThreshold(10)))
+	if err := a.Register(ctx, "n1", membership.Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	go a.Run(ctx)
+	go b.Run(ctx)
+
+	a.Register(ctx, "n2", membership.Meta{Weight: 2})
+	b.Register(ctx, "n3", membership.Meta{})
+	b.Deregister(ctx, "n1")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		epochs, _ := a.Epochs(ctx)
+		if converged, err := a.Converged(ctx); err == nil && converged && len(epochs) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("routers did not converge: %v and %v", ringA.Members(), ringB.Members())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for _, ring := range []*ringtree.Ring{ringA, ringB} {
+		if _, ok := ring.Member("n1"); ok {
+			t.Errorf("ring still holds withdrawn node n1: %v", ring.Members())
+		}
+		member, ok := ring.Member("n2")
+		if !ok || member.(*ringtree.Node).Threshold() != 20 {
+			t.Errorf("node n2: got %v, want threshold 20", member)
+		}
+		if _, ok := ring.Member("n3"); !ok {
+			t.Errorf("ring is missing node n3: %v", ring.Members())
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package discovery
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KV is a key and its value in a Store, with the revision of its last change.
+type KV struct {
+	Key      string
+	Value    []byte
+	Revision int64
+}
+
+// EventType says whether a watched key was written or deleted.
+type EventType int
+
+const (
+	Put EventType = iota
+	Delete
+)
+
+// Event is a change to a watched key. Deletions carry no value.
+type Event struct {
+	Type EventType
+	KV
+}
+
+// Store is the coordination service holding the registrations: etcd, Consul or another strongly consistent
+// key-value store. Revisions grow with every change to the store, like etcd's revision or Consul's modify
+// index.
+type Store interface {
+	// Register writes a key that lives as long as the caller's session (an etcd lease or a Consul session),
+	// so it is deleted when the process stops renewing it.
+	Register(ctx context.Context, key string, value []byte) error
+	// Put writes a key.
+	Put(ctx context.Context, key string, value []byte) error
+	// Delete removes a key.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys under a prefix and the store's revision they were read at.
+	List(ctx context.Context, prefix string) ([]KV, int64, error)
+	// Watch streams the changes under a prefix made after a revision, until ctx is done.
+	Watch(ctx context.Context, prefix string, after int64) <-chan Event
+}
+
+// MemStore is a Store kept in memory, for tests and processes sharing one tree. It keeps every change, so
+// a watch can start from any revision.
+type MemStore struct {
+	keys     map[string]KV
+	revision int64
+	history  []Event
+	watchers map[*memWatcher]bool
+	mu       sync.Mutex
+}
+
+// memWatcher is an open Watch on a MemStore.
+type memWatcher struct {
+	prefix  string
+	events  chan Event
+	pending []Event
+	wake    chan struct{}
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{keys: make(map[string]KV), watchers: make(map[*memWatcher]bool)}
+}
+
+// Register writes a key; in memory it lives until deleted.
+func (m *MemStore) Register(ctx context.Context, key string, value []byte) error {
+	return m.Put(ctx, key, value)
+}
+
+// Put writes a key.
+func (m *MemStore) Put(ctx context.Context, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revision++
+	kv := KV{Key: key, Value: append([]byte(nil), value...), Revision: m.revision}
+	m.keys[key] = kv
+	m.notify(Event{Type: Put, KV: kv})
+	return nil
+}
+
+// Delete removes a key.
+func (m *MemStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key]; !ok {
+		return nil
+	}
+	m.revision++
+	delete(m.keys, key)
+	m.notify(Event{Type: Delete, KV: KV{Key: key, Revision: m.revision}})
+	return nil
+}
+
+// List returns the keys under a prefix in key order.
+func (m *MemStore) List(ctx context.Context, prefix string) ([]KV, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kvs []KV
+	for key, kv := range m.keys {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key < kvs[j].Key
+	})
+	return kvs, m.revision, nil
+}
+
+// Watch streams the changes under a prefix made after a revision.
+func (m *MemStore) Watch(ctx context.Context, prefix string, after int64) <-chan Event {
+	w := &memWatcher{prefix: prefix, events: make(chan Event), wake: make(chan struct{}, 1)}
+	m.mu.Lock()
+	for _, event := range m.history[min(max(int(after), 0), len(m.history)):] {
+		if strings.HasPrefix(event.Key, prefix) {
+			w.pending = append(w.pending, event)
+		}
+	}
+	w.wake <- struct{}{}
+	m.watchers[w] = true
+	m.mu.Unlock()
+
+	go func() {
+		defer close(w.events)
+		defer func() {
+			m.mu.Lock()
+			delete(m.watchers, w)
+			m.mu.Unlock()
+		}()
+		for {
+			m.mu.Lock()
+			pending := w.pending
+			w.pending = nil
+			m.mu.Unlock()
+			for _, event := range pending {
+				select {
+				case w.events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-w.wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return w.events
+}
+
+// notify records an event and queues it for the watchers of its key (assuming the mutex is held).
+func (m *MemStore) notify(event Event) {
+	m.history = append(m.history, event)
+	for w := range m.watchers {
+		if !strings.HasPrefix(event.Key, w.prefix) {
+			continue
+		}
+		w.pending = append(w.pending, event)
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// ErrDistribution is returned by CheckDistribution when the keys of its workload are spread less evenly
+// than the tolerance allows.
+var ErrDistribution = errors.New("key distribution exceeds tolerance")
+
+// DistributionTolerance bounds how unevenly CheckDistribution's workload may spread over the nodes. Zero
+// bounds are not checked; the other zero fields take the defaults noted.
+type DistributionTolerance struct {
+	Keys          int     // Keys of the seeded workload (10000)
+	Seed          int64   // Seed of the workload's keys (1, so a check gives the same result on every run)
+	MaxPeakToMean float64 // Highest node load over the mean load
+	MaxCV         float64 // Standard deviation of the node loads over the mean load
+	MinChiSquareP float64 // Lowest p-value of the chi-square test of the node loads against uniformity
+}
+
+// DistributionCheck is how CheckDistribution's workload spread over the nodes of the tree.
+type DistributionCheck struct {
+	Nodes      int     // Physical nodes the workload spread over
+	Keys       int     // Keys of the workload
+	PeakToMean float64 // Highest node load over the mean load
+	CV         float64 // Standard deviation of the node loads over the mean load
+	ChiSquareP float64 // P-value of the chi-square test of the node loads against uniformity
+}
+
+// CheckDistribution inserts a seeded workload into a shadow of the tree and checks how evenly its keys
+// spread over the physical nodes, so a test or CI job can assert that a configuration still places keys
+// evenly instead of reading a printout. Only the workload's keys are counted; keys already in the tree, and
+// splits the workload causes, shape where they land. The tree itself is not changed. It returns the check
+// with an error wrapping ErrDistribution that names every bound exceeded.
+func CheckDistribution(r *Ring, tolerance DistributionTolerance) (DistributionCheck, error) {
+	tolerance = tolerance.withDefaults()
+	shadow, err := r.Shadow()
+	if err != nil {
+		return DistributionCheck{}, err
+	}
+	gen := workload.Uniform(rand.New(rand.NewSource(tolerance.Seed)), 20)
+	keys, err := workload.Insert(shadow, gen, tolerance.Keys)
+	if err != nil {
+		return DistributionCheck{}, err
+	}
+
+	p := shadow.placement()
+	counts := make(map[string]int, len(p.nodes))
+	for id := range p.nodes {
+		counts[id] = 0
+	}
+	for _, key := range keys {
+		if owner, ok := p.owners[key]; ok {
+			counts[owner]++
+		}
+	}
+	loads := make([]int, 0, len(counts))
+	for _, load := range counts {
+		loads = append(loads, load)
+	}
+	sort.Ints(loads) // Summed in a fixed order, so a seed reproduces the check exactly
+	mean, _, stdDev := calculateStats(loads)
+	check := DistributionCheck{
+		Nodes:      len(loads),
+		Keys:       len(keys),
+		PeakToMean: calculateImbalance(loads).PeakToMean,
+		ChiSquareP: testUniformity(loads).ChiSquareP,
+	}
+	if mean > 0 {
+		check.CV = stdDev / mean
+	}
+
+	var exceeded []string
+	if tolerance.MaxPeakToMean > 0 && check.PeakToMean > tolerance.MaxPeakToMean {
+		exceeded = append(exceeded, fmt.Sprintf("peak-to-mean %.3f above %.3f", check.PeakToMean, tolerance.MaxPeakToMean))
+	}
+	if tolerance.MaxCV > 0 && check.CV > tolerance.MaxCV {
+		exceeded = append(exceeded, fmt.Sprintf("stddev/mean %.3f above %.3f", check.CV, tolerance.MaxCV))
+	}
+	if tolerance.MinChiSquareP > 0 && check.ChiSquareP < tolerance.MinChiSquareP {
+		exceeded = append(exceeded, fmt.Sprintf("chi-square p-value %.3g below %.3g", check.ChiSquareP, tolerance.MinChiSquareP))
+	}
+	if len(exceeded) > 0 {
+		return check, fmt.Errorf("%w: %s", ErrDistribution, strings.Join(exceeded, "; "))
+	}
+	return check, nil
+}
+
+// withDefaults fills in the zero fields of the tolerance.
+func (t DistributionTolerance) withDefaults() DistributionTolerance {
+	if t.Keys < 1 {
+		t.Keys = 10000
+	}
+	if t.Seed == 0 {
+		t.Seed = 1
+	}
+	return t
+}
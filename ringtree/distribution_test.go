@@ -0,0 +1,38 @@
+package ringtree
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckDistribution(t *testing.T) {
+	rt := New(8)
+	for _, id := range []string{"A", "B", "C", "D", "E", "F"} {
+		rt.InsertNode(NewNode(id, 100000))
+	}
+	check, err := CheckDistribution(rt, DistributionTolerance{Keys: 5000, MaxPeakToMean: 3, MaxCV: 1})
+	if err != nil {
+		t.Fatalf("expected the distribution to be within tolerance, got error: %v", err)
+	}
+	if check.Nodes != 6 || check.Keys != 5000 || check.PeakToMean < 1 || check.CV <= 0 {
+		t.Errorf("unexpected check: %+v", check)
+	}
+	if n := rt.root().stats.numKeys.get(); n != 0 {
+		t.Errorf("expected the tree to be left unchanged, got %d keys", n)
+	}
+
+	again, _ := CheckDistribution(rt, DistributionTolerance{Keys: 5000})
+	if again != check {
+		t.Errorf("expected the same seed to give the same check, got %+v and %+v", check, again)
+	}
+
+	// No hashed spread is perfectly even, so bounds at perfection fail
+	_, err = CheckDistribution(rt, DistributionTolerance{Keys: 5000, MaxPeakToMean: 1.001, MaxCV: 0.001, MinChiSquareP: 0.05})
+	if !errors.Is(err, ErrDistribution) {
+		t.Fatalf("expected ErrDistribution, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "peak-to-mean") || !strings.Contains(err.Error(), "stddev/mean") {
+		t.Errorf("expected the exceeded bounds to be named, got %v", err)
+	}
+}
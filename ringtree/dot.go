@@ -0,0 +1,91 @@
+package ringtree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DOTOptions controls what ToDOT draws.
+type DOTOptions struct {
+	VNodes bool // List every vnode of a node with the keys it holds
+}
+
+// ToDOT writes the tree below the ring as a Graphviz DOT graph: every ring is a cluster nested in its
+// parent's, and every node is labeled with its load and threshold, shaded by state and outlined in red once
+// its load exceeds its threshold. Render it with, for example, `dot -Tsvg`.
+func (r *Ring) ToDOT(w io.Writer, opts DOTOptions) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph ringtree {\n\tnode [shape=box, style=filled, fillcolor=white];\n")
+	r.writeDOT(bw, opts, 1)
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// writeDOT writes the ring and its subrings as nested clusters (assuming the tree's writer lock is held).
+func (r *Ring) writeDOT(w io.Writer, opts DOTOptions, depth int) {
+	indent := strings.Repeat("\t", depth)
+	r.RLock()
+	fmt.Fprintf(w, "%ssubgraph %q {\n", indent, "cluster_"+r.id)
+	fmt.Fprintf(w, "%s\tlabel=%q;\n", indent, fmt.Sprintf("%s (level %d, %d/%d members)", r.id, r.level, len(r.members), r.maxCount))
+
+	ids := make([]string, 0, len(r.members))
+	for id := range r.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var subrings []*Ring
+	for _, id := range ids {
+		switch member := r.members[id].(type) {
+		case *Ring:
+			subrings = append(subrings, member)
+		case *Node:
+			fmt.Fprintf(w, "%s\t%q [%s];\n", indent, id, member.dotAttributes(opts))
+		default:
+			label := fmt.Sprintf("%s\n%d keys", id, member.MemberStats().Keys)
+			fmt.Fprintf(w, "%s\t%q [label=%q, shape=ellipse];\n", indent, id, label)
+		}
+	}
+	r.RUnlock()
+
+	for _, subring := range subrings {
+		subring.writeDOT(w, opts, depth+1)
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+// dotAttributes returns the DOT attributes drawing the node (assuming its ring's mutex is already locked).
+func (n *Node) dotAttributes(opts DOTOptions) string {
+	label := fmt.Sprintf("%s\n%d/%d", n.id, n.load, n.threshold)
+	if state := n.State(); state != Up {
+		label += " " + state.String()
+	}
+	if opts.VNodes {
+		hashes := make([]uint32, 0, len(n.keys))
+		for vNodeHash := range n.keys {
+			hashes = append(hashes, vNodeHash)
+		}
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+		for _, vNodeHash := range hashes {
+			label += fmt.Sprintf("\nvnode %d: %d keys", vNodeHash, len(n.keys[vNodeHash]))
+		}
+	}
+
+	attrs := fmt.Sprintf("label=%q", label)
+	switch n.State() {
+	case Down:
+		attrs += ", fillcolor=gray"
+	case Draining, Suspect:
+		attrs += ", fillcolor=lightyellow"
+	case Joining:
+		attrs += ", fillcolor=lightblue"
+	}
+	if n.load > n.threshold {
+		attrs += ", color=red, penwidth=2"
+	}
+	return attrs
+}
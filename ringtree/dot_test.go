@@ -0,0 +1,42 @@
+package ringtree
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestToDOT(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+	for i := 0; i < 40; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	rt.SetNodeState("B", Draining)
+
+	var buf bytes.Buffer
+	if err := rt.ToDOT(&buf, DOTOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph ringtree {") || strings.Count(dot, "{") != strings.Count(dot, "}") {
+		t.Fatalf("got malformed graph:\n%s", dot)
+	}
+	clusters := strings.Count(dot, "subgraph")
+	if want := rt.Counters().Rings; clusters != want {
+		t.Fatalf("got %d clusters, want one per ring (%d)", clusters, want)
+	}
+	if strings.Contains(dot, "vnode") {
+		t.Fatal("expected no vnode detail by default")
+	}
+
+	buf.Reset()
+	if err := rt.ToDOT(&buf, DOTOptions{VNodes: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "vnode") {
+		t.Fatal("expected vnode detail with VNodes set")
+	}
+}
@@ -0,0 +1,21 @@
+package ringtree
+
+import "errors"
+
+// Errors returned by ring operations. Callers should compare against these with errors.Is.
+var (
+	ErrRingAtCapacity     = errors.New("ring is at capacity")
+	ErrRingEmpty          = errors.New("ring is empty")
+	ErrNodeExists         = errors.New("node is already in the ring")
+	ErrNodeNotFound       = errors.New("node not found in the ring")
+	ErrKeyExists          = errors.New("key is already in ring")
+	ErrKeyNotFound        = errors.New("key not found in the ring")
+	ErrRootCollapse       = errors.New("cannot collapse root ring")
+	ErrChecksumMismatch   = errors.New("key checksum mismatch")
+	ErrQuorumNotReached   = errors.New("node failure not confirmed by quorum")
+	ErrNoReplicaAvailable = errors.New("no replica available for key")
+	ErrLeasesDisabled     = errors.New("leases are not enabled")
+	ErrInvariantViolated  = errors.New("tree invariant violated")
+	ErrSubringNotFound    = errors.New("subring not found in the ring")
+	ErrExpvarInUse        = errors.New("expvar name is already published")
+)
@@ -0,0 +1,211 @@
+package ringtree
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Format selects the encoding of ExportStats.
+type Format int
+
+const (
+	FormatJSON Format = iota // One JSON document
+	FormatCSV                // One long-format table: section, name, level, metric, value
+)
+
+// StatsReport holds the evaluation results written by ExportStats.
+type StatsReport struct {
+	Keys       int                       `json:"keys"`
+	Nodes      int                       `json:"nodes"`
+	Depth      int                       `json:"depth"`
+	Levels     []LevelInfo               `json:"levels"`
+	Rings      []RingInfo                `json:"rings"`
+	Imbalance  ImbalanceReport           `json:"imbalance"`
+	Remaps     RemapReport               `json:"remaps"`
+	Operations map[string]OperationStats `json:"operations"`
+}
+
+// ImbalanceReport holds the imbalance of the node loads, system-wide and at each level.
+type ImbalanceReport struct {
+	System Imbalance        `json:"system"`
+	Levels []LevelImbalance `json:"levels"`
+}
+
+// LevelImbalance is the imbalance of the node loads at one level.
+type LevelImbalance struct {
+	Level int `json:"level"`
+	Imbalance
+}
+
+// RemapReport summarizes the keys remapped by membership changes.
+type RemapReport struct {
+	Operations int     `json:"operations"` // Membership changes that remapped keys
+	Total      int     `json:"total"`      // Keys remapped
+	Average    float64 `json:"average"`    // Keys remapped per change
+	Ratio      float64 `json:"ratio"`      // Keys remapped relative to the ideal K/N per change
+}
+
+// OperationStats summarizes the durations of one operation, in microseconds.
+type OperationStats struct {
+	Count    uint64  `json:"count"`
+	Mean     float64 `json:"mean_us"`
+	Variance float64 `json:"variance_us2"`
+	Stdev    float64 `json:"stdev_us"`
+	P50      float64 `json:"p50_us"`
+	P90      float64 `json:"p90_us"`
+	P99      float64 `json:"p99_us"`
+	P999     float64 `json:"p999_us"`
+	Max      float64 `json:"max_us"`
+}
+
+// Report gathers the load distribution, hierarchy, remap and timing statistics of the tree.
+func (r *Ring) Report() StatsReport {
+	r.writer.Lock()
+	root := r.root()
+	depth, levels, keys, nodes := root.hierarchyInfo()
+	rings := root.totalLoads()
+	system, levelImbalance := root.imbalance()
+	r.writer.Unlock()
+
+	report := StatsReport{Keys: keys, Nodes: nodes, Depth: depth, Rings: rings, Operations: make(map[string]OperationStats)}
+	report.Imbalance.System = system
+	for level := 0; level <= depth; level++ {
+		report.Levels = append(report.Levels, levels[level])
+		report.Imbalance.Levels = append(report.Imbalance.Levels, LevelImbalance{Level: level, Imbalance: levelImbalance[level]})
+	}
+	sort.Slice(report.Rings, func(i, j int) bool {
+		if report.Rings[i].Level != report.Rings[j].Level {
+			return report.Rings[i].Level < report.Rings[j].Level
+		}
+		return report.Rings[i].ID < report.Rings[j].ID
+	})
+	for i := range report.Rings {
+		ring := &report.Rings[i]
+		ring.Mean, ring.Variance, ring.Stdev = finite(ring.Mean), finite(ring.Variance), finite(ring.Stdev)
+	}
+
+	remaps, total, average, ratio := r.stats.RemapStats()
+	for _, remap := range remaps {
+		for actual := range remap {
+			if actual > 0 {
+				report.Remaps.Operations++
+			}
+		}
+	}
+	report.Remaps.Total, report.Remaps.Average, report.Remaps.Ratio = total, finite(average), finite(ratio)
+
+	percentiles := r.stats.Percentiles()
+	for operation, timing := range r.stats.TimeStats() {
+		p := percentiles[operation]
+		report.Operations[operation] = OperationStats{
+			Count:    p.Count,
+			Mean:     finite(timing["Mean"]),
+			Variance: finite(timing["Variance"]),
+			Stdev:    finite(timing["Stdev"]),
+			P50:      micros(p.P50),
+			P90:      micros(p.P90),
+			P99:      micros(p.P99),
+			P999:     micros(p.P999),
+			Max:      micros(p.Max),
+		}
+	}
+	return report
+}
+
+// ExportStats writes the load distribution, hierarchy, remap and timing statistics of the tree in a format
+// that analysis tools such as pandas or R read directly.
+func (r *Ring) ExportStats(w io.Writer, format Format) error {
+	report := r.Report()
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case FormatCSV:
+		return report.writeCSV(w)
+	default:
+		return fmt.Errorf("unknown stats format %d", format)
+	}
+}
+
+// writeCSV writes the report as one row per value, so every section loads into the same data frame.
+func (s StatsReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	row := func(section, name string, level int, metric string, value float64) {
+		levelField := ""
+		if level >= 0 {
+			levelField = strconv.Itoa(level)
+		}
+		cw.Write([]string{section, name, levelField, metric, strconv.FormatFloat(value, 'g', -1, 64)})
+	}
+
+	cw.Write([]string{"section", "name", "level", "metric", "value"})
+	row("tree", "", -1, "keys", float64(s.Keys))
+	row("tree", "", -1, "nodes", float64(s.Nodes))
+	row("tree", "", -1, "depth", float64(s.Depth))
+	for _, level := range s.Levels {
+		row("hierarchy", "", level.Level, "nodes", float64(level.NodeCount))
+		row("hierarchy", "", level.Level, "rings", float64(level.RingCount))
+	}
+	for _, ring := range s.Rings {
+		for _, load := range ring.Loads {
+			row("load", ring.ID, ring.Level, "member_load", float64(load))
+		}
+		row("load", ring.ID, ring.Level, "total", float64(ring.Total))
+		row("load", ring.ID, ring.Level, "mean", ring.Mean)
+		row("load", ring.ID, ring.Level, "variance", ring.Variance)
+		row("load", ring.ID, ring.Level, "stdev", ring.Stdev)
+	}
+	imbalance := func(level int, i Imbalance) {
+		row("imbalance", "", level, "peak_to_mean", i.PeakToMean)
+		row("imbalance", "", level, "gini", i.Gini)
+		row("imbalance", "", level, "jain", i.Jain)
+	}
+	for _, level := range s.Imbalance.Levels {
+		imbalance(level.Level, level.Imbalance)
+	}
+	imbalance(-1, s.Imbalance.System)
+	row("remap", "", -1, "operations", float64(s.Remaps.Operations))
+	row("remap", "", -1, "total", float64(s.Remaps.Total))
+	row("remap", "", -1, "average", s.Remaps.Average)
+	row("remap", "", -1, "ratio", s.Remaps.Ratio)
+
+	operations := make([]string, 0, len(s.Operations))
+	for operation := range s.Operations {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+	for _, operation := range operations {
+		o := s.Operations[operation]
+		row("timing", operation, -1, "count", float64(o.Count))
+		row("timing", operation, -1, "mean_us", o.Mean)
+		row("timing", operation, -1, "variance_us2", o.Variance)
+		row("timing", operation, -1, "stdev_us", o.Stdev)
+		row("timing", operation, -1, "p50_us", o.P50)
+		row("timing", operation, -1, "p90_us", o.P90)
+		row("timing", operation, -1, "p99_us", o.P99)
+		row("timing", operation, -1, "p999_us", o.P999)
+		row("timing", operation, -1, "max_us", o.Max)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// finite replaces the NaN of statistics over no samples with 0, which JSON can encode.
+func finite(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// micros converts a duration to microseconds.
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
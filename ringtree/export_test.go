@@ -0,0 +1,57 @@
+package ringtree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestExportStats(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+	for i := 0; i < 60; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	var buf bytes.Buffer
+	if err := rt.ExportStats(&buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var report StatsReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Keys != 60 || len(report.Levels) != report.Depth+1 || len(report.Rings) < 2 {
+		t.Fatalf("got %d keys, %d levels and %d rings, want 60 keys and every level and ring", report.Keys, len(report.Levels), len(report.Rings))
+	}
+	if len(report.Imbalance.Levels) != report.Depth+1 || report.Imbalance.System.Jain <= 0 {
+		t.Fatalf("got imbalance %+v, want every level and a positive fairness index", report.Imbalance)
+	}
+	if report.Operations["InsertKey"].Count == 0 {
+		t.Fatal("expected InsertKey timings in the report")
+	}
+
+	buf.Reset()
+	if err := rt.ExportStats(&buf, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := make(map[string]int)
+	for _, row := range rows[1:] {
+		sections[row[0]]++
+	}
+	for _, section := range []string{"tree", "hierarchy", "load", "imbalance", "remap", "timing"} {
+		if sections[section] == 0 {
+			t.Errorf("expected rows in section %s", section)
+		}
+	}
+	if err := rt.ExportStats(&buf, Format(9)); err == nil {
+		t.Fatal("expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,112 @@
+package ringtree
+
+import (
+	"sync"
+	"time"
+)
+
+// freezeState records whether the topology is frozen and the nodes whose structural changes were deferred.
+type freezeState struct {
+	frozen  bool
+	queued  []*Node
+	pending map[*Node]bool
+	sync.Mutex
+}
+
+// Freeze defers the structural changes key writes would trigger. While frozen, InsertKey places keys on
+// their node even past its threshold instead of adding a node or splitting it, and RemoveKey keeps
+// underloaded nodes instead of removing them. Explicit topology calls such as InsertNode and Split still
+// apply immediately.
+func (r *Ring) Freeze() {
+	f := r.freeze
+	f.Lock()
+	defer f.Unlock()
+	f.frozen = true
+	r.logf("Topology frozen.\n")
+}
+
+// Unfreeze lifts a freeze and applies the structural changes deferred during it, adding nodes, splitting
+// overloaded nodes and removing underloaded ones as their current load requires.
+func (r *Ring) Unfreeze() error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	defer r.timeTrack(time.Now(), "Unfreeze", "to apply deferred structural changes")
+
+	f := r.freeze
+	f.Lock()
+	f.frozen = false
+	queued := f.queued
+	f.queued, f.pending = nil, nil
+	f.Unlock()
+
+	r.logf("Topology unfrozen, applying changes for %d nodes.\n", len(queued))
+	for _, node := range queued {
+		if err := r.root().applyDeferred(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Frozen reports whether the topology is frozen.
+func (r *Ring) Frozen() bool {
+	r.freeze.Lock()
+	defer r.freeze.Unlock()
+	return r.freeze.frozen
+}
+
+// deferChange queues a node whose load calls for a structural change and reports whether the topology is
+// frozen, in which case the caller must leave the structure as it is.
+func (r *Ring) deferChange(node *Node) bool {
+	f := r.freeze
+	f.Lock()
+	defer f.Unlock()
+	if !f.frozen {
+		return false
+	}
+	if !f.pending[node] {
+		if f.pending == nil {
+			f.pending = make(map[*Node]bool)
+		}
+		f.pending[node] = true
+		f.queued = append(f.queued, node)
+	}
+	return true
+}
+
+// applyDeferred makes the structural changes a node's load calls for: nodes are added beside an overloaded
+// node while its ring has room, then the node is split, or its threshold raised at the max depth. An
+// underloaded subring node is removed (assuming the tree's writer lock is held).
+func (r *Ring) applyDeferred(node *Node) error {
+	for {
+		current, ring := r.findMember(node.id)
+		if current != node {
+			return nil // Removed or split since it was queued
+		}
+
+		ring.RLock()
+		capacity := int(ring.high * float64(node.threshold))
+		over := node.load > capacity && node.load > 0
+		under := float64(node.load) <= ring.low*float64(node.threshold) && ring.parent != nil
+		full := ring.Size() >= ring.maxCount
+		ring.RUnlock()
+
+		switch {
+		case over && !full:
+			if err := ring.insertNode(ring.seedNode(node, node.threshold)); err != nil {
+				return err
+			}
+		case over && r.config.MaxDepth > 0 && ring.level >= r.config.MaxDepth:
+			ring.Lock()
+			node.threshold += node.baseThreshold()
+			ring.Unlock()
+		case over:
+			_, err := ring.splitNode(node, 0)
+			return err
+		case under && !node.dwelling(r.config.MinDwell) && !r.pins.targeted(node.id):
+			return ring.removeNode(node)
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestFreezeDefersSplits(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.Freeze()
+	if !rt.Frozen() {
+		t.Fatalf("expected the topology to be frozen")
+	}
+
+	// Keys still land while the structure stays as it was
+	for i := 0; i < 100; i++ {
+		if err := rt.InsertKey("key-" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("expected key to be inserted while frozen, got error: %v", err)
+		}
+	}
+	checkNum(rt.Size(), 1, t)
+	checkNum(rt.GetDepth(), 0, t)
+	checkNum(rt.members["A"].MemberStats().Keys, 100, t)
+
+	// Unfreezing grows the ring and splits the overloaded node
+	if err := rt.Unfreeze(); err != nil {
+		t.Fatalf("expected unfreeze to succeed, got error: %v", err)
+	}
+	if rt.Frozen() {
+		t.Errorf("expected the topology to be unfrozen")
+	}
+	checkNum(rt.Size(), 2, t)
+	if rt.GetDepth() == 0 {
+		t.Errorf("expected the overloaded node to be split")
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := rt.Lookup("key-" + strconv.Itoa(i)); err != nil {
+			t.Errorf("expected key to be found, got error: %v", err)
+		}
+	}
+	checkNum(rt.MemberStats().Keys, 100, t)
+}
+
+func TestFreezeDefersRemovals(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 1000))
+	rt.Split("A")
+	subring := rt.members["A"].(*Ring)
+	nodes := subring.MemberStats().Nodes
+	for i := 0; i < 20; i++ {
+		rt.InsertKey("key-" + strconv.Itoa(i))
+	}
+
+	// Removing keys leaves underloaded subring nodes in place until the unfreeze
+	rt.Freeze()
+	for i := 0; i < 20; i++ {
+		rt.RemoveKey("key-" + strconv.Itoa(i))
+	}
+	checkNum(subring.MemberStats().Nodes, nodes, t)
+	if err := rt.Unfreeze(); err != nil {
+		t.Fatalf("expected unfreeze to succeed, got error: %v", err)
+	}
+	if _, ok := rt.members["A"].(*Node); !ok {
+		t.Errorf("expected the emptied subring to collapse after the unfreeze")
+	}
+}
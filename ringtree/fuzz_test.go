@@ -0,0 +1,97 @@
+package ringtree
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fuzzOptions are the tree configurations FuzzRingOps picks from with the first byte of its input.
+var fuzzOptions = [][]Option{
+	nil,
+	{WithArrayCircle(true)},
+	{WithSiblingMerge(true)},
+	{WithRemapBudget(2)},
+	{WithWatermarks(0.75, 0.25)},
+	{WithKeyIndex(true)},
+}
+
+// FuzzRingOps reads its input as a sequence of two-byte operations (an opcode and an argument) applied to a
+// small tree and to a map of the keys it should hold. It fails when the tree disagrees with the map about a
+// key, loses count of its keys, or breaks a structural invariant. Thresholds and rings are tiny, so a few
+// dozen operations already split nodes into subrings, collapse them and remap keys.
+func FuzzRingOps(f *testing.F) {
+	f.Add([]byte{0, 0, 1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 4, 1, 4, 2})
+	f.Add([]byte{1, 0, 0, 1, 1, 2, 2, 3, 3, 7, 9, 4, 0, 5, 1, 8, 0, 6, 2})
+	f.Add([]byte{2, 0, 10, 1, 11, 2, 12, 3, 13, 4, 14, 5, 15, 6, 16, 4, 10, 4, 11, 4, 12, 4, 13, 8, 1})
+	f.Add([]byte{3, 7, 0, 7, 1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 0, 7, 8, 0, 8, 1, 6, 3})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		if len(ops) == 0 {
+			return
+		}
+		rt := New(3, fuzzOptions[int(ops[0])%len(fuzzOptions)]...)
+		if err := rt.InsertNode(NewNode("node", 3)); err != nil {
+			t.Fatal(err)
+		}
+		model := make(map[string]bool)
+
+		for i := 1; i+1 < len(ops); i += 2 {
+			arg := int(ops[i+1])
+			key := "key" + strconv.Itoa(arg%64)
+			switch ops[i] % 9 {
+			case 0, 1, 2, 3: // Inserts dominate, so the tree grows
+				err := rt.InsertKey(key)
+				if model[key] && !errors.Is(err, ErrKeyExists) {
+					t.Fatalf("op %d: inserting present key %s: got %v, want ErrKeyExists", i, key, err)
+				}
+				if !model[key] && err != nil {
+					t.Fatalf("op %d: inserting key %s: %v", i, key, err)
+				}
+				model[key] = true
+			case 4, 5:
+				err := rt.RemoveKey(key)
+				if !model[key] && !errors.Is(err, ErrKeyNotFound) {
+					t.Fatalf("op %d: removing absent key %s: got %v, want ErrKeyNotFound", i, key, err)
+				}
+				if model[key] && err != nil {
+					t.Fatalf("op %d: removing key %s: %v", i, key, err)
+				}
+				delete(model, key)
+			case 6:
+				_, err := rt.Lookup(key)
+				if model[key] != (err == nil) {
+					t.Fatalf("op %d: looking up key %s (present %t): %v", i, key, model[key], err)
+				}
+			case 7:
+				err := rt.InsertNode(NewNode("node"+strconv.Itoa(arg), 3))
+				if err != nil && !errors.Is(err, ErrNodeExists) && !errors.Is(err, ErrRingAtCapacity) {
+					t.Fatalf("op %d: inserting node: %v", i, err)
+				}
+			case 8:
+				ids := rt.NodeIDs()
+				if len(ids) == 0 {
+					continue
+				}
+				// The last node of the root ring stays, since its keys have nowhere to go
+				err := rt.RemoveNodeByID(ids[arg%len(ids)])
+				if err != nil && !strings.Contains(err.Error(), "not enough nodes") {
+					t.Fatalf("op %d: removing node %s: %v", i, ids[arg%len(ids)], err)
+				}
+			}
+			if got := rt.Stats().Keys(); got != len(model) {
+				t.Fatalf("op %d: tree counts %d keys, want %d", i, got, len(model))
+			}
+		}
+
+		for _, err := range rt.Validate() {
+			t.Error(err)
+		}
+		for key := range model {
+			if _, err := rt.Lookup(key); err != nil {
+				t.Errorf("looking up key %s: %v", key, err)
+			}
+		}
+	})
+}
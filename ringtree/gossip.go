@@ -0,0 +1,255 @@
+package ringtree
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// gossipVersion is the version of the message envelope sent by Gossip.
+const gossipVersion = 1
+
+// defaultGossipTTL is the number of ring hops a message travels when no TTL is configured.
+const defaultGossipTTL = 16
+
+// gossipDedup is the number of message IDs each node and ring remembers to drop duplicates.
+const gossipDedup = 1024
+
+// Message is a gossip message: a typed payload in an envelope naming its origin, the origin's sequence
+// number and the ring hops it may still travel.
+type Message struct {
+	Version int    // Envelope version
+	Type    string // Kind of payload, selecting the handlers it is delivered to
+	Origin  string // Ring or node the message started from
+	Seq     uint64 // Sequence number of the message at its origin
+	TTL     int    // Ring hops left before the message is dropped
+	Payload []byte
+
+	acks *ackSet // Nodes that acknowledged delivery, for messages started in this process
+}
+
+// ID returns the identity of the message used to drop duplicates.
+func (m Message) ID() string {
+	return m.Origin + "#" + strconv.FormatUint(m.Seq, 10)
+}
+
+// GossipHandler is called with each gossip message of its type delivered to a node.
+type GossipHandler func(node *Node, msg Message)
+
+// Delivery reports which nodes acknowledged a gossip message.
+type Delivery struct {
+	ID   string   // ID of the message
+	Acks []string // Nodes the message was delivered to, in sorted order
+}
+
+// gossipState holds the sequence numbers and handlers shared by a tree.
+type gossipState struct {
+	seq      atomic.Uint64
+	handlers map[string][]GossipHandler
+	sync.RWMutex
+}
+
+// ackSet collects delivery acknowledgements.
+type ackSet struct {
+	ids []string
+	sync.Mutex
+}
+
+// seenCache remembers the IDs of the latest messages, dropping the oldest once full.
+type seenCache struct {
+	ids   map[string]bool
+	order []string
+	next  int
+	sync.Mutex
+}
+
+func newSeenCache() *seenCache {
+	return &seenCache{}
+}
+
+// add records an ID and reports whether it is new.
+func (c *seenCache) add(id string) bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.record(id)
+}
+
+// record records an ID and reports whether it is new (assuming the mutex is held).
+func (c *seenCache) record(id string) bool {
+	if c.ids[id] {
+		return false
+	}
+	if c.ids == nil {
+		c.ids = make(map[string]bool)
+	}
+	if len(c.order) < gossipDedup {
+		c.order = append(c.order, id)
+	} else {
+		delete(c.ids, c.order[c.next])
+		c.order[c.next] = id
+		c.next = (c.next + 1) % gossipDedup
+	}
+	c.ids[id] = true
+	return true
+}
+
+// WithGossipFanout makes each ring forward a gossip message to n randomly chosen members instead of all of
+// them. Nodes a ring skips can still receive the message from later rounds.
+func WithGossipFanout(n int) Option {
+	return func(c *Config) {
+		if n >= 0 {
+			c.GossipFanout = n
+		}
+	}
+}
+
+// WithGossipTTL sets how many ring hops a gossip message travels before it is dropped.
+func WithGossipTTL(hops int) Option {
+	return func(c *Config) {
+		if hops > 0 {
+			c.GossipTTL = hops
+		}
+	}
+}
+
+// gossipTTL returns the number of ring hops a message travels.
+func (c *Config) gossipTTL() int {
+	if c.GossipTTL <= 0 {
+		return defaultGossipTTL
+	}
+	return c.GossipTTL
+}
+
+// HandleGossip registers fn to be called with every message of the given type delivered to a node.
+func (r *Ring) HandleGossip(msgType string, fn GossipHandler) {
+	g := r.gossip
+	g.Lock()
+	defer g.Unlock()
+	g.handlers[msgType] = append(g.handlers[msgType], fn)
+}
+
+// NewMessage returns a message originating from the ring, with the next sequence number of the tree and
+// the configured TTL, for sending to other processes as well as spreading it with ReceiveMessage.
+func (r *Ring) NewMessage(msgType string, payload []byte) Message {
+	return Message{
+		Version: gossipVersion,
+		Type:    msgType,
+		Origin:  r.id,
+		Seq:     r.gossip.seq.Add(1),
+		TTL:     r.config.gossipTTL(),
+		Payload: payload,
+	}
+}
+
+// Gossip spreads a message from the ring up to its parents and down to its members, and returns once it has
+// spread. Every ring forwards it once, and every node it reaches acknowledges it once.
+func (r *Ring) Gossip(msgType string, payload []byte) Delivery {
+	msg := r.NewMessage(msgType, payload)
+	msg.acks = &ackSet{}
+	r.ReceiveMessage(msg)
+
+	msg.acks.Lock()
+	defer msg.acks.Unlock()
+	acks := append([]string(nil), msg.acks.ids...)
+	sort.Strings(acks)
+	return Delivery{ID: msg.ID(), Acks: acks}
+}
+
+// ReceiveMessage spreads a message arriving at the ring and returns once it has spread. It reports false,
+// spreading nothing, if the ring has already spread the message or its TTL ran out.
+func (r *Ring) ReceiveMessage(msg Message) bool {
+	if msg.TTL <= 0 || !r.seen.add(msg.ID()) {
+		return false
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	r.forward(msg, &wg)
+	wg.Wait()
+	return true
+}
+
+// spread forwards a message the ring has not seen yet.
+func (r *Ring) spread(msg Message, wg *sync.WaitGroup) {
+	if msg.TTL <= 0 || !r.seen.add(msg.ID()) {
+		wg.Done()
+		return
+	}
+	r.forward(msg, wg)
+}
+
+// forward sends a message to the ring's parent and to its members.
+func (r *Ring) forward(msg Message, wg *sync.WaitGroup) {
+	defer wg.Done()
+	msg.TTL--
+	r.logf("Ring %s spreading message %s of type %s.\n", r.id, msg.ID(), msg.Type)
+
+	if r.parent != nil {
+		wg.Add(1)
+		go r.parent.spread(msg, wg)
+	}
+
+	r.RLock()
+	members := make([]Member, 0, len(r.members))
+	for _, member := range r.members {
+		members = append(members, member)
+	}
+	r.RUnlock()
+	if fanout := r.config.GossipFanout; fanout > 0 && fanout < len(members) {
+		rand.Shuffle(len(members), func(i, j int) {
+			members[i], members[j] = members[j], members[i]
+		})
+		members = members[:fanout]
+	}
+
+	for _, member := range members {
+		switch member := member.(type) {
+		case *Node:
+			wg.Add(1)
+			go func(node *Node) {
+				defer wg.Done()
+				r.deliver(node, msg)
+			}(member)
+		case *Ring:
+			wg.Add(1)
+			go member.spread(msg, wg)
+		}
+	}
+}
+
+// deliver hands a message to a node's handlers and acknowledges it, unless the node has already seen it.
+func (r *Ring) deliver(node *Node, msg Message) {
+	if !node.ReceiveMessage(msg) {
+		return
+	}
+	r.gossip.RLock()
+	handlers := r.gossip.handlers[msg.Type]
+	r.gossip.RUnlock()
+	for _, handler := range handlers {
+		handler(node, msg)
+	}
+	if msg.acks != nil {
+		msg.acks.Lock()
+		msg.acks.ids = append(msg.acks.ids, node.id)
+		msg.acks.Unlock()
+	}
+}
+
+// ReceiveMessage records a message delivered to the node and reports whether it is new to the node.
+func (n *Node) ReceiveMessage(msg Message) bool {
+	n.seen.Lock()
+	defer n.seen.Unlock()
+	if !n.seen.record(msg.ID()) {
+		return false
+	}
+	n.lastMessage = msg
+	return true
+}
+
+// LastMessage returns the last gossip message delivered to the node.
+func (n *Node) LastMessage() Message {
+	n.seen.Lock()
+	defer n.seen.Unlock()
+	return n.lastMessage
+}
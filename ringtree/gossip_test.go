@@ -0,0 +1,83 @@
+package ringtree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGossipDelivery(t *testing.T) {
+	rt := New(3)
+	for i := 0; i < 3; i++ {
+		rt.InsertNode(NewNode("", 20))
+	}
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+	if !rt.hasSubrings() {
+		t.Fatal("expected the tree to have split")
+	}
+
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	rt.HandleGossip("config", func(node *Node, msg Message) {
+		mu.Lock()
+		handled[node.id]++
+		mu.Unlock()
+	})
+
+	// Start from a subring so the message has to climb to the root and come back down
+	var subring *Ring
+	for _, member := range rt.members {
+		if ring, ok := member.(*Ring); ok {
+			subring = ring
+		}
+	}
+	delivery := subring.Gossip("config", []byte("v2"))
+
+	nodes := rt.NodeIDs()
+	if len(delivery.Acks) != len(nodes) {
+		t.Fatalf("got %d acks, want one from each of the %d nodes", len(delivery.Acks), len(nodes))
+	}
+	for _, id := range nodes {
+		if handled[id] != 1 {
+			t.Errorf("node %s handled the message %d times, want once", id, handled[id])
+		}
+		node, _ := rt.Node(id)
+		if msg := node.LastMessage(); msg.ID() != delivery.ID || string(msg.Payload) != "v2" || msg.Version != gossipVersion {
+			t.Errorf("node %s: got last message %+v, want %s", id, msg, delivery.ID)
+		}
+	}
+
+	// A redelivered message is dropped
+	node, _ := rt.Node(nodes[0])
+	rt.ReceiveMessage(node.LastMessage())
+	for _, id := range nodes {
+		if handled[id] != 1 {
+			t.Errorf("node %s handled a duplicate message", id)
+		}
+	}
+}
+
+func TestGossipTTL(t *testing.T) {
+	rt := New(3, WithGossipTTL(1))
+	for i := 0; i < 3; i++ {
+		rt.InsertNode(NewNode("", 20))
+	}
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	// With one hop the message reaches the root's own nodes only
+	delivery := rt.Gossip("config", nil)
+	var direct int
+	for _, member := range rt.members {
+		if _, ok := member.(*Node); ok {
+			direct++
+		}
+	}
+	if len(delivery.Acks) != direct {
+		t.Errorf("got %d acks, want %d from the root's nodes", len(delivery.Acks), direct)
+	}
+}
@@ -0,0 +1,199 @@
+package ringtree
+
+import "time"
+
+// NodeState describes whether a node takes part in routing.
+type NodeState int
+
+const (
+	Up       NodeState = iota // Serves reads and writes
+	Draining                  // Serves reads; new writes are routed to the next available node
+	Down                      // Serves neither; writes are routed to the next available node
+	Suspect                   // Suspected of failing; serves reads while new writes go to the next available node
+	Joining                   // Takes writes but not reads until it has fetched the keys it took over from other nodes
+)
+
+// String returns a readable name for the node state.
+func (s NodeState) String() string {
+	switch s {
+	case Up:
+		return "Up"
+	case Draining:
+		return "Draining"
+	case Down:
+		return "Down"
+	case Suspect:
+		return "Suspect"
+	case Joining:
+		return "Joining"
+	default:
+		return "Unknown"
+	}
+}
+
+// State returns the node's effective state at the current time, taking maintenance windows into account.
+func (n *Node) State() NodeState {
+	return n.stateAt(time.Now())
+}
+
+// stateAt returns the node's effective state at the given time.
+func (n *Node) stateAt(now time.Time) NodeState {
+	if n.state != Up {
+		return n.state
+	}
+	for _, w := range n.maintenance {
+		if w.contains(now) {
+			return Draining
+		}
+	}
+	return Up
+}
+
+// acceptsWrites reports whether new keys may be placed on the node.
+func (n *Node) acceptsWrites() bool {
+	state := n.State()
+	return state == Up || state == Joining
+}
+
+// servesReads reports whether the node can serve the keys it holds, which a Joining node is still fetching.
+func (n *Node) servesReads() bool {
+	state := n.State()
+	return state != Down && state != Joining
+}
+
+// SetNodeState changes the base state of a node anywhere in the tree. Keys written elsewhere while the node
+// was unavailable are moved back once it returns to Up.
+func (r *Ring) SetNodeState(nodeID string, state NodeState) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.setNodeStateOp(nodeID, state)
+}
+
+// setNodeStateOp changes the base state of a node as one logged operation (assuming the tree's writer lock
+// is held).
+func (r *Ring) setNodeStateOp(nodeID string, state NodeState) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpSetState, Node: nodeID, State: state}, r.setNodeState(nodeID, state))
+}
+
+// setNodeState changes the base state of a node anywhere in the tree (assuming the tree's writer lock is held).
+func (r *Ring) setNodeState(nodeID string, state NodeState) error {
+	r.settleRemaps()
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+
+	ring.Lock()
+	previous := node.State()
+	node.state = state
+	current := node.State()
+	if previous != Up && current == Up {
+		ring.reclaimKeys(node)
+	}
+	ring.Unlock()
+
+	if previous != current {
+		ring.emit(Event{Type: NodeStateChanged, RingID: ring.id, NodeID: node.id, Level: ring.level})
+		ring.announceFailover(node, previous, current)
+	}
+	return nil
+}
+
+// Node returns the physical node with the given ID anywhere in the tree.
+func (r *Ring) Node(nodeID string) (*Node, error) {
+	node, _ := r.findMember(nodeID)
+	if node == nil {
+		return nil, ErrNodeNotFound
+	}
+	return node, nil
+}
+
+// LocateNode returns the physical node with the given ID anywhere in the tree, and the ring holding it.
+func (r *Ring) LocateNode(nodeID string) (*Node, *Ring, error) {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return nil, nil, ErrNodeNotFound
+	}
+	return node, ring, nil
+}
+
+// findMember searches the tree for the physical node with the given ID and the ring that holds it. Each
+// ring's lock is released before its subrings are searched.
+func (r *Ring) findMember(nodeID string) (*Node, *Ring) {
+	r.RLock()
+	if node, ok := r.members[nodeID].(*Node); ok {
+		r.RUnlock()
+		return node, r
+	}
+	var subrings []*Ring
+	for _, member := range r.members {
+		if subring, ok := member.(*Ring); ok {
+			subrings = append(subrings, subring)
+		}
+	}
+	r.RUnlock()
+
+	for _, subring := range subrings {
+		if node, ring := subring.findMember(nodeID); node != nil {
+			return node, ring
+		}
+	}
+	return nil, nil
+}
+
+// routeWrite finds the first vnode at or after keyHash whose member accepts writes. If no node accepts
+// writes the plain owner is returned (assuming mutex is already locked).
+func (r *Ring) routeWrite(keyHash uint32) (uint32, string) {
+	ownerHash, ownerID := r.circle.FindClosest(keyHash)
+	vNodeHash, nodeID := ownerHash, ownerID
+	it := r.circle.Iter(ownerHash)
+	for i := 0; i < r.circle.Size(); i++ {
+		node, ok := r.members[nodeID].(*Node)
+		if !ok || node.acceptsWrites() {
+			return vNodeHash, nodeID
+		}
+		vNodeHash, nodeID = it.Next()
+	}
+	return ownerHash, ownerID
+}
+
+// reclaimKeys moves keys that were routed past a node while it was unavailable back onto it. Keys failing
+// their checksum stay where they are (assuming mutex is already locked).
+func (r *Ring) reclaimKeys(node *Node) {
+	for vNodeHash := range node.keys {
+		// Walk to the first vnode of another node that accepted writes in this node's place
+		it := r.circle.Iter(vNodeHash)
+		next, nextID := it.Next()
+		for i := 0; i < r.circle.Size(); i++ {
+			if n, ok := r.members[nextID].(*Node); !ok || (n != node && n.acceptsWrites()) {
+				break
+			}
+			next, nextID = it.Next()
+		}
+
+		switch holder := r.members[nextID].(type) {
+		case *Node:
+			for key, keyHash := range holder.keys[next] {
+				target, targetID := r.routeWrite(keyHash)
+				if targetID == node.id && target != next && r.pinAllows(key, r, node) {
+					r.moveKey(key, keyHash, holder, next, node, target)
+				}
+			}
+		case *Ring:
+			holder.Lock()
+			holder.forEachNode(func(n *Node) {
+				for holderHash, keyHashMap := range n.keys {
+					for key := range keyHashMap {
+						keyHash := r.config.keyHash(key, r.level)
+						target, targetID := r.routeWrite(keyHash)
+						if targetID == node.id && r.pinAllows(key, r, node) {
+							r.moveKey(key, keyHash, n, holderHash, node, target)
+						}
+					}
+				}
+			})
+			holder.Unlock()
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package ringtree
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// heatFloor is the decayed heat below which a key is forgotten, so keys that are no longer accessed stop
+// taking up memory.
+const heatFloor = 0.01
+
+// KeyHeat is the decayed access count of one key.
+type KeyHeat struct {
+	Key  string  `json:"key"`
+	Heat float64 `json:"heat"`
+}
+
+// VNodeHeat is the decayed access count of the keys held by one virtual node.
+type VNodeHeat struct {
+	Hash   uint32  `json:"hash"` // Position of the vnode on its ring
+	NodeID string  `json:"node"`
+	RingID string  `json:"ring"`
+	Level  int     `json:"level"`
+	Keys   int     `json:"keys"` // Keys placed on the vnode
+	Heat   float64 `json:"heat"` // Sum of the heat of its keys
+}
+
+// heatTable holds the decayed access count of every key accessed since heat tracking started.
+type heatTable struct {
+	halfLife time.Duration
+	keys     map[string]heatCount
+	sync.Mutex
+}
+
+// heatCount is an access count as of the last access of its key.
+type heatCount struct {
+	heat float64
+	at   time.Time
+}
+
+func newHeatTable(halfLife time.Duration) *heatTable {
+	return &heatTable{halfLife: halfLife, keys: make(map[string]heatCount)}
+}
+
+// WithHeatTracking counts the lookups and inserts of every key, halving each count every halfLife, for
+// HotKeys and HotVNodes. Counts below 0.01 are forgotten. By default accesses are not tracked.
+func WithHeatTracking(halfLife time.Duration) Option {
+	return func(c *Config) {
+		if halfLife > 0 {
+			c.HeatHalfLife = halfLife
+		}
+	}
+}
+
+// decay returns the heat of a count as of now.
+func (t *heatTable) decay(c heatCount, now time.Time) float64 {
+	return decayed(c.heat, c.at, now, t.halfLife)
+}
+
+// touch counts an access of a key if heat tracking is on.
+func (r *Ring) touch(key string) {
+	if r.heat != nil {
+		r.heat.record(key, time.Now())
+	}
+}
+
+// record counts one access of a key at the given time.
+func (t *heatTable) record(key string, now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.keys[key] = heatCount{heat: t.decay(t.keys[key], now) + 1, at: now}
+}
+
+// forget drops the count of a removed key.
+func (t *heatTable) forget(key string) {
+	if t == nil {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	delete(t.keys, key)
+}
+
+// snapshot returns the heat of every key as of now, forgetting the keys that have cooled below heatFloor.
+func (t *heatTable) snapshot(now time.Time) map[string]float64 {
+	t.Lock()
+	defer t.Unlock()
+	heat := make(map[string]float64, len(t.keys))
+	for key, count := range t.keys {
+		h := t.decay(count, now)
+		if h < heatFloor {
+			delete(t.keys, key)
+			continue
+		}
+		heat[key] = h
+	}
+	return heat
+}
+
+// KeyHeat returns the decayed access count of a key, or 0 if heat tracking is off or the key is cold.
+func (r *Ring) KeyHeat(key string) float64 {
+	t := r.heat
+	if t == nil {
+		return 0
+	}
+	t.Lock()
+	defer t.Unlock()
+	return t.decay(t.keys[key], time.Now())
+}
+
+// HotKeys returns the n most accessed keys by decayed access count, hottest first and ties by key. It
+// returns nil if heat tracking is off.
+func (r *Ring) HotKeys(n int) []KeyHeat {
+	if r.heat == nil {
+		return nil
+	}
+	heat := r.heat.snapshot(time.Now())
+	hot := make([]KeyHeat, 0, len(heat))
+	for key, h := range heat {
+		hot = append(hot, KeyHeat{Key: key, Heat: h})
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Heat != hot[j].Heat {
+			return hot[i].Heat > hot[j].Heat
+		}
+		return hot[i].Key < hot[j].Key
+	})
+	return hot[:min(n, len(hot))]
+}
+
+// HotVNodes returns the n vnodes whose keys are accessed most, by the summed decayed access count of the
+// keys they hold, hottest first. Vnodes are compared across the whole tree, so a read hotspot shows up even
+// where key counts are even. It returns nil if heat tracking is off.
+func (r *Ring) HotVNodes(n int) []VNodeHeat {
+	if r.heat == nil {
+		return nil
+	}
+	heat := r.heat.snapshot(time.Now())
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	var hot []VNodeHeat
+	root := r.root()
+	root.Lock()
+	root.forEachRingNode(func(node *Node, ring *Ring) {
+		for vNodeHash, keys := range node.keys {
+			vnode := VNodeHeat{Hash: vNodeHash, NodeID: node.id, RingID: ring.id, Level: ring.level, Keys: len(keys)}
+			for key := range keys {
+				vnode.Heat += heat[key]
+			}
+			if vnode.Heat > 0 {
+				hot = append(hot, vnode)
+			}
+		}
+	})
+	root.Unlock()
+
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Heat != hot[j].Heat {
+			return hot[i].Heat > hot[j].Heat
+		}
+		if hot[i].RingID != hot[j].RingID {
+			return hot[i].RingID < hot[j].RingID
+		}
+		return hot[i].Hash < hot[j].Hash
+	})
+	return hot[:min(n, len(hot))]
+}
@@ -0,0 +1,107 @@
+package ringtree
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHotKeys(t *testing.T) {
+	rt := New(4, WithHeatTracking(time.Hour))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	var keys []string
+	for i := 0; i < 50; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	for i := 0; i < 20; i++ {
+		rt.Lookup(keys[0])
+	}
+	for i := 0; i < 10; i++ {
+		rt.Lookup(keys[1])
+	}
+	rt.Lookup("missing") // Failed lookups are not counted
+
+	hot := rt.HotKeys(3)
+	checkNum(len(hot), 3, t)
+	if hot[0].Key != keys[0] || hot[1].Key != keys[1] {
+		t.Errorf("expected %s and %s to be the hottest keys, got %+v", keys[0], keys[1], hot)
+	}
+	if math.Abs(hot[0].Heat-21) > 0.01 || math.Abs(hot[1].Heat-11) > 0.01 || math.Abs(hot[2].Heat-1) > 0.01 {
+		t.Errorf("expected heats of 21, 11 and 1, got %+v", hot)
+	}
+	checkNum(len(rt.HotKeys(100)), 50, t)
+	if rt.KeyHeat("missing") != 0 {
+		t.Errorf("expected a key that was never found to have no heat")
+	}
+
+	// Removed keys are forgotten
+	rt.RemoveKey(keys[0])
+	if rt.HotKeys(1)[0].Key != keys[1] {
+		t.Errorf("expected %s to be the hottest key once %s is removed", keys[1], keys[0])
+	}
+
+	if New(4).HotKeys(1) != nil || New(4).HotVNodes(1) != nil {
+		t.Errorf("expected no heat without heat tracking")
+	}
+}
+
+func TestHeatDecay(t *testing.T) {
+	table := newHeatTable(time.Minute)
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		table.record("key", start)
+	}
+	table.record("cold", start.Add(-time.Hour))
+
+	heat := table.snapshot(start.Add(time.Minute))
+	if math.Abs(heat["key"]-4) > 1e-9 {
+		t.Errorf("expected 8 accesses to halve to 4 after one half-life, got %f", heat["key"])
+	}
+	table.record("key", start.Add(2*time.Minute))
+	if h := table.decay(table.keys["key"], start.Add(2*time.Minute)); math.Abs(h-3) > 1e-9 {
+		t.Errorf("expected an access after two half-lives to count 2+1, got %f", h)
+	}
+	if _, ok := heat["cold"]; ok {
+		t.Errorf("expected a key cooled below the floor to be forgotten")
+	}
+	checkNum(len(table.keys), 1, t)
+}
+
+func TestHotVNodes(t *testing.T) {
+	rt := New(4, WithHeatTracking(time.Hour))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	// A read hotspot on one key makes its vnode the hottest, though key counts are spread evenly
+	for i := 0; i < 50; i++ {
+		rt.Lookup(keys[7])
+	}
+	node, parent, vNodeHash, _, err := rt.FindNode(keys[7])
+	if err != nil {
+		t.Fatalf("expected the hot key to be found, got error: %v", err)
+	}
+
+	hot := rt.HotVNodes(2)
+	checkNum(len(hot), 2, t)
+	if hot[0].Hash != vNodeHash || hot[0].NodeID != node.id || hot[0].RingID != parent.id {
+		t.Errorf("expected vnode %d of %s to be the hottest, got %+v", vNodeHash, node.id, hot[0])
+	}
+	if hot[0].Heat < 50.9 || hot[0].Heat <= hot[1].Heat {
+		t.Errorf("expected the hot vnode to sum its keys' heat, got %+v", hot)
+	}
+	total := 0.0
+	for _, vnode := range rt.HotVNodes(1000) {
+		total += vnode.Heat
+	}
+	if math.Abs(total-150) > 0.1 {
+		t.Errorf("expected the vnode heats to add up to every access, got %f", total)
+	}
+}
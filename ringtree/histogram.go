@@ -0,0 +1,177 @@
+package ringtree
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramSubBits sets the precision of latency histograms: every power-of-two range of durations is split
+// into 2^histogramSubBits buckets, so a reported percentile is within about 3% of the recorded duration.
+const histogramSubBits = 5
+
+// histogram counts durations in HDR-style log-linear buckets: exact below 2^(histogramSubBits+1)
+// nanoseconds, and linear within each power of two above, so its size grows with the log of the largest
+// duration rather than with the number of samples.
+type histogram struct {
+	counts []uint64
+	total  uint64
+	max    time.Duration
+}
+
+// bucket returns the bucket index of a duration in nanoseconds.
+func bucket(v uint64) int {
+	const sub = 1 << histogramSubBits
+	if v < 2*sub {
+		return int(v)
+	}
+	k := bits.Len64(v) - 1 // v lies in [2^k, 2^(k+1))
+	shift := k - histogramSubBits
+	return 2*sub + (k-histogramSubBits-1)*sub + int(v>>shift) - sub
+}
+
+// bucketMax returns the largest duration in nanoseconds that falls in a bucket.
+func bucketMax(i int) uint64 {
+	const sub = 1 << histogramSubBits
+	if i < 2*sub {
+		return uint64(i)
+	}
+	band := (i - 2*sub) / sub
+	shift := band + 1
+	low := uint64((i-2*sub)%sub+sub) << shift
+	return low + (1 << shift) - 1
+}
+
+// add records a duration.
+func (h *histogram) add(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := bucket(uint64(d))
+	if i >= len(h.counts) {
+		counts := make([]uint64, i+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[i]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// quantile returns the duration at or below which a fraction q of the recorded durations fall.
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			if d := time.Duration(bucketMax(i)); d < h.max {
+				return d
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
+
+// Percentiles summarizes the tail of an operation's latency.
+type Percentiles struct {
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	P999  time.Duration
+	Max   time.Duration
+}
+
+// percentiles summarizes the histogram.
+func (h *histogram) percentiles() Percentiles {
+	return Percentiles{
+		Count: h.total,
+		P50:   h.quantile(0.5),
+		P90:   h.quantile(0.9),
+		P99:   h.quantile(0.99),
+		P999:  h.quantile(0.999),
+		Max:   h.max,
+	}
+}
+
+// latencyKey identifies the histogram of an operation on one level.
+type latencyKey struct {
+	op    string
+	level int
+}
+
+// recordLatency adds an operation's duration to its histogram for the level it completed on (assuming mu is
+// held).
+func (s *Stats) recordLatency(operation string, level int, elapsed time.Duration) {
+	if s.histograms == nil {
+		s.histograms = make(map[latencyKey]*histogram)
+	}
+	key := latencyKey{operation, level}
+	h := s.histograms[key]
+	if h == nil {
+		h = &histogram{}
+		s.histograms[key] = h
+	}
+	h.add(elapsed)
+}
+
+// Percentiles returns the p50, p90, p99 and p999 latency of each operation since the tree was created or
+// the stats were last Reset.
+func (s *Stats) Percentiles() map[string]Percentiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	merged := make(map[string]*histogram)
+	for key, h := range s.histograms {
+		m := merged[key.op]
+		if m == nil {
+			m = &histogram{}
+			merged[key.op] = m
+		}
+		m.merge(h)
+	}
+	percentiles := make(map[string]Percentiles, len(merged))
+	for op, h := range merged {
+		percentiles[op] = h.percentiles()
+	}
+	return percentiles
+}
+
+// LevelPercentiles returns the latency percentiles of each operation by the level of the ring it completed
+// on, showing how much deeper levels cost.
+func (s *Stats) LevelPercentiles() map[string]map[int]Percentiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	percentiles := make(map[string]map[int]Percentiles)
+	for key, h := range s.histograms {
+		if percentiles[key.op] == nil {
+			percentiles[key.op] = make(map[int]Percentiles)
+		}
+		percentiles[key.op][key.level] = h.percentiles()
+	}
+	return percentiles
+}
+
+// merge adds the counts of another histogram.
+func (h *histogram) merge(other *histogram) {
+	if len(other.counts) > len(h.counts) {
+		counts := make([]uint64, len(other.counts))
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	h.total += other.total
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
@@ -0,0 +1,61 @@
+package ringtree
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHistogramQuantiles(t *testing.T) {
+	var h histogram
+	for i := 1; i <= 10000; i++ {
+		h.add(time.Duration(i) * time.Microsecond)
+	}
+	for q, want := range map[float64]time.Duration{
+		0.5:   5000 * time.Microsecond,
+		0.9:   9000 * time.Microsecond,
+		0.99:  9900 * time.Microsecond,
+		0.999: 9990 * time.Microsecond,
+	} {
+		got := h.quantile(q)
+		if diff := float64(got-want) / float64(want); diff < -0.04 || diff > 0.04 {
+			t.Errorf("q%v: got %v, want within 4%% of %v", q, got, want)
+		}
+	}
+	if h.quantile(1) != 10*time.Millisecond || h.max != 10*time.Millisecond {
+		t.Errorf("got max %v, want 10ms", h.quantile(1))
+	}
+	for v := uint64(0); v < 1<<20; v += 7 {
+		if max := bucketMax(bucket(v)); max < v || bucket(max) != bucket(v) {
+			t.Fatalf("%d: bucket %d ends at %d", v, bucket(v), max)
+		}
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+	for i := 0; i < 60; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	stats := rt.Stats()
+	inserts := stats.Percentiles()["InsertKey"]
+	if inserts.Count == 0 || inserts.P50 > inserts.P99 || inserts.P99 > inserts.Max {
+		t.Fatalf("got InsertKey percentiles %+v, want ordered percentiles over some inserts", inserts)
+	}
+	levels := stats.LevelPercentiles()["InsertKey"]
+	var total uint64
+	for _, p := range levels {
+		total += p.Count
+	}
+	if len(levels) < 2 || total != inserts.Count {
+		t.Fatalf("got inserts on %d levels totalling %d, want several levels totalling %d", len(levels), total, inserts.Count)
+	}
+
+	stats.Reset()
+	if len(stats.Percentiles()) != 0 {
+		t.Fatal("expected Reset to clear the latency histograms")
+	}
+}
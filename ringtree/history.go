@@ -0,0 +1,213 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrEpochNotRetained is returned by AtEpoch for an epoch whose topology is not retained.
+var ErrEpochNotRetained = errors.New("topology of epoch is not retained")
+
+// WithHistory retains the topology of the last n epochs, so AtEpoch can show which node owned a key when an
+// error happened. Each epoch keeps the published circle of every ring, shared with the epochs before and
+// after it unless the ring changed, so a version costs a pointer per ring plus the rings that changed.
+func WithHistory(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.History = n
+		}
+	}
+}
+
+// historyState holds the topology of the tree as of each of its last epochs.
+type historyState struct {
+	limit    int
+	root     *Ring                 // Root of the tree the open rings belong to
+	open     map[*Ring]ringVersion // Latest published snapshot of every ring, not yet sealed into a version
+	versions []*topologyVersion    // Oldest first
+	sync.RWMutex
+}
+
+// topologyVersion is the topology of the tree as of one epoch: the published snapshot of every ring. A
+// version is never changed once recorded.
+type topologyVersion struct {
+	epoch uint64
+	root  *Ring
+	rings map[*Ring]ringVersion
+}
+
+// ringVersion is one ring as of a version.
+type ringVersion struct {
+	id       string
+	level    int
+	snapshot *circleSnapshot
+}
+
+// record takes in a ring's new snapshot (assuming the ring's mutex is held). Outside a batch of events the
+// change is complete and is sealed into a version at once; inside one, rings publish before and after the
+// batch's events bump the epoch, so the version is sealed when the batch ends.
+func (h *historyState) record(r *Ring, snapshot *circleSnapshot) {
+	if h == nil {
+		return
+	}
+	root := r.root()
+	r.hub.mu.Lock()
+	epoch, batched := r.hub.epoch, r.hub.depth > 0
+	r.hub.mu.Unlock()
+	h.Lock()
+	defer h.Unlock()
+
+	if h.open == nil || h.root != root {
+		h.root, h.open = root, make(map[*Ring]ringVersion)
+	}
+	// Subrings the ring no longer holds, such as collapsed ones, leave the topology with everything below them
+	if previous, ok := h.open[r]; ok {
+		for id, member := range previous.snapshot.members {
+			if subring, ok := member.(*Ring); ok && snapshot.members[id] != member {
+				forget(h.open, subring)
+			}
+		}
+	}
+	h.open[r] = ringVersion{id: r.id, level: r.level, snapshot: snapshot}
+	if !batched {
+		h.seal(epoch)
+	}
+}
+
+// seal records the open rings as the version of the given epoch, replacing the latest version if it is not
+// older (assuming the mutex is held).
+func (h *historyState) seal(epoch uint64) {
+	if h.open == nil {
+		return
+	}
+	rings := make(map[*Ring]ringVersion, len(h.open))
+	for ring, v := range h.open {
+		rings[ring] = v
+	}
+	version := &topologyVersion{epoch: epoch, root: h.root, rings: rings}
+	if last := len(h.versions) - 1; last >= 0 && h.versions[last].epoch >= epoch {
+		h.versions[last] = version
+		return
+	}
+	h.versions = append(h.versions, version)
+	if len(h.versions) > h.limit {
+		h.versions = append(h.versions[:0], h.versions[len(h.versions)-h.limit:]...)
+	}
+}
+
+// sealBatch seals the open rings once a batch of events ends, with the epoch the batch brought the tree to.
+func (h *historyState) sealBatch(epoch uint64) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	h.seal(epoch)
+}
+
+// forget removes a ring and its subrings from the rings of a version being built.
+func forget(rings map[*Ring]ringVersion, ring *Ring) {
+	v, ok := rings[ring]
+	if !ok {
+		return
+	}
+	delete(rings, ring)
+	for _, member := range v.snapshot.members {
+		if subring, ok := member.(*Ring); ok {
+			forget(rings, subring)
+		}
+	}
+}
+
+// restart relabels the latest version with the given epoch and drops the others, for a tree whose epoch was
+// restored after it was built.
+func (h *historyState) restart(epoch uint64) {
+	if h == nil {
+		return
+	}
+	h.Lock()
+	defer h.Unlock()
+	if len(h.versions) == 0 {
+		return
+	}
+	latest := *h.versions[len(h.versions)-1]
+	latest.epoch = epoch
+	h.versions = []*topologyVersion{&latest}
+}
+
+// TopologyView is a read-only view of the tree's topology as of a past epoch.
+type TopologyView struct {
+	version *topologyVersion
+	config  *Config
+}
+
+// AtEpoch returns the topology of the tree as of the given epoch: after the last change at or before it.
+// It returns ErrEpochNotRetained if history is off, the epoch is older than the retained versions or the
+// tree has not reached it yet.
+func (r *Ring) AtEpoch(epoch uint64) (*TopologyView, error) {
+	h := r.history
+	if h == nil {
+		return nil, fmt.Errorf("%w: history is off", ErrEpochNotRetained)
+	}
+	if current := r.Epoch(); epoch > current {
+		return nil, fmt.Errorf("%w: epoch %d is ahead of the tree's epoch %d", ErrEpochNotRetained, epoch, current)
+	}
+	h.RLock()
+	defer h.RUnlock()
+	i := sort.Search(len(h.versions), func(i int) bool { return h.versions[i].epoch > epoch })
+	if i == 0 {
+		return nil, fmt.Errorf("%w: epoch %d is older than the %d retained", ErrEpochNotRetained, epoch, len(h.versions))
+	}
+	return &TopologyView{version: h.versions[i-1], config: r.config}, nil
+}
+
+// Epoch returns the epoch of the view's topology, that of the last change at or before the epoch asked for.
+func (v *TopologyView) Epoch() uint64 {
+	return v.version.epoch
+}
+
+// Lookup returns the node that owned a key by hash as of the view's epoch. Pins and node states are not
+// retained, so keys placed by them are reported at their hashed owner.
+func (v *TopologyView) Lookup(key string) (string, error) {
+	ring := v.version.root
+	for {
+		rv, ok := v.version.rings[ring]
+		if !ok || len(rv.snapshot.vNodes) == 0 {
+			return "", ErrRingEmpty
+		}
+		_, memberID := rv.snapshot.find(v.config.keyHash(key, rv.level))
+		switch member := rv.snapshot.members[memberID].(type) {
+		case *Ring:
+			ring = member
+		case nil:
+			return "", fmt.Errorf("vnode of ring %s belongs to unknown member %s", rv.id, memberID)
+		default:
+			return member.ID(), nil
+		}
+	}
+}
+
+// NodeIDs returns the IDs of the physical nodes in the tree as of the view's epoch, sorted.
+func (v *TopologyView) NodeIDs() []string {
+	var ids []string
+	var walk func(ring *Ring)
+	walk = func(ring *Ring) {
+		rv, ok := v.version.rings[ring]
+		if !ok {
+			return
+		}
+		for id, member := range rv.snapshot.members {
+			switch member := member.(type) {
+			case *Node:
+				ids = append(ids, id)
+			case *Ring:
+				walk(member)
+			}
+		}
+	}
+	walk(v.version.root)
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,106 @@
+package ringtree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAtEpoch(t *testing.T) {
+	rt := New(4, WithHistory(100))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	var keys []string
+	for i := 0; i < 500; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	epoch := rt.Epoch()
+	before := owners(rt, keys)
+
+	rt.InsertNode(NewNode("C", 1000))
+	if _, err := rt.Split("A"); err != nil {
+		t.Fatalf("expected node A to be split, got error: %v", err)
+	}
+	middle := rt.Epoch()
+	mid := owners(rt, keys)
+	if _, err := rt.Collapse("A"); err != nil {
+		t.Fatalf("expected subring A to be collapsed, got error: %v", err)
+	}
+	rt.RemoveNodeByID("B")
+
+	view, err := rt.AtEpoch(epoch)
+	if err != nil {
+		t.Fatalf("expected epoch %d to be retained, got error: %v", epoch, err)
+	}
+	if view.Epoch() > epoch {
+		t.Errorf("expected a view at or before epoch %d, got %d", epoch, view.Epoch())
+	}
+	if ids := view.NodeIDs(); len(ids) != 2 || ids[0] != "A" || ids[1] != "B" {
+		t.Errorf("expected nodes A and B at epoch %d, got %v", epoch, ids)
+	}
+	// Every key is reported on the node that owned it back then, not on its owner now
+	changed := 0
+	for _, key := range keys {
+		owner, err := view.Lookup(key)
+		if err != nil || owner != before[key] {
+			t.Fatalf("expected key %s on %s at epoch %d, got %s (%v)", key, before[key], epoch, owner, err)
+		}
+		if now, _ := rt.Lookup(key); now != owner {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Errorf("expected some keys to have changed owner since epoch %d", epoch)
+	}
+
+	// The split subring is part of the topology in between, though it has been collapsed since
+	view, err = rt.AtEpoch(middle)
+	if err != nil {
+		t.Fatalf("expected epoch %d to be retained, got error: %v", middle, err)
+	}
+	for _, key := range keys {
+		if owner, _ := view.Lookup(key); owner != mid[key] {
+			t.Fatalf("expected key %s on %s at epoch %d, got %s", key, mid[key], middle, owner)
+		}
+	}
+
+	if _, err := rt.AtEpoch(rt.Epoch() + 1); !errors.Is(err, ErrEpochNotRetained) {
+		t.Errorf("expected ErrEpochNotRetained for a future epoch, got %v", err)
+	}
+	if _, err := New(4).AtEpoch(0); !errors.Is(err, ErrEpochNotRetained) {
+		t.Errorf("expected ErrEpochNotRetained without history, got %v", err)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	rt := New(8, WithHistory(3))
+	for _, id := range []string{"A", "B", "C", "D", "E", "F"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	if _, err := rt.AtEpoch(1); !errors.Is(err, ErrEpochNotRetained) {
+		t.Errorf("expected the first epochs to be dropped, got %v", err)
+	}
+	view, err := rt.AtEpoch(rt.Epoch())
+	if err != nil {
+		t.Fatalf("expected the current epoch to be retained, got error: %v", err)
+	}
+	checkNum(len(view.NodeIDs()), 6, t)
+
+	// A restored tree's history starts at the epoch it was restored at
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ReadSnapshot(&buf, WithHistory(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err = restored.AtEpoch(rt.Epoch())
+	if err != nil {
+		t.Fatalf("expected the restored epoch to be retained, got error: %v", err)
+	}
+	checkNum(int(view.Epoch()), int(rt.Epoch()), t)
+	checkNum(len(view.NodeIDs()), 6, t)
+}
@@ -0,0 +1,96 @@
+package ringtree
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// spreadTable holds the keys whose reads are spread over followers, with the number of followers of each.
+type spreadTable struct {
+	keys  map[string]int
+	count atomic.Int64 // Keys in the table, so lookups skip it while it is empty
+	sync.RWMutex
+}
+
+func newSpreadTable() *spreadTable {
+	return &spreadTable{keys: make(map[string]int)}
+}
+
+// get returns the number of followers a key's reads are spread over.
+func (t *spreadTable) get(key string) (int, bool) {
+	if t.count.Load() == 0 {
+		return 0, false
+	}
+	t.RLock()
+	defer t.RUnlock()
+	k, ok := t.keys[key]
+	return k, ok
+}
+
+// set spreads a key over k followers, or stops spreading it for k of 0.
+func (t *spreadTable) set(key string, k int) {
+	t.Lock()
+	defer t.Unlock()
+	if k > 0 {
+		t.keys[key] = k
+	} else {
+		delete(t.keys, key)
+	}
+	t.count.Store(int64(len(t.keys)))
+}
+
+// SpreadKey spreads the reads of a hot key over its owner and the next k distinct nodes clockwise on the
+// owner's ring. Lookup then returns one of them, the less loaded of two picked at random, so a cache
+// front-end can serve the key from copies on the followers. Followers that are Down are skipped, and a ring
+// with fewer nodes spreads over all of them. A k of 0 routes the key to its owner again; removing the key
+// stops spreading it too.
+func (r *Ring) SpreadKey(key string, k int) error {
+	if k < 0 {
+		return errors.New("follower count must not be negative")
+	}
+	if _, err := r.lookup(key); err != nil {
+		return err
+	}
+	r.spreads.set(key, k)
+	return nil
+}
+
+// SpreadFollowers returns the number of followers a key's reads are spread over, or 0 if they are not.
+func (r *Ring) SpreadFollowers(key string) int {
+	k, _ := r.spreads.get(key)
+	return k
+}
+
+// leastLoadedOfTwo picks two distinct nodes at random and returns the one with the lower load, counting
+// the reads routed to each (assuming their ring's mutex is held). It returns nil for no nodes.
+func leastLoadedOfTwo(nodes []*Node) *Node {
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	}
+	i := rand.Intn(len(nodes))
+	j := rand.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := nodes[i], nodes[j]
+	if b.readLoad() < a.readLoad() {
+		return b
+	}
+	return a
+}
+
+// readLoad returns the node's load plus the reads routed to it by spreading or a read strategy, or plus its
+// decayed request load when that is tracked (assuming its ring's mutex is held).
+func (n *Node) readLoad() int64 {
+	if requests, tracked := n.requests.value(time.Now()); tracked {
+		return int64(n.load) + int64(math.Round(requests))
+	}
+	return int64(n.load) + n.reads.Load()
+}
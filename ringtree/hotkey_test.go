@@ -0,0 +1,79 @@
+package ringtree
+
+import (
+	"errors"
+	"testing"
+)
+
+// spreadKey spreads a key over k followers and returns the nodes its reads spread over.
+func spreadKey(t *testing.T, rt *Ring, key string, k int) []string {
+	t.Helper()
+	if err := rt.SpreadKey(key, k); err != nil {
+		t.Fatalf("expected the key to be spread, got error: %v", err)
+	}
+	_, parent, vNodeHash, _, _ := rt.FindNode(key)
+	var followers []string
+	for _, node := range parent.replicaWalk(vNodeHash, k+1) {
+		followers = append(followers, node.id)
+	}
+	return followers
+}
+
+func TestSpreadKey(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B", "C", "D", "E"}, 1000, 1)
+	key := keys[0]
+	followers := spreadKey(t, rt, key, 2)
+	checkNum(len(followers), 3, t)
+	checkNum(rt.SpreadFollowers(key), 2, t)
+
+	served := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		owner, err := rt.Lookup(key)
+		if err != nil {
+			t.Fatalf("expected the key to be found, got error: %v", err)
+		}
+		served[owner]++
+	}
+	checkNum(len(served), 3, t)
+	for _, id := range followers {
+		// The reads each node serves count toward its load, so two random choices keep them close
+		if served[id] < 80 {
+			t.Errorf("expected %s to serve about a third of the reads, got %v", id, served)
+		}
+	}
+
+	// A k of 0 routes the key to its owner again
+	rt.SpreadKey(key, 0)
+	for i := 0; i < 10; i++ {
+		if owner, _ := rt.Lookup(key); owner != followers[0] {
+			t.Errorf("expected unspread key to be read from its owner %s, got %s", followers[0], owner)
+		}
+	}
+
+	if err := rt.SpreadKey("missing", 2); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if err := rt.SpreadKey(key, -1); err == nil {
+		t.Errorf("expected a negative follower count to be rejected")
+	}
+}
+
+func TestSpreadKeyDownFollower(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B", "C", "D", "E"}, 1000, 1)
+	key := keys[0]
+	followers := spreadKey(t, rt, key, 2)
+	if err := rt.SetNodeState(followers[1], Down); err != nil {
+		t.Fatalf("expected the follower to be marked down, got error: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if owner, _ := rt.Lookup(key); owner == followers[1] {
+			t.Fatalf("expected down follower %s to be skipped", followers[1])
+		}
+	}
+
+	// Removing the key stops spreading it
+	rt.RemoveKey(key)
+	checkNum(rt.SpreadFollowers(key), 0, t)
+	rt.InsertKey(key)
+	checkNum(rt.SpreadFollowers(key), 0, t)
+}
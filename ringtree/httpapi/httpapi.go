@@ -0,0 +1,268 @@
+// Package httpapi exposes a ring tree over HTTP with JSON endpoints for membership, key lookup, load
+// statistics, hierarchy information and snapshot download.
+//
+// Routes:
+//
+//	GET    /members          Members of every ring, nested by subring
+//	POST   /members          Add a node to the root ring, from {"id": "...", "threshold": n}
+//	DELETE /members/{id}     Remove a node anywhere in the tree
+//	GET    /keys/{key}       Owner and replicas of a key
+//	GET    /stats            Node and key counts, and the load of every ring
+//	GET    /counters         Live counters for scraping: keys, nodes, depth, remaps and epoch
+//	GET    /hierarchy        Node and ring counts of every level
+//	GET    /snapshot         The whole tree in the ringtree snapshot format
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// Handler serves the management API of one ring tree.
+type Handler struct {
+	ring *ringtree.Ring
+	mux  *http.ServeMux
+}
+
+// New returns a handler for the tree rooted at ring. Mount it with http.StripPrefix to serve it below a
+// path.
+func New(ring *ringtree.Ring) *Handler {
+	h := &Handler{ring: ring, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/members", h.members)
+	h.mux.HandleFunc("/members/", h.member)
+	h.mux.HandleFunc("/keys/", h.key)
+	h.mux.HandleFunc("/stats", h.stats)
+	h.mux.HandleFunc("/counters", h.counters)
+	h.mux.HandleFunc("/hierarchy", h.hierarchy)
+	h.mux.HandleFunc("/snapshot", h.snapshot)
+	return h
+}
+
+// ServeHTTP dispatches a request to its endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Member describes a ring member in the /members listing.
+type Member struct {
+	ID        string   `json:"id"`
+	Kind      string   `json:"kind"`
+	Level     int      `json:"level"`
+	Nodes     int      `json:"nodes"`
+	Keys      int      `json:"keys"`
+	Load      int      `json:"load"`
+	Threshold int      `json:"threshold,omitempty"`
+	State     string   `json:"state,omitempty"`
+	Members   []Member `json:"members,omitempty"`
+}
+
+// NewNode is the body of a POST to /members.
+type NewNode struct {
+	ID        string `json:"id"`
+	Threshold int    `json:"threshold"`
+}
+
+// KeyInfo is the response to a key lookup.
+type KeyInfo struct {
+	Key      string   `json:"key"`
+	Owner    string   `json:"owner"`
+	Replicas []string `json:"replicas,omitempty"`
+}
+
+// Stats is the response of /stats.
+type Stats struct {
+	Nodes int                 `json:"nodes"`
+	Keys  int                 `json:"keys"`
+	Depth int                 `json:"depth"`
+	Rings []ringtree.RingInfo `json:"rings"`
+}
+
+// Hierarchy is the response of /hierarchy.
+type Hierarchy struct {
+	Depth  int                  `json:"depth"`
+	Nodes  int                  `json:"nodes"`
+	Keys   int                  `json:"keys"`
+	Levels []ringtree.LevelInfo `json:"levels"`
+}
+
+// members lists the tree, or adds a node to the root ring.
+func (h *Handler) members(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, describe(h.ring))
+	case http.MethodPost:
+		var body NewNode
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if body.Threshold < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("threshold must be positive"))
+			return
+		}
+		node := ringtree.NewNode(body.ID, body.Threshold)
+		if err := h.ring.InsertNode(node); err != nil {
+			writeError(w, status(err), err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, describeNode(h.ring, node.ID()))
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// member removes a node from the tree.
+func (h *Handler) member(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w, http.MethodDelete)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/members/")
+	node, ring, err := h.ring.LocateNode(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := ring.RemoveNode(node); err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// key reports the owner and replicas of a key.
+func (h *Handler) key(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/keys/")
+	owner, err := h.ring.Lookup(key)
+	if err != nil {
+		writeError(w, status(err), err)
+		return
+	}
+	info := KeyInfo{Key: key, Owner: owner}
+	if h.ring.Config().Replication > 1 {
+		info.Replicas, _ = h.ring.Replicas(key)
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// stats reports the counts and the load of every ring.
+func (h *Handler) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	// Report gathers everything under the tree's writer lock, so the counts and loads are consistent
+	report := h.ring.Report()
+	writeJSON(w, http.StatusOK, Stats{
+		Nodes: report.Nodes,
+		Keys:  report.Keys,
+		Depth: report.Depth,
+		Rings: report.Rings,
+	})
+}
+
+// counters reports the live counters of the tree.
+func (h *Handler) counters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.ring.Counters())
+}
+
+// hierarchy reports the node and ring counts of every level.
+func (h *Handler) hierarchy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	report := h.ring.Report()
+	writeJSON(w, http.StatusOK, Hierarchy{Depth: report.Depth, Nodes: report.Nodes, Keys: report.Keys, Levels: report.Levels})
+}
+
+// snapshot downloads the whole tree.
+func (h *Handler) snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="ringtree.json"`)
+	if err := h.ring.WriteSnapshot(w); err != nil {
+		writeError(w, status(err), err)
+	}
+}
+
+// describe returns the listing of the members of ring, with the members of each subring nested below it.
+// Each ring's members are read under its lock, so nodes taking or handing off keys are never read midway.
+func describe(ring *ringtree.Ring) []Member {
+	var members []Member
+	for _, info := range ring.MemberInfo() {
+		m := describeInfo(info)
+		if subring, ok := ring.Subring(info.ID); ok {
+			m.Members = describe(subring)
+		}
+		members = append(members, m)
+	}
+	return members
+}
+
+// describeNode returns the listing of a physical node anywhere in the tree.
+func describeNode(ring *ringtree.Ring, id string) Member {
+	if _, holder, err := ring.LocateNode(id); err == nil {
+		for _, info := range holder.MemberInfo() {
+			if info.ID == id {
+				return describeInfo(info)
+			}
+		}
+	}
+	return Member{ID: id, Kind: ringtree.KindNode.String()} // Removed again before it could be listed
+}
+
+// describeInfo converts a member's information to its listing, without nested members.
+func describeInfo(info ringtree.MemberInfo) Member {
+	m := Member{ID: info.ID, Kind: info.Kind.String(), Level: info.Level, Nodes: info.Nodes, Keys: info.Keys, Load: info.Load}
+	if info.Kind == ringtree.KindNode {
+		m.Threshold = info.Threshold
+		m.State = info.State.String()
+	}
+	return m
+}
+
+// status maps a ring error to an HTTP status code.
+func status(err error) int {
+	switch {
+	case errors.Is(err, ringtree.ErrKeyNotFound), errors.Is(err, ringtree.ErrNodeNotFound), errors.Is(err, ringtree.ErrRingEmpty):
+		return http.StatusNotFound
+	case errors.Is(err, ringtree.ErrNodeExists), errors.Is(err, ringtree.ErrRingAtCapacity), errors.Is(err, ringtree.ErrNodePinned):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error as a JSON response.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// methodNotAllowed rejects a request made with an unsupported method.
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
@@ -0,0 +1,175 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+func TestHandler(t *testing.T) {
+	ring := ringtree.New(4)
+	if err := ring.InsertNode(ringtree.NewNode("A", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.InsertKey("k1"); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(New(ring))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/members", "application/json", bytes.NewBufferString(`{"id":"B","threshold":1000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /members: got status %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+	resp, _ = http.Post(server.URL+"/members", "application/json", bytes.NewBufferString(`{"id":"B","threshold":1000}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("POST /members of an existing node: got status %d, want %d", resp.StatusCode, http.StatusConflict)
+	}
+
+	var members []Member
+	get(t, server.URL+"/members", http.StatusOK, &members)
+	if len(members) != 2 || members[0].ID != "A" || members[1].ID != "B" {
+		t.Errorf("GET /members: got %+v, want nodes A and B", members)
+	}
+
+	var info KeyInfo
+	get(t, server.URL+"/keys/k1", http.StatusOK, &info)
+	if info.Owner != "A" && info.Owner != "B" {
+		t.Errorf("GET /keys/k1: got owner %q", info.Owner)
+	}
+
+	var stats Stats
+	get(t, server.URL+"/stats", http.StatusOK, &stats)
+	if stats.Nodes != 2 || stats.Keys != 1 {
+		t.Errorf("GET /stats: got %d nodes and %d keys, want 2 and 1", stats.Nodes, stats.Keys)
+	}
+
+	var counters ringtree.Counters
+	get(t, server.URL+"/counters", http.StatusOK, &counters)
+	if counters.Nodes != 2 || counters.Keys != 1 || counters.Rings != 1 {
+		t.Errorf("GET /counters: got %+v, want 2 nodes, 1 key and 1 ring", counters)
+	}
+
+	var hierarchy Hierarchy
+	get(t, server.URL+"/hierarchy", http.StatusOK, &hierarchy)
+	if len(hierarchy.Levels) != 1 {
+		t.Errorf("GET /hierarchy: got %d levels, want 1", len(hierarchy.Levels))
+	}
+
+	resp, err = http.Get(server.URL + "/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ringtree.ReadSnapshot(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("reading downloaded snapshot: %v", err)
+	}
+	if restored.Stats().Keys() != 1 {
+		t.Errorf("downloaded snapshot holds %d keys, want 1", restored.Stats().Keys())
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/members/B", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE /members/B: got status %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("DELETE /members/B again: got status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestHandlerConcurrentReadsAndWrites(t *testing.T) {
+	ring := ringtree.New(8)
+	if err := ring.InsertNode(ringtree.NewNode("A", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := ring.InsertKey("k" + strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := New(ring)
+
+	// Nodes join and leave while the listings and statistics are read
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for _, path := range []string{"/stats", "/hierarchy", "/members", "/counters"} {
+		readers.Add(1)
+		go func(path string) {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("GET %s: got status %d, want %d", path, rec.Code, http.StatusOK)
+					return
+				}
+			}
+		}(path)
+	}
+
+	for i := 0; i < 50; i++ {
+		id := "N" + strconv.Itoa(i)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/members", bytes.NewBufferString(`{"id":"`+id+`","threshold":1000}`)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST /members %s: got status %d, want %d", id, rec.Code, http.StatusCreated)
+		}
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/members/"+id, nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("DELETE /members/%s: got status %d, want %d", id, rec.Code, http.StatusNoContent)
+		}
+	}
+	close(done)
+	readers.Wait()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var stats Stats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Nodes != 1 || stats.Keys != 100 {
+		t.Errorf("GET /stats: got %d nodes and %d keys, want 1 and 100", stats.Nodes, stats.Keys)
+	}
+}
+
+// get fetches a URL, checks its status and decodes its JSON body into v.
+func get(t *testing.T, url string, code int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != code {
+		t.Fatalf("GET %s: got status %d, want %d", url, resp.StatusCode, code)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package ringtree
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// indexEntry records the node holding a key and the ring that node is a member of.
+type indexEntry struct {
+	node *Node
+	ring *Ring
+}
+
+// keyIndex maps every key held by the tree to its node. A nil index is disabled and ignores all updates.
+type keyIndex struct {
+	entries map[string]indexEntry
+	sync.RWMutex
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{entries: make(map[string]indexEntry)}
+}
+
+// get returns the node holding a key and its ring.
+func (idx *keyIndex) get(key string) (indexEntry, bool) {
+	if idx == nil {
+		return indexEntry{}, false
+	}
+	idx.RLock()
+	defer idx.RUnlock()
+	entry, ok := idx.entries[key]
+	return entry, ok
+}
+
+// set records that a key is held by node on ring.
+func (idx *keyIndex) set(key string, node *Node, ring *Ring) {
+	if idx == nil {
+		return
+	}
+	idx.Lock()
+	idx.entries[key] = indexEntry{node: node, ring: ring}
+	idx.Unlock()
+}
+
+// delete drops a key from the index.
+func (idx *keyIndex) delete(key string) {
+	if idx == nil {
+		return
+	}
+	idx.Lock()
+	delete(idx.entries, key)
+	idx.Unlock()
+}
+
+// WithKeyIndex maintains a root-level index of every key's node, so Lookup answers in constant time and
+// InsertKey rejects a key already held anywhere in the tree.
+func WithKeyIndex(enabled bool) Option {
+	return func(c *Config) {
+		c.KeyIndex = enabled
+	}
+}
+
+// lookupIndexed answers a lookup from the key index. It reports false when the index is disabled or does
+// not hold the key, which is the case for keys handed to custom members.
+func (r *Ring) lookupIndexed(key string, start time.Time) (string, bool, error) {
+	entry, ok := r.index.get(key)
+	if !ok {
+		return "", false, nil
+	}
+
+	entry.ring.RLock()
+	if !entry.ring.verifyHeld(entry.node, key) {
+		entry.ring.RUnlock()
+		return "", true, ErrChecksumMismatch
+	}
+	down := !entry.node.servesReads()
+	entry.ring.RUnlock()
+	if r.config.replication() > 1 && down {
+		owner, err := r.Primary(key)
+		return owner, true, err
+	}
+	r.timeTrackAt(start, "Lookup", entry.ring.level, "to find an indexed key at level "+strconv.Itoa(entry.ring.level))
+	return entry.node.id, true, nil
+}
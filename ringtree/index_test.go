@@ -0,0 +1,73 @@
+package ringtree
+
+import "testing"
+
+func TestKeyIndexRejectsDuplicateOnAnotherNode(t *testing.T) {
+	rt := New(5, WithKeyIndex(true))
+	nodeA := NewNode("A", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 1000))
+
+	// Find a key owned by A, then drain A so a second write would be routed to B
+	var key string
+	for i := 0; ; i++ {
+		key, _ = GenerateRandomString(20)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+		if owner, _ := rt.Lookup(key); owner == nodeA.id {
+			break
+		}
+	}
+	rt.SetNodeState(nodeA.id, Draining)
+
+	if err := rt.InsertKey(key); err != ErrKeyExists {
+		t.Errorf("expected ErrKeyExists for a key held by a draining node, got %v", err)
+	}
+	checkNum(rt.MemberStats().Keys, rt.Stats().Keys(), t)
+}
+
+func TestKeyIndexFollowsMoves(t *testing.T) {
+	rt := New(2, WithKeyIndex(true))
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+
+	var keys []string
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys[:150] {
+		if err := rt.RemoveKey(key); err != nil {
+			t.Fatalf("expected key %s to be removed, got error: %v", key, err)
+		}
+	}
+
+	// Every remaining key is indexed at the node that actually holds it
+	for _, key := range keys[150:] {
+		entry, ok := rt.index.get(key)
+		if !ok {
+			t.Fatalf("expected key %s to be indexed", key)
+		}
+		held := false
+		for _, keyHashMap := range entry.node.keys {
+			if _, ok := keyHashMap[key]; ok {
+				held = true
+			}
+		}
+		if !held {
+			t.Errorf("expected key %s to be held by indexed node %s", key, entry.node.id)
+		}
+		if _, err := rt.Lookup(key); err != nil {
+			t.Errorf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys[:150] {
+		if _, ok := rt.index.get(key); ok {
+			t.Errorf("expected removed key %s to be dropped from the index", key)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ringtree
+
+// The iterators below have the shape of Go 1.23's iter.Seq, func(yield func(V) bool), without importing the
+// iter package, which the module's Go version predates. Callers on Go 1.23 or later can range over them
+// directly or convert them to iter.Seq.
+//
+// Iterators read a snapshot instead of holding the tree: taking one copies each node's map of vnodes and
+// marks its key sets shared, under the writer lock, which costs one entry per vnode rather than per key.
+// Writers copy a shared key set before their first change to it, so the snapshot's sets are never written
+// again and the iteration runs while inserts and removals proceed.
+
+// Nodes returns an iterator over the physical nodes held below the ring, visiting each ring's members by ID
+// and the nodes of a subring in its place. The nodes yielded are read-only copies taken when the iteration
+// starts, with the ID, load, threshold, state, locality and keys each node had then; writers proceed during
+// the iteration, and changing a copy does not change the tree.
+func (r *Ring) Nodes() func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		for _, node := range r.snapshotNodes() {
+			if !yield(node) {
+				return
+			}
+		}
+	}
+}
+
+// snapshotNodes returns read-only copies of the nodes below the ring, in the order of Nodes, sharing their
+// key sets with the tree.
+func (r *Ring) snapshotNodes() []*Node {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	var nodes []*Node
+	var visit func(ring *Ring)
+	visit = func(ring *Ring) {
+		for _, member := range ring.sortedMembers() {
+			switch member := member.(type) {
+			case *Node:
+				ring.RLock()
+				nodes = append(nodes, member.snapshot())
+				ring.RUnlock()
+			case *Ring:
+				visit(member)
+			}
+		}
+	}
+	visit(r)
+	return nodes
+}
+
+// snapshot returns a read-only copy of the node sharing its key sets, which are copied before the node next
+// writes them (assuming the tree's writer lock is held).
+func (n *Node) snapshot() *Node {
+	view := &Node{
+		id:        n.id,
+		keys:      make(map[uint32]keySet, len(n.keys)),
+		load:      n.load,
+		threshold: n.threshold,
+		state:     n.state,
+		base:      n.base,
+		locality:  n.locality,
+	}
+	view.maintenance = append(view.maintenance, n.maintenance...)
+	if n.shared == nil {
+		n.shared = make(map[uint32]bool, len(n.keys))
+	}
+	for vNodeHash, keys := range n.keys {
+		view.keys[vNodeHash] = keys
+		n.shared[vNodeHash] = true
+	}
+	return view
+}
+
+// writable returns the key set of a vnode for writing, first replacing it with a copy if a snapshot shares
+// it (assuming the tree's writer lock is held).
+func (n *Node) writable(vNodeHash uint32) keySet {
+	keys := n.keys[vNodeHash]
+	if !n.shared[vNodeHash] {
+		return keys
+	}
+	delete(n.shared, vNodeHash)
+	if keys == nil {
+		return nil
+	}
+	copied := make(keySet, len(keys))
+	for key, keyHash := range keys {
+		copied[key] = keyHash
+	}
+	n.keys[vNodeHash] = copied
+	return copied
+}
+
+// AllKeys returns an iterator over the keys held below the ring, node by node in the order of Nodes. Keys
+// held by custom members are not included. Like Nodes, it reads the tree as of the moment the iteration
+// starts while writers proceed, and streams keys from the nodes instead of collecting them first.
+func (r *Ring) AllKeys() func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		r.Nodes()(func(node *Node) bool {
+			more := true
+			node.Keys()(func(key string) bool {
+				more = yield(key)
+				return more
+			})
+			return more
+		})
+	}
+}
+
+// Keys returns an iterator over the node's keys, in no particular order. It needs no lock on a node yielded
+// by Nodes; on a node of the tree, its ring's mutex must be held.
+func (n *Node) Keys() func(yield func(string) bool) {
+	return func(yield func(string) bool) {
+		for _, keys := range n.keys {
+			for key := range keys {
+				if !yield(key) {
+					return
+				}
+			}
+		}
+	}
+}
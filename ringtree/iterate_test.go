@@ -0,0 +1,139 @@
+package ringtree
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	rt := New(4)
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	if _, err := rt.Split("A"); err != nil {
+		t.Fatal(err)
+	}
+	inserted := make(map[string]bool)
+	for i := 0; i < 500; i++ {
+		key, _ := GenerateRandomString(20)
+		inserted[key] = true
+		rt.InsertKey(key)
+	}
+
+	seen := make(map[string]bool)
+	rt.AllKeys()(func(key string) bool {
+		if seen[key] {
+			t.Errorf("key %s visited twice", key)
+		}
+		seen[key] = true
+		return true
+	})
+	if !reflect.DeepEqual(seen, inserted) {
+		t.Errorf("expected the %d inserted keys, got %d", len(inserted), len(seen))
+	}
+
+	var ids []string
+	keys := 0
+	rt.Nodes()(func(node *Node) bool {
+		ids = append(ids, node.ID())
+		node.Keys()(func(string) bool {
+			keys++
+			return true
+		})
+		return true
+	})
+	checkNum(keys, 500, t)
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	if !reflect.DeepEqual(sorted, rt.NodeIDs()) {
+		t.Errorf("expected nodes %v, got %v", rt.NodeIDs(), ids)
+	}
+	// The nodes of subring A come in its place, before B and C
+	if ids[len(ids)-2] != "B" || ids[len(ids)-1] != "C" {
+		t.Errorf("expected nodes B and C last, got %v", ids)
+	}
+
+	// Breaking out stops the iteration and releases the tree
+	visited := 0
+	rt.AllKeys()(func(string) bool {
+		visited++
+		return visited < 10
+	})
+	checkNum(visited, 10, t)
+	if err := rt.InsertKey("after"); err != nil {
+		t.Errorf("expected writes to proceed after the iteration, got %v", err)
+	}
+}
+
+func TestIteratorsConcurrentWrites(t *testing.T) {
+	rt := New(4, WithCheckpointInterval(16))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 200))
+	}
+	before := make(map[string]bool)
+	for i := 0; i < 400; i++ {
+		key, _ := GenerateRandomString(20)
+		before[key] = true
+		rt.InsertKey(key)
+	}
+
+	// Writes made from the loop body proceed, and the iteration still sees the keys it started with
+	var added, removed []string
+	seen := make(map[string]bool)
+	rt.AllKeys()(func(key string) bool {
+		if len(seen) == 0 {
+			for k := range before {
+				if len(removed) == 50 {
+					break
+				}
+				if err := rt.RemoveKey(k); err != nil {
+					t.Fatal(err)
+				}
+				removed = append(removed, k)
+			}
+			for i := 0; i < 500; i++ {
+				k, _ := GenerateRandomString(20)
+				if err := rt.InsertKey(k); err != nil {
+					t.Fatal(err)
+				}
+				added = append(added, k)
+			}
+		}
+		seen[key] = true
+		return true
+	})
+	if !reflect.DeepEqual(seen, before) {
+		t.Errorf("expected the %d keys of the snapshot, got %d", len(before), len(seen))
+	}
+	checkNum(rt.stats.numKeys.get(), 400-len(removed)+len(added), t)
+
+	// Writers on other goroutines race with iterations without corrupting either
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 300; i++ {
+				key, _ := GenerateRandomString(20)
+				rt.InsertKey(key)
+				rt.RemoveKey(key)
+			}
+		}()
+	}
+	want := 400 - len(removed) + len(added)
+	for i := 0; i < 5; i++ {
+		keys := 0
+		rt.AllKeys()(func(string) bool {
+			keys++
+			return true
+		})
+		// Each writer may hold one key it has not removed yet
+		if keys < want || keys > want+2 {
+			t.Errorf("expected %d to %d keys, got %d", want, want+2, keys)
+		}
+	}
+	wg.Wait()
+	checkValid(rt, t)
+}
@@ -0,0 +1,205 @@
+package ringtree
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Keyspace is a named partition of the ring tree. Keys are hashed with the keyspace name as a salt so
+// tenants sharing a tree never collide, and load is reported per keyspace.
+type Keyspace struct {
+	name      string
+	prefix    string  // Length-prefixed name every key of the keyspace is stored under
+	ring      *Ring   // Root ring the keyspace stores its keys in
+	threshold int     // Max keys in the keyspace (0 means unlimited)
+	keys      counter // Keys of the keyspace in the tree, counted as the tree's keys are
+	sync.Mutex
+}
+
+// KeyspaceStats represents the load of a single keyspace across the tree.
+type KeyspaceStats struct {
+	Name      string
+	Keys      int
+	Threshold int
+	Loads     map[string]int // Keys of this keyspace held by each node
+}
+
+// keyspaceRegistry tracks the keyspaces of a tree.
+type keyspaceRegistry struct {
+	keyspaces map[string]*Keyspace
+	byPrefix  atomic.Pointer[map[string]*Keyspace] // Keyspaces by prefix, replaced as a whole, for key counting
+	sync.Mutex
+}
+
+func newKeyspaceRegistry() *keyspaceRegistry {
+	return &keyspaceRegistry{keyspaces: make(map[string]*Keyspace)}
+}
+
+// Keyspace returns the keyspace with the given name, creating it on first use. A keyspace created over keys
+// already in the tree, such as after a restore, counts them once when it is created.
+func (r *Ring) Keyspace(name string) *Keyspace {
+	reg := r.keyspaces
+	reg.Lock()
+	defer reg.Unlock()
+
+	if ks, exists := reg.keyspaces[name]; exists {
+		return ks
+	}
+	ks := &Keyspace{name: name, prefix: keyspacePrefix(name), ring: r.root()}
+	reg.keyspaces[name] = ks
+
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	byPrefix := make(map[string]*Keyspace, len(reg.keyspaces))
+	for _, other := range reg.keyspaces {
+		byPrefix[other.prefix] = other
+	}
+	reg.byPrefix.Store(&byPrefix)
+	ks.keys.add(ks.countKeys(nil))
+	return ks
+}
+
+// keyspacePrefix returns the prefix the keys of a keyspace are stored under: a NUL byte, which keys outside
+// keyspaces are not expected to start with, and the name prefixed with its length.
+func keyspacePrefix(name string) string {
+	return "\x00" + strconv.Itoa(len(name)) + ":" + name
+}
+
+// splitKeyspace splits a stored key into the prefix of its keyspace and the key within the keyspace. Keys
+// outside keyspaces are returned whole with an empty prefix.
+func splitKeyspace(key string) (string, string) {
+	if len(key) < 3 || key[0] != 0 {
+		return "", key
+	}
+	i, n := 1, 0
+	for ; i < len(key) && key[i] >= '0' && key[i] <= '9' && n <= len(key); i++ {
+		n = n*10 + int(key[i]-'0')
+	}
+	if i == 1 || i == len(key) || key[i] != ':' || n > len(key)-i-1 {
+		return "", key
+	}
+	end := i + 1 + n
+	return key[:end], key[end:]
+}
+
+// count adds n to the key count of the keyspace a stored key belongs to, if it has one.
+func (reg *keyspaceRegistry) count(key string, n int) {
+	byPrefix := reg.byPrefix.Load()
+	if byPrefix == nil {
+		return
+	}
+	if prefix, _ := splitKeyspace(key); prefix != "" {
+		if ks := (*byPrefix)[prefix]; ks != nil {
+			ks.keys.add(n)
+		}
+	}
+}
+
+// countKey adds n to the tree's key count and to the count of the key's keyspace.
+func (r *Ring) countKey(key string, n int) {
+	r.stats.numKeys.add(n)
+	r.keyspaces.count(key, n)
+}
+
+// Keyspaces returns the names of all keyspaces in the tree.
+func (r *Ring) Keyspaces() []string {
+	r.keyspaces.Lock()
+	defer r.keyspaces.Unlock()
+
+	var names []string
+	for name := range r.keyspaces.keyspaces {
+		names = append(names, name)
+	}
+	return names
+}
+
+// root returns the top-level ring of the tree.
+func (r *Ring) root() *Ring {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// Name returns the keyspace name.
+func (ks *Keyspace) Name() string {
+	return ks.name
+}
+
+// SetThreshold limits the number of keys the keyspace may hold (0 means unlimited).
+func (ks *Keyspace) SetThreshold(threshold int) {
+	ks.Lock()
+	defer ks.Unlock()
+	ks.threshold = threshold
+}
+
+// saltKey returns the key as stored in the tree. The name is prefixed with its length, so no name and key
+// of one keyspace spell out the stored key of another, whatever bytes they contain.
+func (ks *Keyspace) saltKey(key string) string {
+	return ks.prefix + key
+}
+
+// InsertKey inserts a key into the keyspace. The keyspace's keys are counted as the tree adds and removes
+// them, so keys removed through the Ring, such as by RemoveKeysByPrefix, free capacity too.
+func (ks *Keyspace) InsertKey(key string) error {
+	ks.Lock()
+	defer ks.Unlock()
+
+	ks.ring.writer.Lock()
+	defer ks.ring.writer.Unlock()
+	if ks.threshold > 0 && ks.keys.get() >= ks.threshold {
+		return errors.New("keyspace is at capacity")
+	}
+	return ks.ring.insertKeyOp(ks.saltKey(key), nil)
+}
+
+// RemoveKey removes a key from the keyspace.
+func (ks *Keyspace) RemoveKey(key string) error {
+	return ks.ring.RemoveKey(ks.saltKey(key))
+}
+
+// Lookup finds the node holding a key of the keyspace.
+func (ks *Keyspace) Lookup(key string) (string, error) {
+	return ks.ring.Lookup(ks.saltKey(key))
+}
+
+// Stats reports the keyspace's key count and how its keys are spread across nodes.
+func (ks *Keyspace) Stats() KeyspaceStats {
+	ks.Lock()
+	defer ks.Unlock()
+
+	stats := KeyspaceStats{
+		Name:      ks.name,
+		Threshold: ks.threshold,
+		Loads:     make(map[string]int),
+	}
+	ks.ring.writer.Lock()
+	defer ks.ring.writer.Unlock()
+	ks.countKeys(stats.Loads)
+	stats.Keys = ks.keys.get()
+	return stats
+}
+
+// countKeys counts the keys of the keyspace held in the tree, adding each node's count to loads if it is
+// not nil (assuming the tree's writer lock is held).
+func (ks *Keyspace) countKeys(loads map[string]int) int {
+	count := 0
+	ks.ring.RLock()
+	defer ks.ring.RUnlock()
+	ks.ring.forEachNode(func(node *Node) {
+		for _, keys := range node.keys {
+			for key := range keys {
+				if strings.HasPrefix(key, ks.prefix) {
+					count++
+					if loads != nil {
+						loads[node.id]++
+					}
+				}
+			}
+		}
+	})
+	return count
+}
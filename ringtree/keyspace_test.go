@@ -0,0 +1,154 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestKeyspaceIsolation(t *testing.T) {
+	rt := New(5)
+	rt.InsertNode(NewNode("", 100))
+	rt.InsertNode(NewNode("", 100))
+
+	sessions := rt.Keyspace("sessions")
+	carts := rt.Keyspace("carts")
+	if rt.Keyspace("sessions") != sessions {
+		t.Fatalf("expected the same keyspace to be returned for the same name")
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := sessions.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	// The same key may exist in another keyspace
+	if err := carts.InsertKey("a"); err != nil {
+		t.Fatalf("expected key a to be inserted into a second keyspace, got error: %v", err)
+	}
+
+	if _, err := sessions.Lookup("b"); err != nil {
+		t.Errorf("expected key b to be found, got error: %v", err)
+	}
+	if _, err := carts.Lookup("b"); err == nil {
+		t.Errorf("expected key b to be missing from the carts keyspace")
+	}
+
+	stats := sessions.Stats()
+	checkNum(stats.Keys, 3, t)
+	total := 0
+	for _, load := range stats.Loads {
+		total += load
+	}
+	checkNum(total, 3, t)
+	checkNum(carts.Stats().Keys, 1, t)
+}
+
+func TestKeyspaceThreshold(t *testing.T) {
+	rt := New(5)
+	rt.InsertNode(NewNode("", 100))
+
+	ks := rt.Keyspace("tenant")
+	ks.SetThreshold(2)
+	ks.InsertKey("a")
+	ks.InsertKey("b")
+	if err := ks.InsertKey("c"); err == nil {
+		t.Errorf("expected error when exceeding the keyspace threshold")
+	}
+	if err := ks.RemoveKey("a"); err != nil {
+		t.Fatalf("expected key a to be removed, got error: %v", err)
+	}
+	if err := ks.InsertKey("c"); err != nil {
+		t.Errorf("expected key c to be inserted after freeing capacity, got error: %v", err)
+	}
+}
+
+func TestKeyspaceSaltingNeverCollides(t *testing.T) {
+	rt := New(5)
+	rt.InsertNode(NewNode("", 100))
+
+	// With a plain separator, "a" + "\x00b" + "c" and "a\x00b" + "c" would be stored under the same key
+	first := rt.Keyspace("a")
+	second := rt.Keyspace("a\x00b")
+	if err := first.InsertKey("\x00bc"); err != nil {
+		t.Fatalf("expected key to be inserted, got error: %v", err)
+	}
+	if err := second.InsertKey("c"); err != nil {
+		t.Fatalf("expected the key of another keyspace to be inserted, got error: %v", err)
+	}
+	checkNum(first.Stats().Keys, 1, t)
+	checkNum(second.Stats().Keys, 1, t)
+	checkNum(rt.Stats().Keys(), 2, t)
+}
+
+func TestKeyspaceCountFollowsTree(t *testing.T) {
+	rt := New(5)
+	rt.InsertNode(NewNode("", 100))
+
+	ks := rt.Keyspace("tenant")
+	ks.SetThreshold(2)
+	ks.InsertKey("a")
+	ks.InsertKey("b")
+
+	// Keys removed through the Ring are no longer counted, and free capacity
+	if _, err := rt.RemoveKeysByPrefix(ks.saltKey("")); err != nil {
+		t.Fatalf("unexpected error removing the keyspace's keys: %v", err)
+	}
+	checkNum(ks.Stats().Keys, 0, t)
+	if err := ks.InsertKey("c"); err != nil {
+		t.Errorf("expected key c to be inserted after the tenant's keys were removed, got error: %v", err)
+	}
+	checkNum(ks.Stats().Keys, 1, t)
+}
+
+func TestKeyspaceHashTags(t *testing.T) {
+	rt := New(5, WithKeyExtractor(HashTag))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+
+	// Tags co-locate keys within a keyspace, and the keyspace salt still applies to the tag
+	users := rt.Keyspace("users")
+	if got, want := rt.config.keyHash(users.saltKey("user:{42}:cart"), 0), hash(users.prefix+"42", 0); got != want {
+		t.Errorf("expected the tag to be hashed with the keyspace salt, got %d, want %d", got, want)
+	}
+	if rt.config.keyHash(users.saltKey("{42}"), 0) == rt.config.keyHash(rt.Keyspace("carts").saltKey("{42}"), 0) {
+		t.Errorf("expected the same tag in two keyspaces to hash apart")
+	}
+
+	// A tag in the keyspace name does not become the hash tag of its keys
+	tagged := rt.Keyspace("{tenant}")
+	owners := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if err := tagged.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+		owner, _ := tagged.Lookup(key)
+		owners[owner] = true
+	}
+	if len(owners) < 2 {
+		t.Errorf("expected the keys of a keyspace named with a tag to spread over nodes, got %d owners", len(owners))
+	}
+}
+
+func TestKeyspaceCreatedOverKeys(t *testing.T) {
+	rt := New(5)
+	rt.InsertNode(NewNode("", 100))
+
+	// Keys stored before the keyspace is created, as after a restore, are counted when it is created
+	for _, key := range []string{"a", "b"} {
+		if err := rt.InsertKey(keyspacePrefix("tenant") + key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	ks := rt.Keyspace("tenant")
+	checkNum(ks.Stats().Keys, 2, t)
+	ks.SetThreshold(2)
+	if err := ks.InsertKey("c"); err == nil {
+		t.Errorf("expected error when exceeding the keyspace threshold")
+	}
+	if err := rt.RemoveKey(keyspacePrefix("tenant") + "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkNum(ks.Stats().Keys, 1, t)
+}
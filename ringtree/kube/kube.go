@@ -0,0 +1,133 @@
+// Package kube keeps the nodes of a ring tree in sync with the ready pods behind a Kubernetes Service, as
+// listed by its EndpointSlices. Every pod is a node named after it: a pod is added once it is Ready,
+// drained while it is Terminating, marked Down while it is otherwise not ready, and removed once it leaves
+// every slice.
+//
+// The package does not depend on client-go. An EndpointSlice informer forwards its events after converting
+// each discoveryv1.EndpointSlice with a few lines of glue:
+//
+//	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+//		AddFunc:    func(obj any) { w.Apply(convert(obj.(*discoveryv1.EndpointSlice))) },
+//		UpdateFunc: func(_, obj any) { w.Apply(convert(obj.(*discoveryv1.EndpointSlice))) },
+//		DeleteFunc: func(obj any) {
+//			if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+//				w.Delete(slice.Name)
+//			}
+//		},
+//	})
+//
+// where convert copies each endpoint's target pod name, zone and conditions into an EndpointSlice.
+package kube
+
+import (
+	"sort"
+	"sync"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+	"github.com/kagwave/ring-tree/ringtree/membership"
+)
+
+// Endpoint is one pod of an EndpointSlice.
+type Endpoint struct {
+	Pod         string // Name of the target pod, used as the node ID
+	Zone        string // Zone of the pod, exposed as the "zone" label
+	Ready       bool
+	Terminating bool
+}
+
+// EndpointSlice is the part of a discovery.k8s.io/v1 EndpointSlice the watcher needs.
+type EndpointSlice struct {
+	Name      string
+	Endpoints []Endpoint
+}
+
+// Watcher applies EndpointSlice changes of one Service to a ring tree.
+type Watcher struct {
+	sync   *membership.Sync
+	slices map[string][]Endpoint
+	states map[string]ringtree.NodeState // State applied to each pod with a node
+	mu     sync.Mutex
+}
+
+// NewWatcher returns a watcher maintaining the nodes of ring. The membership options set the nodes'
+// threshold and error handling.
+func NewWatcher(ring *ringtree.Ring, opts ...membership.Option) *Watcher {
+	return &Watcher{
+		sync:   membership.New(ring, opts...),
+		slices: make(map[string][]Endpoint),
+		states: make(map[string]ringtree.NodeState),
+	}
+}
+
+// Apply records a created or updated EndpointSlice and brings the ring in line with the Service's pods.
+func (w *Watcher) Apply(slice EndpointSlice) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.slices[slice.Name] = append([]Endpoint(nil), slice.Endpoints...)
+	w.reconcile()
+}
+
+// Delete forgets a deleted EndpointSlice, removing the nodes of pods no other slice lists.
+func (w *Watcher) Delete(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.slices, name)
+	w.reconcile()
+}
+
+// Pods returns the sorted names of the pods with a node in the ring.
+func (w *Watcher) Pods() []string {
+	return w.sync.Peers()
+}
+
+// reconcile adds, drains and removes nodes so they match the pods of every slice (assuming the mutex is
+// held).
+func (w *Watcher) reconcile() {
+	pods := make(map[string]Endpoint)
+	for _, endpoints := range w.slices {
+		for _, ep := range endpoints {
+			// A pod listed by two slices during a rollout counts as ready if either says so
+			if seen, ok := pods[ep.Pod]; !ok || ep.Ready && !seen.Ready {
+				pods[ep.Pod] = ep
+			}
+		}
+	}
+
+	names := make([]string, 0, len(pods))
+	for pod := range pods {
+		names = append(names, pod)
+	}
+	sort.Strings(names)
+	for _, pod := range names {
+		ep := pods[pod]
+		state := ringtree.Up
+		switch {
+		case ep.Terminating:
+			state = ringtree.Draining
+		case !ep.Ready:
+			state = ringtree.Down
+		}
+
+		current, ok := w.states[pod]
+		switch {
+		case !ok && state == ringtree.Up:
+			var labels map[string]string
+			if ep.Zone != "" {
+				labels = map[string]string{"zone": ep.Zone}
+			}
+			w.sync.JoinMeta(pod, membership.Meta{Labels: labels})
+		case !ok:
+			continue // Never added to the ring before it is ready
+		case current != state:
+			w.sync.SetState(pod, state)
+		}
+		w.states[pod] = state
+	}
+
+	for pod := range w.states {
+		if _, ok := pods[pod]; !ok {
+			w.sync.Leave(pod)
+			delete(w.states, pod)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package kube
+
+import (
+	"reflect"
+	"testing"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+func TestWatcher(t *testing.T) {
+	ring := ringtree.New(8)
+	w := NewWatcher(ring)
+	state := func(pod string) ringtree.NodeState {
+		member, ok := ring.Member(pod)
+		if !ok {
+			t.Fatalf("pod %s has no node", pod)
+		}
+		return member.(*ringtree.Node).State()
+	}
+
+	w.Apply(EndpointSlice{Name: "web-1", Endpoints: []Endpoint{
+		{Pod: "web-a", Ready: true},
+		{Pod: "web-b", Ready: true},
+		{Pod: "web-c"},
+	}})
+	if got := w.Pods(); !reflect.DeepEqual(got, []string{"web-a", "web-b"}) {
+		t.Fatalf("got pods %v, want the ready pods web-a and web-b", got)
+	}
+
+	w.Apply(EndpointSlice{Name: "web-1", Endpoints: []Endpoint{
+		{Pod: "web-a", Ready: true},
+		{Pod: "web-b", Terminating: true},
+		{Pod: "web-c", Ready: true},
+	}})
+	w.Apply(EndpointSlice{Name: "web-2", Endpoints: []Endpoint{{Pod: "web-d", Ready: true}}})
+	if state("web-b") != ringtree.Draining {
+		t.Errorf("terminating pod web-b: got state %v, want Draining", state("web-b"))
+	}
+	if state("web-c") != ringtree.Up || state("web-d") != ringtree.Up {
+		t.Error("pods web-c and web-d are not Up once ready")
+	}
+
+	w.Apply(EndpointSlice{Name: "web-1", Endpoints: []Endpoint{{Pod: "web-a"}, {Pod: "web-c", Ready: true}}})
+	if state("web-a") != ringtree.Down {
+		t.Errorf("unready pod web-a: got state %v, want Down", state("web-a"))
+	}
+	if _, ok := ring.Member("web-b"); ok {
+		t.Error("pod web-b still has a node after leaving the slice")
+	}
+
+	w.Delete("web-2")
+	if got := w.Pods(); !reflect.DeepEqual(got, []string{"web-a", "web-c"}) {
+		t.Errorf("got pods %v after deleting slice web-2, want web-a and web-c", got)
+	}
+}
@@ -0,0 +1,136 @@
+package ringtree
+
+import "sync"
+
+// lazyRemap records a key a node join left on its old node, to be moved to the node it hashes to when the
+// key is next accessed.
+type lazyRemap struct {
+	keyHash   uint32 // Hash of the key on the ring the node joined
+	from      *Node  // Node still holding the key
+	fromRing  *Ring  // Ring whose lock guards from
+	fromVNode uint32 // Vnode of from holding the key
+	ring      *Ring  // Ring the node joined
+}
+
+// remapTable holds the keys of a transition epoch that have not been remapped yet. The epoch ends once every
+// key has been accessed or the next structural change settles the rest.
+type remapTable struct {
+	keys map[string]lazyRemap
+	sync.Mutex
+}
+
+func newRemapTable() *remapTable {
+	return &remapTable{keys: make(map[string]lazyRemap)}
+}
+
+// get returns the deferred remap of a key.
+func (t *remapTable) get(key string) (lazyRemap, bool) {
+	t.Lock()
+	defer t.Unlock()
+	remap, ok := t.keys[key]
+	return remap, ok
+}
+
+// take removes and returns the deferred remap of a key.
+func (t *remapTable) take(key string) (lazyRemap, bool) {
+	t.Lock()
+	defer t.Unlock()
+	remap, ok := t.keys[key]
+	delete(t.keys, key)
+	return remap, ok
+}
+
+// WithRemapBudget bounds the keys a node join moves eagerly to n. The remaining keys stay on their old node
+// and are moved when next accessed, or before the next structural change, so a join does not stall on a
+// large remap. Zero remaps every key at once.
+func WithRemapBudget(n int) Option {
+	return func(c *Config) {
+		if n >= 0 {
+			c.RemapBudget = n
+		}
+	}
+}
+
+// remapOrDefer moves a key onto a joining node while the join's budget lasts, and defers the move otherwise
+// (assuming the locks of both rings are held).
+func (r *Ring) remapOrDefer(key string, keyHash uint32, from *Node, fromRing *Ring, fromVNode uint32, to *Node, toVNode uint32, budget *int) {
+	if r.config.RemapBudget <= 0 || *budget > 0 {
+		*budget--
+		r.moveKey(key, keyHash, from, fromVNode, to, toVNode)
+		return
+	}
+	r.remaps.Lock()
+	r.remaps.keys[key] = lazyRemap{keyHash: keyHash, from: from, fromRing: fromRing, fromVNode: fromVNode, ring: r}
+	r.remaps.Unlock()
+}
+
+// settleKey completes the deferred remap of a key, if it has one. A key failing its checksum stays on its old
+// node, where VerifyChecksums reports it (assuming the tree's writer lock is held).
+func (r *Ring) settleKey(key string) {
+	remap, ok := r.remaps.take(key)
+	if !ok {
+		return
+	}
+	ring := remap.ring
+	ring.Lock()
+	if remap.fromRing != ring {
+		remap.fromRing.Lock()
+	}
+	// The key may have been removed or moved by a node state change since the join, and the vnode it was
+	// deferred to may have been dropped to even out the joining node's share
+	if _, exists := remap.from.keys[remap.fromVNode][key]; exists {
+		vNodeHash, nodeID := ring.circle.FindClosest(remap.keyHash)
+		if to, ok := ring.members[nodeID].(*Node); ok && to != remap.from && ring.pinAllows(key, ring, to) {
+			ring.moveKey(key, remap.keyHash, remap.from, remap.fromVNode, to, vNodeHash)
+		}
+	}
+	if remap.fromRing != ring {
+		remap.fromRing.Unlock()
+	}
+	ring.Unlock()
+}
+
+// settleRemaps completes every deferred remap, ending the transition epoch. Structural changes call it first
+// so they only ever see keys on the nodes they hash to (assuming the tree's writer lock is held and no ring
+// lock is).
+func (r *Ring) settleRemaps() {
+	r.remaps.Lock()
+	keys := make([]string, 0, len(r.remaps.keys))
+	for key := range r.remaps.keys {
+		keys = append(keys, key)
+	}
+	r.remaps.Unlock()
+	for _, key := range keys {
+		r.settleKey(key)
+	}
+	if len(keys) > 0 {
+		r.stats.calculateRemapComplexity()
+	}
+}
+
+// SettleRemaps moves every key a node join deferred under the remap budget onto its new node.
+func (r *Ring) SettleRemaps() {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.settleRemaps()
+}
+
+// PendingRemaps returns the number of keys still waiting on a deferred remap.
+func (r *Ring) PendingRemaps() int {
+	r.remaps.Lock()
+	defer r.remaps.Unlock()
+	return len(r.remaps.keys)
+}
+
+// deferredOwner finds a key still held by its old node during a transition epoch, returning that node, the
+// ring whose lock guards it and the vnode holding the key.
+func (r *Ring) deferredOwner(key string) (*Node, *Ring, uint32, bool) {
+	remap, ok := r.remaps.get(key)
+	if !ok {
+		return nil, nil, 0, false
+	}
+	remap.fromRing.RLock()
+	_, exists := remap.from.keys[remap.fromVNode][key]
+	remap.fromRing.RUnlock()
+	return remap.from, remap.fromRing, remap.fromVNode, exists
+}
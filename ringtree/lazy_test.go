@@ -0,0 +1,73 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRemapBudget(t *testing.T) {
+	rt := New(4, WithRemapBudget(5))
+	rt.InsertNode(NewNode("A", 1000))
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key := "key-" + strconv.Itoa(i)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	// The join moves only the budget and leaves the rest on A
+	rt.InsertNode(NewNode("B", 1000))
+	checkNum(rt.members["B"].MemberStats().Keys, 5, t)
+	pending := rt.PendingRemaps()
+	if pending == 0 {
+		t.Fatalf("expected keys waiting on a deferred remap")
+	}
+
+	// Every key is still found, and an access moves a deferred key to B
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Errorf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+	checkNum(rt.PendingRemaps(), 0, t)
+	checkNum(rt.members["B"].MemberStats().Keys, 5+pending, t)
+	checkNum(rt.MemberStats().Keys, len(keys), t)
+}
+
+func TestRemapBudgetSettledByNextJoin(t *testing.T) {
+	rt := New(4, WithRemapBudget(1))
+	rt.InsertNode(NewNode("A", 1000))
+	for i := 0; i < 200; i++ {
+		rt.InsertKey("key-" + strconv.Itoa(i))
+	}
+	rt.InsertNode(NewNode("B", 1000))
+
+	// Removing a deferred key takes it from where it is, and the next join settles the rest first
+	var remap string
+	for i := 0; i < 200 && remap == ""; i++ {
+		if _, pending := rt.remaps.get("key-" + strconv.Itoa(i)); pending {
+			remap = "key-" + strconv.Itoa(i)
+		}
+	}
+	if err := rt.RemoveKey(remap); err != nil {
+		t.Fatalf("expected deferred key to be removed, got error: %v", err)
+	}
+	rt.InsertNode(NewNode("C", 1000))
+	rt.SettleRemaps()
+	checkNum(rt.PendingRemaps(), 0, t)
+	checkNum(rt.MemberStats().Keys, 199, t)
+	for i := 0; i < 200; i++ {
+		key := "key-" + strconv.Itoa(i)
+		owner, err := rt.Lookup(key)
+		if key == remap {
+			if err != ErrKeyNotFound {
+				t.Errorf("expected removed key to be gone, got %v", err)
+			}
+			continue
+		}
+		node, parent, _, _, _ := rt.FindNode(key)
+		if err != nil || parent == nil || owner != node.id {
+			t.Errorf("expected key %s on its hashed node, found on %s (%v)", key, owner, err)
+		}
+	}
+}
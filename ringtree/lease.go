@@ -0,0 +1,179 @@
+package ringtree
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// leaseTable tracks the heartbeat leases of nodes and the janitor expiring them.
+type leaseTable struct {
+	expires map[string]time.Time // Lease expiry of every node that has sent a heartbeat
+	downAt  map[string]time.Time // Time each node was marked Down by an expired lease
+	running bool                 // Whether the janitor goroutine is running
+	stop    chan struct{}        // Closed when the tree is closed, stopping the janitor
+	done    chan struct{}        // Closed when the last janitor started exits
+	closed  bool
+	sync.Mutex
+}
+
+func newLeaseTable() *leaseTable {
+	return &leaseTable{expires: make(map[string]time.Time), downAt: make(map[string]time.Time), stop: make(chan struct{})}
+}
+
+// close stops the janitor goroutine, if it is running, and waits for it to exit. No janitor is started
+// afterwards.
+func (l *leaseTable) close() {
+	l.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.stop)
+	}
+	done := l.done
+	l.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// WithLease makes every heartbeat keep its node Up for d. A node whose lease expires is marked Down and,
+// with a positive grace, removed once it has stayed Down for grace, moving its keys to the remaining
+// nodes. Nodes that never send a heartbeat are not subject to leases.
+func WithLease(d, grace time.Duration) Option {
+	return func(c *Config) {
+		if d > 0 && grace >= 0 {
+			c.LeaseDuration, c.LeaseGrace = d, grace
+		}
+	}
+}
+
+// Heartbeat renews a node's lease. A node marked Down by an expired lease is set Up again while it is still
+// in the tree, and the keys written past it move back.
+func (r *Ring) Heartbeat(nodeID string) error {
+	d := r.config.LeaseDuration
+	if d <= 0 {
+		return ErrLeasesDisabled
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	if node, _ := r.findMember(nodeID); node == nil {
+		return ErrNodeNotFound
+	}
+
+	l := r.leases
+	l.Lock()
+	l.expires[nodeID] = time.Now().Add(d)
+	_, expired := l.downAt[nodeID]
+	delete(l.downAt, nodeID)
+	start := !l.running && !l.closed
+	if start {
+		l.running = true
+		l.done = make(chan struct{})
+	}
+	done := l.done
+	l.Unlock()
+
+	if start {
+		go r.root().expireLeases(d, done)
+	}
+	if expired {
+		r.logf("Node %s renewed its expired lease.\n", nodeID)
+		return r.setNodeStateOp(nodeID, Up)
+	}
+	return nil
+}
+
+// Lease returns the time a node's lease expires, and false if the node holds no lease.
+func (r *Ring) Lease(nodeID string) (time.Time, bool) {
+	r.leases.Lock()
+	defer r.leases.Unlock()
+	expires, ok := r.leases.expires[nodeID]
+	return expires, ok
+}
+
+// expireLeases is the janitor goroutine: it checks leases several times per lease duration, marking nodes
+// whose lease expired Down and draining them after the grace period. It exits once no lease remains or the
+// tree is closed, closing done.
+func (r *Ring) expireLeases(d time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(d / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.leases.stop:
+			r.leases.Lock()
+			r.leases.running = false
+			r.leases.Unlock()
+			return
+		case <-ticker.C:
+			if !r.checkLeases(time.Now()) {
+				return
+			}
+		}
+	}
+}
+
+// checkLeases acts on the leases that expired by now and reports whether any lease remains. Nodes past
+// their grace period are removed as AutoRemoveNode removes them, so the removal quorum applies and the
+// removal is logged; a node the quorum does not confirm is tried again on the next check.
+func (r *Ring) checkLeases(now time.Time) bool {
+	for _, nodeID := range r.expiredLeases(now) {
+		r.logf("Node %s stayed Down past its lease grace period; draining it.\n", nodeID)
+		if err := r.AutoRemoveNode(nodeID); err != nil && !errors.Is(err, ErrNodeNotFound) {
+			r.logf("Failed to drain node %s: %v.\n", nodeID, err)
+			continue
+		}
+		r.leases.Lock()
+		delete(r.leases.expires, nodeID)
+		delete(r.leases.downAt, nodeID)
+		r.leases.Unlock()
+	}
+
+	l := r.leases
+	l.Lock()
+	defer l.Unlock()
+	if len(l.expires) == 0 {
+		l.running = false
+		return false
+	}
+	return true
+}
+
+// expiredLeases marks the nodes whose lease expired by now Down, drops the leases of nodes no longer in the
+// tree and returns the nodes that stayed Down past the grace period, in ID order.
+func (r *Ring) expiredLeases(now time.Time) []string {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	l := r.leases
+	l.Lock()
+	defer l.Unlock()
+
+	var expired []string
+	for nodeID, expires := range l.expires {
+		if node, _ := r.findMember(nodeID); node == nil {
+			delete(l.expires, nodeID)
+			delete(l.downAt, nodeID)
+			continue
+		}
+		if now.Before(expires) {
+			continue
+		}
+
+		downAt, down := l.downAt[nodeID]
+		if !down {
+			r.logf("Lease of node %s expired; marking it Down.\n", nodeID)
+			if err := r.setNodeStateOp(nodeID, Down); err != nil {
+				r.logf("Failed to mark node %s Down: %v.\n", nodeID, err)
+				continue
+			}
+			l.downAt[nodeID] = now
+			continue
+		}
+		if grace := r.config.LeaseGrace; grace > 0 && !now.Before(downAt.Add(grace)) {
+			expired = append(expired, nodeID)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
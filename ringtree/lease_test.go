@@ -0,0 +1,154 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatLease(t *testing.T) {
+	rt := New(8, WithLease(time.Hour, time.Minute))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	for i := 0; i < 100; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	if err := New(4).Heartbeat("A"); !errors.Is(err, ErrLeasesDisabled) {
+		t.Fatalf("got %v, want ErrLeasesDisabled without a lease duration", err)
+	}
+	if err := rt.Heartbeat("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("got %v, want ErrNodeNotFound", err)
+	}
+	if err := rt.Heartbeat("A"); err != nil {
+		t.Fatal(err)
+	}
+	expires, ok := rt.Lease("A")
+	if !ok {
+		t.Fatal("expected node A to hold a lease")
+	}
+	if _, ok := rt.Lease("B"); ok {
+		t.Fatal("expected node B, which never sent a heartbeat, to hold no lease")
+	}
+
+	rt.checkLeases(expires.Add(-time.Second))
+	if node, _ := rt.Node("A"); node.State() != Up {
+		t.Fatalf("got state %v before the lease expired, want Up", node.State())
+	}
+	rt.checkLeases(expires)
+	if node, _ := rt.Node("A"); node.State() != Down {
+		t.Fatalf("got state %v after the lease expired, want Down", node.State())
+	}
+
+	// A heartbeat within the grace period restores the node
+	if err := rt.Heartbeat("A"); err != nil {
+		t.Fatal(err)
+	}
+	if node, _ := rt.Node("A"); node.State() != Up {
+		t.Fatalf("got state %v after a heartbeat, want Up", node.State())
+	}
+
+	expires, _ = rt.Lease("A")
+	rt.checkLeases(expires)
+	rt.checkLeases(expires.Add(time.Minute))
+	if _, err := rt.Node("A"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatal("expected node A to be drained after the grace period")
+	}
+	for i := 0; i < 100; i++ {
+		if owner, err := rt.Lookup(fmt.Sprintf("key-%d", i)); err != nil || owner == "A" {
+			t.Fatalf("key-%d: got owner %q (%v) after draining, want another node", i, owner, err)
+		}
+	}
+	if _, ok := rt.Lease("A"); ok {
+		t.Fatal("expected the drained node's lease to be dropped")
+	}
+}
+
+func TestLeaseJanitor(t *testing.T) {
+	rt := New(8, WithLease(20*time.Millisecond, 0))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	if err := rt.Heartbeat("A"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	node, _ := rt.Node("A")
+	for {
+		rt.RLock()
+		state := node.State()
+		rt.RUnlock()
+		if state == Down {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the janitor to mark node A Down")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := rt.Node("A"); err != nil {
+		t.Fatal("expected node A to stay in the tree without a grace period")
+	}
+}
+
+func TestCloseStopsLeaseJanitor(t *testing.T) {
+	rt := New(8, WithLease(time.Hour, 0))
+	rt.InsertNode(NewNode("A", 100))
+	if err := rt.Heartbeat("A"); err != nil {
+		t.Fatal(err)
+	}
+	rt.leases.Lock()
+	done := rt.leases.done
+	rt.leases.Unlock()
+	select {
+	case <-done:
+		t.Fatal("expected the janitor to run while a lease remains")
+	default:
+	}
+
+	if err := rt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the janitor to exit once the tree is closed")
+	}
+
+	// A heartbeat after closing does not start another janitor
+	rt.Heartbeat("A")
+	rt.leases.Lock()
+	running := rt.leases.running
+	rt.leases.Unlock()
+	if running {
+		t.Error("expected no janitor to start after the tree is closed")
+	}
+}
+
+func TestLeaseExpiryRequiresQuorum(t *testing.T) {
+	confirm := false
+	rt := New(8, WithLease(time.Hour, time.Minute), WithRemovalQuorum(1, ObserverFunc(func(string) bool { return confirm })))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	if err := rt.Heartbeat("A"); err != nil {
+		t.Fatal(err)
+	}
+	expires, _ := rt.Lease("A")
+	rt.checkLeases(expires)
+
+	// The grace period is over, but no observer confirms the failure yet
+	rt.checkLeases(expires.Add(time.Minute))
+	if _, err := rt.Node("A"); err != nil {
+		t.Fatal("expected node A to stay until the quorum confirms its failure")
+	}
+	if _, ok := rt.Lease("A"); !ok {
+		t.Fatal("expected node A to keep its lease while its removal is pending")
+	}
+
+	confirm = true
+	rt.checkLeases(expires.Add(time.Minute))
+	if _, err := rt.Node("A"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatal("expected node A to be drained once the quorum confirms its failure")
+	}
+}
@@ -0,0 +1,98 @@
+package ringtree
+
+// Locality places a node or a client in a region and a zone within it.
+type Locality struct {
+	Region string `json:"region,omitempty"`
+	Zone   string `json:"zone,omitempty"`
+}
+
+// distance returns how far apart two localities are: 0 in the same zone, 1 in the same region and 2
+// otherwise. Empty labels match nothing, so a node without a locality is always furthest.
+func (l Locality) distance(other Locality) int {
+	switch {
+	case l.Region == "" || l.Region != other.Region:
+		return 2
+	case l.Zone == "" || l.Zone != other.Zone:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Locality returns the region and zone of the node.
+func (n *Node) Locality() Locality {
+	return n.locality
+}
+
+// SetLocality sets the region and zone of a node that is not in a tree yet. Use Ring.SetNodeLocality for a
+// node already inserted.
+func (n *Node) SetLocality(locality Locality) {
+	n.locality = locality
+}
+
+// seedNode returns a node the tree creates to take load off another, in the same locality.
+func (r *Ring) seedNode(from *Node, threshold int) *Node {
+	node := NewNode(r.newNodeID(), threshold)
+	node.locality = from.locality
+	return node
+}
+
+// SetNodeLocality sets the region and zone of a node anywhere in the tree.
+func (r *Ring) SetNodeLocality(nodeID string, locality Locality) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	ring.Lock()
+	defer ring.Unlock()
+	node.locality = locality
+	return nil
+}
+
+// NearestReplica returns the replica of a key nearest to the given locality: one in the same zone if there
+// is one, else one in the same region, else any. Replicas are those of replicated mode, or just the owner
+// without it; ties go to the replica first in ring order, and replicas that are Down or Joining are skipped.
+func (r *Ring) NearestReplica(key string, locality Locality) (string, error) {
+	_, parent, vNodeHash, _, err := r.findNode(key, false)
+	if err != nil {
+		return "", err
+	}
+	parent.RLock()
+	defer parent.RUnlock()
+	var replicas []*Node
+	for _, node := range parent.replicaWalk(vNodeHash, r.config.replication()) {
+		if node.servesReads() {
+			replicas = append(replicas, node)
+		}
+	}
+	if nearest := nearestNode(replicas, locality); nearest != nil {
+		return nearest.id, nil
+	}
+	return "", ErrNoReplicaAvailable
+}
+
+// nearestNode returns the first of the nodes nearest to the locality, or nil for no nodes (assuming their
+// ring's mutex is held).
+func nearestNode(nodes []*Node, locality Locality) *Node {
+	var nearest *Node
+	best := 3
+	for _, node := range nodes {
+		if d := locality.distance(node.locality); d < best {
+			nearest, best = node, d
+		}
+	}
+	return nearest
+}
+
+// LocalityRead reads each key from the replica nearest to a client's locality, preferring its zone, then
+// its region, like NearestReplica.
+type LocalityRead struct {
+	Locality Locality
+}
+
+// Choose implements ReadStrategy.
+func (l LocalityRead) Choose(key string, replicas []*Node) *Node {
+	return nearestNode(replicas, l.Locality)
+}
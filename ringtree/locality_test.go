@@ -0,0 +1,124 @@
+package ringtree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// setZones spreads the nodes A to E of a tree over two regions, leaving E without a locality.
+func setZones(t *testing.T, rt *Ring) {
+	t.Helper()
+	zones := map[string]Locality{
+		"A": {"eu", "eu-1"}, "B": {"eu", "eu-2"}, "C": {"us", "us-1"}, "D": {"us", "us-2"}, "E": {},
+	}
+	for id, locality := range zones {
+		if err := rt.SetNodeLocality(id, locality); err != nil {
+			t.Fatalf("expected the locality of %s to be set, got error: %v", id, err)
+		}
+	}
+}
+
+func TestNearestReplica(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B", "C", "D", "E"}, 1000, 1, WithReplication(3))
+	setZones(t, rt)
+	key := keys[0]
+	replicas, err := rt.Replicas(key)
+	if err != nil {
+		t.Fatalf("expected the key's replicas, got error: %v", err)
+	}
+	for _, id := range replicas {
+		node, _ := rt.findMember(id)
+		locality := node.Locality()
+		if locality == (Locality{}) {
+			continue
+		}
+		// A client in a replica's zone reads from it
+		if nearest, err := rt.NearestReplica(key, locality); err != nil || nearest != id {
+			t.Errorf("expected a client in %v to read from %s, got %s (%v)", locality, id, nearest, err)
+		}
+		// A client in another zone of its region reads from the first replica in that region
+		other := Locality{Region: locality.Region, Zone: "elsewhere"}
+		want := ""
+		for _, rid := range replicas {
+			if n, _ := rt.findMember(rid); n.Locality().Region == locality.Region {
+				want = rid
+				break
+			}
+		}
+		if nearest, _ := rt.NearestReplica(key, other); nearest != want {
+			t.Errorf("expected a client in %v to read from %s, got %s", other, want, nearest)
+		}
+	}
+	// A client with no match reads from the owner
+	if nearest, _ := rt.NearestReplica(key, Locality{"ap", "ap-1"}); nearest != replicas[0] {
+		t.Errorf("expected a client far from every replica to read from the owner %s, got %s", replicas[0], nearest)
+	}
+
+	// Down replicas are skipped, and a key with none up has no nearest replica
+	for _, id := range replicas {
+		rt.SetNodeState(id, Down)
+	}
+	if _, err := rt.NearestReplica(key, Locality{"eu", "eu-1"}); !errors.Is(err, ErrNoReplicaAvailable) {
+		t.Errorf("expected ErrNoReplicaAvailable, got %v", err)
+	}
+	if _, err := New(8).NearestReplica(key, Locality{}); err == nil {
+		t.Errorf("expected an error for an empty tree's key")
+	}
+}
+
+func TestLocalityRead(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B", "C", "D", "E"}, 1000, 1, WithReplication(3))
+	setZones(t, rt)
+	key := keys[0]
+	replicas, err := rt.Replicas(key)
+	if err != nil {
+		t.Fatalf("expected the key's replicas, got error: %v", err)
+	}
+	// Put the last replica alone in a zone no other node is in
+	target := replicas[len(replicas)-1]
+	client := Locality{"ap", "ap-1"}
+	if err := rt.SetNodeLocality(target, client); err != nil {
+		t.Fatalf("expected the locality to be set, got error: %v", err)
+	}
+	if err := rt.SetNodeLocality("missing", client); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("expected the snapshot to be written, got error: %v", err)
+	}
+	reader, err := ReadSnapshot(&buf, WithReplication(3), WithReadStrategy(LocalityRead{Locality: client}))
+	if err != nil {
+		t.Fatalf("expected the snapshot to be read, got error: %v", err)
+	}
+	// Localities survive the snapshot, so the reader routes to the replica in the client's zone
+	if owner, err := reader.Lookup(key); err != nil || owner != target {
+		t.Errorf("expected a read from %s in %v, got %s (%v)", target, client, owner, err)
+	}
+}
+
+func TestSplitKeepsLocality(t *testing.T) {
+	rt := New(2)
+	node := NewNode("A", 2)
+	node.SetLocality(Locality{"eu", "eu-1"})
+	rt.InsertNode(node)
+	other := NewNode("B", 1000)
+	rt.InsertNode(other)
+	for i := 0; i < 20; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+	subring, ok := rt.Subring("A")
+	if !ok {
+		t.Fatalf("expected node A to be split into a subring")
+	}
+	subring.Lock()
+	defer subring.Unlock()
+	subring.forEachNode(func(seed *Node) {
+		if seed.Locality() != (Locality{"eu", "eu-1"}) {
+			t.Errorf("expected seed %s to be in the split node's zone, got %v", seed.id, seed.Locality())
+		}
+	})
+}
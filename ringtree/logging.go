@@ -0,0 +1,95 @@
+package ringtree
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// logState samples and rate limits the operation log lines of a tree.
+type logState struct {
+	mu         sync.Mutex
+	seen       uint64    // Log lines offered, for sampling
+	window     time.Time // Start of the current one-second rate window
+	written    int       // Lines written in the current window
+	suppressed int       // Lines dropped since the last line written
+}
+
+// WithLogSampling writes one of every n operation log lines. Lines dropped by sampling are counted and
+// reported with the next line written.
+func WithLogSampling(n int) Option {
+	return func(c *Config) {
+		if n > 0 {
+			c.LogSampling = n
+		}
+	}
+}
+
+// WithLogRate writes at most perSecond operation log lines each second, dropping the rest.
+func WithLogRate(perSecond int) Option {
+	return func(c *Config) {
+		if perSecond > 0 {
+			c.LogRate = perSecond
+		}
+	}
+}
+
+// WithSlowLog writes a line to l for every operation that takes threshold or longer, whatever the sampling
+// and rate of the operation log. A nil l writes to the operation logger.
+func WithSlowLog(threshold time.Duration, l *log.Logger) Option {
+	return func(c *Config) {
+		if threshold > 0 {
+			c.SlowThreshold = threshold
+			c.SlowLogger = l
+		}
+	}
+}
+
+// allow reports whether a log line passes sampling and the rate limit, and returns the number of lines
+// dropped since the last one written.
+func (s *logState) allow(c *Config) (bool, int) {
+	if s == nil || c.LogSampling <= 1 && c.LogRate <= 0 {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen++
+	if c.LogSampling > 1 && s.seen%uint64(c.LogSampling) != 0 {
+		s.suppressed++
+		return false, 0
+	}
+	if c.LogRate > 0 {
+		if now := time.Now(); now.Sub(s.window) >= time.Second {
+			s.window, s.written = now, 0
+		}
+		if s.written >= c.LogRate {
+			s.suppressed++
+			return false, 0
+		}
+		s.written++
+	}
+	suppressed := s.suppressed
+	s.suppressed = 0
+	return true, suppressed
+}
+
+// slowLogger returns the logger slow operations are written to, or nil if the slow log is disabled.
+func (c *Config) slowLogger() *log.Logger {
+	if c.SlowThreshold <= 0 {
+		return nil
+	}
+	if c.SlowLogger != nil {
+		return c.SlowLogger
+	}
+	return c.Logger
+}
+
+// logSlow writes an operation to the slow log if it took at least the slow threshold.
+func (r *Ring) logSlow(operation string, level int, elapsed time.Duration, message string) {
+	if r.config == nil || elapsed < r.config.SlowThreshold {
+		return
+	}
+	if l := r.config.slowLogger(); l != nil {
+		l.Printf("Slow %s on ring %s at level %d took %s %s.\n", operation, r.id, level, elapsed, message)
+	}
+}
@@ -0,0 +1,87 @@
+package ringtree
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logLines returns the lines written to a buffer, and how many were suppression notes.
+func logLines(buf *bytes.Buffer) (lines []string, notes int) {
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "Suppressed") {
+			notes++
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, notes
+}
+
+func TestLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	rt := New(4, WithLogger(log.New(&buf, "", 0)), WithLogSampling(10))
+	for i := 0; i < 100; i++ {
+		rt.logf("line %d\n", i)
+	}
+	lines, notes := logLines(&buf)
+	checkNum(len(lines), 10, t)
+	checkNum(notes, 10, t)
+	if !strings.HasPrefix(buf.String(), "Suppressed 9 log lines.\nline 9\n") {
+		t.Errorf("expected every tenth line to follow a note of the nine before it, got %q", buf.String()[:40])
+	}
+}
+
+func TestLogRate(t *testing.T) {
+	var buf bytes.Buffer
+	rt := New(4, WithLogger(log.New(&buf, "", 0)), WithLogRate(5))
+	for i := 0; i < 50; i++ {
+		rt.logf("line %d\n", i)
+	}
+	lines, notes := logLines(&buf)
+	checkNum(len(lines), 5, t)
+	checkNum(notes, 0, t)
+
+	// The next window writes again, first noting the lines dropped by the last
+	rt.logs.window = time.Now().Add(-time.Second)
+	rt.logf("line %d\n", 50)
+	if !strings.HasSuffix(buf.String(), "Suppressed 45 log lines.\nline 50\n") {
+		t.Errorf("expected the dropped lines to be noted, got %q", buf.String())
+	}
+
+	// Subrings share the tree's limit
+	sub := newRing(rt.config, rt, "sub", 1, 4)
+	buf.Reset()
+	for i := 0; i < 10; i++ {
+		sub.logf("line %d\n", i)
+	}
+	lines, _ = logLines(&buf)
+	checkNum(len(lines), 4, t)
+}
+
+func TestSlowLog(t *testing.T) {
+	var ops, slow bytes.Buffer
+	rt := New(4, WithLogger(log.New(&ops, "", 0)), WithLogSampling(1000), WithSlowLog(time.Nanosecond, log.New(&slow, "", 0)))
+	rt.InsertNode(NewNode("A", 100))
+	for i := 0; i < 10; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+	if n := strings.Count(slow.String(), "Slow InsertKey on ring main at level 0"); n != 10 {
+		t.Errorf("expected every insert in the slow log despite sampling, got %d:\n%s", n, slow.String())
+	}
+
+	slow.Reset()
+	rt = New(4, WithSlowLog(time.Hour, log.New(&slow, "", 0)))
+	rt.InsertNode(NewNode("A", 100))
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	if slow.Len() != 0 {
+		t.Errorf("expected no operation to be slow, got:\n%s", slow.String())
+	}
+}
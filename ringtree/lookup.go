@@ -0,0 +1,83 @@
+package ringtree
+
+import "time"
+
+// LookupMany finds the nodes holding several keys, returning for each found key the node Lookup would
+// return. Keys are grouped by the member they route to, so each ring is locked once for the whole batch
+// instead of once per key; every found key is then served like a Lookup, counting its heat and request load
+// and applying spreads and the read strategy. Keys not in the tree are left out of the result.
+func (r *Ring) LookupMany(keys []string) map[string]string {
+	defer r.timeTrack(time.Now(), "LookupMany", "to find a batch of keys")
+	found := make(map[string]string, len(keys))
+
+	var batch, fallback []string
+	for _, key := range keys {
+		if r.batchable(key) {
+			batch = append(batch, key)
+		} else {
+			fallback = append(fallback, key)
+		}
+	}
+	owners := make(map[string]string, len(batch))
+	fallback = append(fallback, r.lookupMany(batch, owners)...)
+	for key, owner := range owners {
+		found[key] = r.serveRead(key, owner)
+	}
+
+	// Keys held somewhere other than their write route, such as on a draining owner, take the full path
+	for _, key := range fallback {
+		if owner, err := r.Lookup(key); err == nil {
+			found[key] = owner
+		}
+	}
+	return found
+}
+
+// batchable reports whether a key is found where writes to it are routed. Pinned keys are held elsewhere,
+// and indexed keys and keys with a pending remap are handled by lookup itself.
+func (r *Ring) batchable(key string) bool {
+	if _, pinned := r.pins.get(key); pinned {
+		return false
+	}
+	if _, indexed := r.index.get(key); indexed {
+		return false
+	}
+	_, pending := r.remaps.get(key)
+	return !pending
+}
+
+// lookupMany records the node of every key held where writes to it are routed, and returns the keys that
+// need a full lookup.
+func (r *Ring) lookupMany(keys []string, found map[string]string) []string {
+	var fallback []string
+	subrings := make(map[*Ring][]string)
+
+	r.RLock()
+	if r.Size() == 0 {
+		r.RUnlock()
+		return nil
+	}
+	for _, key := range keys {
+		vNodeHash, nodeID := r.routeWrite(r.config.keyHash(key, r.level))
+		switch member := r.members[nodeID].(type) {
+		case *Node:
+			keyHash, held := member.keys[vNodeHash][key]
+			if !held || !r.verifyKey(member, key, keyHash) || (r.config.replication() > 1 && !member.servesReads()) {
+				fallback = append(fallback, key)
+				continue
+			}
+			found[key] = member.id
+		case *Ring:
+			subrings[member] = append(subrings[member], key)
+		default:
+			fallback = append(fallback, key)
+		}
+	}
+	r.RUnlock()
+
+	// Descend after releasing this ring, so only one ring is locked at a time
+	for subring, batch := range subrings {
+		fallback = append(fallback, subring.lookupMany(batch, found)...)
+	}
+	return fallback
+}
@@ -0,0 +1,106 @@
+package ringtree
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLookupMany(t *testing.T) {
+	rt := New(2)
+	nodeA := NewNode("A", 20)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 20))
+
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+
+	found := rt.LookupMany(append(keys, "missing"))
+	checkNum(len(found), len(keys), t)
+	for _, key := range keys {
+		owner, _ := rt.Lookup(key)
+		if found[key] != owner {
+			t.Errorf("expected key %s on %s, got %s", key, owner, found[key])
+		}
+	}
+	if _, ok := found["missing"]; ok {
+		t.Errorf("expected a missing key to be left out")
+	}
+}
+
+func TestLookupManyDrainingNode(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("A", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 1000))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	// Keys on a draining node are found even though writes are routed past it
+	rt.SetNodeState(nodeA.id, Draining)
+	found := rt.LookupMany(keys)
+	checkNum(len(found), len(keys), t)
+	for _, key := range keys {
+		owner, _ := rt.Lookup(key)
+		if found[key] != owner {
+			t.Errorf("expected key %s on %s, got %s", key, owner, found[key])
+		}
+	}
+}
+
+func TestLookupManyServesReads(t *testing.T) {
+	rt := New(4, WithHeatTracking(time.Hour), WithRequestLoad(time.Hour))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	for i := 0; i < 10; i++ {
+		rt.LookupMany([]string{key})
+	}
+
+	// Batched reads count toward heat and request load like single lookups
+	owner, _ := rt.Lookup(key)
+	if load := mustNode(t, rt, owner).RequestLoad(); math.Abs(load-11) > 0.1 {
+		t.Errorf("expected a request load near 11 on %s, got %f", owner, load)
+	}
+	if hot := rt.HotKeys(1); len(hot) != 1 || hot[0].Key != key {
+		t.Errorf("expected %s to be the hottest key, got %+v", key, hot)
+	}
+
+	// A spread key is served by its followers, not only its owner
+	spread, keys := buildTree(t, 8, []string{"A", "B", "C", "D", "E"}, 1000, 1)
+	key = keys[0]
+	followers := spreadKey(t, spread, key, 2)
+	served := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		served[spread.LookupMany([]string{key})[key]]++
+	}
+	checkNum(len(served), len(followers), t)
+}
+
+func TestLocateNode(t *testing.T) {
+	rt, _ := buildTree(t, 3, []string{"A"}, 5, 200)
+	for _, id := range rt.NodeIDs() {
+		node, ring, err := rt.LocateNode(id)
+		if err != nil {
+			t.Fatalf("expected node %s to be found, got error: %v", id, err)
+		}
+		if member, _ := ring.Member(id); member != node {
+			t.Errorf("expected node %s to be returned with the ring holding it, got ring %s", id, ring.id)
+		}
+	}
+	if _, _, err := rt.LocateNode("missing"); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package ringtree
+
+import (
+	"errors"
+	"time"
+)
+
+// maintenanceWindow is a period during which a node is treated as Draining.
+type maintenanceWindow struct {
+	start  time.Time
+	end    time.Time
+	timers *maintenanceTimers // Nil on copies of the node, which the timers do not act on
+}
+
+// maintenanceTimers fire at the start and end of a maintenance window.
+type maintenanceTimers struct {
+	start *time.Timer
+	end   *time.Timer
+}
+
+func (w maintenanceWindow) contains(t time.Time) bool {
+	return !t.Before(w.start) && t.Before(w.end)
+}
+
+// ScheduleMaintenance drains a node for writes between start and start+duration. The node keeps serving
+// reads for the keys it holds, and returns to Up automatically once the window closes.
+func (r *Ring) ScheduleMaintenance(nodeID string, start time.Time, duration time.Duration) error {
+	if duration <= 0 {
+		return errors.New("maintenance window must have a positive duration")
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.scheduleMaintenanceOp(nodeID, start, duration)
+}
+
+// scheduleMaintenanceOp schedules a maintenance window as one logged operation (assuming the tree's writer
+// lock is held).
+func (r *Ring) scheduleMaintenanceOp(nodeID string, start time.Time, duration time.Duration) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpMaintenance, Node: nodeID, Start: &start, Duration: duration}, r.scheduleMaintenance(nodeID, start, duration))
+}
+
+// scheduleMaintenance schedules a maintenance window (assuming the tree's writer lock is held).
+func (r *Ring) scheduleMaintenance(nodeID string, start time.Time, duration time.Duration) error {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+
+	window := maintenanceWindow{start: start, end: start.Add(duration), timers: &maintenanceTimers{}}
+	if !window.end.After(time.Now()) {
+		return errors.New("maintenance window is already over")
+	}
+
+	// The node may have moved to another ring, or its tree merged into another, by the time the timers fire,
+	// so they find the tree through its writer lock and the node again from the root
+	writer := r.writer
+	window.timers.start = time.AfterFunc(time.Until(window.start), func() {
+		root, w := writer.lockTree()
+		found, ring := root.findMember(node.id)
+		w.Unlock()
+		if found == node {
+			ring.emit(Event{Type: NodeStateChanged, RingID: ring.id, NodeID: node.id, Level: ring.level})
+		}
+	})
+	window.timers.end = time.AfterFunc(time.Until(window.end), func() {
+		root, w := writer.lockTree()
+		ring, up := root.endMaintenance(node, window)
+		w.Unlock()
+		if up {
+			ring.emit(Event{Type: NodeStateChanged, RingID: ring.id, NodeID: node.id, Level: ring.level})
+		}
+	})
+	ring.Lock()
+	node.maintenance = append(node.maintenance, window)
+	ring.Unlock()
+	return nil
+}
+
+// Maintenance returns the node's pending and active maintenance windows as start/end pairs.
+func (n *Node) Maintenance() [][2]time.Time {
+	var windows [][2]time.Time
+	for _, w := range n.maintenance {
+		windows = append(windows, [2]time.Time{w.start, w.end})
+	}
+	return windows
+}
+
+// stopMaintenance stops the timers of the node's windows and drops them, as the node leaves the tree
+// (assuming its ring's mutex is already locked).
+func (n *Node) stopMaintenance() {
+	for _, w := range n.maintenance {
+		if w.timers != nil {
+			w.timers.start.Stop()
+			w.timers.end.Stop()
+		}
+	}
+	n.maintenance = nil
+}
+
+// endMaintenance drops an expired window and, once the node is Up again, moves back the keys written past
+// it. The node is looked up again from the root, on whichever ring it is now a member of, which is returned
+// along with whether the node is Up (assuming the tree's writer lock is held).
+func (r *Ring) endMaintenance(node *Node, window maintenanceWindow) (*Ring, bool) {
+	found, ring := r.findMember(node.id)
+	if found != node {
+		return nil, false
+	}
+	ring.Lock()
+	for i, w := range node.maintenance {
+		if w == window {
+			node.maintenance = append(node.maintenance[:i], node.maintenance[i+1:]...)
+			break
+		}
+	}
+	up := node.State() == Up
+	if up {
+		ring.reclaimKeys(node)
+	}
+	ring.Unlock()
+	return ring, up
+}
@@ -0,0 +1,180 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowDrainsWrites(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("", 1000)
+	nodeB := NewNode("", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(nodeB)
+
+	if err := rt.ScheduleMaintenance(nodeA.id, time.Now(), 200*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error scheduling maintenance: %v", err)
+	}
+	if nodeA.State() != Draining {
+		t.Fatalf("expected node to be Draining, got %s", nodeA.State())
+	}
+
+	var keys []string
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	rt.RLock()
+	checkNum(nodeA.load, 0, t)
+	checkNum(nodeB.load, 200, t)
+	rt.RUnlock()
+
+	// The window closes on a timer goroutine, so read the node under the ring's lock
+	reclaimed := func() bool {
+		rt.RLock()
+		defer rt.RUnlock()
+		return nodeA.State() == Up && nodeA.load > 0
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !reclaimed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected node to return to Up and reclaim its keys")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rt.RLock()
+	checkNum(nodeA.load+nodeB.load, 200, t)
+	rt.RUnlock()
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after maintenance, got error: %v", key, err)
+		}
+	}
+}
+
+// waitReclaimed waits for a node to come out of maintenance and take back keys, reading it under the
+// tree's lock since the window closes on a timer goroutine.
+func waitReclaimed(rt *Ring, node *Node, t *testing.T) {
+	t.Helper()
+	reclaimed := func() bool {
+		rt.writer.Lock()
+		defer rt.writer.Unlock()
+		return node.State() == Up && node.load > 0
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !reclaimed() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected node %s to return to Up and reclaim its keys", node.id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMaintenanceWindowAcrossSplit(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("A", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 1000))
+
+	if err := rt.ScheduleMaintenance("A", time.Now(), 200*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error scheduling maintenance: %v", err)
+	}
+	var keys []string
+	for i := 0; i < 200; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		rt.InsertKey(keys[i])
+	}
+	if _, err := rt.Split("B"); err != nil {
+		t.Fatalf("expected B to be split, got error: %v", err)
+	}
+
+	// Keys written past A are reclaimed out of the subring that replaced B
+	waitReclaimed(rt, nodeA, t)
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after maintenance, got error: %v", key, err)
+		}
+	}
+	checkValid(rt, t)
+}
+
+func TestMaintenanceWindowAcrossMerge(t *testing.T) {
+	a := New(5)
+	a.InsertNode(NewNode("A", 1000))
+	b := New(5)
+	nodeB := NewNode("B", 1000)
+	b.InsertNode(nodeB)
+	b.InsertNode(NewNode("C", 1000))
+
+	if err := b.ScheduleMaintenance("B", time.Now(), 300*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error scheduling maintenance: %v", err)
+	}
+	var keys []string
+	for i := 0; i < 200; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		b.InsertKey(keys[i])
+	}
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("expected trees to be merged, got error: %v", err)
+	}
+
+	// B now sits on the merged root and still reclaims its keys there
+	waitReclaimed(merged, nodeB, t)
+	for _, key := range keys {
+		if _, err := merged.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after maintenance, got error: %v", key, err)
+		}
+	}
+	checkValid(merged, t)
+}
+
+func TestRemoveNodeStopsMaintenance(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("A", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 1000))
+
+	if err := rt.ScheduleMaintenance("A", time.Now().Add(time.Hour), time.Hour); err != nil {
+		t.Fatalf("unexpected error scheduling maintenance: %v", err)
+	}
+	timers := nodeA.maintenance[0].timers
+	if err := rt.RemoveNode(nodeA); err != nil {
+		t.Fatalf("expected A to be removed, got error: %v", err)
+	}
+	if len(nodeA.Maintenance()) != 0 {
+		t.Errorf("expected the removed node's windows to be dropped")
+	}
+	if timers.start.Stop() || timers.end.Stop() {
+		t.Errorf("expected the removed node's timers to be stopped")
+	}
+}
+
+func TestDrainingNodeServesReads(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("", 1000)
+	nodeB := NewNode("", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(nodeB)
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	load := nodeA.load
+
+	rt.SetNodeState(nodeA.id, Draining)
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be readable while draining, got error: %v", key, err)
+		}
+	}
+	checkNum(nodeA.load, load, t)
+}
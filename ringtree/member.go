@@ -0,0 +1,308 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MemberKind identifies what a ring member is.
+type MemberKind int
+
+const (
+	KindNode   MemberKind = iota // A physical node holding keys
+	KindRing                     // A subring of the tree
+	KindCustom                   // A caller-defined member, e.g. a proxy to a remote ring
+)
+
+// String returns a readable name for the member kind.
+func (k MemberKind) String() string {
+	switch k {
+	case KindNode:
+		return "Node"
+	case KindRing:
+		return "Ring"
+	default:
+		return "Custom"
+	}
+}
+
+// Member is anything that owns vnodes on a ring. Nodes and subrings are members; callers can embed their own
+// member types with InsertMember.
+type Member interface {
+	ID() string
+	Kind() MemberKind
+	Route(key string, keyHash uint32) (string, error) // Returns the ID of the node serving the key, given its hash on the parent ring
+	MemberStats() MemberStats
+}
+
+// KeyWriter is implemented by members that accept key writes. Custom members that do not implement it are
+// read-only: keys routed to them cannot be inserted or removed through the ring.
+type KeyWriter interface {
+	InsertKey(key string) error
+	RemoveKey(key string) error
+}
+
+// MemberStats summarizes the nodes, keys and load behind a member.
+type MemberStats struct {
+	Nodes int
+	Keys  int
+	Load  int
+}
+
+// MemberInfo describes one member of a ring.
+type MemberInfo struct {
+	ID        string
+	Kind      MemberKind
+	Level     int // Level of the ring holding the member, or the subring's own level
+	Nodes     int // Physical nodes behind the member
+	Keys      int
+	Load      int       // Load of a node, or the total load behind a subring or custom member
+	Threshold int       // Threshold of a node; 0 for other members
+	State     NodeState // Effective state of a node; Up for other members
+	Children  int       // Members of a subring; 0 for other members
+}
+
+// ErrReadOnlyMember is returned when a write is routed to a custom member that does not implement KeyWriter.
+var ErrReadOnlyMember = errors.New("member does not accept key writes")
+
+// customRoute is returned by findNode when a key is routed to a custom member instead of a physical node.
+type customRoute struct {
+	member  Member
+	keyHash uint32
+}
+
+func (c customRoute) Error() string {
+	return "key is routed to custom member " + c.member.ID()
+}
+
+// writer returns the custom member as a KeyWriter.
+func (c customRoute) writer() (KeyWriter, error) {
+	if w, ok := c.member.(KeyWriter); ok {
+		return w, nil
+	}
+	return nil, ErrReadOnlyMember
+}
+
+// Kind reports that a node is a physical node.
+func (n *Node) Kind() MemberKind {
+	return KindNode
+}
+
+// Route returns the node itself, since a node serves every key routed to it.
+func (n *Node) Route(key string, keyHash uint32) (string, error) {
+	return n.id, nil
+}
+
+// MemberStats returns the node's key count and load.
+func (n *Node) MemberStats() MemberStats {
+	stats := MemberStats{Nodes: 1, Load: n.load}
+	for _, keys := range n.keys {
+		stats.Keys += len(keys)
+	}
+	return stats
+}
+
+// Kind reports that a ring is a subring.
+func (r *Ring) Kind() MemberKind {
+	return KindRing
+}
+
+// Route returns the ID of the physical node the key is routed to within the ring.
+func (r *Ring) Route(key string, keyHash uint32) (string, error) {
+	if r.config.LockFreeReads {
+		if owner, _ := r.snapshotOwner(key); owner != "" {
+			return owner, nil
+		}
+	}
+	node, _, _, _, err := r.findNode(key, false)
+	var custom customRoute
+	if errors.As(err, &custom) {
+		return custom.member.Route(key, custom.keyHash)
+	}
+	if err != nil {
+		return "", err
+	}
+	return node.id, nil
+}
+
+// MemberStats returns the totals of all members of the ring.
+func (r *Ring) MemberStats() MemberStats {
+	r.RLock()
+	defer r.RUnlock()
+	var stats MemberStats
+	for _, member := range r.members {
+		s := member.MemberStats()
+		stats.Nodes += s.Nodes
+		stats.Keys += s.Keys
+		stats.Load += s.Load
+	}
+	return stats
+}
+
+// MemberInfo returns the members of the ring sorted by ID, with their kind, level and load.
+func (r *Ring) MemberInfo() []MemberInfo {
+	r.RLock()
+	defer r.RUnlock()
+	infos := make([]MemberInfo, 0, len(r.members))
+	for id, member := range r.members {
+		stats := member.MemberStats()
+		info := MemberInfo{ID: id, Kind: member.Kind(), Level: r.level, Nodes: stats.Nodes, Keys: stats.Keys, Load: stats.Load}
+		switch member := member.(type) {
+		case *Node:
+			info.Threshold = member.threshold
+			info.State = member.State()
+		case *Ring:
+			info.Level = member.level
+			member.RLock()
+			info.Children = len(member.members)
+			member.RUnlock()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Member returns the member of this ring with the given ID.
+func (r *Ring) Member(id string) (Member, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	member, ok := r.members[id]
+	return member, ok
+}
+
+// Subring returns the subring of this ring with the given ID.
+func (r *Ring) Subring(id string) (*Ring, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	subring, ok := r.members[id].(*Ring)
+	return subring, ok
+}
+
+// Descend follows a path of subring IDs down from the ring and returns the ring at its end, or the ring
+// itself for an empty path. The error wraps ErrSubringNotFound and names the first ID that is not a subring.
+func (r *Ring) Descend(path ...string) (*Ring, error) {
+	ring := r
+	for i, id := range path {
+		subring, ok := ring.Subring(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s at depth %d below %s", ErrSubringNotFound, id, i, ring.id)
+		}
+		ring = subring
+	}
+	return ring, nil
+}
+
+// InsertMember adds a custom member and its vnodes to the ring. Keys falling in its arcs are handed to it if
+// it implements KeyWriter, and released otherwise. Nodes are added with InsertNode.
+func (r *Ring) InsertMember(member Member) error {
+	defer r.timeTrack(time.Now(), "InsertMember", "to insert a custom member")
+	if member.Kind() != KindCustom {
+		return errors.New("only custom members can be inserted with InsertMember")
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	if len(r.members) >= r.maxCount {
+		return ErrRingAtCapacity
+	}
+	if r.members[member.ID()] != nil {
+		return ErrNodeExists
+	}
+	r.members[member.ID()] = member
+
+	newVNodes := make(map[uint32]bool)
+	for i := 0; i < r.config.Replicas; i++ {
+		vNodeHash := hash(member.ID(), i)
+		if r.circle.Insert(vNodeHash, member.ID()) {
+			newVNodes[vNodeHash] = true
+		}
+	}
+	r.circle.Sort()
+
+	err := r.handOffKeys(member, newVNodes)
+	r.emit(Event{Type: NodeAdded, RingID: r.id, NodeID: member.ID(), Level: r.level})
+	return err
+}
+
+// RemoveMember removes a custom member and its vnodes from the ring. Keys it holds stay with it.
+func (r *Ring) RemoveMember(id string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	member, ok := r.members[id]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if member.Kind() != KindCustom {
+		return errors.New("nodes and subrings are removed with RemoveNode")
+	}
+	for i := 0; i < r.config.Replicas; i++ {
+		vNodeHash := hash(id, i)
+		if _, owner := r.circle.FindClosest(vNodeHash); owner == id {
+			r.circle.Delete(vNodeHash)
+		}
+	}
+	r.circle.Sort()
+	delete(r.members, id)
+	r.emit(Event{Type: NodeRemoved, RingID: r.id, NodeID: id, Level: r.level})
+	return nil
+}
+
+// handOffKeys moves the keys that now fall in a custom member's vnodes out of the tree and into the member
+// (assuming mutex is already locked).
+func (r *Ring) handOffKeys(member Member, newVNodes map[uint32]bool) error {
+	writer, _ := member.(KeyWriter)
+	var err error
+	release := func(node *Node, vNodeHash uint32, key string, keyHash uint32) {
+		if owner, _ := r.circle.FindClosest(keyHash); !newVNodes[owner] {
+			return
+		}
+		if _, pinned := r.pins.get(key); pinned {
+			return // Pinned keys stay in the tree
+		}
+		delete(node.writable(vNodeHash), key)
+		r.index.delete(key)
+		node.clearCost(key)
+		delete(node.checksums, key)
+		r.countKey(key, -1)
+		r.stats.remapped.add(1)
+		if writer == nil {
+			r.logf("Released key %s to read-only member %s.\n", key, member.ID())
+			return
+		}
+		if werr := writer.InsertKey(key); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	for _, m := range r.members {
+		switch m := m.(type) {
+		case *Node:
+			for vNodeHash, keys := range m.keys {
+				for key, keyHash := range keys {
+					release(m, vNodeHash, key, keyHash)
+				}
+			}
+		case *Ring:
+			m.Lock()
+			m.forEachNode(func(node *Node) {
+				for vNodeHash, keys := range node.keys {
+					for key := range keys {
+						release(node, vNodeHash, key, r.config.keyHash(key, r.level))
+					}
+				}
+			})
+			m.Unlock()
+		}
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package ringtree
+
+import (
+	"errors"
+	"testing"
+)
+
+// remoteRing is a custom member that proxies to a separate ring tree.
+type remoteRing struct {
+	id   string
+	ring *Ring
+}
+
+func (m *remoteRing) ID() string                                       { return m.id }
+func (m *remoteRing) Kind() MemberKind                                 { return KindCustom }
+func (m *remoteRing) Route(key string, keyHash uint32) (string, error) { return m.ring.Lookup(key) }
+func (m *remoteRing) MemberStats() MemberStats                         { return m.ring.MemberStats() }
+func (m *remoteRing) InsertKey(key string) error                       { return m.ring.InsertKey(key) }
+func (m *remoteRing) RemoveKey(key string) error                       { return m.ring.RemoveKey(key) }
+
+// readOnly is a custom member that does not accept writes.
+type readOnly struct{ id string }
+
+func (m *readOnly) ID() string                                       { return m.id }
+func (m *readOnly) Kind() MemberKind                                 { return KindCustom }
+func (m *readOnly) Route(key string, keyHash uint32) (string, error) { return m.id, nil }
+func (m *readOnly) MemberStats() MemberStats                         { return MemberStats{} }
+
+func TestCustomMember(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	remote := New(2)
+	remote.InsertNode(NewNode("R", 1000))
+	proxy := &remoteRing{id: "proxy", ring: remote}
+	if err := rt.InsertMember(proxy); err != nil {
+		t.Fatalf("expected custom member to be inserted, got error: %v", err)
+	}
+	if member, ok := rt.Member("proxy"); !ok || member.Kind() != KindCustom {
+		t.Errorf("expected proxy to be a custom member")
+	}
+
+	// Keys in the proxy's arcs were handed to the remote ring
+	handed := proxy.MemberStats().Keys
+	if handed == 0 {
+		t.Errorf("expected keys to be handed to the custom member")
+	}
+	checkNum(rt.MemberStats().Keys, 100, t)
+
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys {
+		if err := rt.RemoveKey(key); err != nil {
+			t.Fatalf("expected key %s to be removed, got error: %v", key, err)
+		}
+	}
+	checkNum(rt.MemberStats().Keys, 0, t)
+
+	if err := rt.RemoveMember("proxy"); err != nil {
+		t.Fatalf("expected custom member to be removed, got error: %v", err)
+	}
+	checkNum(rt.circle.Size(), 2*rt.config.Replicas, t)
+}
+
+func TestReadOnlyMember(t *testing.T) {
+	rt := New(2)
+	rt.InsertMember(&readOnly{id: "static"})
+
+	if err := rt.InsertKey("key"); !errors.Is(err, ErrReadOnlyMember) {
+		t.Errorf("expected ErrReadOnlyMember, got %v", err)
+	}
+	if owner, err := rt.Lookup("key"); err != nil || owner != "static" {
+		t.Errorf("expected key to be routed to static, got %s (%v)", owner, err)
+	}
+	if err := rt.InsertMember(NewNode("A", 10)); err == nil {
+		t.Errorf("expected nodes to be rejected by InsertMember")
+	}
+}
+
+func TestMemberInfo(t *testing.T) {
+	rt := New(3)
+	for _, id := range []string{"C", "A", "B", "D", "E"} {
+		if err := growTree(rt, id, 1000); err != nil {
+			t.Fatalf("expected node %s to be added, got error: %v", id, err)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+	rt.InsertMember(&readOnly{id: "static"})
+
+	infos := rt.MemberInfo()
+	if len(infos) != len(rt.Members()) {
+		t.Fatalf("expected %d members, got %d", len(rt.Members()), len(infos))
+	}
+	keys, subrings := 0, 0
+	for i, info := range infos {
+		if i > 0 && infos[i-1].ID >= info.ID {
+			t.Errorf("expected members sorted by ID, got %s before %s", infos[i-1].ID, info.ID)
+		}
+		member, _ := rt.Member(info.ID)
+		if info.Kind != member.Kind() {
+			t.Errorf("expected %s to be a %s, got %s", info.ID, member.Kind(), info.Kind)
+		}
+		stats := member.MemberStats()
+		if info.Keys != stats.Keys || info.Load != stats.Load || info.Nodes != stats.Nodes {
+			t.Errorf("expected %s to report %+v, got %+v", info.ID, stats, info)
+		}
+		keys += info.Keys
+		switch member := member.(type) {
+		case *Node:
+			checkNum(info.Level, 0, t)
+			checkNum(info.Threshold, 1000, t)
+			checkNum(info.Children, 0, t)
+		case *Ring:
+			subrings++
+			checkNum(info.Level, 1, t)
+			checkNum(info.Children, len(member.Members()), t)
+		default:
+			checkNum(info.Children, 0, t)
+		}
+	}
+	if subrings == 0 {
+		t.Errorf("expected a subring among the members")
+	}
+	checkNum(keys, rt.MemberStats().Keys, t)
+}
+
+func TestDescend(t *testing.T) {
+	rt := New(2)
+	for _, id := range []string{"A", "B", "C", "D", "E"} {
+		if err := growTree(rt, id, 1000); err != nil {
+			t.Fatalf("expected node %s to be added, got error: %v", id, err)
+		}
+	}
+
+	// Follow the first subring at every level down to the deepest ring
+	var path []string
+	want := rt
+	for {
+		var next *Ring
+		for _, info := range want.MemberInfo() {
+			if info.Kind == KindRing {
+				next, _ = want.Subring(info.ID)
+				path = append(path, info.ID)
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		want = next
+	}
+	if len(path) < 2 {
+		t.Fatalf("expected a tree at least 2 subrings deep, got path %v", path)
+	}
+
+	if ring, err := rt.Descend(path...); err != nil || ring != want {
+		t.Errorf("expected %v to lead to ring %s, got %v (%v)", path, want.ID(), ring, err)
+	}
+	if ring, err := rt.Descend(); err != nil || ring != rt {
+		t.Errorf("expected an empty path to return the ring itself, got %v (%v)", ring, err)
+	}
+	for _, info := range rt.MemberInfo() {
+		if _, ok := rt.Subring(info.ID); ok != (info.Kind == KindRing) {
+			t.Errorf("expected Subring(%s) to report %t for a %s", info.ID, !ok, info.Kind)
+		}
+	}
+	if _, err := rt.Descend(path[0], "missing"); !errors.Is(err, ErrSubringNotFound) {
+		t.Errorf("expected ErrSubringNotFound, got %v", err)
+	}
+}
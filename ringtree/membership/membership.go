@@ -0,0 +1,225 @@
+// Package membership keeps the nodes of a ring tree in sync with the peers of a cluster membership protocol
+// such as hashicorp/memberlist. Peers that join are inserted into the root ring, peers that leave are
+// removed, and failed peers are marked Down and removed once the ring's removal quorum confirms the failure.
+//
+// A memberlist.EventDelegate forwards its notifications to a Sync:
+//
+//	type delegate struct{ sync *membership.Sync }
+//
+//	func (d delegate) NotifyJoin(n *memberlist.Node)   { d.sync.Join(n.Name, n.Meta) }
+//	func (d delegate) NotifyUpdate(n *memberlist.Node) { d.sync.Update(n.Name, n.Meta) }
+//	func (d delegate) NotifyLeave(n *memberlist.Node) {
+//		if n.State == memberlist.StateDead {
+//			d.sync.Fail(n.Name)
+//		} else {
+//			d.sync.Leave(n.Name)
+//		}
+//	}
+//
+// A full root ring rejects joins unless the tree was created with ringtree.WithAutoSplit.
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// Meta is the metadata a peer advertises: the weight scaling its node's threshold and free-form labels.
+type Meta struct {
+	Weight float64           `json:"weight,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// MetaDecoder decodes the metadata a peer advertises.
+type MetaDecoder func(meta []byte) (Meta, error)
+
+// JSONMeta decodes metadata encoded as a JSON Meta object. Empty metadata decodes to the zero Meta.
+func JSONMeta(meta []byte) (Meta, error) {
+	var m Meta
+	if len(meta) == 0 {
+		return m, nil
+	}
+	err := json.Unmarshal(meta, &m)
+	return m, err
+}
+
+// config holds the settings of a Sync.
+type config struct {
+	threshold int
+	decode    MetaDecoder
+	onError   func(peer string, err error)
+}
+
+// Option configures a Sync.
+type Option func(*config)
+
+// WithThreshold sets the threshold of a node whose peer has weight 1. Defaults to 100.
+func WithThreshold(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.threshold = n
+		}
+	}
+}
+
+// WithMetaDecoder decodes peer metadata with fn instead of JSONMeta.
+func WithMetaDecoder(fn MetaDecoder) Option {
+	return func(c *config) {
+		c.decode = fn
+	}
+}
+
+// WithErrorHandler calls fn when a membership change cannot be applied to the ring, since the protocol's
+// notifications cannot return errors. By default such errors are dropped.
+func WithErrorHandler(fn func(peer string, err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// Sync applies membership notifications to the root ring of a tree.
+type Sync struct {
+	ring   *ringtree.Ring
+	config config
+	peers  map[string]Meta
+	mu     sync.Mutex
+}
+
+// New returns a Sync maintaining the nodes of ring.
+func New(ring *ringtree.Ring, opts ...Option) *Sync {
+	s := &Sync{ring: ring, config: config{threshold: 100, decode: JSONMeta}, peers: make(map[string]Meta)}
+	for _, opt := range opts {
+		opt(&s.config)
+	}
+	return s
+}
+
+// Join inserts a node for a peer that joined the cluster. A peer rejoining after a failure that was not
+// confirmed gets its node back Up.
+func (s *Sync) Join(peer string, meta []byte) {
+	m, err := s.config.decode(meta)
+	if err != nil {
+		s.fail(peer, err)
+		return
+	}
+	s.JoinMeta(peer, m)
+}
+
+// JoinMeta is Join for a source that provides the peer's metadata already decoded.
+func (s *Sync) JoinMeta(peer string, m Meta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ring.SetNodeState(peer, ringtree.Up); err == nil {
+		s.peers[peer] = m
+		return
+	} else if !errors.Is(err, ringtree.ErrNodeNotFound) {
+		s.fail(peer, err)
+		return
+	}
+	if err := s.ring.InsertNode(ringtree.NewNode(peer, s.threshold(m))); err != nil {
+		s.fail(peer, err)
+		return
+	}
+	s.peers[peer] = m
+}
+
+// Update records new metadata of a peer. Labels apply at once; a changed weight applies when the peer
+// next joins, since a node's threshold is fixed while it is in the ring.
+func (s *Sync) Update(peer string, meta []byte) {
+	m, err := s.config.decode(meta)
+	if err != nil {
+		s.fail(peer, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.peers[peer]; ok {
+		s.peers[peer] = m
+	}
+}
+
+// SetState changes the state of a peer's node, such as Draining for a peer that is shutting down but still
+// serves the keys it holds.
+func (s *Sync) SetState(peer string, state ringtree.NodeState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ring.SetNodeState(peer, state); err != nil {
+		s.fail(peer, err)
+	}
+}
+
+// Leave removes the node of a peer that left the cluster gracefully.
+func (s *Sync) Leave(peer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.peers, peer)
+	if err := s.ring.RemoveNodeByID(peer); err != nil && !errors.Is(err, ringtree.ErrNodeNotFound) {
+		s.fail(peer, err)
+	}
+}
+
+// Fail marks the node of a failed peer Down and removes it once the ring's removal quorum confirms the
+// failure. Until then the node stays Down and its writes are routed to the next available node.
+func (s *Sync) Fail(peer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ring.SetNodeState(peer, ringtree.Down); err != nil {
+		if !errors.Is(err, ringtree.ErrNodeNotFound) {
+			s.fail(peer, err)
+		}
+		return
+	}
+	err := s.ring.AutoRemoveNode(peer)
+	switch {
+	case err == nil:
+		delete(s.peers, peer)
+	case !errors.Is(err, ringtree.ErrQuorumNotReached):
+		s.fail(peer, err)
+	}
+}
+
+// Peers returns the sorted names of the peers with a node in the ring.
+func (s *Sync) Peers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peers := make([]string, 0, len(s.peers))
+	for peer := range s.peers {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// Labels returns the labels a peer advertises.
+func (s *Sync) Labels(peer string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := make(map[string]string, len(s.peers[peer].Labels))
+	for k, v := range s.peers[peer].Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// threshold returns the node threshold for a peer's weight.
+func (s *Sync) threshold(m Meta) int {
+	if m.Weight <= 0 {
+		return s.config.threshold
+	}
+	if t := int(m.Weight * float64(s.config.threshold)); t > 0 {
+		return t
+	}
+	return 1
+}
+
+// fail reports an error applying a change for a peer.
+func (s *Sync) fail(peer string, err error) {
+	if s.config.onError != nil {
+		s.config.onError(peer, err)
+	}
+}
@@ -0,0 +1,80 @@
+package membership
+
+import (
+	"reflect"
+	"testing"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+func TestSync(t *testing.T) {
+	ring := ringtree.New(8)
+	var errs []error
+	s := New(ring, WithThreshold(10), WithErrorHandler(func(peer string, err error) {
+		errs = append(errs, err)
+	}))
+
+	s.Join("a", nil)
+	s.Join("b", []byte(`{"weight": 2.5, "labels": {"zone": "eu"}}`))
+	s.Join("c", nil)
+	if got := s.Peers(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("got peers %v, want [a b c]", got)
+	}
+	member, ok := ring.Member("b")
+	if !ok || member.(*ringtree.Node).Threshold() != 25 {
+		t.Errorf("node b: got %v, want threshold 25", member)
+	}
+	if got := s.Labels("b")["zone"]; got != "eu" {
+		t.Errorf("got zone label %q, want eu", got)
+	}
+
+	s.Update("b", []byte(`{"weight": 2.5, "labels": {"zone": "us"}}`))
+	if got := s.Labels("b")["zone"]; got != "us" {
+		t.Errorf("got zone label %q after update, want us", got)
+	}
+
+	s.Leave("a")
+	if _, ok := ring.Member("a"); ok {
+		t.Error("node a still in the ring after leaving")
+	}
+	s.Fail("c")
+	if _, ok := ring.Member("c"); ok {
+		t.Error("node c still in the ring after failing without a quorum")
+	}
+
+	s.Join("d", []byte("not json"))
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one for the undecodable metadata", errs)
+	}
+}
+
+// observer confirms the failures it is told about.
+type observer map[string]bool
+
+func (o observer) ConfirmDown(nodeID string) bool {
+	return o[nodeID]
+}
+
+func TestSyncFailQuorum(t *testing.T) {
+	seen := observer{}
+	ring := ringtree.New(8, ringtree.WithRemovalQuorum(1, seen))
+	s := New(ring)
+	s.Join("a", nil)
+	s.Join("b", nil)
+
+	s.Fail("b")
+	member, ok := ring.Member("b")
+	if !ok || member.(*ringtree.Node).State() != ringtree.Down {
+		t.Fatalf("unconfirmed failure: got %v, want node b kept Down", member)
+	}
+	s.Join("b", nil)
+	if member.(*ringtree.Node).State() != ringtree.Up {
+		t.Error("node b not back Up after rejoining")
+	}
+
+	seen["b"] = true
+	s.Fail("b")
+	if _, ok := ring.Member("b"); ok {
+		t.Error("node b still in the ring after a confirmed failure")
+	}
+}
@@ -0,0 +1,378 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MergeNodes combines two sibling nodes of this ring into one. The node with the smaller load hands its
+// vnodes and keys to the larger one, so no key changes position on the circle.
+func (r *Ring) MergeNodes(aID, bID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.mergeNodesOp(aID, bID)
+}
+
+// mergeNodesOp merges two sibling nodes as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) mergeNodesOp(aID, bID string) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpMergeNodes, Nodes: []string{aID, bID}}, r.mergeNodesByID(aID, bID))
+}
+
+// mergeNodesByID merges two sibling nodes of this ring (assuming the tree's writer lock is held).
+func (r *Ring) mergeNodesByID(aID, bID string) error {
+	r.settleRemaps()
+	r.hub.begin()
+	defer r.hub.end()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	a, okA := r.members[aID].(*Node)
+	b, okB := r.members[bID].(*Node)
+	if !okA || !okB {
+		return ErrNodeNotFound
+	}
+	if a == b {
+		return errors.New("cannot merge a node with itself")
+	}
+	if a.load < b.load {
+		a, b = b, a
+	}
+	// Keep the node keys are pinned to
+	if r.pins.targeted(b.id) {
+		if r.pins.targeted(a.id) {
+			return ErrNodePinned
+		}
+		a, b = b, a
+	}
+	r.mergeNodes(a, b)
+	return nil
+}
+
+// mergeUnderflow merges an underloaded node into its least-loaded adjacent sibling if their combined load
+// fits below the sibling's split point. It reports whether a merge happened.
+func (r *Ring) mergeUnderflow(node *Node) (bool, error) {
+	r.settleRemaps()
+	r.hub.begin()
+	defer r.hub.end()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	if r.members[node.id] != node {
+		return false, nil
+	}
+
+	// Adjacent siblings own the vnodes directly following the node's vnodes
+	var sibling *Node
+	for vNodeHash := range node.keys {
+		_, nextID := r.circle.FindNextClosest(vNodeHash)
+		next, ok := r.members[nextID].(*Node)
+		if !ok || next == node {
+			continue
+		}
+		if sibling == nil || next.load < sibling.load || (next.load == sibling.load && next.id < sibling.id) {
+			sibling = next
+		}
+	}
+	if sibling == nil {
+		return false, nil
+	}
+
+	into, from := sibling, node
+	if from.load > into.load {
+		into, from = from, into
+	}
+	if float64(into.load+from.load) > r.high*float64(into.threshold) || r.pins.targeted(from.id) {
+		return false, nil
+	}
+	if err := r.verifyNode(from); err != nil {
+		return false, err
+	}
+	r.mergeNodes(into, from)
+	return true, nil
+}
+
+// mergeNodes hands every vnode of from, with its keys, over to into and removes from from the ring
+// (assuming mutex is already locked).
+func (r *Ring) mergeNodes(into, from *Node) {
+	defer r.timeTrack(time.Now(), "mergeNodes", "to merge two nodes on level "+strconv.Itoa(r.level))
+
+	for vNodeHash, keys := range from.keys {
+		r.circle.Delete(vNodeHash)
+		r.circle.Insert(vNodeHash, into.id)
+		into.keys[vNodeHash] = make(keySet, len(keys))
+		for key, keyHash := range keys {
+			r.moveKey(key, keyHash, from, vNodeHash, into, vNodeHash)
+		}
+	}
+	r.circle.Sort()
+
+	from.keys = make(map[uint32]keySet)
+	from.stopMaintenance()
+	delete(r.members, from.id)
+	into.changedAt = time.Now()
+	r.stats.numNodes.add(-1)
+	r.logf("Merged node %s into node %s (Load: %d).\n", from.id, into.id, into.load)
+	r.emit(Event{Type: NodesMerged, RingID: r.id, NodeID: from.id, Level: r.level, Remapped: r.stats.remapped.get()})
+	r.stats.calculateRemapComplexity()
+}
+
+// Merge combines two independently built trees into a, which keeps its configuration, and returns it. If the
+// root of a has a slot for every member of b's root, they are interleaved on it and b's nodes and subrings
+// keep their levels. Otherwise b's root is re-leveled as a subring of the shallowest ring of a with a free
+// slot. Either way only keys whose route changed are moved: keys of a in the arcs b's members take over on
+// the ring they join, and keys of b outside them, so remapping is bounded by the arcs the trees trade
+// rather than the size of the merged tree. Re-leveling rehashes b's keys within its subring as well.
+//
+// Both trees must be roots with the same vnode count, and must not share a node ID or a key. Pins on b's
+// keys are carried over; its watchers, keyspaces, leases and access counts are not. b must not be used
+// after the merge.
+func Merge(a, b *Ring) (*Ring, error) {
+	if a == nil || b == nil || a.parent != nil || b.parent != nil {
+		return nil, errors.New("only the roots of two trees can be merged")
+	}
+	if a.writer == b.writer {
+		return nil, errors.New("cannot merge a tree with itself")
+	}
+	if a.config.Replicas != b.config.Replicas {
+		return nil, fmt.Errorf("cannot merge trees with %d and %d vnodes per member", a.config.Replicas, b.config.Replicas)
+	}
+	// Lock the older tree first, so concurrent merges of the same trees in either order do not deadlock
+	first, second := a.writer, b.writer
+	if second.seq < first.seq {
+		first, second = second, first
+	}
+	first.Lock()
+	defer first.Unlock()
+	second.Lock()
+	defer second.Unlock()
+	defer a.timeTrack(time.Now(), "Merge", "to merge two trees")
+	if a.Frozen() || b.Frozen() {
+		return nil, errors.New("cannot merge a frozen tree")
+	}
+	a.settleRemaps()
+	b.settleRemaps()
+	if err := disjoint(a, b); err != nil {
+		return nil, err
+	}
+	a.hub.begin()
+	defer a.hub.end()
+
+	a.stats.numNodes.add(b.stats.numNodes.get())
+	a.stats.numKeys.add(b.stats.numKeys.get())
+	b.Lock()
+	b.forEachMember(func(string) {}, func(key string) { a.keyspaces.count(key, 1) })
+	b.Unlock()
+	b.pins.RLock()
+	for key, p := range b.pins.pins {
+		a.pins.set(key, p.target, p.sticky)
+	}
+	b.pins.RUnlock()
+
+	a.RLock()
+	fits := len(a.members)+len(a.reserved)+len(b.members) <= a.maxCount
+	a.RUnlock()
+	// Timers holding b's writer lock, such as those of maintenance windows, follow it to the merged tree
+	b.writer.merged = a.writer
+	var detached []detachedKey
+	ring, id := a, ""
+	if fits {
+		detached = a.interleave(b)
+		a.logf("Interleaved the %d members of another tree on ring %s.\n", len(b.members), a.id)
+	} else {
+		if ring = a.freeRing(); ring == nil {
+			return nil, ErrRingAtCapacity
+		}
+		id = a.newNodeID()
+		ring.attach(b, id)
+		a.logf("Attached another tree as subring %s of ring %s at level %d.\n", id, ring.id, ring.level+1)
+	}
+
+	if err := a.resettle(detached); err != nil {
+		return nil, err
+	}
+	a.emit(Event{Type: TreesMerged, RingID: ring.id, NodeID: id, Level: ring.level, Remapped: a.stats.remapped.get()})
+	a.stats.calculateRemapComplexity()
+	return a, nil
+}
+
+// disjoint returns an error if two trees share a node ID, subring ID or key (assuming both writer locks are
+// held).
+func disjoint(a, b *Ring) error {
+	ids, keys := make(map[string]bool), make(map[string]bool)
+	b.Lock()
+	b.forEachMember(func(id string) { ids[id] = true }, func(key string) { keys[key] = true })
+	b.Unlock()
+
+	var err error
+	a.Lock()
+	a.forEachMember(func(id string) {
+		if ids[id] && err == nil {
+			err = fmt.Errorf("%w: %s is in both trees", ErrNodeExists, id)
+		}
+	}, func(key string) {
+		if keys[key] && err == nil {
+			err = fmt.Errorf("%w: %s is in both trees", ErrKeyExists, key)
+		}
+	})
+	a.Unlock()
+	return err
+}
+
+// forEachMember calls member with the ID of every member of the ring and its subrings, and key with every
+// key their nodes hold (assuming mutex is already locked).
+func (r *Ring) forEachMember(member func(id string), key func(key string)) {
+	for id, m := range r.members {
+		member(id)
+		switch m := m.(type) {
+		case *Node:
+			for _, keys := range m.keys {
+				for k := range keys {
+					key(k)
+				}
+			}
+		case *Ring:
+			m.Lock()
+			m.forEachMember(member, key)
+			m.Unlock()
+		}
+	}
+}
+
+// detachedKey is a key taken off its node by a merge, to be reinserted where it routes to.
+type detachedKey struct {
+	key  string
+	cost int
+}
+
+// interleave moves every member of another tree's root, with its vnodes, onto the ring. Vnodes of the other
+// tree that collide with one already on the ring stay with the ring's member, and the keys they held are
+// returned to be reinserted.
+func (r *Ring) interleave(other *Ring) []detachedKey {
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+	other.Lock()
+	defer other.Unlock()
+
+	for id, member := range other.members {
+		r.members[id] = member
+		if subring, ok := member.(*Ring); ok {
+			subring.reparent(r)
+		}
+	}
+	vNodes := circleVNodes(other.circle)
+	inserted := make(map[uint32]bool, len(vNodes))
+	for _, vNode := range r.circle.InsertBatch(vNodes) {
+		inserted[vNode.hash] = true
+	}
+
+	var detached []detachedKey
+	for _, vNode := range vNodes {
+		node, ok := other.members[vNode.nodeID].(*Node)
+		if inserted[vNode.hash] || !ok {
+			continue
+		}
+		for key := range node.keys[vNode.hash] {
+			detached = append(detached, detachedKey{key: key, cost: node.clearCost(key)})
+			delete(node.checksums, key)
+			r.countKey(key, -1)
+		}
+		delete(node.keys, vNode.hash)
+	}
+	return detached
+}
+
+// attach places another tree's root on the ring as a subring with the given ID, one level below it.
+func (r *Ring) attach(other *Ring, id string) {
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+	other.Lock()
+	defer other.Unlock()
+
+	other.id = id
+	other.reparent(r)
+	r.members[id] = other
+	r.circle.InsertBatch(r.config.vNodes(id))
+}
+
+// reparent makes the ring, with its subrings, part of its new parent's tree on the level below the parent
+// (assuming mutex is already locked).
+func (r *Ring) reparent(parent *Ring) {
+	r.parent = parent
+	r.level = parent.level + 1
+	r.config = parent.config
+	r.share(parent)
+	for _, member := range r.members {
+		if subring, ok := member.(*Ring); ok {
+			subring.reparent(r)
+		}
+	}
+	r.publish()
+}
+
+// heldKey is a key as stored on a node before a merge.
+type heldKey struct {
+	key       string
+	keyHash   uint32
+	node      *Node
+	ring      *Ring
+	vNodeHash uint32
+}
+
+// resettle moves every key of the tree that no longer routes to the vnode holding it, then reinserts the
+// detached keys (assuming the tree's writer lock is held and no ring lock is).
+func (r *Ring) resettle(detached []detachedKey) error {
+	var held []heldKey
+	r.Lock()
+	r.forEachRingNode(func(node *Node, ring *Ring) {
+		for vNodeHash, keys := range node.keys {
+			for key, keyHash := range keys {
+				held = append(held, heldKey{key: key, keyHash: keyHash, node: node, ring: ring, vNodeHash: vNodeHash})
+				r.index.set(key, node, ring)
+			}
+		}
+	})
+	r.Unlock()
+
+	for _, h := range held {
+		node, _, vNodeHash, keyHash, err := r.findNode(h.key, false)
+		var custom customRoute
+		if err != nil && !errors.As(err, &custom) {
+			return err
+		}
+		h.ring.Lock()
+		if _, ok := h.node.keys[h.vNodeHash][h.key]; !ok {
+			h.ring.Unlock()
+			continue // Moved by a split while reinserting an earlier key
+		}
+		if node == h.node && vNodeHash == h.vNodeHash {
+			// A re-leveled key stays put but is hashed on its new level
+			if err := r.rehashChecksum(h.node, h.key, h.keyHash, keyHash); err != nil {
+				h.ring.Unlock()
+				return err
+			}
+			h.node.writable(h.vNodeHash)[h.key] = keyHash
+			h.ring.Unlock()
+			continue
+		}
+		delete(h.node.writable(h.vNodeHash), h.key)
+		delete(h.node.checksums, h.key)
+		detached = append(detached, detachedKey{key: h.key, cost: h.node.clearCost(h.key)})
+		r.countKey(h.key, -1)
+		h.ring.Unlock()
+	}
+
+	for _, d := range detached {
+		r.stats.remapped.add(1)
+		if err := r.insertKey(d.key, d.cost, true); err != nil {
+			return fmt.Errorf("error reinserting key %s: %v", d.key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package ringtree
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMergeNodes(t *testing.T) {
+	rt := New(5)
+	nodeA := NewNode("", 1000)
+	nodeB := NewNode("", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(nodeB)
+	rt.InsertNode(NewNode("", 1000))
+
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+	combined := nodeA.load + nodeB.load
+
+	if err := rt.MergeNodes(nodeA.id, nodeB.id); err != nil {
+		t.Fatalf("unexpected error merging nodes: %v", err)
+	}
+	checkNum(rt.Size(), 2, t)
+	checkNum(rt.circle.Size(), 3*defaultReplicas, t)
+
+	survivor := nodeA
+	if rt.members[nodeA.id] == nil {
+		survivor = nodeB
+	}
+	checkNum(survivor.load, combined, t)
+	checkNum(len(survivor.keys), 2*defaultReplicas, t)
+
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after merge, got error: %v", key, err)
+		}
+	}
+}
+
+func TestSiblingMergeOnUnderflow(t *testing.T) {
+	rt := New(2, WithSiblingMerge(true))
+	events, cancel := rt.Watch(10000)
+	defer cancel()
+	rt.InsertNode(NewNode("", 20))
+	rt.InsertNode(NewNode("", 20))
+
+	var keys []string
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys[:190] {
+		if err := rt.RemoveKey(key); err != nil {
+			t.Fatalf("expected key %s to be removed, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys[190:] {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+
+	merged := 0
+	for len(events) > 0 {
+		for _, event := range <-events {
+			if event.Type == NodesMerged {
+				merged++
+			}
+		}
+	}
+	if merged == 0 {
+		t.Errorf("expected underloaded subring nodes to be merged into siblings")
+	}
+}
+
+// checkMerged fails the test unless every key of both trees is in the merged tree, and returns the number of
+// keys that moved. Keys of a may only move to nodes of b, and so may keys of b if its members were
+// interleaved; a re-leveled b rehashes its keys among its own nodes.
+func checkMerged(t *testing.T, merged *Ring, ownersA, ownersB map[string]string, interleaved bool) int {
+	t.Helper()
+	checkValid(merged, t)
+	nodesA, nodesB := make(map[string]bool), make(map[string]bool)
+	for _, owner := range ownersA {
+		nodesA[owner] = true
+	}
+	for _, owner := range ownersB {
+		nodesB[owner] = true
+	}
+	moved := 0
+	check := func(owners map[string]string, other map[string]bool, strict bool) {
+		for key, owner := range owners {
+			now, err := merged.Lookup(key)
+			if err != nil {
+				t.Fatalf("expected key %s to be found after the merge, got error: %v", key, err)
+			}
+			if now == owner {
+				continue
+			}
+			moved++
+			if strict && !other[now] {
+				t.Errorf("expected key %s to stay on %s or move to the other tree, got %s", key, owner, now)
+			}
+		}
+	}
+	check(ownersA, nodesB, true)
+	check(ownersB, nodesA, interleaved)
+	checkNum(merged.Stats().Keys(), len(ownersA)+len(ownersB), t)
+	return moved
+}
+
+func TestMergeInterleave(t *testing.T) {
+	a, keysA := buildTree(t, 8, []string{"a1", "a2", "a3"}, 1000, 600)
+	ownersA := owners(a, keysA)
+	b, keysB := buildTree(t, 8, []string{"b1", "b2", "b3"}, 1000, 600)
+	ownersB := owners(b, keysB)
+	events, cancel := a.Watch(100)
+	defer cancel()
+
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("expected the trees to be merged, got error: %v", err)
+	}
+	if merged != a {
+		t.Fatalf("expected the merged tree to be a")
+	}
+	checkNum(merged.Size(), 6, t)
+	checkNum(merged.Stats().Nodes(), 6, t)
+	// Each key stays put unless the other tree's vnodes took over its arc, roughly half of them here
+	if moved := checkMerged(t, merged, ownersA, ownersB, true); moved == 0 || moved > 900 {
+		t.Errorf("expected about half of the 1200 keys to move, got %d", moved)
+	}
+	batch := <-events
+	if last := batch[len(batch)-1]; last.Type != TreesMerged {
+		t.Errorf("expected the merge to end with a TreesMerged event, got %v", last.Type)
+	}
+}
+
+func TestMergeRelevel(t *testing.T) {
+	a, keysA := buildTree(t, 3, []string{"a1", "a2", "a3"}, 1000, 600)
+	ownersA := owners(a, keysA)
+	b, keysB := buildTree(t, 3, []string{"b1", "b2"}, 1000, 300)
+	ownersB := owners(b, keysB)
+	// Grow a past its root, so b cannot be interleaved and goes into a subring
+	if err := growTree(a, "a4", 1000); err != nil {
+		t.Fatalf("expected a to grow, got error: %v", err)
+	}
+	for key := range ownersA {
+		ownersA[key], _ = a.Lookup(key)
+	}
+	free := a.freeRing()
+	if free == nil || free.parent == nil {
+		t.Fatalf("expected a subring of a with a free slot")
+	}
+	nodes := a.Stats().Nodes() + 2
+
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("expected the trees to be merged, got error: %v", err)
+	}
+	checkMerged(t, merged, ownersA, ownersB, false)
+	checkNum(merged.Stats().Nodes(), nodes, t)
+	_, ring := merged.findMember("b1")
+	if ring == nil || ring.parent != free || ring.Level() != free.Level()+1 {
+		t.Fatalf("expected b to be attached below ring %s", free.ID())
+	}
+	checkNum(ring.Size(), 2, t)
+	if _, ok := free.Subring(ring.ID()); !ok {
+		t.Errorf("expected b to be subring %s of ring %s", ring.ID(), free.ID())
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	a, _ := buildTree(t, 8, []string{"a1", "a2"}, 1000, 50)
+	if _, err := Merge(a, a); err == nil {
+		t.Errorf("expected merging a tree with itself to fail")
+	}
+	shared, _ := buildTree(t, 8, []string{"a1", "c1"}, 1000, 0)
+	if _, err := Merge(a, shared); !errors.Is(err, ErrNodeExists) {
+		t.Errorf("expected ErrNodeExists for a shared node, got %v", err)
+	}
+	dup, _ := buildTree(t, 8, []string{"c1"}, 1000, 0)
+	key := a.NodeIDs()[0]
+	keys, _ := a.KeysForNode(key)
+	dup.InsertKey(keys[0])
+	if _, err := Merge(a, dup); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("expected ErrKeyExists for a shared key, got %v", err)
+	}
+	if _, err := Merge(a, New(8, WithReplicas(40))); err == nil {
+		t.Errorf("expected trees with different vnode counts not to merge")
+	}
+	// A failed merge leaves both trees as they were
+	checkValid(a, t)
+	checkNum(a.Size(), 2, t)
+	checkNum(a.Stats().Keys(), 50, t)
+}
+
+func TestMergeLockOrder(t *testing.T) {
+	a, _ := buildTree(t, 8, []string{"a1", "c1"}, 1000, 0)
+	b, _ := buildTree(t, 8, []string{"b1", "c1"}, 1000, 0)
+
+	// Merges of the same trees in opposite orders take their locks in the same order; the shared node makes
+	// each one fail, leaving both trees to merge again
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 2000; i++ {
+			wg.Add(2)
+			go func() { defer wg.Done(); Merge(a, b) }()
+			go func() { defer wg.Done(); Merge(b, a) }()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected concurrent merges in opposite orders not to deadlock")
+	}
+}
@@ -0,0 +1,76 @@
+package ringtree
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// Counters is a point-in-time view of the tree's live counters, shaped for scraping.
+type Counters struct {
+	Keys             int            `json:"keys"`              // Keys in the tree
+	Nodes            int            `json:"nodes"`             // Physical nodes in the tree
+	Rings            int            `json:"rings"`             // Rings in the tree, the root included
+	Depth            int            `json:"depth"`             // Deepest level holding a subring
+	Remapped         int            `json:"remapped"`          // Keys remapped by membership changes so far
+	ChecksumFailures int            `json:"checksum_failures"` // Keys that failed checksum verification
+	Epoch            uint64         `json:"epoch"`             // Topology epoch
+	Operations       map[string]int `json:"operations"`        // Timed operations completed, by operation
+}
+
+// Counters returns the tree's live counters. It waits for a mutation in progress to finish.
+func (r *Ring) Counters() Counters {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	root := r.root()
+	s := r.stats
+	c := Counters{
+		Keys:             s.numKeys.get(),
+		Nodes:            s.numNodes.get(),
+		Remapped:         s.remappedTotal.get() + s.remapped.get(),
+		ChecksumFailures: s.corrupted.get(),
+		Epoch:            r.Epoch(),
+		Operations:       make(map[string]int),
+	}
+	var walk func(ring *Ring, level int)
+	walk = func(ring *Ring, level int) {
+		c.Rings++
+		if level > c.Depth {
+			c.Depth = level
+		}
+		for _, subring := range ring.subrings() {
+			walk(subring, level+1)
+		}
+	}
+	walk(root, 0)
+
+	s.mu.Lock()
+	for operation, times := range s.operationTimes {
+		c.Operations[operation] = len(times)
+	}
+	s.mu.Unlock()
+	return c
+}
+
+// StatsJSON returns the tree's live counters as JSON.
+func (r *Ring) StatsJSON() ([]byte, error) {
+	return json.Marshal(r.Counters())
+}
+
+// publishing serializes PublishExpvar, so two trees published under one name cannot both find it free.
+var publishing sync.Mutex
+
+// PublishExpvar publishes the tree's live counters as the expvar variable name, served with every other
+// expvar at /debug/vars. It returns ErrExpvarInUse if the name is already published.
+func (r *Ring) PublishExpvar(name string) error {
+	publishing.Lock()
+	defer publishing.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("%w: %s", ErrExpvarInUse, name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return r.Counters()
+	}))
+	return nil
+}
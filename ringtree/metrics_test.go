@@ -0,0 +1,53 @@
+package ringtree
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+	for i := 0; i < 60; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	rt.InsertNode(NewNode("C", 10))
+
+	data, err := rt.StatsJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Counters
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Keys != 60 || c.Nodes != rt.Stats().Nodes() {
+		t.Fatalf("got %d keys and %d nodes, want 60 and %d", c.Keys, c.Nodes, rt.Stats().Nodes())
+	}
+	if c.Depth != rt.GetDepth() || c.Rings < 2 {
+		t.Fatalf("got depth %d over %d rings, want depth %d with subrings", c.Depth, c.Rings, rt.GetDepth())
+	}
+	if c.Epoch != rt.Epoch() || c.Operations["InsertKey"] == 0 {
+		t.Fatalf("got epoch %d and %d timed inserts, want epoch %d and some inserts", c.Epoch, c.Operations["InsertKey"], rt.Epoch())
+	}
+
+	// Names are process-wide, so each run of the test publishes under its own
+	name := "ringtree_test_" + createId()
+	if err := rt.PublishExpvar(name); err != nil {
+		t.Fatal(err)
+	}
+	var published Counters
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Keys != 60 {
+		t.Fatalf("got %d keys from expvar, want 60", published.Keys)
+	}
+	if err := New(2).PublishExpvar(name); !errors.Is(err, ErrExpvarInUse) {
+		t.Fatalf("got %v publishing a name twice, want ErrExpvarInUse", err)
+	}
+}
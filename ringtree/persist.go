@@ -0,0 +1,262 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot format written by WriteSnapshot.
+const snapshotVersion = 1
+
+// ErrCustomMember is returned when snapshotting a tree that holds custom members, which cannot be encoded.
+var ErrCustomMember = errors.New("custom members cannot be snapshotted")
+
+// treeFile is the encoded form of a whole ring tree.
+type treeFile struct {
+	Version      int       `json:"version"`
+	Epoch        uint64    `json:"epoch,omitempty"`
+	Seq          uint64    `json:"seq,omitempty"` // Last write-ahead log operation the snapshot includes
+	BranchFactor int       `json:"branch_factor,omitempty"`
+	Schedule     []int     `json:"schedule,omitempty"` // Max members per level that subrings are created with
+	Root         ringFile  `json:"root"`
+	Pins         []pinFile `json:"pins,omitempty"`
+	Written      time.Time `json:"written"`
+}
+
+// ringFile is the encoded form of one ring and everything below it.
+type ringFile struct {
+	ID       string      `json:"id"`
+	Level    int         `json:"level"`
+	MaxCount int         `json:"max_count"`
+	High     float64     `json:"high"`
+	Low      float64     `json:"low"`
+	VNodes   []vNodeFile `json:"vnodes"`
+	Nodes    []nodeFile  `json:"nodes,omitempty"`
+	Subrings []ringFile  `json:"subrings,omitempty"`
+}
+
+// vNodeFile is the encoded form of one vnode.
+type vNodeFile struct {
+	Hash   uint32 `json:"hash"`
+	Member string `json:"member"`
+}
+
+// nodeFile is the encoded form of one physical node and its keys.
+type nodeFile struct {
+	ID        string              `json:"id"`
+	Threshold int                 `json:"threshold"`
+	Base      int                 `json:"base"`
+	State     NodeState           `json:"state"`
+	Keys      map[uint32][]string `json:"keys"`
+	Costs     map[string]int      `json:"costs,omitempty"`
+	Checksums map[string]uint32   `json:"checksums,omitempty"` // Secondary hash of each key, with Checksums
+	Locality  *Locality           `json:"locality,omitempty"`
+	WarmingAt *time.Time          `json:"warming_at,omitempty"`
+}
+
+// pinFile is the encoded form of one placement override.
+type pinFile struct {
+	Key    string `json:"key"`
+	Target string `json:"target"`
+	Sticky bool   `json:"sticky"`
+}
+
+// WriteSnapshot writes the whole tree as JSON: the branch factor and capacity schedule, every ring with its
+// vnodes and watermarks, every node with its threshold, state and keys, and the pinned keys. Options such as
+// LoadFunc and CapacityFunc are not part of a snapshot and are given again to ReadSnapshot. With snapshot
+// options the JSON is compressed and followed by a checksum that ReadSnapshot verifies.
+func (r *Ring) WriteSnapshot(w io.Writer, opts ...SnapshotOption) error {
+	r.writer.Lock()
+	file, err := r.snapshotFile()
+	r.writer.Unlock()
+	if err != nil {
+		return err
+	}
+	return encodeSnapshot(w, file, opts...)
+}
+
+// snapshotFile returns the encoded form of the whole tree (assuming the tree's writer lock is held).
+func (r *Ring) snapshotFile() (treeFile, error) {
+	root := r.root()
+	file := treeFile{Version: snapshotVersion, Epoch: r.Epoch(), Seq: r.wal.seq, BranchFactor: r.config.BranchFactor,
+		Schedule: r.config.Schedule, Written: time.Now()}
+	var err error
+	file.Root, err = root.encode()
+	if err != nil {
+		return treeFile{}, err
+	}
+	root.pins.RLock()
+	for key, p := range root.pins.pins {
+		file.Pins = append(file.Pins, pinFile{Key: key, Target: p.target, Sticky: p.sticky})
+	}
+	root.pins.RUnlock()
+	sort.Slice(file.Pins, func(i, j int) bool {
+		return file.Pins[i].Key < file.Pins[j].Key
+	})
+	return file, nil
+}
+
+// encode returns the encoded form of the ring and its subrings.
+func (r *Ring) encode() (ringFile, error) {
+	r.RLock()
+	defer r.RUnlock()
+	file := ringFile{ID: r.id, Level: r.level, MaxCount: r.maxCount, High: r.high, Low: r.low}
+	for _, vNode := range circleVNodes(r.circle) {
+		file.VNodes = append(file.VNodes, vNodeFile{Hash: vNode.hash, Member: vNode.nodeID})
+	}
+
+	ids := make([]string, 0, len(r.members))
+	for id := range r.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		switch member := r.members[id].(type) {
+		case *Node:
+			file.Nodes = append(file.Nodes, member.encode())
+		case *Ring:
+			subring, err := member.encode()
+			if err != nil {
+				return ringFile{}, err
+			}
+			file.Subrings = append(file.Subrings, subring)
+		default:
+			return ringFile{}, ErrCustomMember
+		}
+	}
+	return file, nil
+}
+
+// encode returns the encoded form of the node (assuming its ring's mutex is already locked).
+func (n *Node) encode() nodeFile {
+	file := nodeFile{ID: n.id, Threshold: n.threshold, Base: n.base, State: n.state, Keys: make(map[uint32][]string)}
+	for vNodeHash, keys := range n.keys {
+		list := make([]string, 0, len(keys))
+		for key := range keys {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+		file.Keys[vNodeHash] = list
+	}
+	if n.locality != (Locality{}) {
+		locality := n.locality
+		file.Locality = &locality
+	}
+	if !n.warmingAt.IsZero() {
+		warmingAt := n.warmingAt
+		file.WarmingAt = &warmingAt
+	}
+	if len(n.costs) > 0 {
+		file.Costs = make(map[string]int, len(n.costs))
+		for key, cost := range n.costs {
+			file.Costs[key] = cost
+		}
+	}
+	if len(n.checksums) > 0 {
+		file.Checksums = make(map[string]uint32, len(n.checksums))
+		for key, sum := range n.checksums {
+			file.Checksums[key] = sum
+		}
+	}
+	return file
+}
+
+// ReadSnapshot rebuilds a tree written by WriteSnapshot, configured with the given options. Keys keep the
+// nodes they were on; the snapshot is trusted and not re-routed once its checksum, if it has one, matches.
+// Key checksums written with Checksums are verified against the keys read, failing with ErrChecksumMismatch.
+func ReadSnapshot(rd io.Reader, opts ...Option) (*Ring, error) {
+	file, err := decodeSnapshot(rd)
+	if err != nil {
+		return nil, err
+	}
+	if file.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", file.Version)
+	}
+
+	// The tree sizes new subrings as it did when written, unless the options say otherwise
+	sizing := []Option{WithBranchFactor(file.BranchFactor), WithCapacitySchedule(file.Schedule)}
+	root := New(file.Root.MaxCount, append(sizing, opts...)...)
+	root.maxCount = file.Root.MaxCount
+	root.config.MaxCount = file.Root.MaxCount
+	root.writer.Lock()
+	defer root.writer.Unlock()
+	if err := root.decode(file.Root); err != nil {
+		return nil, err
+	}
+	for _, p := range file.Pins {
+		root.pins.set(p.Key, p.Target, p.Sticky)
+	}
+	root.hub.epoch = file.Epoch
+	root.history.restart(file.Epoch)
+	root.wal.seq = file.Seq
+	return root, nil
+}
+
+// decode fills an empty ring from its encoded form, creating its subrings.
+func (r *Ring) decode(file ringFile) error {
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+	r.high, r.low = file.High, file.Low
+
+	vNodes := make([]VNode, 0, len(file.VNodes))
+	for _, vNode := range file.VNodes {
+		vNodes = append(vNodes, VNode{hash: vNode.Hash, nodeID: vNode.Member})
+	}
+	r.circle.InsertBatch(vNodes)
+
+	for _, nf := range file.Nodes {
+		node := NewNode(nf.ID, nf.Threshold)
+		node.base, node.state = nf.Base, nf.State
+		if nf.Locality != nil {
+			node.locality = *nf.Locality
+		}
+		if nf.WarmingAt != nil {
+			node.warmingAt = *nf.WarmingAt
+		}
+		for vNodeHash, keys := range nf.Keys {
+			node.keys[vNodeHash] = make(keySet, len(keys))
+			for _, key := range keys {
+				keyHash := r.config.keyHash(key, r.level)
+				node.keys[vNodeHash][key] = keyHash
+				cost, ok := nf.Costs[key]
+				if !ok {
+					cost = 1
+				}
+				node.setCost(key, cost)
+				r.index.set(key, node, r)
+				// A snapshot written with checksums must hold a matching one for every key
+				if len(nf.Checksums) > 0 {
+					if sum, ok := nf.Checksums[key]; !ok || sum != checksum(key, keyHash) {
+						return fmt.Errorf("%w: key %s on node %s", ErrChecksumMismatch, key, nf.ID)
+					}
+				}
+				if r.config.Checksums {
+					node.setChecksum(key, keyHash)
+				}
+				r.countKey(key, 1)
+			}
+		}
+		r.members[node.id] = node
+		r.stats.numNodes.add(1)
+	}
+
+	for _, sf := range file.Subrings {
+		subring := newRing(r.config, r, sf.ID, sf.Level, sf.MaxCount)
+		if err := subring.decode(sf); err != nil {
+			return err
+		}
+		r.members[subring.id] = subring
+	}
+
+	// Every vnode must belong to a member of the ring
+	for _, vNode := range vNodes {
+		if r.members[vNode.nodeID] == nil {
+			return fmt.Errorf("vnode %d of ring %s belongs to unknown member %s", vNode.hash, r.id, vNode.nodeID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package ringtree
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	rt := New(3, WithLoadFunc(func(key string, value []byte) int { return len(value) }))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	rt.Split("A")
+	var keys []string
+	for i := 0; i < 400; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		rt.InsertKeyValue(keys[i], []byte("xy"))
+	}
+	rt.PinKey("pinned", "B")
+	rt.SetNodeState("B", Draining)
+
+	var buf bytes.Buffer
+	if err := rt.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("expected snapshot to be written, got error: %v", err)
+	}
+	restored, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("expected snapshot to be read, got error: %v", err)
+	}
+
+	checkNum(restored.GetDepth(), 1, t)
+	checkNum(restored.Stats().Nodes(), rt.Stats().Nodes(), t)
+	checkNum(restored.Stats().Keys(), rt.Stats().Keys(), t)
+	checkNum(restored.MemberStats().Load, rt.MemberStats().Load, t)
+	for _, key := range keys {
+		want, _ := rt.Lookup(key)
+		if got, err := restored.Lookup(key); err != nil || got != want {
+			t.Errorf("expected key %s on %s, got %s (%v)", key, want, got, err)
+		}
+	}
+	if node, _ := restored.findMember("B"); node == nil || node.State() != Draining {
+		t.Errorf("expected B to be restored as draining")
+	}
+
+	// The restored tree keeps working
+	restored.InsertKey("pinned")
+	if owner, _ := restored.Lookup("pinned"); owner != "B" {
+		t.Errorf("expected pinned key on B, found on %s", owner)
+	}
+	if err := restored.RemoveKey(keys[0]); err != nil {
+		t.Errorf("expected key to be removed, got error: %v", err)
+	}
+}
+
+func TestSnapshotKeepsCapacitySchedule(t *testing.T) {
+	for _, opt := range []Option{WithCapacitySchedule([]int{2, 8}), WithBranchFactor(4)} {
+		rt := New(2, opt)
+		rt.InsertNode(NewNode("A", 1000))
+		rt.InsertNode(NewNode("B", 1000))
+
+		var buf bytes.Buffer
+		if err := rt.WriteSnapshot(&buf); err != nil {
+			t.Fatalf("expected snapshot to be written, got error: %v", err)
+		}
+		restored, err := ReadSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("expected snapshot to be read, got error: %v", err)
+		}
+
+		// A subring created after the restore is sized as in the tree that was written
+		want, err := rt.Split("A")
+		if err != nil {
+			t.Fatalf("expected A to be split, got error: %v", err)
+		}
+		got, err := restored.Split("A")
+		if err != nil {
+			t.Fatalf("expected restored A to be split, got error: %v", err)
+		}
+		checkNum(got.MaxCount(), want.MaxCount(), t)
+		checkNum(got.MaxCount(), 8, t)
+	}
+}
@@ -0,0 +1,258 @@
+package ringtree
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNodePinned is returned when removing or merging away a node that keys are pinned to.
+var ErrNodePinned = errors.New("keys are pinned to the node")
+
+// pin overrides the placement of one key.
+type pin struct {
+	target string // Member the key is placed on
+	sticky bool   // Set by PinKey and kept when the key is removed; MoveKey overrides end with the key
+}
+
+// pinTable holds the placement overrides of a tree. Keys pinned to a node are stored on that node; keys
+// pinned to a subring, such as a node that has since been split, are placed by hash within it.
+type pinTable struct {
+	pins    map[string]pin
+	targets map[string]int // Number of keys pinned to each member
+	sync.RWMutex
+}
+
+func newPinTable() *pinTable {
+	return &pinTable{pins: make(map[string]pin), targets: make(map[string]int)}
+}
+
+// get returns a key's pin.
+func (t *pinTable) get(key string) (pin, bool) {
+	t.RLock()
+	defer t.RUnlock()
+	p, ok := t.pins[key]
+	return p, ok
+}
+
+// set pins a key to a member, replacing any previous pin.
+func (t *pinTable) set(key, target string, sticky bool) {
+	t.Lock()
+	defer t.Unlock()
+	if p, ok := t.pins[key]; ok {
+		t.targets[p.target]--
+	}
+	t.pins[key] = pin{target: target, sticky: sticky}
+	t.targets[target]++
+}
+
+// drop removes a key's pin. Unless all is set, only overrides made by MoveKey are removed.
+func (t *pinTable) drop(key string, all bool) {
+	t.Lock()
+	defer t.Unlock()
+	p, ok := t.pins[key]
+	if !ok || (p.sticky && !all) {
+		return
+	}
+	delete(t.pins, key)
+	if t.targets[p.target]--; t.targets[p.target] <= 0 {
+		delete(t.targets, p.target)
+	}
+}
+
+// targeted reports whether any key is pinned to the member.
+func (t *pinTable) targeted(id string) bool {
+	t.RLock()
+	defer t.RUnlock()
+	return t.targets[id] > 0
+}
+
+// PinKey places a key on a node, or within a subring, regardless of its hash. The pin applies to the key if
+// it is already in the tree and to every later insert of it, survives remaps and splits, and lasts until
+// UnpinKey. Pinned keys are not counted against their node's threshold.
+func (r *Ring) PinKey(key, nodeID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.pinKeyOp(key, nodeID)
+}
+
+// pinKeyOp pins a key as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) pinKeyOp(key, nodeID string) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpPinKey, Key: key, Node: nodeID}, r.relocateKey(key, func() error {
+		if member, _ := r.root().findTarget(nodeID); member == nil {
+			return ErrNodeNotFound
+		}
+		r.pins.set(key, nodeID, true)
+		return nil
+	}, false))
+}
+
+// MoveKey moves a key that is already in the tree onto a node, regardless of its hash. Like a pin, the
+// override survives remaps and splits; it ends when the key is removed.
+func (r *Ring) MoveKey(key, targetNodeID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.moveKeyOp(key, targetNodeID)
+}
+
+// moveKeyOp moves a key as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) moveKeyOp(key, targetNodeID string) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpMoveKey, Key: key, Node: targetNodeID}, r.relocateKey(key, func() error {
+		member, _ := r.root().findTarget(targetNodeID)
+		if _, ok := member.(*Node); !ok {
+			return ErrNodeNotFound
+		}
+		if p, pinned := r.pins.get(key); pinned && p.sticky && p.target != targetNodeID {
+			return errors.New("key is pinned to another node")
+		}
+		r.pins.set(key, targetNodeID, false)
+		return nil
+	}, true))
+}
+
+// UnpinKey removes a key's pin or MoveKey override and returns the key to its hashed placement.
+func (r *Ring) UnpinKey(key string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.unpinKeyOp(key)
+}
+
+// unpinKeyOp unpins a key as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) unpinKeyOp(key string) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpUnpinKey, Key: key}, r.relocateKey(key, func() error {
+		if _, pinned := r.pins.get(key); !pinned {
+			return errors.New("key is not pinned")
+		}
+		r.pins.drop(key, true)
+		return nil
+	}, false))
+}
+
+// relocateKey takes a key out of the tree, applies a change to its placement and reinserts it. For a key not
+// in the tree only the change is applied, unless required is set, in which case ErrKeyNotFound is returned
+// (assuming the tree's writer lock is held).
+func (r *Ring) relocateKey(key string, change func() error, required bool) error {
+	r.settleKey(key)
+	node, parent, vNodeHash, err := r.locateKey(key)
+	var custom customRoute
+	if err != nil && !errors.As(err, &custom) {
+		return err
+	}
+
+	held := false
+	if node != nil {
+		parent.RLock()
+		_, held = node.keys[vNodeHash][key]
+		parent.RUnlock()
+	}
+	if !held {
+		if required {
+			return ErrKeyNotFound
+		}
+		return change()
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	r.wal.applied = true // The placement changed even if reinserting the key fails
+
+	parent.Lock()
+	delete(node.writable(vNodeHash), key)
+	delete(node.checksums, key)
+	cost := node.clearCost(key)
+	r.countKey(key, -1)
+	r.stats.remapped.add(1)
+	parent.Unlock()
+	return r.root().insertKey(key, cost, true)
+}
+
+// pinnedRoute finds where a pinned key is placed. Only the ring's own subtree is searched, since a key routed
+// into a subring during a split, collapse or removal must stay within it. It reports false when the key is
+// not pinned or its member is not in the subtree, in which case the key is placed by hash.
+func (r *Ring) pinnedRoute(key string, write bool) (*Node, *Ring, uint32, uint32, error, bool) {
+	p, ok := r.pins.get(key)
+	if !ok {
+		return nil, nil, 0, 0, nil, false
+	}
+	var member Member = r
+	holder := r.parent
+	if r.id != p.target {
+		member, holder = r.findTarget(p.target)
+	}
+	switch member := member.(type) {
+	case *Node:
+		holder.RLock()
+		defer holder.RUnlock()
+		keyHash := r.config.keyHash(key, holder.level)
+		return member, holder, member.pinnedVNode(key), keyHash, nil, true
+	case *Ring:
+		node, parent, vNodeHash, keyHash, err := member.routeKey(key, write)
+		return node, parent, vNodeHash, keyHash, err, true
+	default:
+		return nil, nil, 0, 0, nil, false
+	}
+}
+
+// findTarget searches the tree for the node or subring with the given ID and the ring that holds it. Each
+// ring's lock is released before its subrings are searched.
+func (r *Ring) findTarget(id string) (Member, *Ring) {
+	r.RLock()
+	switch member := r.members[id].(type) {
+	case *Node, *Ring:
+		r.RUnlock()
+		return member, r
+	}
+	var subrings []*Ring
+	for _, member := range r.members {
+		if subring, ok := member.(*Ring); ok {
+			subrings = append(subrings, subring)
+		}
+	}
+	r.RUnlock()
+
+	for _, subring := range subrings {
+		if member, ring := subring.findTarget(id); member != nil {
+			return member, ring
+		}
+	}
+	return nil, nil
+}
+
+// pinnedVNode returns the vnode of the node holding a pinned key, or the node's lowest vnode for a key it
+// does not hold yet (assuming the ring's mutex is already locked).
+func (n *Node) pinnedVNode(key string) uint32 {
+	var lowest uint32
+	first := true
+	for vNodeHash, keyHashMap := range n.keys {
+		if _, ok := keyHashMap[key]; ok {
+			return vNodeHash
+		}
+		if first || vNodeHash < lowest {
+			lowest, first = vNodeHash, false
+		}
+	}
+	return lowest
+}
+
+// pinAllows reports whether a remap may move a key onto a node of ring: either the key is not pinned, or
+// the node lies within the member it is pinned to.
+func (r *Ring) pinAllows(key string, ring *Ring, node *Node) bool {
+	p, ok := r.pins.get(key)
+	if !ok || node.id == p.target {
+		return true
+	}
+	for ; ring != nil; ring = ring.parent {
+		if ring.id == p.target {
+			return true
+		}
+	}
+	return false
+}
+
+// pinnedTo reports whether a key is pinned to the node.
+func (r *Ring) pinnedTo(key string, node *Node) bool {
+	p, ok := r.pins.get(key)
+	return ok && p.target == node.id
+}
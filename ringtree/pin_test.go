@@ -0,0 +1,138 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+// foundWithin reports whether a key is found on the member with the given ID or on a node nested below it.
+func foundWithin(t *testing.T, rt *Ring, key, id string) bool {
+	owner, err := rt.Lookup(key)
+	if err != nil {
+		t.Fatalf("expected key %s to be found, got error: %v", key, err)
+	}
+	if owner == id {
+		return true
+	}
+	for _, ring := rt.findMember(owner); ring != nil; ring = ring.parent {
+		if ring.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPinKeySurvivesRemapsAndSplits(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 20))
+	rt.InsertNode(NewNode("B", 20))
+
+	// Pin a key to the node that does not own it by hash
+	key := "pinned"
+	rt.InsertKey(key)
+	owner, _ := rt.Lookup(key)
+	target := "A"
+	if owner == "A" {
+		target = "B"
+	}
+	if err := rt.PinKey(key, target); err != nil {
+		t.Fatalf("expected key to be pinned, got error: %v", err)
+	}
+	if owner, _ := rt.Lookup(key); owner != target {
+		t.Fatalf("expected pinned key on %s, found on %s", target, owner)
+	}
+
+	// Joins remap keys and further inserts split the pinned node
+	rt.InsertNode(NewNode("C", 20))
+	rt.InsertNode(NewNode("D", 20))
+	for i := 0; i < 300; i++ {
+		if err := rt.InsertKey("key-" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("expected key to be inserted, got error: %v", err)
+		}
+	}
+
+	if !foundWithin(t, rt, key, target) {
+		t.Errorf("expected pinned key within %s", target)
+	}
+	checkNum(rt.MemberStats().Keys, 301, t)
+}
+
+func TestPinKeyBeforeInsert(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	for _, target := range []string{"A", "B"} {
+		key := "future-" + target
+		if err := rt.PinKey(key, target); err != nil {
+			t.Fatalf("expected key to be pinned, got error: %v", err)
+		}
+		rt.InsertKey(key)
+		if owner, _ := rt.Lookup(key); owner != target {
+			t.Errorf("expected key %s on %s, found on %s", key, target, owner)
+		}
+	}
+
+	if err := rt.PinKey("other", "missing"); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestMoveKey(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	if err := rt.MoveKey("missing", "A"); err != ErrKeyNotFound {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	key := "moved"
+	rt.InsertKey(key)
+	hashed, _ := rt.Lookup(key)
+	target := "A"
+	if hashed == "A" {
+		target = "B"
+	}
+	if err := rt.MoveKey(key, target); err != nil {
+		t.Fatalf("expected key to be moved, got error: %v", err)
+	}
+	if owner, _ := rt.Lookup(key); owner != target {
+		t.Errorf("expected moved key on %s, found on %s", target, owner)
+	}
+
+	// The override ends with the key
+	if err := rt.RemoveKey(key); err != nil {
+		t.Fatalf("expected key to be removed, got error: %v", err)
+	}
+	rt.InsertKey(key)
+	if owner, _ := rt.Lookup(key); owner != hashed {
+		t.Errorf("expected reinserted key on %s, found on %s", hashed, owner)
+	}
+	checkNum(rt.Stats().Keys(), 1, t)
+}
+
+func TestPinnedNodeRemoval(t *testing.T) {
+	rt := New(4)
+	nodeA := NewNode("A", 100)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 100))
+	rt.InsertNode(NewNode("C", 100))
+
+	key := "pinned"
+	rt.InsertKey(key)
+	rt.PinKey(key, "A")
+	if err := rt.RemoveNode(nodeA); err != ErrNodePinned {
+		t.Fatalf("expected ErrNodePinned, got %v", err)
+	}
+
+	if err := rt.UnpinKey(key); err != nil {
+		t.Fatalf("expected key to be unpinned, got error: %v", err)
+	}
+	if err := rt.RemoveNode(nodeA); err != nil {
+		t.Fatalf("expected node to be removed, got error: %v", err)
+	}
+	if owner, _ := rt.Lookup(key); owner == "A" || owner == "" {
+		t.Errorf("expected key on a remaining node, found on %q", owner)
+	}
+}
@@ -0,0 +1,212 @@
+package ringtree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/kagwave/ring-tree/ringtree/workload"
+)
+
+// ScalingPlan is a sequence of node counts. The first entry is the starting size and every later entry is
+// the size after one step.
+type ScalingPlan []int
+
+// GrowthPlan returns a plan growing (or shrinking) linearly from `from` to `to` nodes over the given number
+// of steps.
+func GrowthPlan(from, to, steps int) ScalingPlan {
+	if steps < 1 {
+		steps = 1
+	}
+	plan := ScalingPlan{from}
+	for i := 1; i <= steps; i++ {
+		plan = append(plan, from+(to-from)*i/steps)
+	}
+	return plan
+}
+
+// StepReport records the keys moved by one step of a scaling plan.
+type StepReport struct {
+	Nodes     int // Node count after the step
+	TreeMoved int // Keys whose owner changed in the ring tree during the step
+	FlatMoved int // Keys whose owner changed on the flat ring during the step
+	TreeTotal int // Keys moved in the ring tree so far
+	FlatTotal int // Keys moved on the flat ring so far
+}
+
+// StabilityReport compares key ownership churn between a ring tree and a flat ring across a scaling plan.
+type StabilityReport struct {
+	Keys       int
+	Steps      []StepReport
+	TreeByNode map[string]int // Keys each ring-tree node received over the plan
+	FlatByNode map[string]int // Keys each flat-ring node received over the plan
+}
+
+// SimulateScalingPlan inserts numKeys random keys into a ring tree whose root holds maxCount members and
+// into a flat ring, then applies the plan to both and reports the keys moved at each step and per node.
+// The ring tree grows onto the shallowest ring with a free slot, splitting its most loaded node when every
+// ring is full, and both shrink by removing their least loaded node. Keys are drawn from the source set with
+// WithRandSource, so a seeded plan reports the same moves on every run.
+func SimulateScalingPlan(plan ScalingPlan, numKeys, maxCount int, opts ...Option) (*StabilityReport, error) {
+	if len(plan) == 0 || plan[0] < 1 {
+		return nil, fmt.Errorf("scaling plan must start with at least one node")
+	}
+	largest := 0
+	for _, n := range plan {
+		if n > largest {
+			largest = n
+		}
+	}
+
+	// Thresholds are high enough that only the plan, not key load, changes membership
+	tree := New(maxCount, opts...)
+	flat := New(largest, opts...)
+	var rng *rand.Rand
+	if src := tree.config.RandSource; src != nil {
+		rng = rand.New(rand.NewSource(src.Int63())) // Seeded before any split draws node IDs from the source
+	}
+	gen := workload.Uniform(rng, 20)
+	next := 0
+	grow := func() error {
+		id := "node" + strconv.Itoa(next)
+		next++
+		if err := growTree(tree, id, numKeys); err != nil {
+			return err
+		}
+		return flat.InsertNode(NewNode(id, numKeys))
+	}
+	for i := 0; i < plan[0]; i++ {
+		if err := grow(); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := make([]string, numKeys)
+	for i := range keys {
+		keys[i] = gen.Next()
+		if err := tree.InsertKey(keys[i]); err != nil {
+			return nil, err
+		}
+		if err := flat.InsertKey(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &StabilityReport{Keys: numKeys, TreeByNode: make(map[string]int), FlatByNode: make(map[string]int)}
+	treeOwners, flatOwners := owners(tree, keys), owners(flat, keys)
+	var treeTotal, flatTotal int
+	for _, target := range plan[1:] {
+		for flat.Size() < target {
+			if err := grow(); err != nil {
+				return nil, err
+			}
+		}
+		for flat.Size() > target && flat.Size() > 1 {
+			if err := shrink(tree); err != nil {
+				return nil, err
+			}
+			if err := shrink(flat); err != nil {
+				return nil, err
+			}
+		}
+
+		treeAfter, flatAfter := owners(tree, keys), owners(flat, keys)
+		treeMoved := moved(treeOwners, treeAfter, report.TreeByNode)
+		flatMoved := moved(flatOwners, flatAfter, report.FlatByNode)
+		treeOwners, flatOwners = treeAfter, flatAfter
+		treeTotal += treeMoved
+		flatTotal += flatMoved
+		report.Steps = append(report.Steps, StepReport{
+			Nodes:     flat.Size(),
+			TreeMoved: treeMoved,
+			FlatMoved: flatMoved,
+			TreeTotal: treeTotal,
+			FlatTotal: flatTotal,
+		})
+	}
+	return report, nil
+}
+
+// growTree adds a physical node to the shallowest ring with a free slot, or splits the most loaded node if
+// every ring is full.
+func growTree(rt *Ring, id string, threshold int) error {
+	rt.writer.Lock()
+	defer rt.writer.Unlock()
+	var heaviest *Node
+	var heaviestRing *Ring
+	queue := []*Ring{rt}
+	for len(queue) > 0 {
+		ring := queue[0]
+		queue = queue[1:]
+		if len(ring.members) < ring.maxCount {
+			return ring.insertNode(NewNode(id, threshold))
+		}
+		// Visited in ID order, so ties between equally loaded nodes and full rings break the same way each run
+		ids := make([]string, 0, len(ring.members))
+		for id := range ring.members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			switch member := ring.members[id].(type) {
+			case *Node:
+				if heaviest == nil || member.load > heaviest.load {
+					heaviest, heaviestRing = member, ring
+				}
+			case *Ring:
+				queue = append(queue, member)
+			}
+		}
+	}
+	if heaviest == nil {
+		return ErrRingAtCapacity
+	}
+	_, err := heaviestRing.splitNode(heaviest, 0)
+	return err
+}
+
+// shrink removes the least loaded physical node in the tree.
+func shrink(rt *Ring) error {
+	var lightest *Node
+	var lightestRing *Ring
+	var visit func(ring *Ring)
+	visit = func(ring *Ring) {
+		for _, member := range ring.members {
+			switch member := member.(type) {
+			case *Node:
+				if lightest == nil || member.load < lightest.load {
+					lightest, lightestRing = member, ring
+				}
+			case *Ring:
+				visit(member)
+			}
+		}
+	}
+	visit(rt)
+	if lightest == nil {
+		return ErrRingEmpty
+	}
+	return lightestRing.RemoveNode(lightest)
+}
+
+// owners returns the node each key is found on.
+func owners(rt *Ring, keys []string) map[string]string {
+	owner := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owner[key], _ = rt.Lookup(key)
+	}
+	return owner
+}
+
+// moved counts the keys whose owner changed, crediting each move to the receiving node.
+func moved(before, after map[string]string, byNode map[string]int) int {
+	count := 0
+	for key, owner := range after {
+		if before[key] != owner {
+			count++
+			byNode[owner]++
+		}
+	}
+	return count
+}
@@ -0,0 +1,62 @@
+package ringtree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestGrowthPlan(t *testing.T) {
+	plan := GrowthPlan(10, 50, 5)
+	expected := []int{10, 18, 26, 34, 42, 50}
+	checkNum(len(plan), len(expected), t)
+	for i := range expected {
+		checkNum(plan[i], expected[i], t)
+	}
+}
+
+func TestSimulateScalingPlan(t *testing.T) {
+	plan := ScalingPlan{4, 8, 12, 8}
+	report, err := SimulateScalingPlan(plan, 2000, 4)
+	if err != nil {
+		t.Fatalf("expected the plan to be simulated, got error: %v", err)
+	}
+	checkNum(len(report.Steps), 3, t)
+
+	treeTotal, flatTotal := 0, 0
+	for i, step := range report.Steps {
+		checkNum(step.Nodes, plan[i+1], t)
+		if step.TreeMoved == 0 || step.FlatMoved == 0 {
+			t.Errorf("expected keys to move at step %d", i+1)
+		}
+		treeTotal += step.TreeMoved
+		flatTotal += step.FlatMoved
+		checkNum(step.TreeTotal, treeTotal, t)
+		checkNum(step.FlatTotal, flatTotal, t)
+	}
+
+	received := 0
+	for _, n := range report.TreeByNode {
+		received += n
+	}
+	checkNum(received, treeTotal, t)
+}
+
+func TestSimulateScalingPlanSeeded(t *testing.T) {
+	plan := ScalingPlan{4, 8, 12, 8}
+	run := func(seed int64) *StabilityReport {
+		report, err := SimulateScalingPlan(plan, 2000, 4, WithRandSource(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("expected the plan to be simulated, got error: %v", err)
+		}
+		return report
+	}
+
+	first, second := run(1), run(1)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same seed to give the same report, got %+v and %+v", first.Steps, second.Steps)
+	}
+	if reflect.DeepEqual(first, run(2)) {
+		t.Errorf("expected another seed to draw other keys")
+	}
+}
@@ -0,0 +1,146 @@
+package ringtree
+
+import (
+	"strconv"
+	"time"
+)
+
+// SplitPolicy decides how an overloaded node on a full ring is turned into a subring. Split is called with
+// the tree's writer lock held and no ring locked, and returns the subring it created. Custom policies can
+// delegate to the built-in ones.
+type SplitPolicy interface {
+	Split(parent *Ring, node *Node) (*Ring, error)
+}
+
+// SeedSplit replaces the whole node with a subring seeded with that many nodes, each with the node's
+// threshold as scaled by the configured ThresholdScale, and reinserts all of the node's keys into it.
+type SeedSplit int
+
+// TwoNodeSplit seeds every subring with two nodes.
+const TwoNodeSplit SeedSplit = 2
+
+// Split implements SplitPolicy.
+func (s SeedSplit) Split(parent *Ring, node *Node) (*Ring, error) {
+	return parent.replaceNode(node, func(subring *Ring) (int, int) {
+		seed := int(s)
+		if seed < 2 {
+			seed = 2
+		}
+		if seed > subring.maxCount {
+			seed = subring.maxCount
+		}
+		return seed, subring.config.childThreshold(subring.level, node.threshold)
+	})
+}
+
+// HotVNodeSplit moves only the node's most loaded vnode into a new subring seeded with Seed nodes (the
+// configured seed count if unset), leaving the node's other arcs and keys in place. A node with a single
+// vnode is split whole.
+type HotVNodeSplit struct {
+	Seed int
+}
+
+// Split implements SplitPolicy.
+func (h HotVNodeSplit) Split(parent *Ring, node *Node) (*Ring, error) {
+	seed := h.Seed
+	if seed < 2 {
+		seed = parent.config.seedNodes()
+	}
+	if len(node.keys) < 2 {
+		return SeedSplit(seed).Split(parent, node)
+	}
+	return parent.splitVNode(node, seed)
+}
+
+// WithSplitPolicy sets the split policy of every ring in the tree. Rings can override it with
+// SetSplitPolicy. Without a policy, nodes are replaced by a subring sized for their load.
+func WithSplitPolicy(policy SplitPolicy) Option {
+	return func(c *Config) {
+		c.SplitPolicy = policy
+	}
+}
+
+// SetSplitPolicy sets the split policy of this ring only. A nil policy falls back to the tree's.
+func (r *Ring) SetSplitPolicy(policy SplitPolicy) {
+	r.Lock()
+	defer r.Unlock()
+	r.policy = policy
+}
+
+// splitPolicy returns the split policy in effect on this ring, or nil for the default sized split.
+func (r *Ring) splitPolicy() SplitPolicy {
+	r.RLock()
+	defer r.RUnlock()
+	if r.policy != nil {
+		return r.policy
+	}
+	return r.config.SplitPolicy
+}
+
+// splitVNode hands the node's most loaded vnode to a new subring seeded with the given number of nodes and
+// moves that vnode's keys into it. Keys pinned to the node stay on one of its other vnodes.
+func (r *Ring) splitVNode(node *Node, seed int) (*Ring, error) {
+	defer r.timeTrack(time.Now(), "splitVNode", "to create a subring for a vnode")
+	r.hub.begin()
+	defer r.hub.end()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	// Find the vnode carrying the most load
+	var hot uint32
+	hotLoad := -1
+	for vNodeHash, keys := range node.keys {
+		load := 0
+		for key := range keys {
+			load += node.cost(key)
+		}
+		if load > hotLoad || (load == hotLoad && vNodeHash < hot) {
+			hot, hotLoad = vNodeHash, load
+		}
+	}
+
+	// A corrupted key aborts the split before the vnode changes hands
+	for key, keyHash := range node.keys[hot] {
+		if !r.verifyKey(node, key, keyHash) {
+			return nil, ErrChecksumMismatch
+		}
+	}
+
+	// The subring takes over the vnode's position on the circle
+	id := node.id + "/" + strconv.FormatUint(uint64(hot), 10)
+	subring := newRing(r.config, r, id, r.level+1, r.config.capacity(r.level+1, r.maxCount))
+	r.members[id] = subring
+	r.circle.Delete(hot)
+	r.circle.Insert(hot, id)
+	r.circle.Sort()
+	keys := node.keys[hot]
+	delete(node.keys, hot)
+	r.logf("Created subring %s at level %d for vnode %d of node %s.\n", id, r.level+1, hot, node.id)
+
+	if seed > subring.maxCount {
+		seed = subring.maxCount
+	}
+	for i := 0; i < seed; i++ {
+		if err := subring.insertNode(r.seedNode(node, r.config.childThreshold(subring.level, node.threshold))); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, keyHash := range keys {
+		if r.pinnedTo(key, node) {
+			node.writable(node.pinnedVNode(key))[key] = keyHash
+			continue
+		}
+		r.countKey(key, -1)
+		delete(node.checksums, key)
+		if err := subring.insertKey(key, node.clearCost(key), true); err != nil {
+			return nil, err
+		}
+	}
+
+	r.stats.recordSplit()
+	r.emit(Event{Type: SubringCreated, RingID: r.id, NodeID: id, Level: r.level + 1, Remapped: r.stats.remapped.get()})
+	r.stats.calculateRemapComplexity()
+	return subring, nil
+}
@@ -0,0 +1,62 @@
+package ringtree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeedSplitPolicy(t *testing.T) {
+	rt := New(2, WithSplitPolicy(SeedSplit(4)), WithCapacitySchedule([]int{2, 8}))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	keys := fillKeys(t, rt, 100)
+
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+	checkNum(len(subring.members), 4, t)
+	for _, member := range subring.members {
+		checkNum(member.(*Node).threshold, 100, t)
+	}
+
+	// A ring's own policy takes precedence over the tree's
+	rt.SetSplitPolicy(TwoNodeSplit)
+	subring, err = rt.Split("B")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+	checkNum(len(subring.members), 2, t)
+	checkNum(len(rt.LookupMany(keys)), len(keys), t)
+}
+
+func TestHotVNodeSplitPolicy(t *testing.T) {
+	rt := New(2, WithSplitPolicy(HotVNodeSplit{}))
+	nodeA := NewNode("A", 100)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("B", 100))
+	keys := fillKeys(t, rt, 100)
+	vNodes, load := len(nodeA.keys), nodeA.load
+
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+
+	// Only one vnode moves; A keeps its other arcs and stays a node
+	if rt.members["A"] != nodeA || !strings.HasPrefix(subring.id, "A/") {
+		t.Fatalf("expected node A to remain beside subring %s", subring.id)
+	}
+	checkNum(len(nodeA.keys), vNodes-1, t)
+	checkNum(len(subring.members), 2, t)
+	checkNum(nodeA.load+subring.MemberStats().Load, load, t)
+	checkNum(rt.Stats().Keys(), len(keys), t)
+	checkNum(len(rt.LookupMany(keys)), len(keys), t)
+
+	// Collapsing the subring returns its vnode to the circle as a node
+	if _, err := rt.Collapse(subring.id); err != nil {
+		t.Fatalf("expected subring to be collapsed, got error: %v", err)
+	}
+	checkNum(rt.circle.Size(), 2*rt.config.Replicas, t)
+	checkNum(len(rt.LookupMany(keys)), len(keys), t)
+}
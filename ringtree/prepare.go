@@ -0,0 +1,138 @@
+package ringtree
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrPendingClosed is returned when a prepared node addition is committed or aborted a second time.
+var ErrPendingClosed = errors.New("node addition was already committed or aborted")
+
+// Pending is a node addition prepared by PrepareInsertNode. Its vnode positions and a slot on the ring are
+// reserved until it is committed or aborted; routing is unchanged until Commit.
+type Pending struct {
+	ring   *Ring
+	node   *Node
+	vNodes []VNode
+	keys   []string
+	closed bool
+	mu     sync.Mutex
+}
+
+// PrepareInsertNode reserves a slot and the vnode positions of a node on the ring and returns the keys the
+// node will take over, so their data can be copied before Commit flips routing to the node. Keys inserted
+// into the node's arcs after the prepare also move on Commit.
+func (r *Ring) PrepareInsertNode(node *Node) (*Pending, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.settleRemaps()
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.members)+len(r.reserved) >= r.maxCount {
+		return nil, ErrRingAtCapacity
+	}
+	if r.members[node.id] != nil || r.reserved[node.id] != nil {
+		return nil, ErrNodeExists
+	}
+
+	// Route every key on the ring against the circle as it will be with the node's vnodes
+	vNodes := r.config.vNodes(node.id)
+	future := &circleSnapshot{vNodes: append(circleVNodes(r.circle), vNodes...)}
+	sort.Slice(future.vNodes, func(i, j int) bool {
+		return future.vNodes[i].hash < future.vNodes[j].hash
+	})
+	var keys []string
+	takes := func(key string, keyHash uint32) {
+		if _, owner := future.find(keyHash); owner == node.id && r.pinAllows(key, r, node) {
+			keys = append(keys, key)
+		}
+	}
+	for _, member := range r.members {
+		switch member := member.(type) {
+		case *Node:
+			for _, keyHashMap := range member.keys {
+				for key, keyHash := range keyHashMap {
+					takes(key, keyHash)
+				}
+			}
+		case *Ring:
+			member.Lock()
+			member.forEachNode(func(n *Node) {
+				for _, keyHashMap := range n.keys {
+					for key := range keyHashMap {
+						takes(key, r.config.keyHash(key, r.level))
+					}
+				}
+			})
+			member.Unlock()
+		}
+	}
+	sort.Strings(keys)
+
+	p := &Pending{ring: r, node: node, vNodes: vNodes, keys: keys}
+	if r.reserved == nil {
+		r.reserved = make(map[string]*Pending)
+	}
+	r.reserved[node.id] = p
+	r.logf("Prepared node %s on ring %s, taking over %d keys.\n", node.id, r.id, len(keys))
+	return p, nil
+}
+
+// Node returns the node being added.
+func (p *Pending) Node() *Node {
+	return p.node
+}
+
+// VNodes returns the vnode positions reserved for the node, in hash order.
+func (p *Pending) VNodes() []VNode {
+	vNodes := append([]VNode(nil), p.vNodes...)
+	sort.Slice(vNodes, func(i, j int) bool {
+		return vNodes[i].hash < vNodes[j].hash
+	})
+	return vNodes
+}
+
+// Keys returns, in sorted order, the keys the node takes over on Commit as of the prepare.
+func (p *Pending) Keys() []string {
+	return append([]string(nil), p.keys...)
+}
+
+// Commit adds the node to the ring, releasing its reservation and moving the keys in its arcs onto it.
+func (p *Pending) Commit() error {
+	if err := p.close(); err != nil {
+		return err
+	}
+	r := p.ring
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.Lock()
+	delete(r.reserved, p.node.id)
+	r.Unlock()
+	return r.insertNodeOp(p.node)
+}
+
+// Abort releases the node's reservation without changing the ring.
+func (p *Pending) Abort() error {
+	if err := p.close(); err != nil {
+		return err
+	}
+	r := p.ring
+	r.Lock()
+	delete(r.reserved, p.node.id)
+	r.Unlock()
+	r.logf("Aborted adding node %s to ring %s.\n", p.node.id, r.id)
+	return nil
+}
+
+// close marks the addition as finished, failing if it already was.
+func (p *Pending) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrPendingClosed
+	}
+	p.closed = true
+	return nil
+}
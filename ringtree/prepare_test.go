@@ -0,0 +1,70 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPrepareInsertNodeCommit(t *testing.T) {
+	rt := New(3)
+	rt.InsertNode(NewNode("A", 1000))
+	for i := 0; i < 300; i++ {
+		rt.InsertKey("key-" + strconv.Itoa(i))
+	}
+
+	pending, err := rt.PrepareInsertNode(NewNode("B", 1000))
+	if err != nil {
+		t.Fatalf("expected node to be prepared, got error: %v", err)
+	}
+	keys := pending.Keys()
+	if len(keys) == 0 {
+		t.Fatalf("expected the prepared node to take over keys")
+	}
+
+	// Routing is unchanged and the slot is held until the commit
+	for _, key := range keys {
+		if owner, _ := rt.Lookup(key); owner != "A" {
+			t.Errorf("expected key %s to stay on A before commit, found on %s", key, owner)
+		}
+	}
+	if err := rt.InsertNode(NewNode("B", 1000)); err != ErrNodeExists {
+		t.Errorf("expected ErrNodeExists for a prepared node, got %v", err)
+	}
+	rt.InsertNode(NewNode("C", 1000))
+	if err := rt.InsertNode(NewNode("D", 1000)); err != ErrRingAtCapacity {
+		t.Errorf("expected ErrRingAtCapacity with a slot reserved, got %v", err)
+	}
+	rt.RemoveNode(rt.members["C"].(*Node))
+
+	if err := pending.Commit(); err != nil {
+		t.Fatalf("expected commit to succeed, got error: %v", err)
+	}
+	moved, _ := rt.KeysForNode("B")
+	if len(moved) != len(keys) {
+		t.Fatalf("expected B to hold the %d prepared keys, got %d", len(keys), len(moved))
+	}
+	for i := range keys {
+		if moved[i] != keys[i] {
+			t.Errorf("expected key %s on B, got %s", keys[i], moved[i])
+		}
+	}
+	if err := pending.Commit(); err != ErrPendingClosed {
+		t.Errorf("expected ErrPendingClosed on a second commit, got %v", err)
+	}
+}
+
+func TestPrepareInsertNodeAbort(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 1000))
+	pending, err := rt.PrepareInsertNode(NewNode("B", 1000))
+	if err != nil {
+		t.Fatalf("expected node to be prepared, got error: %v", err)
+	}
+	if err := pending.Abort(); err != nil {
+		t.Fatalf("expected abort to succeed, got error: %v", err)
+	}
+	if err := rt.InsertNode(NewNode("C", 1000)); err != nil {
+		t.Errorf("expected the released slot to be free, got error: %v", err)
+	}
+	checkNum(rt.Size(), 2, t)
+}
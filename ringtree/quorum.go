@@ -0,0 +1,54 @@
+package ringtree
+
+import "fmt"
+
+// Observer is a process that can independently confirm whether a node is down.
+type Observer interface {
+	ConfirmDown(nodeID string) bool
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(nodeID string) bool
+
+// ConfirmDown calls f(nodeID).
+func (f ObserverFunc) ConfirmDown(nodeID string) bool {
+	return f(nodeID)
+}
+
+// AutoRemoveNode removes a node reported as failed. When a removal quorum is configured, the node is only
+// marked Down and removed once enough observers confirm the failure, so a single partitioned process cannot
+// dismantle the topology.
+func (r *Ring) AutoRemoveNode(nodeID string) error {
+	if confirmed, needed := r.confirmDown(nodeID); confirmed < needed {
+		r.logf("Node %s removal confirmed by %d of %d required observers.\n", nodeID, confirmed, needed)
+		return fmt.Errorf("%w: %d of %d observers confirmed", ErrQuorumNotReached, confirmed, needed)
+	}
+
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	if err := r.setNodeStateOp(nodeID, Down); err != nil {
+		return err
+	}
+	r.beginOp()
+	return r.logOp(Op{Type: OpRemoveNode, Node: nodeID}, ring.removeNode(node))
+}
+
+// confirmDown asks every configured observer about a node and returns the confirmations and the quorum. A
+// quorum larger than the observers can give is never reached.
+func (r *Ring) confirmDown(nodeID string) (int, int) {
+	needed := r.config.Quorum
+	if needed <= 0 {
+		return 0, 0
+	}
+	confirmed := 0
+	for _, observer := range r.config.Observers {
+		if observer.ConfirmDown(nodeID) {
+			confirmed++
+		}
+	}
+	return confirmed, needed
+}
@@ -0,0 +1,42 @@
+package ringtree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAutoRemoveNodeRequiresQuorum(t *testing.T) {
+	votes := map[string]bool{}
+	observer := func(name string) Observer {
+		return ObserverFunc(func(nodeID string) bool { return votes[name] })
+	}
+	rt := New(5, WithRemovalQuorum(2, observer("a"), observer("b"), observer("c")))
+	nodeA := NewNode("", 100)
+	nodeB := NewNode("", 100)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(nodeB)
+
+	votes["a"] = true
+	if err := rt.AutoRemoveNode(nodeB.id); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatalf("expected quorum error, got %v", err)
+	}
+	checkNum(rt.Size(), 2, t)
+
+	votes["c"] = true
+	if err := rt.AutoRemoveNode(nodeB.id); err != nil {
+		t.Fatalf("expected node to be removed once quorum is reached, got error: %v", err)
+	}
+	checkNum(rt.Size(), 1, t)
+}
+
+func TestAutoRemoveNodeWithoutObservers(t *testing.T) {
+	rt := New(5, WithRemovalQuorum(2))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	// No observer can confirm the failure, so the quorum is never reached
+	if err := rt.AutoRemoveNode("B"); !errors.Is(err, ErrQuorumNotReached) {
+		t.Fatalf("expected quorum error without observers, got %v", err)
+	}
+	checkNum(rt.Size(), 2, t)
+}
@@ -1,5 +1,7 @@
 package ringtree
 
+import "fmt"
+
 // redBlackTree is an implementation of a Red-Black Tree
 type redBlackTree struct {
 	root *redBlackNode
@@ -37,6 +39,17 @@ func findMin(h *redBlackNode) *redBlackNode {
 	return h
 }
 
+// findMax returns the node with the maximum key in the subtree rooted at h.
+func findMax(h *redBlackNode) *redBlackNode {
+	if h == nil {
+		return nil
+	}
+	for h.right != nil {
+		h = h.right
+	}
+	return h
+}
+
 // Inserts a value into the tree with a given key key.
 // Returns true on successful insertion, false if duplicate exists.
 func (t *redBlackTree) Insert(key uint32, value string) (ret bool) {
@@ -260,6 +273,51 @@ func (t *redBlackTree) FindNextClosest(key uint32) (uint32, string) {
 	return nextNode.key, nextNode.value
 }
 
+// FindPrevClosest finds the closest node strictly less than the key, wrapping around to the largest key.
+func (t *redBlackTree) FindPrevClosest(key uint32) (uint32, string) {
+	if t.root == nil {
+		return 0, ""
+	}
+
+	var prevNode *redBlackNode
+	currentNode := t.root
+	for currentNode != nil {
+		if key > currentNode.key {
+			// Candidate for the previous closest
+			prevNode = currentNode
+			currentNode = currentNode.right
+		} else {
+			currentNode = currentNode.left
+		}
+	}
+
+	// If no smaller key is found, wrap around to the largest key in the tree
+	if prevNode == nil {
+		prevNode = findMax(t.root)
+	}
+	return prevNode.key, prevNode.value
+}
+
+// Range calls fn in key order for every node with from <= key <= to, skipping subtrees outside the range.
+func (t *redBlackTree) Range(from, to uint32, fn func(*redBlackNode)) {
+	var walk func(*redBlackNode)
+	walk = func(n *redBlackNode) {
+		if n == nil {
+			return
+		}
+		if n.key > from {
+			walk(n.left)
+		}
+		if n.key >= from && n.key <= to {
+			fn(n)
+		}
+		if n.key < to {
+			walk(n.right)
+		}
+	}
+	walk(t.root)
+}
+
 func (t *redBlackTree) TraverseWhile(condition func(*redBlackNode) bool) bool {
 	// If the tree is empty, there's nothing to traverse
 	if t.root == nil {
@@ -332,3 +390,91 @@ func doubleRotate(root *redBlackNode, dir bool) *redBlackNode {
 	root.setChild(!dir, singleRotate(root.Child(!dir), !dir))
 	return singleRotate(root, dir)
 }
+
+// redBlackIterator walks the tree in key order using an explicit stack, so each step is amortized O(1).
+type redBlackIterator struct {
+	tree  *redBlackTree
+	stack []*redBlackNode
+}
+
+// iterAfter returns an iterator positioned before the first node with a key strictly greater than key.
+func (t *redBlackTree) iterAfter(key uint32) *redBlackIterator {
+	it := &redBlackIterator{tree: t}
+	for h := t.root; h != nil; {
+		if key < h.key {
+			it.stack = append(it.stack, h)
+			h = h.left
+		} else {
+			h = h.right
+		}
+	}
+	return it
+}
+
+// pushLeft pushes h and its chain of left children onto the stack.
+func (it *redBlackIterator) pushLeft(h *redBlackNode) {
+	for ; h != nil; h = h.left {
+		it.stack = append(it.stack, h)
+	}
+}
+
+// next returns the next node in key order, wrapping around to the smallest key after the largest.
+func (it *redBlackIterator) next() *redBlackNode {
+	if len(it.stack) == 0 {
+		it.pushLeft(it.tree.root)
+		if len(it.stack) == 0 {
+			return nil
+		}
+	}
+	n := it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+	it.pushLeft(n.right)
+	return n
+}
+
+// validate checks the red-black properties of the tree: keys in order, a black root, no red node with a red
+// child, the same number of black nodes on every path, and a size matching the nodes. It returns the first
+// violation found.
+func (t *redBlackTree) validate() error {
+	if isRed(t.root) {
+		return fmt.Errorf("root %d is red", t.root.key)
+	}
+	count := 0
+	var prev *redBlackNode
+	var check func(h *redBlackNode) (int, error)
+	check = func(h *redBlackNode) (int, error) {
+		if h == nil {
+			return 1, nil
+		}
+		if isRed(h) && (isRed(h.left) || isRed(h.right)) {
+			return 0, fmt.Errorf("red node %d has a red child", h.key)
+		}
+		left, err := check(h.left)
+		if err != nil {
+			return 0, err
+		}
+		if prev != nil && prev.key >= h.key {
+			return 0, fmt.Errorf("node %d follows %d out of order", h.key, prev.key)
+		}
+		prev = h
+		count++
+		right, err := check(h.right)
+		if err != nil {
+			return 0, err
+		}
+		if left != right {
+			return 0, fmt.Errorf("node %d has black heights %d and %d", h.key, left, right)
+		}
+		if !isRed(h) {
+			left++
+		}
+		return left, nil
+	}
+	if _, err := check(t.root); err != nil {
+		return err
+	}
+	if count != t.size {
+		return fmt.Errorf("size is %d but the tree holds %d nodes", t.size, count)
+	}
+	return nil
+}
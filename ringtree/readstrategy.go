@@ -0,0 +1,75 @@
+package ringtree
+
+// ReadStrategy chooses the replica that serves a read of a key in replicated mode. Choose is called with the
+// key's replicas in ring order, starting with its owner and leaving out nodes that are Down or Joining,
+// while their ring is read-locked: it may read their Load but must not call back into the tree. Returning
+// nil reads from the owner.
+type ReadStrategy interface {
+	Choose(key string, replicas []*Node) *Node
+}
+
+// PrimaryRead reads every key from its first replica that is not Down or Joining, as Lookup does without a
+// strategy.
+type PrimaryRead struct{}
+
+// Choose implements ReadStrategy.
+func (PrimaryRead) Choose(key string, replicas []*Node) *Node {
+	if len(replicas) == 0 {
+		return nil
+	}
+	return replicas[0]
+}
+
+// TwoChoiceRead reads each key from the less loaded of two of its replicas picked at random. A node's load
+// counts its keys and the reads already routed to it, so reads even out across replicas without any
+// coordination.
+type TwoChoiceRead struct{}
+
+// Choose implements ReadStrategy.
+func (TwoChoiceRead) Choose(key string, replicas []*Node) *Node {
+	return leastLoadedOfTwo(replicas)
+}
+
+// WithReadStrategy makes Lookup route reads in replicated mode to the replica the strategy chooses, rather
+// than always to the key's owner. It has no effect unless Replication is above 1.
+func WithReadStrategy(strategy ReadStrategy) Option {
+	return func(c *Config) {
+		c.ReadStrategy = strategy
+	}
+}
+
+// readReplica returns the node to serve a read of a key found on owner: one of the followers of a spread
+// key, one of the replicas chosen by the read strategy in replicated mode, or otherwise the owner itself.
+func (r *Ring) readReplica(key string, owner string) string {
+	var n int
+	var strategy ReadStrategy
+	if k, ok := r.spreads.get(key); ok {
+		n, strategy = k+1, TwoChoiceRead{}
+	} else if r.config.replication() > 1 && r.config.ReadStrategy != nil {
+		n, strategy = r.config.replication(), r.config.ReadStrategy
+	} else {
+		r.recordRequest(key, owner)
+		return owner
+	}
+
+	_, parent, vNodeHash, _, err := r.findNode(key, false)
+	if err != nil {
+		return owner
+	}
+	parent.RLock()
+	defer parent.RUnlock()
+	var replicas []*Node
+	for _, node := range parent.replicaWalk(vNodeHash, n) {
+		if node.servesReads() {
+			replicas = append(replicas, node)
+		}
+	}
+	node := strategy.Choose(key, replicas)
+	if node == nil {
+		r.recordRequest(key, owner)
+		return owner
+	}
+	node.reads.Add(1)
+	r.countRead(node)
+	return node.id
+}
@@ -0,0 +1,77 @@
+package ringtree
+
+import "testing"
+
+// lastReplica is a read strategy that always reads from the last replica.
+type lastReplica struct{}
+
+func (lastReplica) Choose(key string, replicas []*Node) *Node { return replicas[len(replicas)-1] }
+
+func TestReadStrategy(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		strategy ReadStrategy
+	}{{"default", nil}, {"primary", PrimaryRead{}}, {"two-choice", TwoChoiceRead{}}, {"custom", lastReplica{}}} {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := New(8, WithReplication(3), WithReadStrategy(tc.strategy))
+			for _, id := range []string{"A", "B", "C", "D", "E"} {
+				rt.InsertNode(NewNode(id, 1000))
+			}
+			key, _ := GenerateRandomString(20)
+			rt.InsertKey(key)
+			replicas, _ := rt.Replicas(key)
+
+			served := make(map[string]int)
+			for i := 0; i < 300; i++ {
+				owner, err := rt.Lookup(key)
+				if err != nil {
+					t.Fatalf("expected the key to be found, got error: %v", err)
+				}
+				served[owner]++
+			}
+			switch tc.strategy.(type) {
+			case nil, PrimaryRead:
+				checkNum(served[replicas[0]], 300, t)
+			case lastReplica:
+				checkNum(served[replicas[2]], 300, t)
+			case TwoChoiceRead:
+				for _, id := range replicas {
+					if served[id] < 80 {
+						t.Errorf("expected %s to serve about a third of the reads, got %v", id, served)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadStrategyRequiresReplication(t *testing.T) {
+	rt := New(8, WithReadStrategy(lastReplica{}))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	node, _, _, _, _ := rt.FindNode(key)
+	for i := 0; i < 10; i++ {
+		if owner, _ := rt.Lookup(key); owner != node.id {
+			t.Errorf("expected reads from the owner %s without replication, got %s", node.id, owner)
+		}
+	}
+}
+
+func TestTwoChoiceReadSkipsDown(t *testing.T) {
+	rt := New(8, WithReplication(3), WithReadStrategy(TwoChoiceRead{}))
+	for _, id := range []string{"A", "B", "C", "D"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	replicas, _ := rt.Replicas(key)
+	rt.SetNodeState(replicas[0], Down)
+	for i := 0; i < 100; i++ {
+		if owner, _ := rt.Lookup(key); owner == replicas[0] {
+			t.Fatalf("expected down replica %s to be skipped", replicas[0])
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package ringtree
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	defaultRebalanceSkew = 1.25 // Skew factor used when none is configured
+	maxRebalanceMoves    = 64   // Most moves a single Rebalance makes, so uneven hashing cannot keep it busy
+)
+
+// WithRebalanceSkew sets how far a subring's load per node may exceed the tree's mean before Rebalance moves
+// load from it to a sibling. Factors of 1 or below fall back to the default.
+func WithRebalanceSkew(factor float64) Option {
+	return func(c *Config) {
+		c.RebalanceSkew = factor
+	}
+}
+
+// rebalanceSkew returns the configured skew factor.
+func (c *Config) rebalanceSkew() float64 {
+	if c.RebalanceSkew <= 1 {
+		return defaultRebalanceSkew
+	}
+	return c.RebalanceSkew
+}
+
+// Rebalance evens out load between sibling subrings. Splits only ever push load down the tree, so a subring
+// that received a hot part of the key space stays hot while its siblings idle. Rebalance computes the mean
+// load per node across the tree and, on every ring, relieves subrings whose load per node exceeds the mean
+// by the configured skew factor: it moves a node over from the least loaded sibling when the hot subring has
+// room for one, and otherwise hands one of the hot subring's vnode arcs, with its keys, to that sibling.
+// Every move must lower the worst load per node of the pair, so Rebalance settles rather than oscillates.
+func (r *Ring) Rebalance() error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.rebalanceOp()
+}
+
+// rebalanceOp rebalances the tree as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) rebalanceOp() error {
+	defer r.timeTrack(time.Now(), "Rebalance", "to rebalance the tree")
+
+	stats := r.root().MemberStats()
+	if stats.Nodes == 0 || stats.Load == 0 {
+		return nil
+	}
+	limit := r.config.rebalanceSkew() * float64(stats.Load) / float64(stats.Nodes)
+	r.beginOp()
+	moves := 0
+	err := r.rebalance(limit, &moves)
+	if moves == 0 {
+		return err // Nothing moved, so there is nothing to replay
+	}
+	r.wal.applied = true
+	return r.logOp(Op{Type: OpRebalance}, err)
+}
+
+// rebalance relieves the ring's overloaded subrings, then those nested below them (assuming the tree's
+// writer lock is held).
+func (r *Ring) rebalance(limit float64, moves *int) error {
+	for *moves < maxRebalanceMoves {
+		moved, err := r.rebalanceOnce(limit)
+		if moved {
+			*moves++
+		}
+		if err != nil {
+			return err
+		}
+		if !moved {
+			break
+		}
+	}
+
+	for _, subring := range r.subrings() {
+		if err := subring.rebalance(limit, moves); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subrings returns the ring's subring members in ID order, so a replayed rebalance breaks ties the same way.
+func (r *Ring) subrings() []*Ring {
+	r.RLock()
+	defer r.RUnlock()
+	var subrings []*Ring
+	for _, member := range r.members {
+		if subring, ok := member.(*Ring); ok {
+			subrings = append(subrings, subring)
+		}
+	}
+	sort.Slice(subrings, func(i, j int) bool { return subrings[i].id < subrings[j].id })
+	return subrings
+}
+
+// subringLoad is the load of one subring taken at the start of a rebalance step.
+type subringLoad struct {
+	ring  *Ring
+	nodes int
+	load  int
+}
+
+// perNode returns the subring's load per node.
+func (s subringLoad) perNode() float64 {
+	return float64(s.load) / float64(s.nodes)
+}
+
+// rebalanceOnce makes a single move from the ring's most loaded subring to its least loaded one, if the
+// former exceeds the limit and a move improves on it. It reports whether anything moved.
+func (r *Ring) rebalanceOnce(limit float64) (bool, error) {
+	var hot, cool *subringLoad
+	for _, subring := range r.subrings() {
+		stats := subring.MemberStats()
+		if stats.Nodes == 0 {
+			continue
+		}
+		s := &subringLoad{ring: subring, nodes: stats.Nodes, load: stats.Load}
+		if hot == nil || s.perNode() > hot.perNode() {
+			hot = s
+		}
+		if cool == nil || s.perNode() < cool.perNode() {
+			cool = s
+		}
+	}
+	if hot == nil || hot == cool || hot.perNode() <= limit {
+		return false, nil
+	}
+
+	if node := cool.ring.spareNode(); node != nil && hot.ring.Size() < hot.ring.maxCount &&
+		float64(cool.load)/float64(cool.nodes-1) < hot.perNode() {
+		return true, r.moveNode(node, cool.ring, hot.ring)
+	}
+	return r.moveArc(hot, cool)
+}
+
+// spareNode returns the least loaded node the subring can give up without collapsing, or nil if it has none.
+func (r *Ring) spareNode() *Node {
+	r.RLock()
+	defer r.RUnlock()
+	if r.Size() < 3 {
+		return nil
+	}
+	var spare *Node
+	for _, member := range r.members {
+		node, ok := member.(*Node)
+		if !ok || node.State() != Up || r.pins.targeted(node.id) {
+			continue
+		}
+		if spare == nil || node.load < spare.load || (node.load == spare.load && node.id < spare.id) {
+			spare = node
+		}
+	}
+	return spare
+}
+
+// moveNode removes a node from one subring and joins a node with the same ID and threshold to another, which
+// takes over keys from its new neighbours (assuming the tree's writer lock is held).
+func (r *Ring) moveNode(node *Node, from *Ring, to *Ring) error {
+	r.logf("Rebalancing: moving node %s from subring %s to subring %s.\n", node.id, from.id, to.id)
+	threshold := node.threshold
+	if err := from.removeNode(node); err != nil {
+		return err
+	}
+	remapped := r.stats.remapped.get()
+	if err := to.insertNode(NewNode(node.id, threshold)); err != nil {
+		return err
+	}
+	r.emit(Event{Type: Rebalanced, RingID: r.id, NodeID: node.id, Level: to.level, Remapped: r.stats.remapped.get() - remapped})
+	return nil
+}
+
+// moveArc hands the hot subring's vnode whose arc best evens out the pair to the cool subring, and moves the
+// keys hashed into that arc across. Pinned keys stay where they are. It reports false when the hot subring
+// has a single vnode or no arc would improve on it (assuming the tree's writer lock is held).
+func (r *Ring) moveArc(hot, cool *subringLoad) (bool, error) {
+	r.settleRemaps()
+	r.hub.begin()
+	defer r.hub.end()
+	r.Lock()
+	owner := func(key string) uint32 {
+		vNodeHash, _ := r.circle.FindClosest(r.config.keyHash(key, r.level))
+		return vNodeHash
+	}
+
+	// Sum the load hashed into each of the hot subring's arcs
+	arcs := make(map[uint32]int)
+	for _, vNode := range circleVNodes(r.circle) {
+		if vNode.nodeID == hot.ring.id {
+			arcs[vNode.hash] = 0
+		}
+	}
+	hot.ring.Lock()
+	hot.ring.forEachNode(func(node *Node) {
+		for _, keys := range node.keys {
+			for key := range keys {
+				if _, pinned := r.pins.get(key); !pinned {
+					arcs[owner(key)] += node.cost(key)
+				}
+			}
+		}
+	})
+	hot.ring.Unlock()
+
+	worst := hot.perNode()
+	var arc uint32
+	found := false
+	for vNodeHash, load := range arcs {
+		after := float64(hot.load-load) / float64(hot.nodes)
+		if moved := float64(cool.load+load) / float64(cool.nodes); moved > after {
+			after = moved
+		}
+		if load > 0 && (after < worst || (found && after == worst && vNodeHash < arc)) {
+			arc, worst, found = vNodeHash, after, true
+		}
+	}
+	if len(arcs) < 2 || !found {
+		r.Unlock()
+		return false, nil
+	}
+
+	// Take the arc's keys out of the hot subring, then hand the vnode to the cool one
+	r.logf("Rebalancing: moving vnode %d from subring %s to subring %s.\n", arc, hot.ring.id, cool.ring.id)
+	costs := make(map[string]int)
+	hot.ring.Lock()
+	if err := hot.ring.verifyRing(); err != nil {
+		hot.ring.Unlock()
+		r.Unlock()
+		return false, err
+	}
+	hot.ring.forEachNode(func(node *Node) {
+		for vNodeHash := range node.keys {
+			keys := node.writable(vNodeHash)
+			for key := range keys {
+				if _, pinned := r.pins.get(key); pinned || owner(key) != arc {
+					continue
+				}
+				delete(keys, key)
+				delete(node.checksums, key)
+				costs[key] = node.clearCost(key)
+				r.countKey(key, -1)
+			}
+		}
+	})
+	hot.ring.publish()
+	hot.ring.Unlock()
+	r.circle.Delete(arc)
+	r.circle.Insert(arc, cool.ring.id)
+	r.circle.Sort()
+	r.publish()
+	r.Unlock()
+
+	// Reinsert the keys, which now route into the cool subring
+	for key, cost := range costs {
+		r.stats.remapped.add(1)
+		if err := cool.ring.insertKey(key, cost, true); err != nil {
+			return true, err
+		}
+	}
+	r.emit(Event{Type: Rebalanced, RingID: r.id, NodeID: hot.ring.id, Level: cool.ring.level, Remapped: len(costs)})
+	return true, nil
+}
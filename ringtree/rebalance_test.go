@@ -0,0 +1,77 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// subringPerNode returns the load per node of a subring of the root.
+func subringPerNode(rt *Ring, id string) float64 {
+	stats := rt.members[id].MemberStats()
+	return float64(stats.Load) / float64(stats.Nodes)
+}
+
+func TestRebalance(t *testing.T) {
+	for _, seeds := range []int{2, 3} {
+		// Root with subrings A and B holding keys only in A; a long dwell keeps B's emptied nodes
+		rt, _ := buildTree(t, 2, []string{"A", "B"}, 10000, 0,
+			WithBranchFactor(3), WithSeedNodes(seeds), WithRebalanceSkew(1.1), WithMinDwell(time.Hour))
+		for _, id := range []string{"A", "B"} {
+			if _, err := rt.Split(id); err != nil {
+				t.Fatalf("expected %s to be split, got error: %v", id, err)
+			}
+		}
+		var keys []string
+		for i := 0; i < 600; i++ {
+			key := "key-" + strconv.Itoa(i)
+			rt.InsertKey(key)
+			if foundWithin(t, rt, key, "B") {
+				rt.RemoveKey(key)
+			} else {
+				keys = append(keys, key)
+			}
+		}
+
+		before := subringPerNode(rt, "A")
+		nodes := rt.MemberStats().Nodes
+		if err := rt.Rebalance(); err != nil {
+			t.Fatalf("expected rebalance to succeed, got error: %v", err)
+		}
+
+		if after := subringPerNode(rt, "A"); after >= before {
+			t.Errorf("expected load per node of A to drop below %.1f, got %.1f (seeds %d)", before, after, seeds)
+		}
+		if subringPerNode(rt, "B") == 0 {
+			t.Errorf("expected B to take load (seeds %d)", seeds)
+		}
+		for _, key := range keys {
+			if _, err := rt.Lookup(key); err != nil {
+				t.Errorf("expected key %s to be found, got error: %v", key, err)
+			}
+		}
+		checkNum(rt.MemberStats().Keys, len(keys), t)
+		checkNum(rt.Stats().Keys(), len(keys), t)
+		checkNum(rt.MemberStats().Nodes, nodes, t)
+		// With three nodes B can spare one, so a node moves instead of an arc
+		if seeds == 3 {
+			checkNum(rt.members["A"].MemberStats().Nodes, seeds+1, t)
+		}
+	}
+}
+
+func TestRebalanceBalancedTree(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 10000))
+	rt.InsertNode(NewNode("B", 10000))
+	for i := 0; i < 100; i++ {
+		rt.InsertKey("key-" + strconv.Itoa(i))
+	}
+
+	// Without sibling subrings there is nothing to move
+	remapped := rt.Stats().remapped.get()
+	if err := rt.Rebalance(); err != nil {
+		t.Fatalf("expected rebalance to succeed, got error: %v", err)
+	}
+	checkNum(rt.Stats().remapped.get(), remapped, t)
+}
@@ -0,0 +1,178 @@
+package ringtree
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemberLoad is the load of one node or ring at the time of a sample.
+type MemberLoad struct {
+	ID    string `json:"id"`
+	Level int    `json:"level"`
+	Load  int    `json:"load"`
+}
+
+// LoadSample is the load of every node and ring of the tree at one point in time.
+type LoadSample struct {
+	Seq   int          `json:"seq"` // Position of the sample in the recording, from 0
+	Time  time.Time    `json:"time"`
+	Keys  int          `json:"keys"`
+	Nodes []MemberLoad `json:"nodes"` // Physical nodes by level, then by ID
+	Rings []MemberLoad `json:"rings"` // Rings by level, then by ID, with their total load
+}
+
+// Recorder samples the per-node and per-ring load of a tree into a fixed-size ring buffer, so simulations
+// can plot how load converges over a workload. Samples are taken every interval once Start is called, or on
+// demand with Sample; once the buffer is full the oldest sample is overwritten.
+type Recorder struct {
+	ring *Ring
+
+	mu      sync.Mutex
+	samples []LoadSample // Ring buffer of at most cap(samples) samples
+	next    int          // Index of the slot the next sample overwrites once the buffer is full
+	seq     int          // Samples taken so far
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewRecorder returns a recorder keeping the last capacity samples of the tree rooted at r.
+func NewRecorder(r *Ring, capacity int) *Recorder {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Recorder{ring: r.root(), samples: make([]LoadSample, 0, capacity)}
+}
+
+// Start samples the tree every interval until Stop is called. Starting a running recorder does nothing.
+func (rec *Recorder) Start(interval time.Duration) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.stop != nil {
+		return
+	}
+	rec.stop, rec.done = make(chan struct{}), make(chan struct{})
+	go rec.run(interval, rec.stop, rec.done)
+}
+
+// Stop ends periodic sampling and waits for a sample in progress to finish.
+func (rec *Recorder) Stop() {
+	rec.mu.Lock()
+	stop, done := rec.stop, rec.done
+	rec.stop, rec.done = nil, nil
+	rec.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// run takes a sample on every tick until stop is closed.
+func (rec *Recorder) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rec.Sample()
+		}
+	}
+}
+
+// Sample records the current load of every node and ring, and returns the sample.
+func (rec *Recorder) Sample() LoadSample {
+	sample := rec.ring.loadSample()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	sample.Seq = rec.seq
+	rec.seq++
+	if len(rec.samples) < cap(rec.samples) {
+		rec.samples = append(rec.samples, sample)
+	} else {
+		rec.samples[rec.next] = sample
+		rec.next = (rec.next + 1) % len(rec.samples)
+	}
+	return sample
+}
+
+// Samples returns the recorded samples, oldest first.
+func (rec *Recorder) Samples() []LoadSample {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	samples := make([]LoadSample, 0, len(rec.samples))
+	samples = append(samples, rec.samples[rec.next:]...)
+	return append(samples, rec.samples[:rec.next]...)
+}
+
+// Export writes the recorded samples, oldest first. CSV has one row per member and sample: seq, time (in
+// seconds since the first sample), kind (node or ring), id, level and load.
+func (rec *Recorder) Export(w io.Writer, format Format) error {
+	samples := rec.Samples()
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(samples)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"seq", "time", "kind", "id", "level", "load"})
+		for _, sample := range samples {
+			elapsed := strconv.FormatFloat(sample.Time.Sub(samples[0].Time).Seconds(), 'f', -1, 64)
+			seq := strconv.Itoa(sample.Seq)
+			for _, node := range sample.Nodes {
+				cw.Write([]string{seq, elapsed, "node", node.ID, strconv.Itoa(node.Level), strconv.Itoa(node.Load)})
+			}
+			for _, ring := range sample.Rings {
+				cw.Write([]string{seq, elapsed, "ring", ring.ID, strconv.Itoa(ring.Level), strconv.Itoa(ring.Load)})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown stats format %d", format)
+	}
+}
+
+// loadSample gathers the load of every node and ring of the tree. It waits for a mutation in progress to
+// finish.
+func (r *Ring) loadSample() LoadSample {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	sample := LoadSample{Time: time.Now(), Keys: r.stats.numKeys.get()}
+	var walk func(ring *Ring) int
+	walk = func(ring *Ring) int {
+		total := 0
+		for _, member := range ring.members {
+			switch member := member.(type) {
+			case *Node:
+				sample.Nodes = append(sample.Nodes, MemberLoad{ID: member.id, Level: ring.level, Load: member.load})
+				total += member.load
+			case *Ring:
+				total += walk(member)
+			}
+		}
+		sample.Rings = append(sample.Rings, MemberLoad{ID: ring.id, Level: ring.level, Load: total})
+		return total
+	}
+	walk(r.root())
+
+	for _, loads := range [][]MemberLoad{sample.Nodes, sample.Rings} {
+		sort.Slice(loads, func(i, j int) bool {
+			if loads[i].Level != loads[j].Level {
+				return loads[i].Level < loads[j].Level
+			}
+			return loads[i].ID < loads[j].ID
+		})
+	}
+	return sample
+}
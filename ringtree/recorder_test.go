@@ -0,0 +1,87 @@
+package ringtree
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	rec := NewRecorder(rt, 3)
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 10; j++ {
+			rt.InsertKey(fmt.Sprintf("key-%d-%d", i, j))
+		}
+		rec.Sample()
+	}
+
+	samples := rec.Samples()
+	if len(samples) != 3 || samples[0].Seq != 2 || samples[2].Seq != 4 {
+		t.Fatalf("got samples %v, want the last 3 of 5 oldest first", seqs(samples))
+	}
+	last := samples[2]
+	if last.Keys != 50 || len(last.Nodes) != 2 || last.Nodes[0].ID != "A" || len(last.Rings) != 1 {
+		t.Fatalf("got last sample %+v, want 50 keys on nodes A and B in one ring", last)
+	}
+	if last.Nodes[0].Load+last.Nodes[1].Load != last.Rings[0].Load || last.Rings[0].Load != 50 {
+		t.Errorf("node loads %+v do not add up to the ring load %d", last.Nodes, last.Rings[0].Load)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Export(&buf, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+3*3 || rows[1][0] != "2" || rows[1][2] != "node" || rows[1][3] != "A" {
+		t.Errorf("got CSV %v, want a header and 3 rows per sample", rows)
+	}
+	buf.Reset()
+	if err := rec.Export(&buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []LoadSample
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 3 {
+		t.Errorf("got %d samples from JSON (%v), want 3", len(decoded), err)
+	}
+}
+
+func TestRecorderStart(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 1000))
+	rec := NewRecorder(rt, 100)
+	rec.Start(time.Millisecond)
+	rec.Start(time.Millisecond)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.Samples()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected periodic samples")
+		}
+		rt.InsertKey(fmt.Sprintf("key-%d", len(rec.Samples())))
+		time.Sleep(time.Millisecond)
+	}
+	rec.Stop()
+	taken := len(rec.Samples())
+	time.Sleep(10 * time.Millisecond)
+	if len(rec.Samples()) != taken {
+		t.Error("expected sampling to stop")
+	}
+	rec.Stop()
+}
+
+// seqs returns the sequence numbers of the samples.
+func seqs(samples []LoadSample) []int {
+	var seq []int
+	for _, sample := range samples {
+		seq = append(seq, sample.Seq)
+	}
+	return seq
+}
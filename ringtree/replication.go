@@ -0,0 +1,94 @@
+package ringtree
+
+// replicaWalk returns up to n distinct physical nodes starting at the vnode owning keyHash and walking
+// clockwise (assuming mutex is already locked).
+func (r *Ring) replicaWalk(vNodeHash uint32, n int) []*Node {
+	var replicas []*Node
+	seen := make(map[string]bool)
+	vNodeHash, nodeID := r.circle.FindClosest(vNodeHash)
+	it := r.circle.Iter(vNodeHash)
+	for i := 0; i < r.circle.Size() && len(replicas) < n; i++ {
+		if i > 0 {
+			_, nodeID = it.Next()
+		}
+		node, ok := r.members[nodeID].(*Node)
+		if !ok || seen[nodeID] {
+			continue
+		}
+		seen[nodeID] = true
+		replicas = append(replicas, node)
+	}
+	return replicas
+}
+
+// Replicas returns the IDs of the nodes holding a key in replicated mode: its owner followed by the next
+// distinct nodes clockwise on the owner's ring.
+func (r *Ring) Replicas(key string) ([]string, error) {
+	_, parent, vNodeHash, _, err := r.findNode(key, false)
+	if err != nil {
+		return nil, err
+	}
+	parent.RLock()
+	defer parent.RUnlock()
+
+	var ids []string
+	for _, node := range parent.replicaWalk(vNodeHash, r.config.replication()) {
+		ids = append(ids, node.id)
+	}
+	return ids, nil
+}
+
+// Primary returns the node currently serving a key: its owner, or the first replica that is not Down or
+// Joining when the owner cannot serve it.
+func (r *Ring) Primary(key string) (string, error) {
+	replicas, err := r.Replicas(key)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range replicas {
+		if node, _ := r.findMember(id); node != nil && node.servesReads() {
+			return id, nil
+		}
+	}
+	return "", ErrNoReplicaAvailable
+}
+
+// promoted returns the nodes that take over a node's arcs while it is down (assuming mutex is already
+// locked).
+func (r *Ring) promoted(node *Node) []*Node {
+	var nodes []*Node
+	seen := make(map[string]bool)
+	for vNodeHash := range node.keys {
+		for _, replica := range r.replicaWalk(vNodeHash, r.config.replication()) {
+			if replica == node || replica.State() == Down {
+				continue
+			}
+			if !seen[replica.id] {
+				seen[replica.id] = true
+				nodes = append(nodes, replica)
+			}
+			break
+		}
+	}
+	return nodes
+}
+
+// announceFailover emits promotion events when a node goes Down in replicated mode, and demotion events
+// when it recovers.
+func (r *Ring) announceFailover(node *Node, previous, current NodeState) {
+	if r.config.replication() < 2 || (previous == Down) == (current == Down) {
+		return
+	}
+	eventType := ReplicaPromoted
+	if current != Down {
+		eventType = ReplicaDemoted
+	}
+
+	r.RLock()
+	replicas := r.promoted(node)
+	r.RUnlock()
+	for _, replica := range replicas {
+		r.logf("%s replica %s for arcs of node %s.\n", eventType, replica.id, node.id)
+		r.emit(Event{Type: eventType, RingID: r.id, NodeID: replica.id, Level: r.level})
+	}
+}
@@ -0,0 +1,66 @@
+package ringtree
+
+import "testing"
+
+func TestReplicaPromotion(t *testing.T) {
+	rt := New(5, WithReplication(2))
+	nodeA := NewNode("", 1000)
+	rt.InsertNode(nodeA)
+	rt.InsertNode(NewNode("", 1000))
+	rt.InsertNode(NewNode("", 1000))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	for _, key := range keys {
+		replicas, err := rt.Replicas(key)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkNum(len(replicas), 2, t)
+		if replicas[0] == replicas[1] {
+			t.Fatalf("expected distinct replicas, got %v", replicas)
+		}
+	}
+
+	events, cancel := rt.Watch(16)
+	defer cancel()
+	rt.SetNodeState(nodeA.id, Down)
+
+	promoted := 0
+	for len(events) > 0 {
+		for _, event := range <-events {
+			if event.Type == ReplicaPromoted {
+				promoted++
+			}
+		}
+	}
+	if promoted == 0 {
+		t.Errorf("expected a replica to be promoted")
+	}
+
+	for _, key := range keys {
+		owner, err := rt.Lookup(key)
+		if err != nil {
+			t.Fatalf("expected key %s to be served by a replica, got error: %v", key, err)
+		}
+		if owner == nodeA.id {
+			t.Fatalf("expected key %s to be served by a promoted replica", key)
+		}
+	}
+
+	rt.SetNodeState(nodeA.id, Up)
+	demoted := 0
+	for len(events) > 0 {
+		for _, event := range <-events {
+			if event.Type == ReplicaDemoted {
+				demoted++
+			}
+		}
+	}
+	checkNum(demoted, promoted, t)
+}
@@ -0,0 +1,79 @@
+package ringtree
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// WithRequestLoad keeps a decayed count of the reads each node serves, halving every halfLife, so routing
+// and rebalancing can weigh recent request load rather than only the keys a node holds. By default request
+// load is not tracked.
+func WithRequestLoad(halfLife time.Duration) Option {
+	return func(c *Config) {
+		if halfLife > 0 {
+			c.RequestHalfLife = halfLife
+		}
+	}
+}
+
+// requestLoad is the decayed count of the reads served by a node, as of its last read.
+type requestLoad struct {
+	count    float64
+	at       time.Time
+	halfLife time.Duration // Zero until the first read is counted
+	sync.Mutex
+}
+
+// record counts one read at the given time.
+func (l *requestLoad) record(now time.Time, halfLife time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+	l.count = decayed(l.count, l.at, now, halfLife) + 1
+	l.at, l.halfLife = now, halfLife
+}
+
+// value returns the count as of now, and whether any read was counted.
+func (l *requestLoad) value(now time.Time) (float64, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if l.halfLife == 0 {
+		return 0, false
+	}
+	return decayed(l.count, l.at, now, l.halfLife), true
+}
+
+// RequestLoad returns the decayed count of the reads the node served, each read counting one and halving
+// every RequestHalfLife. It is 0 if request load is not tracked. Read strategies and split policies can use
+// it to weigh how busy a node is now rather than how many keys it holds.
+func (n *Node) RequestLoad() float64 {
+	count, _ := n.requests.value(time.Now())
+	return count
+}
+
+// recordRequest counts a read of a key served by a node in the node's request load, if request load is
+// tracked.
+func (r *Ring) recordRequest(key string, server string) {
+	if r.config.RequestHalfLife <= 0 {
+		return
+	}
+	if node, _, _, _, err := r.findNode(key, false); err == nil && node.id == server {
+		r.countRead(node)
+	}
+}
+
+// countRead counts a read served by a node in its request load, if request load is tracked.
+func (r *Ring) countRead(node *Node) {
+	if halfLife := r.config.RequestHalfLife; halfLife > 0 {
+		node.requests.record(time.Now(), halfLife)
+	}
+}
+
+// decayed returns a count recorded at the given time as of now, halving every halfLife.
+func decayed(count float64, at time.Time, now time.Time, halfLife time.Duration) float64 {
+	elapsed := now.Sub(at)
+	if elapsed <= 0 {
+		return count
+	}
+	return count * math.Exp2(-float64(elapsed)/float64(halfLife))
+}
@@ -0,0 +1,57 @@
+package ringtree
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRequestLoad(t *testing.T) {
+	rt := New(4, WithRequestLoad(time.Hour))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	owner, _ := rt.Lookup(key)
+	for i := 1; i < 100; i++ {
+		rt.Lookup(key)
+	}
+
+	served, idle := mustNode(t, rt, "A"), mustNode(t, rt, "B")
+	if owner == "B" {
+		served, idle = idle, served
+	}
+	if load := served.RequestLoad(); math.Abs(load-100) > 0.1 {
+		t.Errorf("expected a request load near 100 on %s, got %f", owner, load)
+	}
+	if load := idle.RequestLoad(); load != 0 {
+		t.Errorf("expected no request load on the idle node, got %f", load)
+	}
+	// Recent requests stand in for the all-time read count when comparing read load
+	if got := served.readLoad(); got != 1+100 {
+		t.Errorf("expected a read load of 101, got %d", got)
+	}
+
+	untracked := New(4)
+	untracked.InsertNode(NewNode("A", 1000))
+	untracked.InsertKey(key)
+	untracked.Lookup(key)
+	if load := mustNode(t, untracked, "A").RequestLoad(); load != 0 {
+		t.Errorf("expected no request load without tracking, got %f", load)
+	}
+}
+
+func TestRequestLoadDecay(t *testing.T) {
+	rt := New(4, WithRequestLoad(20*time.Millisecond))
+	rt.InsertNode(NewNode("A", 1000))
+	key, _ := GenerateRandomString(20)
+	rt.InsertKey(key)
+	for i := 0; i < 64; i++ {
+		rt.Lookup(key)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// Three half-lives or more leave at most an eighth
+	if load := mustNode(t, rt, "A").RequestLoad(); load > 8 || load <= 0 {
+		t.Errorf("expected the request load to decay below 8, got %f", load)
+	}
+}
@@ -0,0 +1,285 @@
+// Package ringtest checks a ring tree against a reference model. The model is a plain set of keys; Run
+// applies a sequence of operations to both, and Verify compares them, recomputing where every key should be
+// from a fresh routing table of the tree.
+//
+// Programs embedding a ring tree can drive their own configuration through the same harness:
+//
+//	rt := ringtree.New(4, opts...)
+//	rt.InsertNode(ringtree.NewNode("a", 10))
+//	ops := ringtest.RandomOps(rand.New(rand.NewSource(1)), 5000, 200)
+//	if err := ringtest.Run(rt, ops); err != nil {
+//		t.Fatal(err)
+//	}
+package ringtest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// Kind is the kind of an operation.
+type Kind int
+
+const (
+	InsertKey Kind = iota
+	RemoveKey
+	Lookup
+	InsertNode
+	RemoveNode
+)
+
+func (k Kind) String() string {
+	switch k {
+	case InsertKey:
+		return "InsertKey"
+	case RemoveKey:
+		return "RemoveKey"
+	case Lookup:
+		return "Lookup"
+	case InsertNode:
+		return "InsertNode"
+	case RemoveNode:
+		return "RemoveNode"
+	default:
+		return "Kind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// Op is one operation of a sequence.
+type Op struct {
+	Kind      Kind
+	Key       string // Key of InsertKey, RemoveKey and Lookup
+	Node      string // Node of InsertNode and RemoveNode
+	Threshold int    // Threshold of the node of InsertNode
+	Pick      int    // With RemoveNode and no Node, the index of the node to remove among the sorted node IDs, modulo their number
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case InsertNode:
+		return fmt.Sprintf("%v(%s, %d)", op.Kind, op.Node, op.Threshold)
+	case RemoveNode:
+		if op.Node == "" {
+			return fmt.Sprintf("%v(#%d)", op.Kind, op.Pick)
+		}
+		return fmt.Sprintf("%v(%s)", op.Kind, op.Node)
+	default:
+		return fmt.Sprintf("%v(%s)", op.Kind, op.Key)
+	}
+}
+
+// RandomOps returns n operations drawn from rng over keys distinct keys: mostly key inserts, then removals
+// and lookups, with an occasional node join or leave. Joining nodes take a threshold of 1 to 10 keys, so
+// the tree splits and collapses rings as it runs.
+func RandomOps(rng *rand.Rand, n, keys int) []Op {
+	keys = max(keys, 1)
+	ops := make([]Op, n)
+	for i := range ops {
+		key := "key" + strconv.Itoa(rng.Intn(keys))
+		switch x := rng.Intn(100); {
+		case x < 55:
+			ops[i] = Op{Kind: InsertKey, Key: key}
+		case x < 80:
+			ops[i] = Op{Kind: RemoveKey, Key: key}
+		case x < 95:
+			ops[i] = Op{Kind: Lookup, Key: key}
+		case x < 98:
+			ops[i] = Op{Kind: InsertNode, Node: "node" + strconv.Itoa(i), Threshold: 1 + rng.Intn(10)}
+		default:
+			ops[i] = Op{Kind: RemoveNode, Pick: rng.Intn(1 << 16)}
+		}
+	}
+	return ops
+}
+
+// Model is the reference a tree is checked against: the set of keys the tree should hold.
+type Model struct {
+	keys map[string]bool
+}
+
+// NewModel returns an empty model.
+func NewModel() *Model {
+	return &Model{keys: make(map[string]bool)}
+}
+
+// Apply applies an operation to the model and returns the error the tree should return for it, matched
+// with errors.Is: ErrKeyExists for inserting a present key and ErrKeyNotFound for removing or looking up an
+// absent one. Node operations do not change the keys, so their outcome is not modelled.
+func (m *Model) Apply(op Op) error {
+	switch op.Kind {
+	case InsertKey:
+		if m.keys[op.Key] {
+			return ringtree.ErrKeyExists
+		}
+		m.keys[op.Key] = true
+	case RemoveKey:
+		if !m.keys[op.Key] {
+			return ringtree.ErrKeyNotFound
+		}
+		delete(m.keys, op.Key)
+	case Lookup:
+		if !m.keys[op.Key] {
+			return ringtree.ErrKeyNotFound
+		}
+	}
+	return nil
+}
+
+// Has reports whether the model holds a key.
+func (m *Model) Has(key string) bool {
+	return m.keys[key]
+}
+
+// Len returns the number of keys in the model.
+func (m *Model) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of the model in sorted order.
+func (m *Model) Keys() []string {
+	keys := make([]string, 0, len(m.keys))
+	for key := range m.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Divergence reports an operation on which the tree and the model disagreed.
+type Divergence struct {
+	Step int   // Index of the operation in the sequence
+	Op   Op    // Operation applied
+	Err  error // What went wrong
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("step %d: %v: %v", d.Step, d.Op, d.Err)
+}
+
+func (d *Divergence) Unwrap() error {
+	return d.Err
+}
+
+// Run applies the operations to the tree and to a model of the keys the tree holds at the start, in
+// order, and returns a *Divergence for the first operation whose outcome the model does not predict, or after
+// which the tree counts a different number of keys. Once every operation has run it checks the tree with
+// Verify. Node joins may fail with ErrNodeExists or ErrRingAtCapacity, and the last node of the root ring
+// may refuse to leave; neither counts as a divergence.
+func Run(rt *ringtree.Ring, ops []Op) error {
+	m := NewModel()
+	for _, id := range rt.NodeIDs() {
+		keys, err := rt.KeysForNode(id)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			m.keys[key] = true
+		}
+	}
+
+	for step, op := range ops {
+		if err := apply(rt, m, op); err != nil {
+			return &Divergence{Step: step, Op: op, Err: err}
+		}
+		if got := rt.Stats().Keys(); got != m.Len() {
+			return &Divergence{Step: step, Op: op, Err: fmt.Errorf("tree counts %d keys, model holds %d", got, m.Len())}
+		}
+	}
+	return Verify(rt, m)
+}
+
+// apply applies an operation to the tree and the model, and returns an error if the tree's outcome differs
+// from the model's.
+func apply(rt *ringtree.Ring, m *Model, op Op) error {
+	var err error
+	switch op.Kind {
+	case InsertKey:
+		err = rt.InsertKey(op.Key)
+	case RemoveKey:
+		err = rt.RemoveKey(op.Key)
+	case Lookup:
+		_, err = rt.Lookup(op.Key)
+	case InsertNode:
+		err = rt.InsertNode(ringtree.NewNode(op.Node, op.Threshold))
+		if err != nil && !errors.Is(err, ringtree.ErrNodeExists) && !errors.Is(err, ringtree.ErrRingAtCapacity) {
+			return err
+		}
+		return nil
+	case RemoveNode:
+		id := op.Node
+		if id == "" {
+			ids := rt.NodeIDs()
+			if len(ids) == 0 {
+				return nil
+			}
+			id = ids[op.Pick%len(ids)]
+		}
+		err = rt.RemoveNodeByID(id)
+		if err != nil && !errors.Is(err, ringtree.ErrNodeNotFound) && !strings.Contains(err.Error(), "not enough nodes") {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation kind %v", op.Kind)
+	}
+
+	want := m.Apply(op)
+	switch {
+	case want == nil && err != nil:
+		return fmt.Errorf("got %v, want success", err)
+	case want != nil && !errors.Is(err, want):
+		return fmt.Errorf("got %v, want %v", err, want)
+	}
+	return nil
+}
+
+// Verify compares the tree with the model: it must hold exactly the model's keys, each on the node a fresh
+// routing table places it on, and satisfy every structural invariant of Ring.Validate. All differences are
+// returned, joined. Keys a node join left behind are moved first, since looking them up would move them.
+func Verify(rt *ringtree.Ring, m *Model) error {
+	rt.SettleRemaps()
+	errs := rt.Validate()
+	if got := rt.Stats().Keys(); got != m.Len() {
+		errs = append(errs, fmt.Errorf("tree counts %d keys, model holds %d", got, m.Len()))
+	}
+
+	stored := make(map[string]string)
+	for _, id := range rt.NodeIDs() {
+		keys, err := rt.KeysForNode(id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, key := range keys {
+			if other, dup := stored[key]; dup {
+				errs = append(errs, fmt.Errorf("key %s is stored on both %s and %s", key, other, id))
+			}
+			stored[key] = id
+			if !m.Has(key) {
+				errs = append(errs, fmt.Errorf("key %s is stored on %s but not in the model", key, id))
+			}
+		}
+	}
+
+	table := rt.RoutingTable()
+	for _, key := range m.Keys() {
+		owner, err := rt.Lookup(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("looking up key %s: %w", key, err))
+			continue
+		}
+		if want, err := table.Owner(key); err == nil && owner != want {
+			errs = append(errs, fmt.Errorf("key %s is on %s, routing places it on %s", key, owner, want))
+		}
+		if stored[key] != owner {
+			errs = append(errs, fmt.Errorf("key %s is looked up on %s but stored on %q", key, owner, stored[key]))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+package ringtest
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+func TestRun(t *testing.T) {
+	configs := map[string][]ringtree.Option{
+		"default":       nil,
+		"array":         {ringtree.WithArrayCircle(true)},
+		"sibling merge": {ringtree.WithSiblingMerge(true)},
+		"remap budget":  {ringtree.WithRemapBudget(3)},
+		"key index":     {ringtree.WithKeyIndex(true)},
+	}
+	for name, opts := range configs {
+		t.Run(name, func(t *testing.T) {
+			rt := ringtree.New(3, opts...)
+			if err := rt.InsertNode(ringtree.NewNode("a", 4)); err != nil {
+				t.Fatal(err)
+			}
+			ops := RandomOps(rand.New(rand.NewSource(1)), 4000, 150)
+			if err := Run(rt, ops); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRunExistingKeys(t *testing.T) {
+	rt := ringtree.New(4)
+	rt.InsertNode(ringtree.NewNode("a", 100))
+	rt.InsertKeys("key1", "key2")
+
+	// Keys already in the tree are part of the model, so inserting them again is expected to fail
+	ops := []Op{{Kind: InsertKey, Key: "key1"}, {Kind: RemoveKey, Key: "key2"}, {Kind: Lookup, Key: "key2"}, {Kind: RemoveNode, Node: "a"}}
+	if err := Run(rt, ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(rt, []Op{{Kind: Kind(9)}}); err == nil {
+		t.Fatal("expected an unknown operation to diverge")
+	} else if d := (*Divergence)(nil); !errors.As(err, &d) || d.Step != 0 {
+		t.Errorf("expected a divergence at step 0, got %v", err)
+	}
+}
+
+func TestModel(t *testing.T) {
+	m := NewModel()
+	if err := m.Apply(Op{Kind: InsertKey, Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply(Op{Kind: InsertKey, Key: "k"}); !errors.Is(err, ringtree.ErrKeyExists) {
+		t.Errorf("expected ErrKeyExists, got %v", err)
+	}
+	if err := m.Apply(Op{Kind: RemoveKey, Key: "k"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply(Op{Kind: Lookup, Key: "k"}); !errors.Is(err, ringtree.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if m.Len() != 0 || m.Has("k") {
+		t.Errorf("expected an empty model, got %v", m.Keys())
+	}
+}
+
+func TestVerify(t *testing.T) {
+	rt := ringtree.New(4)
+	rt.InsertNode(ringtree.NewNode("a", 100))
+	rt.InsertKeys("key1", "key2")
+	m := NewModel()
+	m.Apply(Op{Kind: InsertKey, Key: "key1"})
+	m.Apply(Op{Kind: InsertKey, Key: "key3"})
+	err := Verify(rt, m)
+	for _, want := range []string{"key key2 is stored on a but not in the model", "looking up key key3"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+	m.Apply(Op{Kind: RemoveKey, Key: "key3"})
+	m.Apply(Op{Kind: InsertKey, Key: "key2"})
+	if err := Verify(rt, m); err != nil {
+		t.Errorf("expected the tree to match the model, got %v", err)
+	}
+}
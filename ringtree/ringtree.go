@@ -1,79 +1,232 @@
 package ringtree
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	stdhash "hash"
+	"math/rand"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spaolacci/murmur3"
 )
 
-var useArray = false     // Array or Red-Black tree
-var branchFactor int = 1 // Global branch factor (can increase or decrease maxCount)
-var NumReplicas int = 20 // Global number of replicas (vnodes)
+var trees atomic.Uint64 // Number of trees created, numbering their writer locks
 
-// hash returns a hash value based on the key and level, ensuring remap compatibility.
-func hash(key string, level int) uint32 {
-	// Create a new Murmur3 hash instance.
-	h := murmur3.New32()
+// writerLock serializes the mutations of a tree. seq numbers trees in creation order, so operations spanning
+// two trees take their locks in the same order. root and merged are guarded by the lock, so callbacks running
+// outside any operation, such as maintenance timers, find the tree through it.
+type writerLock struct {
+	sync.Mutex
+	seq    uint64
+	root   *Ring       // Root of the tree
+	merged *writerLock // Lock of the tree this one was merged into, if any
+}
 
-	// Encode the level as binary data.
-	levelBytes := make([]byte, 4)
-	binary.LittleEndian.PutUint32(levelBytes, uint32(level))
+// lockTree takes the writer lock of the tree the lock's tree was last merged into, or its own, and returns
+// that tree's root along with the lock held.
+func (w *writerLock) lockTree() (*Ring, *writerLock) {
+	w.Lock()
+	for w.merged != nil {
+		next := w.merged
+		w.Unlock()
+		w = next
+		w.Lock()
+	}
+	return w.root, w
+}
 
-	// Concatenate the key bytes with the level bytes and write them to the hash.
-	h.Write([]byte(key))
-	h.Write(levelBytes)
+// keyHasher is a murmur3 hasher with a buffer for its input, reused through hashers.
+type keyHasher struct {
+	h   stdhash.Hash32
+	buf []byte
+}
+
+// hashers reuses hashers and their buffers, so hashing a key does not allocate.
+var hashers = sync.Pool{New: func() any { return &keyHasher{h: murmur3.New32()} }}
 
-	// Return the computed hash value.
-	return h.Sum32()
+// sum returns the murmur3 hash of the key bytes followed by the suffix.
+func (k *keyHasher) sum(key string, suffix []byte) uint32 {
+	k.buf = append(append(k.buf[:0], key...), suffix...)
+	k.h.Reset()
+	k.h.Write(k.buf)
+	return k.h.Sum32()
+}
+
+// hash returns a hash value based on the key and level, ensuring remap compatibility.
+func hash(key string, level int) uint32 {
+	// Hash the key bytes followed by the level as little-endian binary data
+	var levelBytes [4]byte
+	binary.LittleEndian.PutUint32(levelBytes[:], uint32(level))
+	k := hashers.Get().(*keyHasher)
+	defer hashers.Put(k)
+	return k.sum(key, levelBytes[:])
 }
 
 // Ring is the main structure for hierarchical consistent hashing implementation.
+//
+// A Ring is safe for concurrent use. Mutations anywhere in the tree (node and key inserts and removals,
+// splits, collapses, merges and state changes) are serialized by a writer lock shared by the whole tree, and
+// each ring's RWMutex guards its circle and members against concurrent readers. Lookups hold at most one
+// ring's lock at a time, and when two ring locks are held together the parent's is always taken first.
 type Ring struct {
-	id       string                 // Physical ring identifier
-	level    int                    // Level of the hierarchy the ring exists on
-	circle   Circle                 // Storing sorted virtual node hashes, maps virtual nodes to physical nodes
-	members  map[string]interface{} // Tracks physical nodes and subrings objects on the ring
-	maxCount int                    // Max members on the ring
-	parent   *Ring                  // Reference to parent ring
+	id        string                         // Physical ring identifier
+	level     int                            // Level of the hierarchy the ring exists on
+	circle    Circle                         // Storing sorted virtual node hashes, maps virtual nodes to physical nodes
+	members   map[string]Member              // Tracks physical nodes, subrings and custom members on the ring
+	maxCount  int                            // Max members on the ring
+	parent    *Ring                          // Reference to parent ring
+	config    *Config                        // Configuration shared with the whole tree
+	hub       *watchHub                      // Watch subscribers, shared with the whole tree
+	keyspaces *keyspaceRegistry              // Keyspaces, shared with the whole tree
+	batch     *joinBatch                     // Pending node joins waiting for the batch window
+	migrating *Node                          // Node whose keys are still being moved into this subring
+	reserved  map[string]*Pending            // Prepared node additions holding a slot on this ring
+	snapshot  atomic.Pointer[circleSnapshot] // Circle and members as of the last membership change, for lock-free reads
+	stats     *Stats                         // Operation statistics, shared with the whole tree
+	index     *keyIndex                      // Node of every key when KeyIndex is set, shared with the whole tree
+	pins      *pinTable                      // Placement overrides, shared with the whole tree
+	remaps    *remapTable                    // Keys whose remap a node join deferred, shared with the whole tree
+	freeze    *freezeState                   // Topology freeze and deferred structural changes, shared with the whole tree
+	gossip    *gossipState                   // Gossip sequence numbers and handlers, shared with the whole tree
+	seen      *seenCache                     // IDs of the gossip messages this ring has spread
+	leases    *leaseTable                    // Heartbeat leases of nodes, shared with the whole tree
+	wal       *walState                      // Operation sequence and generated node IDs, shared with the whole tree
+	trace     *traceState                    // Span of the mutation in progress, shared with the whole tree
+	logs      *logState                      // Sampling and rate limit of operation logs, shared with the whole tree
+	heat      *heatTable                     // Decayed access counts of keys when HeatHalfLife is set, shared with the whole tree
+	spreads   *spreadTable                   // Hot keys whose reads are spread over followers, shared with the whole tree
+	history   *historyState                  // Topology of past epochs when History is set, shared with the whole tree
+	joins     *joinState                     // Keys taken over by the node being joined, shared with the whole tree
+	policy    SplitPolicy                    // Split policy of this ring, overriding the tree's
+	writer    *writerLock                    // Serializes mutations across the whole tree
+	high      float64                        // Fraction of a node's threshold at which it splits
+	low       float64                        // Fraction of a node's threshold below which it is removed
 	sync.RWMutex
 }
 
 // Node represents a node (physical server) in the ring tree.
 type Node struct {
-	id        string                        // Physical node identifer
-	keys      map[uint32]map[string]*uint32 // Map of virtual nodes to key string to key hash
-	load      int                           // Tracks load of node
-	threshold int                           // Threshold of keys before node is considered overloaded
+	id          string              // Physical node identifer
+	keys        map[uint32]keySet   // Keys of each virtual node
+	load        int                 // Tracks load of node, in keys or LoadFunc units
+	costs       map[string]int      // Load of keys that do not cost exactly one unit
+	threshold   int                 // Threshold of keys before node is considered overloaded
+	state       NodeState           // Base health state of the node
+	maintenance []maintenanceWindow // Scheduled periods during which the node is draining
+	changedAt   time.Time           // Last structural change involving the node
+	base        int                 // Threshold the node was created with
+	lastMessage Message             // Last gossip message delivered to the node
+	seen        *seenCache          // IDs of the gossip messages delivered to the node
+	checksums   map[string]uint32   // Secondary hash of each key, kept when checksums are enabled
+	reads       atomic.Int64        // Reads routed to the node by spreading or a read strategy
+	locality    Locality            // Region and zone of the node
+	vNodeStreak int                 // Consecutive AdjustVNodes rounds past the factor, above the mean if positive
+	requests    requestLoad         // Decayed count of the reads served, when RequestHalfLife is set
+	shared      map[uint32]bool     // Vnodes whose key set an iterator's snapshot holds, copied on the next write
+	warmingAt   time.Time           // Start of the node's warm-up ramp, zero once it has all of its vnodes
 }
 
-// newRingTree initializes a new ring tree at level 0.
-func New(maxCount int) *Ring {
-	remapped = 0
-	numNodes = 0
-	numKeys = 0
+// keySet maps the keys of a virtual node to their hashes. Hashes are stored by value, so storing a key costs
+// its map entry and nothing more.
+type keySet map[string]uint32
+
+// New initializes a new ring tree at level 0.
+func New(maxCount int, opts ...Option) *Ring {
 	if maxCount < 2 {
 		maxCount = 2
 	}
-	r := newRing(nil, "main", 0, maxCount)
+	config := defaultConfig(maxCount)
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.CapacityFunc != nil || len(config.Schedule) > 0 {
+		maxCount = config.capacity(0, maxCount)
+		config.MaxCount = maxCount
+	}
+	return newRoot(config, maxCount)
+}
+
+// newRoot initializes the root ring of a new tree with the given configuration, and the state its rings
+// share.
+func newRoot(config *Config, maxCount int) *Ring {
+	r := newRing(config, nil, "main", 0, maxCount)
+	r.hub = newWatchHub()
+	r.keyspaces = newKeyspaceRegistry()
+	r.stats = newStats()
+	r.writer = &writerLock{seq: trees.Add(1), root: r}
+	r.pins = newPinTable()
+	r.remaps = newRemapTable()
+	r.freeze = &freezeState{}
+	r.gossip = &gossipState{handlers: make(map[string][]GossipHandler)}
+	r.leases = newLeaseTable()
+	r.wal = &walState{}
+	if config.RandSource != nil {
+		r.wal.ids = rand.New(config.RandSource)
+	}
+	r.trace = &traceState{}
+	r.logs = &logState{}
+	r.spreads = newSpreadTable()
+	r.joins = &joinState{}
+	if config.KeyIndex {
+		r.index = newKeyIndex()
+	}
+	if config.HeatHalfLife > 0 {
+		r.heat = newHeatTable(config.HeatHalfLife)
+	}
+	if config.History > 0 {
+		r.history = &historyState{limit: config.History}
+		r.hub.history = r.history
+	}
 	return r
 }
 
-// newRing initializes a new subring with the current level's maxCount (adjusted by branchFactor).
-func newRing(parent *Ring, id string, level int, maxCount int) *Ring {
-	circle := NewCircle(useArray)
-	return &Ring{
+// newRing initializes a new subring with the current level's maxCount (from the capacity schedule or branch factor).
+func newRing(config *Config, parent *Ring, id string, level int, maxCount int) *Ring {
+	circle := config.newCircle()
+	r := &Ring{
 		id:       id,
 		parent:   parent,
 		level:    level,
 		circle:   circle,
-		members:  make(map[string]interface{}),
+		members:  make(map[string]Member),
 		maxCount: maxCount,
+		batch:    &joinBatch{},
+		seen:     newSeenCache(),
+		config:   config,
+		high:     config.HighWatermark,
+		low:      config.LowWatermark,
 	}
+	if parent != nil {
+		r.share(parent)
+	}
+	return r
+}
+
+// share points the ring at the state its parent shares with the whole tree.
+func (r *Ring) share(parent *Ring) {
+	r.hub = parent.hub
+	r.keyspaces = parent.keyspaces
+	r.stats = parent.stats
+	r.writer = parent.writer
+	r.index = parent.index
+	r.pins = parent.pins
+	r.remaps = parent.remaps
+	r.freeze = parent.freeze
+	r.gossip = parent.gossip
+	r.leases = parent.leases
+	r.wal = parent.wal
+	r.trace = parent.trace
+	r.joins = parent.joins
+	r.logs = parent.logs
+	r.heat = parent.heat
+	r.spreads = parent.spreads
+	r.history = parent.history
 }
 
 // NewNode initialize a new Node with a threshold.
@@ -83,104 +236,202 @@ func NewNode(id string, threshold int) *Node {
 	}
 	return &Node{
 		id:        id,
-		keys:      make(map[uint32]map[string]*uint32),
+		keys:      make(map[uint32]keySet),
 		load:      0,
 		threshold: threshold,
+		base:      threshold,
+		seen:      newSeenCache(),
 	}
 }
 
-// InsertNode adds a physical node and its virtual nodes to the ring.
+// InsertNode adds a physical node and its virtual nodes to the ring. With AutoSplit, a node joining a full
+// ring is placed in a subring instead of failing with ErrRingAtCapacity.
 func (r *Ring) InsertNode(node *Node) error {
-	defer timeTrack(time.Now(), "InsertNode", "to insert a node on level "+strconv.Itoa(r.level))
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.insertNodeOp(node)
+}
+
+// insertNodeOp inserts a node as one logged operation, splitting the root if it is full and AutoSplit is
+// set (assuming the tree's writer lock is held).
+func (r *Ring) insertNodeOp(node *Node) error {
+	r.beginOp()
+	op := Op{Type: OpInsertNode, Node: node.id, Threshold: node.threshold, State: node.state}
+	span := r.traceOp("InsertNode", Attribute{AttrNodeID, node.id})
+	r.config.startWarmUp(node)
+	err := r.insertNode(node)
+	if err == ErrRingAtCapacity && r.config.AutoSplit {
+		if member, _ := r.root().findTarget(node.id); member != nil {
+			span.end(ErrNodeExists)
+			return ErrNodeExists
+		}
+		err = r.insertNodeSplitting(node)
+	}
+	span.end(err)
+	return r.logOp(op, err)
+}
+
+// insertNode adds a physical node to the ring (assuming the tree's writer lock is held).
+func (r *Ring) insertNode(node *Node) error {
+	r.settleRemaps()
+	defer r.timeTrack(time.Now(), "InsertNode", "to insert a node on level "+strconv.Itoa(r.level))
 	r.Lock()
 	defer r.Unlock()
+	defer r.publish()
 
-	// Check if ring has reached the max number of physical nodes
-	if len(r.members) >= r.maxCount {
-		return errors.New("ring is at capacity")
+	// Check if ring has reached the max number of physical nodes, counting prepared additions
+	if len(r.members)+len(r.reserved) >= r.maxCount {
+		return ErrRingAtCapacity
 	}
-	if r.members[node.id] != nil {
-		return errors.New("node is already in the ring")
+	if r.members[node.id] != nil || r.reserved[node.id] != nil {
+		return ErrNodeExists
 	}
 
-	// Add the node to members
-	r.members[node.id] = node
+	// Add all vNodes to the circle in one batch, then remap the keys they take over in one pass
+	newVNodes := make(map[uint32]*Node)
+	for _, vNode := range r.circle.InsertBatch(r.admitNode(node)) {
+		node.keys[vNode.hash] = make(keySet) // Initialize key map for this vNode
+		newVNodes[vNode.hash] = node
+		r.logf("Virtual node %d added to the ring.\n", vNode.hash)
+	}
+	if r.Size() > 1 && (!r.IsEmpty() || r.hasSubrings()) {
+		r.remapBatch(newVNodes)
+	}
 
-	// Add vNodes to the circle and remap keys after each addition
-	for i := 0; i < NumReplicas; i++ {
-		vNodeHash := hash(node.id, i)
-		r.circle.Insert(vNodeHash, node.id)             // Insert vNode into the circle
-		r.circle.Sort()                                 // Ensure the circle remains sorted
-		node.keys[vNodeHash] = make(map[string]*uint32) // Initialize key map for this vNode
-		fmt.Printf("Virtual node %d added to the ring.\n", vNodeHash)
-
-		// Remap keys for this specific vnode
-		if r.Size() > 1 && !r.IsEmpty() {
-			err := r.remapKeys(node, vNodeHash)
-			if err != nil {
-				return err
-			}
-		}
+	// Correct the node's arc share if its vnodes landed unevenly
+	if err := r.enforceSpread(node); err != nil {
+		return err
 	}
 
-	fmt.Printf("Node %s successfully added to the ring.\n", node.id)
-	numNodes++
-	calculateRemapComplexity()
+	r.logf("Node %s successfully added to the ring.\n", node.id)
+	r.stats.numNodes.add(1)
+	r.emit(Event{Type: NodeAdded, RingID: r.id, NodeID: node.id, Level: r.level, Remapped: r.stats.remapped.get()})
+	r.stats.calculateRemapComplexity()
 	return nil
 }
 
+// admitNode adds a node to the ring's members and returns the vnodes to place for it: all of them, or the
+// first of them while it warms up, gaining the rest as its ramp advances (assuming mutex is already locked).
+func (r *Ring) admitNode(node *Node) []VNode {
+	r.members[node.id] = node
+	node.changedAt = time.Now()
+	vNodes := r.config.vNodes(node.id)
+	if node.warming() {
+		vNodes = vNodes[:r.config.warmUpVNodes(0)]
+	}
+	return vNodes
+}
+
 // RemoveNode removes a physical node and its vNodes, from the ring and remaps its keys to the next closest node or subring.
 func (r *Ring) RemoveNode(node *Node) error {
-	defer timeTrack(time.Now(), "RemoveNode", "to remove a node on level "+strconv.Itoa(r.level))
-	r.Lock()
-	defer r.Unlock()
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.beginOp()
+	return r.logOp(Op{Type: OpRemoveNode, Node: node.id}, r.removeNode(node))
+}
+
+// RemoveNodeByID removes a physical node anywhere in the tree and remaps its keys within the ring holding it.
+func (r *Ring) RemoveNodeByID(nodeID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.removeNodeOp(nodeID)
+}
 
-	if r.Size() <= 1 && r.parent == nil {
+// removeNodeOp removes a node anywhere in the tree as one logged operation (assuming the tree's writer lock
+// is held).
+func (r *Ring) removeNodeOp(nodeID string) error {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	r.beginOp()
+	return r.logOp(Op{Type: OpRemoveNode, Node: nodeID}, ring.removeNode(node))
+}
+
+// removeNode removes a physical node from the ring (assuming the tree's writer lock is held).
+func (r *Ring) removeNode(node *Node) error {
+	defer r.timeTrack(time.Now(), "RemoveNode", "to remove a node on level "+strconv.Itoa(r.level))
+	if r.pins.targeted(node.id) {
+		return ErrNodePinned
+	}
+	r.settleRemaps()
+	r.RLock()
+	tooSmall, collapse := r.Size() <= 1 && r.parent == nil, r.shouldCollapse()
+	r.RUnlock()
+
+	if tooSmall {
 		return errors.New("not enough nodes in the circle to perform remapping")
 	}
 
-	// Check and collapse the ring if necessary
-	if r.shouldCollapse() {
-		_, err := r.collapseRing(node)
+	// Check and collapse the ring if necessary; the collapse locks the parent before this ring
+	if collapse {
+		_, err := r.collapseRing(node.threshold)
 		return err
 	}
 
-	fmt.Printf("Removing node %s with load %d and remapping its keys.\n", node.id, node.load)
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	r.logf("Removing node %s with load %d and remapping its keys.\n", node.id, node.load)
+	if err := r.verifyNode(node); err != nil {
+		return err
+	}
 
 	// Iterate over the vNodes of the node being removed
 	for vNodeHash := range node.keys {
 		if len(node.keys[vNodeHash]) > 0 {
 			// Find the next closest vNode in the ring for remapping
-			nextVNodeHash, nextNodeId := r.circle.FindNextClosest(vNodeHash)
-			for nextNodeId == node.id {
-				nextVNodeHash, nextNodeId = r.circle.FindNextClosest(nextVNodeHash)
+			it := r.circle.Iter(vNodeHash)
+			nextVNodeHash, nextNodeId := it.Next()
+			for i := 0; i < r.circle.Size() && nextNodeId == node.id; i++ {
+				nextVNodeHash, nextNodeId = it.Next()
 			}
-			if nextNodeId == "" {
+			if nextNodeId == "" || nextNodeId == node.id {
 				return errors.New("no valid next node found for remapping")
 			}
-			fmt.Printf("Remapping keys from vnode %d to next vnode %d (node %s).\n", vNodeHash, nextVNodeHash, nextNodeId)
+			r.logf("Remapping keys from vnode %d to next vnode %d (node %s).\n", vNodeHash, nextVNodeHash, nextNodeId)
 			// Handle the case where the next node is a subring
 			switch nextNode := r.members[nextNodeId].(type) {
 			case *Node:
 				// Move the keys from the removed node's vNode to the next physical node's vNode
 				for key, hashValue := range node.keys[vNodeHash] {
-					r.moveKey(key, hashValue, node, vNodeHash, nextNode, nextVNodeHash)
+					if err := r.moveKey(key, hashValue, node, vNodeHash, nextNode, nextVNodeHash); err != nil {
+						return err
+					}
 				}
 			case *Ring:
 				// Remap the keys into the next subring
-				fmt.Printf("Remapping keys into subring %s for vnode %d.\n", nextNode.id, nextVNodeHash)
+				r.logf("Remapping keys into subring %s for vnode %d.\n", nextNode.id, nextVNodeHash)
 				for key := range node.keys[vNodeHash] {
-					remapped++
-					numKeys--
-					node.load--
-					err := nextNode.InsertKey(key) // Insert the key into the subring
+					r.stats.remapped.add(1)
+					r.countKey(key, -1)
+					cost := node.cost(key)
+					node.load -= cost
+					delete(node.costs, key)
+					err := nextNode.insertKey(key, cost, true) // Insert the key into the subring
 					if err != nil {
-						fmt.Printf("Error inserting key %s into subring: %v\n", key, err)
+						r.logf("Error inserting key %s into subring: %v\n", key, err)
 						return err
 					}
 				}
 			default:
-				return errors.New("next node is not valid")
+				// Hand the keys to the custom member
+				custom := customRoute{member: nextNode}
+				writer, err := custom.writer()
+				if err != nil {
+					return err
+				}
+				for key := range node.keys[vNodeHash] {
+					r.stats.remapped.add(1)
+					r.countKey(key, -1)
+					node.clearCost(key)
+					delete(node.checksums, key)
+					r.index.delete(key)
+					if err := writer.InsertKey(key); err != nil {
+						return err
+					}
+				}
 			}
 		}
 
@@ -189,105 +440,195 @@ func (r *Ring) RemoveNode(node *Node) error {
 
 		// Remove the vNode from the circle
 		r.circle.Delete(vNodeHash)
-		fmt.Printf("Virtual node %d removed from the ring.\n", vNodeHash)
+		r.logf("Virtual node %d removed from the ring.\n", vNodeHash)
 	}
 
 	r.circle.Sort()
 	if node.load != 0 {
-		fmt.Printf("Node still has %v keys.\n", node.load)
+		r.logf("Node still has %v keys.\n", node.load)
 		return errors.New("error removing keys from node")
 	}
 
 	// Remove the physical node from the members
 	if _, exists := r.members[node.id]; exists {
 		delete(r.members, node.id)
-		fmt.Printf("Node %s removed.\n", node.id)
+		node.stopMaintenance()
+		r.logf("Node %s removed.\n", node.id)
 	} else {
 		return errors.New("node not found in members during removal")
 	}
 
-	numNodes--
-	calculateRemapComplexity()
+	r.stats.numNodes.add(-1)
+	r.emit(Event{Type: NodeRemoved, RingID: r.id, NodeID: node.id, Level: r.level, Remapped: r.stats.remapped.get()})
+	r.stats.calculateRemapComplexity()
 	return nil
 }
 
 // FindNode finds the node responsible for a given key.
-func (r *Ring) FindNode(key string) (*Node, *Ring, uint32, *uint32, error) {
-	r.RLock()
-	defer r.RUnlock()
+func (r *Ring) FindNode(key string) (*Node, *Ring, uint32, uint32, error) {
+	return r.findNode(key, true)
+}
 
+// findNode finds the node for a key, honouring pins. Writes skip nodes that are not accepting writes, while
+// reads go to the key's plain owner.
+func (r *Ring) findNode(key string, write bool) (*Node, *Ring, uint32, uint32, error) {
+	if node, parent, vNodeHash, keyHash, err, pinned := r.pinnedRoute(key, write); pinned {
+		return node, parent, vNodeHash, keyHash, err
+	}
+	return r.routeKey(key, write)
+}
+
+// routeKey finds the node for a key by its hash.
+func (r *Ring) routeKey(key string, write bool) (*Node, *Ring, uint32, uint32, error) {
+	r.RLock()
 	if r.Size() == 0 {
-		return nil, nil, 0, nil, errors.New("ring is empty")
+		r.RUnlock()
+		return nil, nil, 0, 0, ErrRingEmpty
 	}
 
 	// Hash the key and find the closest node in the ring
-	keyHash := hash(key, r.level)
+	keyHash := r.config.keyHash(key, r.level)
 	vNodeHash, nodeId := r.circle.FindClosest(keyHash)
-	fmt.Printf("FindNode found vNodeHash: %d, value: %s.\n", vNodeHash, nodeId)
+	if write {
+		vNodeHash, nodeId = r.routeWrite(keyHash)
+	}
+	if r.logging() {
+		r.logf("FindNode found vNodeHash: %d, value: %s.\n", vNodeHash, nodeId)
+	}
 
 	// Check if node id has a corresponding entry in the circle map
-	if nodeId == "" || r.members[nodeId] == nil {
-		fmt.Println(nodeId)
-		return nil, nil, 0, nil, errors.New("hash not found in circle map")
+	member := r.members[nodeId]
+	r.RUnlock()
+	if nodeId == "" || member == nil {
+		r.logf("Member %s not found.\n", nodeId)
+		return nil, nil, 0, 0, errors.New("hash not found in circle map")
 	}
 
-	// If the result is a subring, recurse into the subring
-	switch node := r.members[nodeId].(type) {
+	// If the result is a subring, recurse into the subring after releasing this ring, so a reader never
+	// holds a parent's lock while waiting on a child's
+	switch node := member.(type) {
 	case *Node:
-		return node, r, vNodeHash, &keyHash, nil
+		return node, r, vNodeHash, keyHash, nil
 	case *Ring:
-		return node.FindNode(key)
+		return node.routeKey(key, write)
 	default:
-		return nil, nil, 0, nil, errors.New("invalid object in ring")
+		return nil, r, vNodeHash, keyHash, customRoute{member: node, keyHash: keyHash}
 	}
 }
 
 // InsertKey inserts a key into the node that handles it. If the node is overloaded, the system balances the load.
 func (r *Ring) InsertKey(key string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.insertKeyOp(key, nil)
+}
+
+// InsertKeyValue inserts a key whose load is measured from its value by the configured LoadFunc.
+func (r *Ring) InsertKeyValue(key string, value []byte) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.insertKeyOp(key, value)
+}
+
+// insertKeyOp inserts a key as one logged operation, with its load measured from value if it has one
+// (assuming the tree's writer lock is held).
+func (r *Ring) insertKeyOp(key string, value []byte) error {
+	r.beginOp()
+	span := r.traceOp("InsertKey", Attribute{AttrKey, key})
+	err := r.insertKey(key, r.config.cost(key, value), false)
+	if err == nil {
+		r.touch(key)
+	}
+	span.end(err)
+	return r.logOp(Op{Type: OpInsertKey, Key: key, Value: value}, err)
+}
+
+// insertKey inserts a key with the given load, treating the node as full at its rebalance capacity when the
+// key is being redistributed by a split, collapse or removal rather than written by a caller (assuming the
+// tree's writer lock is held).
+func (r *Ring) insertKey(key string, cost int, rebalance bool) error {
 	start := time.Now()
-	fmt.Printf("Inserting key %s.\n", key)
+	if r.logging() {
+		r.logf("Inserting key %s.\n", key)
+	}
+	// Keys redistributed by a rebalance are still indexed at their old node
+	if _, exists := r.index.get(key); exists && !rebalance {
+		return ErrKeyExists
+	}
+	r.settleKey(key)
 	node, parent, vNodeHash, keyHash, err := r.FindNode(key)
-	fmt.Printf("FindNode for %d finished: %s.\n", *keyHash, node.id)
-
+	var custom customRoute
+	if errors.As(err, &custom) {
+		writer, err := custom.writer()
+		if err != nil {
+			return err
+		}
+		return writer.InsertKey(key)
+	}
 	if err != nil {
 		return err
 	}
+	if r.logging() {
+		r.logf("FindNode for %d finished: %s.\n", keyHash, node.id)
+	}
 
-	if node.keys[vNodeHash][key] != nil {
-		return errors.New("key is already in ring")
+	if _, exists := node.keys[vNodeHash][key]; exists {
+		return ErrKeyExists
 	}
 
 	// Add key if the node is not overloaded
 	parent.Lock()
-	if node.load < node.threshold {
-		node.keys[vNodeHash][key] = keyHash
-		node.load++
-		numKeys++
-		fmt.Printf("Key %s inserted into node %s (Load: %d).\n", key, node.id, node.load)
-		timeTrack(start, "InsertKey", "to insert "+key+" on level "+strconv.Itoa(parent.level))
+	capacity := int(parent.high * float64(node.threshold))
+	if rebalance {
+		capacity -= int(r.config.Headroom * float64(node.threshold))
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	// While the topology is frozen an overloaded node takes the key and its growth is queued
+	if node.load == 0 || node.load+cost <= capacity || (parent.Size() >= parent.maxCount && node.dwelling(r.config.MinDwell)) ||
+		r.pinnedTo(key, node) || r.deferChange(node) {
+		node.writable(vNodeHash)[key] = keyHash
+		node.setCost(key, cost)
+		r.index.set(key, node, parent)
+		if r.config.Checksums {
+			node.setChecksum(key, keyHash)
+		}
+		r.countKey(key, 1)
+		if r.logging() {
+			r.logf("Key %s inserted into node %s (Load: %d).\n", key, node.id, node.load)
+		}
+		r.timeTrackAt(start, "InsertKey", parent.level, "to insert "+key+" on level "+strconv.Itoa(parent.level))
 	} else {
-		timeTrack(start, "InsertKey", "to insert "+key+" on level "+strconv.Itoa(parent.level))
+		r.timeTrackAt(start, "InsertKey", parent.level, "to insert "+key+" on level "+strconv.Itoa(parent.level))
 		// Node is overloaded, check if a new node can be added to the parent ring first
 		if parent.Size() < parent.maxCount {
-			fmt.Printf("Adding new node for key: %s\n", key)
-			NewNode := NewNode("", node.threshold)
+			r.logf("Adding new node for key: %s\n", key)
+			NewNode := r.seedNode(node, node.threshold)
 			parent.Unlock()
-			err := parent.InsertNode(NewNode)
+			err := parent.insertNode(NewNode)
 			if err != nil {
 				return err
 			}
-			return parent.InsertKey(key)
+			return parent.insertKey(key, cost, rebalance)
+		} else if r.config.MaxDepth > 0 && parent.level >= r.config.MaxDepth {
+			// The tree may not grow deeper, so raise the node's threshold instead of splitting it
+			node.threshold += node.baseThreshold()
+			r.logf("Raised threshold of node %s to %d at max depth %d.\n", node.id, node.threshold, r.config.MaxDepth)
+			parent.Unlock()
+			return parent.insertKey(key, cost, rebalance)
 		} else {
 			// If the parent ring has reached its capacity, split the node into a subring
-			fmt.Printf("Adding new subring for node: %s\n", node.id)
+			r.logf("Adding new subring for node: %s\n", node.id)
 			parent.Unlock()
-			timeTrack(start, "InsertKey", "to insert "+key+" on level "+strconv.Itoa(parent.level))
-			subring, err := parent.splitNode(node)
+			r.timeTrackAt(start, "InsertKey", parent.level, "to insert "+key+" on level "+strconv.Itoa(parent.level))
+			subring, err := parent.splitNode(node, cost)
 			if err != nil {
 				return errors.New("expected subring, got nil or invalid object")
 			}
-			fmt.Printf("Inserting key into subring: %s.\n", key)
-			return subring.InsertKey(key)
+			r.logf("Inserting key after creating subring: %s.\n", subring.id)
+			// Route from the parent, since a split policy may move only part of the node into the subring
+			return parent.insertKey(key, cost, rebalance)
 		}
 	}
 
@@ -295,13 +636,82 @@ func (r *Ring) InsertKey(key string) error {
 	return nil
 }
 
+// baseThreshold returns the threshold the node was created with.
+func (n *Node) baseThreshold() int {
+	if n.base < 1 {
+		return 1
+	}
+	return n.base
+}
+
+// dwelling reports whether the node changed structurally too recently to be split or removed again.
+func (n *Node) dwelling(minDwell time.Duration) bool {
+	return minDwell > 0 && time.Since(n.changedAt) < minDwell
+}
+
+// cost returns the load a key contributes to the node.
+func (n *Node) cost(key string) int {
+	if cost, ok := n.costs[key]; ok {
+		return cost
+	}
+	return 1
+}
+
+// setCost records the load of a newly stored key and adds it to the node's load.
+func (n *Node) setCost(key string, cost int) {
+	if cost != 1 {
+		if n.costs == nil {
+			n.costs = make(map[string]int)
+		}
+		n.costs[key] = cost
+	}
+	n.load += cost
+}
+
+// clearCost removes a key's load from the node and returns it.
+func (n *Node) clearCost(key string) int {
+	cost := n.cost(key)
+	delete(n.costs, key)
+	n.load -= cost
+	return cost
+}
+
 // RemoveKey removes a key from the ring (R0 or any subring).
 func (r *Ring) RemoveKey(key string) error {
-	start := time.Now()
-	fmt.Printf("Removing key %s.\n", key)
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.removeKeyOp(key)
+}
 
-	// Find the node or subring responsible for the key
-	node, parent, vNodeHash, _, err := r.FindNode(key)
+// removeKeyOp removes a key as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) removeKeyOp(key string) error {
+	r.beginOp()
+	span := r.traceOp("RemoveKey", Attribute{AttrKey, key})
+	err := r.removeKey(key)
+	if err == nil {
+		r.heat.forget(key)
+		r.spreads.set(key, 0)
+	}
+	span.end(err)
+	return r.logOp(Op{Type: OpRemoveKey, Key: key}, err)
+}
+
+// removeKey removes a key from the ring (assuming the tree's writer lock is held).
+func (r *Ring) removeKey(key string) error {
+	start := time.Now()
+	r.logf("Removing key %s.\n", key)
+	r.settleKey(key)
+
+	// Find the node or subring holding the key
+	node, parent, vNodeHash, err := r.locateKey(key)
+	var custom customRoute
+	if errors.As(err, &custom) {
+		writer, err := custom.writer()
+		if err != nil {
+			return err
+		}
+		return writer.RemoveKey(key)
+	}
 	if err != nil {
 		return err
 	}
@@ -310,34 +720,93 @@ func (r *Ring) RemoveKey(key string) error {
 	// Check if the key exists in the vnode's keys map and remove it
 	if _, exists := node.keys[vNodeHash]; exists {
 		if _, keyExists := node.keys[vNodeHash][key]; keyExists {
-			delete(node.keys[vNodeHash], key)
-			numKeys--
-			node.load--
-			fmt.Printf("Key %s removed from node %s (Load: %d).\n", key, node.id, node.load)
-			timeTrack(start, "RemoveKey", "to remove a key on level "+strconv.Itoa(parent.level))
+			r.dropKey(node, vNodeHash, key)
+			r.logf("Key %s removed from node %s (Load: %d).\n", key, node.id, node.load)
+			r.timeTrackAt(start, "RemoveKey", parent.level, "to remove a key on level "+strconv.Itoa(parent.level))
 			parent.Unlock()
-
-			// TODO: Handle underflow
-			if node.load <= int(float64(0.1)*float64(node.threshold)) && parent.parent != nil {
-				//fmt.Printf("Before RemoveNode: ring size = %d\n", parent.Size())
-				err := parent.RemoveNode(node)
-				return err
-			}
-			return nil
+			return r.relieveUnderflow(node, parent)
 		}
 	}
 
 	parent.Unlock()
-	return errors.New("key not found in the ring")
+	return ErrKeyNotFound
+}
+
+// dropKey deletes a key from one of the node's vnodes and from the tree's key tables (assuming the node's
+// ring's mutex is held).
+func (r *Ring) dropKey(node *Node, vNodeHash uint32, key string) {
+	delete(node.writable(vNodeHash), key)
+	r.index.delete(key)
+	r.pins.drop(key, false)
+	r.countKey(key, -1)
+	node.clearCost(key)
+	delete(node.checksums, key)
+}
+
+// relieveUnderflow removes a subring node that removals left underloaded, or merges it into a sibling,
+// unless it changed too recently (assuming the tree's writer lock is held and no ring lock is).
+func (r *Ring) relieveUnderflow(node *Node, parent *Ring) error {
+	parent.RLock()
+	under := float64(node.load) <= parent.low*float64(node.threshold) && parent.parent != nil
+	parent.RUnlock()
+	if !under || node.dwelling(r.config.MinDwell) || r.pins.targeted(node.id) || r.deferChange(node) {
+		return nil
+	}
+	if r.config.SiblingMerge {
+		if merged, err := parent.mergeUnderflow(node); merged || err != nil {
+			return err
+		}
+	}
+	return parent.removeNode(node)
 }
 
 // Lookup finds a key in the ring
 func (r *Ring) Lookup(key string) (string, error) {
+	span := r.traceRead("Lookup", Attribute{AttrKey, key})
+	owner, err := r.lookup(key)
+	if err == nil {
+		owner = r.serveRead(key, owner)
+	}
+	endRead(span, err)
+	return owner, err
+}
+
+// serveRead counts a read of a found key and returns the node chosen to serve it.
+func (r *Ring) serveRead(key string, owner string) string {
+	r.touch(key)
+	return r.readReplica(key, owner)
+}
+
+// lookup finds a key in the ring.
+func (r *Ring) lookup(key string) (string, error) {
 	start := time.Now()
-	fmt.Printf("Searching for key %s.\n", key)
+	if r.logging() {
+		r.logf("Searching for key %s.\n", key)
+	}
 
-	// Find the node or subring responsible for the key
-	node, parent, vNodeHash, _, err := r.FindNode(key)
+	if owner, indexed, err := r.lookupIndexed(key, start); indexed {
+		return owner, err
+	}
+
+	// Move a key left behind by a budgeted remap, unless a writer is busy; then it is read where it is
+	if _, pending := r.remaps.get(key); pending && r.writer.TryLock() {
+		r.settleKey(key)
+		r.writer.Unlock()
+	}
+
+	// Answer from the snapshot instead of waiting for a membership change to finish
+	if r.config.LockFreeReads {
+		if owner, answered, err := r.lookupSnapshot(key); answered {
+			return owner, err
+		}
+	}
+
+	// Find the node or subring holding the key
+	node, parent, vNodeHash, err := r.locateKey(key)
+	var custom customRoute
+	if errors.As(err, &custom) {
+		return custom.member.Route(key, custom.keyHash)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -345,16 +814,117 @@ func (r *Ring) Lookup(key string) (string, error) {
 	// Check if the key exists in the vnode's keys map
 	parent.RLock()
 	if _, exists := node.keys[vNodeHash]; exists {
-		if _, keyExists := node.keys[vNodeHash][key]; keyExists {
-			fmt.Printf("Found key %s at node %s.\n", key, node.id)
+		if keyHash, keyExists := node.keys[vNodeHash][key]; keyExists {
+			if r.logging() {
+				r.logf("Found key %s at node %s.\n", key, node.id)
+			}
+			if !parent.verifyKey(node, key, keyHash) {
+				parent.RUnlock()
+				return "", ErrChecksumMismatch
+			}
+			down := !node.servesReads()
 			parent.RUnlock()
-			timeTrack(start, "Lookup", "to find a key at level "+strconv.Itoa(parent.level))
+			if r.config.replication() > 1 && down {
+				return r.Primary(key)
+			}
+			r.timeTrackAt(start, "Lookup", parent.level, "to find a key at level "+strconv.Itoa(parent.level))
 			return node.id, nil
 		}
 	}
 
 	parent.RUnlock()
-	return "", errors.New("key not found")
+	if owner, _, _ := parent.migratingKey(key); owner != nil {
+		return owner.id, nil
+	}
+	return "", ErrKeyNotFound
+}
+
+// locateKey finds where a key is stored: on the node writes are routed to, or on its owner if that node was
+// draining when the key was written.
+func (r *Ring) locateKey(key string) (*Node, *Ring, uint32, error) {
+	node, parent, vNodeHash, _, err := r.findNode(key, true)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	parent.RLock()
+	_, exists := node.keys[vNodeHash][key]
+	parent.RUnlock()
+	if exists {
+		return node, parent, vNodeHash, nil
+	}
+
+	// During a transition epoch the key may still be on the node that owned it before a join
+	if from, fromRing, fromVNodeHash, ok := r.deferredOwner(key); ok {
+		return from, fromRing, fromVNodeHash, nil
+	}
+
+	owner, ownerParent, ownerVNodeHash, _, err := r.findNode(key, false)
+	if err != nil || owner == node {
+		return node, parent, vNodeHash, nil
+	}
+	return owner, ownerParent, ownerVNodeHash, nil
+}
+
+// ID returns the ring identifier.
+func (r *Ring) ID() string {
+	return r.id
+}
+
+// Level returns the level of the hierarchy the ring exists on.
+func (r *Ring) Level() int {
+	return r.level
+}
+
+// Parent returns the parent ring, or nil for the root ring.
+func (r *Ring) Parent() *Ring {
+	return r.parent
+}
+
+// ID returns the node identifier.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Load returns the current load of the node.
+func (n *Node) Load() int {
+	return n.load
+}
+
+// Threshold returns the load at which the node is considered overloaded.
+func (n *Node) Threshold() int {
+	return n.threshold
+}
+
+// SetNodeThreshold changes the threshold τ of a node anywhere in the tree, and the threshold it was created
+// with. A node already past its new threshold splits or hands off load on its next insert, as usual.
+func (r *Ring) SetNodeThreshold(nodeID string, threshold int) error {
+	if threshold < 1 {
+		return errors.New("threshold must be positive")
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.setNodeThresholdOp(nodeID, threshold)
+}
+
+// setNodeThresholdOp changes the threshold of a node as one logged operation (assuming the tree's writer
+// lock is held).
+func (r *Ring) setNodeThresholdOp(nodeID string, threshold int) error {
+	r.beginOp()
+	return r.logOp(Op{Type: OpSetThreshold, Node: nodeID, Threshold: threshold}, r.setNodeThreshold(nodeID, threshold))
+}
+
+// setNodeThreshold changes the threshold of a node (assuming the tree's writer lock is held).
+func (r *Ring) setNodeThreshold(nodeID string, threshold int) error {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	ring.Lock()
+	node.threshold, node.base = threshold, threshold
+	ring.Unlock()
+	r.logf("Set threshold of node %s to %d.\n", nodeID, threshold)
+	return nil
 }
 
 // Members returns a list of all the members (servers) in the consistent hash circle.
@@ -369,6 +939,48 @@ func (r *Ring) Members() []string {
 	return m
 }
 
+// NodeIDs returns, in sorted order, the IDs of every physical node in the tree below the ring.
+func (r *Ring) NodeIDs() []string {
+	r.RLock()
+	var ids []string
+	var subrings []*Ring
+	for id, member := range r.members {
+		switch member := member.(type) {
+		case *Node:
+			ids = append(ids, id)
+		case *Ring:
+			subrings = append(subrings, member)
+		}
+	}
+	r.RUnlock()
+
+	for _, subring := range subrings {
+		ids = append(ids, subring.NodeIDs()...)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// KeysForNode returns, in sorted order, every key held by a physical node anywhere in the tree across all of
+// its vnodes.
+func (r *Ring) KeysForNode(nodeID string) ([]string, error) {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return nil, ErrNodeNotFound
+	}
+
+	ring.RLock()
+	var keys []string
+	for _, keyHashMap := range node.keys {
+		for key := range keyHashMap {
+			keys = append(keys, key)
+		}
+	}
+	ring.RUnlock()
+	sort.Strings(keys)
+	return keys, nil
+}
+
 // Size gets the number of physical nodes and rings.
 func (r *Ring) Size() int {
 	// assuming mutex is already locked
@@ -388,144 +1000,213 @@ func (r *Ring) IsEmpty() bool {
 	return true
 }
 
-// Traversal propagates an update through the ring tree.
+// Traversal calls operation on every node below the ring, or below its parent when called on a subring
+// with level 0, and returns once every call has.
+//
+// Deprecated: Use Walk, which also visits rings, bounds its parallelism and stops on errors.
 func (r *Ring) Traversal(operation func(node *Node), level int) {
-	r.RLock()
-	defer r.RUnlock()
+	if r.parent != nil && level == 0 {
+		r = r.parent
+	}
+	r.Walk(context.Background(), func(_ context.Context, member Member, _ *Ring) error {
+		operation(member.(*Node))
+		return nil
+	})
+}
 
-	// Step 1: Traverse the current ring
-	for _, member := range r.members {
-		switch member := member.(type) {
-		case *Node:
-			// Perform the operation on each node in the ring
-			operation(member)
-		case *Ring:
-			// Recursively traverse subrings
-			go member.Traversal(operation, level+1) // Parallelize traversal to subrings
+// splitNode converts an overloaded node into a subring using the ring's split policy, or by default into a
+// subring sized for the node's load plus the incoming load.
+func (r *Ring) splitNode(node *Node, incoming int) (subring *Ring, err error) {
+	span := r.traceOp("splitNode", Attribute{AttrNodeID, node.id})
+	defer func() { span.end(err) }()
+	if span.tracing() {
+		for _, keys := range node.keys {
+			span.moved += len(keys)
 		}
 	}
-
-	// Step 2: Propagate to the parent ring (if it exists)
-	if r.parent != nil && level == 0 { // Top-level traversal initiates upward propagation
-		r.parent.Traversal(operation, level-1)
+	if policy := r.splitPolicy(); policy != nil {
+		return policy.Split(r, node)
 	}
+	return r.replaceNode(node, func(subring *Ring) (int, int) {
+		return subring.splitWidth(node, incoming)
+	})
 }
 
-// splitNode converts an overloaded node into a subring.
-func (r *Ring) splitNode(node *Node) (*Ring, error) {
-	defer timeTrack(time.Now(), "splitNode", "to create a subring")
+// replaceNode converts a node into a subring with the node's ID, seeded with the given nodes plus as many
+// new nodes of the given threshold as width returns for the new subring, and moves the node's keys into it.
+func (r *Ring) replaceNode(node *Node, width func(subring *Ring) (int, int), seeds ...*Node) (*Ring, error) {
+	r.settleRemaps()
+	defer r.timeTrack(time.Now(), "splitNode", "to create a subring")
+	r.hub.begin()
+	defer r.hub.end()
 	r.Lock()
 	defer r.Unlock()
-	numNodes--
+	defer r.publish()
+	if err := r.verifyNode(node); err != nil {
+		return nil, err
+	}
+	r.stats.numNodes.add(-1)
+	node.stopMaintenance()
 
 	// Create a ring with the node's ID and replace the node with the ring in members
 	// The virtual nodes in circle will now point to the subring
-	subring := newRing(r, node.id, r.level+1, r.maxCount*branchFactor)
+	subring := newRing(r.config, r, node.id, r.level+1, r.config.capacity(r.level+1, r.maxCount))
 	r.members[node.id] = subring
-	fmt.Printf("Created subring at level %d for node: %s\n", r.level+1, node.id)
+	r.logf("Created subring at level %d for node: %s\n", r.level+1, node.id)
 
 	// Backup the old keys and id from the node
 	oldKeys := node.keys
 	oldNodeID := node.id
 
-	// Add 2 nodes to the subring to balance the load
-	err := subring.InsertNode(NewNode("", node.threshold))
-	if err != nil {
-		return nil, err
+	// Add enough nodes to the subring to hold the load in one step
+	children, threshold := width(subring)
+	for i := 0; i < children; i++ {
+		seeds = append(seeds, r.seedNode(node, threshold))
 	}
-	err = subring.InsertNode(NewNode("", node.threshold))
-	if err != nil {
-		return nil, err
+	for _, seed := range seeds {
+		if err := subring.insertNode(seed); err != nil {
+			return nil, err
+		}
 	}
 
-	// Re-insert the keys from the overloaded node into the subring
-	for _, keysMap := range oldKeys {
+	// Re-insert the keys from the overloaded node into the subring. The node keeps the keys not yet moved,
+	// so lookups can still find them while the lock is released at each checkpoint. Other writers keep
+	// waiting on the tree's writer lock.
+	subring.migrating = node
+	moved := 0
+	for vNodeHash := range oldKeys {
+		keysMap := node.writable(vNodeHash)
 		for key := range keysMap {
 			//remapped++ // TODO: SOURCE
-			numKeys--
-			err := subring.InsertKey(key)
+			r.countKey(key, -1)
+			delete(keysMap, key)
+			delete(node.checksums, key)
+			cost := node.clearCost(key)
+			err := subring.insertKey(key, cost, true)
 			if err != nil {
 				return nil, fmt.Errorf("error reinserting key %s: %v", key, err)
 			}
+
+			moved++
+			if interval := r.config.CheckpointInterval; interval > 0 && moved%interval == 0 {
+				r.yield()
+			}
 		}
 	}
+	subring.migrating = nil
 
-	fmt.Printf("Finished replacing node %s with subring\n", oldNodeID)
-	calculateRemapComplexity()
+	r.logf("Finished replacing node %s with subring\n", oldNodeID)
+	r.stats.recordSplit()
+	r.emit(Event{Type: SubringCreated, RingID: r.id, NodeID: oldNodeID, Level: r.level + 1, Remapped: r.stats.remapped.get()})
+	r.stats.calculateRemapComplexity()
 	return subring, nil
 }
 
-// collapseRing merges the subring's nodes into a single node and reinserts all keys into the parent ring.
-func (r *Ring) collapseRing(node *Node) (*Node, error) {
-	defer timeTrack(time.Now(), "collapseRing", "to collapse a ring on level "+strconv.Itoa(r.level))
-
-	// Ensure the subring has two or fewer members
-	if len(r.members) > 2 {
-		return nil, errors.New("can only collapse subrings with two or fewer nodes")
+// collapseRing merges the subring's nodes, including those of nested subrings, into a single node with the
+// given threshold and reinserts all keys into the parent ring.
+func (r *Ring) collapseRing(threshold int) (node *Node, err error) {
+	span := r.traceOp("collapseRing")
+	defer func() { span.end(err) }()
+	if span.tracing() {
+		r.RLock()
+		r.forEachNode(func(node *Node) {
+			for _, keys := range node.keys {
+				span.moved += len(keys)
+			}
+		})
+		r.RUnlock()
 	}
+	defer r.timeTrack(time.Now(), "collapseRing", "to collapse a ring on level "+strconv.Itoa(r.level))
+	r.hub.begin()
+	defer r.hub.end()
 
-	fmt.Printf("Collapsing ring %s.\n", r.id)
+	r.logf("Collapsing ring %s.\n", r.id)
 
 	// Ensure the parent ring exists
 	if r.parent == nil {
-		return nil, errors.New("cannot collapse root ring")
+		return nil, ErrRootCollapse
+	}
+	r.settleRemaps()
+
+	// Lock parent before child, the order every other path takes
+	r.parent.Lock()
+	r.Lock()
+	if err := r.verifyRing(); err != nil {
+		r.Unlock()
+		r.parent.Unlock()
+		return nil, err
 	}
 
 	// Collect all keys from the current ring
-	oldKeys := make(map[string]*uint32) // Flattened map of all keys in the subring
-	for _, member := range r.members {
-		if node, ok := member.(*Node); ok {
-			// Gather all keys from each vnode
-			for _, keys := range node.keys {
-				for key, keyHash := range keys {
-					oldKeys[key] = keyHash
-				}
+	oldKeys := make(keySet)          // Flattened map of all keys in the subring
+	oldCosts := make(map[string]int) // Load of each key in the subring
+	nodes := 0
+	r.forEachNode(func(node *Node) {
+		// Gather all keys from each vnode
+		for _, keys := range node.keys {
+			for key, keyHash := range keys {
+				oldKeys[key] = keyHash
+				oldCosts[key] = node.cost(key)
 			}
 		}
+		// Clear the node's keys and its membership
+		node.stopMaintenance()
+		node.keys = nil
+		node.costs = nil
+		node.load = 0
+		nodes++
+	})
+	r.members = nil // Remove all subring members
+	if nodes > 1 {
+		r.stats.numNodes.add(-(nodes - 1))
 	}
 
-	// Remove all members from the subring
-	for _, member := range r.members {
-		if node, ok := member.(*Node); ok {
-			// Clear the node's keys and its membership
-			node.keys = nil
-			node.load = 0
+	// Create a new node using the subring's ID and insert it into the parent ring
+	newNode := NewNode(r.id, threshold)
+	// The node takes over the subring's vnodes, or gets its own if the subring had none
+	var vNodes []VNode
+	for _, vNode := range circleVNodes(r.parent.circle) {
+		if vNode.nodeID == r.id {
+			vNodes = append(vNodes, vNode)
 		}
 	}
-	r.members = nil // Remove all subring members
-
-	// Create a new node using the subring's ID and insert it into the parent ring
-	newNode := NewNode(r.id, node.threshold)
-	r.parent.members[newNode.id] = newNode
-
-	// Add vNodes to the circle for the new node
-	for i := 0; i < NumReplicas; i++ {
-		vNodeHash := hash(newNode.id, i)
-		newNode.keys[vNodeHash] = make(map[string]*uint32) // Initialize key map for this vNode
-		fmt.Printf("Virtual node %d added to the parent ring.\n", vNodeHash)
+	if len(vNodes) == 0 {
+		vNodes = r.config.vNodes(newNode.id)
 	}
+	for _, vNode := range vNodes {
+		newNode.keys[vNode.hash] = make(keySet) // Initialize key map for this vNode
+		r.logf("Virtual node %d added to the parent ring.\n", vNode.hash)
+	}
+	r.parent.members[newNode.id] = newNode
+	r.parent.circle.InsertBatch(vNodes)
+	r.publish()
+	r.Unlock()
+	r.parent.publish()
+	r.parent.Unlock()
 
 	// Reinsert all old keys into the parent ring
 	for key, keyHash := range oldKeys {
-		numKeys--
-		if err := r.parent.InsertKey(key); err != nil {
+		r.countKey(key, -1)
+		if err := r.parent.insertKey(key, oldCosts[key], true); err != nil {
 			return nil, fmt.Errorf("error inserting key %s into parent ring: %v", key, err)
 		}
-		fmt.Printf("Reinserted key %s with hash %d into the parent ring.\n", key, *keyHash)
+		r.logf("Reinserted key %s with hash %d into the parent ring.\n", key, keyHash)
 	}
 
-	fmt.Printf("Collapsed subring %s into node %s and reinserted keys into parent ring\n", r.id, newNode.id)
+	r.logf("Collapsed subring %s into node %s and reinserted keys into parent ring\n", r.id, newNode.id)
+	r.emit(Event{Type: SubringCollapsed, RingID: r.parent.id, NodeID: newNode.id, Level: r.level})
 	r = nil
 	return newNode, nil
 }
 
 // remapKeys remaps keys after each vnode has been added
 func (r *Ring) remapKeys(newNode *Node, newVNodeHash uint32) error {
-	fmt.Printf("Remapping keys for newly added vnode %d.\n", newVNodeHash)
+	r.logf("Remapping keys for newly added vnode %d.\n", newVNodeHash)
 
-	// Find the next vnode's hash and corresponding node ID in the ring
+	// Find the next vnode's hash and corresponding node ID in the ring, and the start of the new vnode's arc
 	nextVNodeHash, nextNodeId := r.circle.FindNextClosest(newVNodeHash)
-	fmt.Printf("FindNextClosest found next vNodeHash: %d, value: %v.\n", nextVNodeHash, nextNodeId)
+	prevVNodeHash, _ := r.circle.FindPrevClosest(newVNodeHash)
+	r.logf("FindNextClosest found next vNodeHash: %d, value: %v.\n", nextVNodeHash, nextNodeId)
 
 	// Handle the case where the next node is either a Node or a Ring
 	switch nextNode := r.members[nextNodeId].(type) {
@@ -533,32 +1214,44 @@ func (r *Ring) remapKeys(newNode *Node, newVNodeHash uint32) error {
 		// Get the map of keys to hash values associated with the next vnode
 		keyHashMap := nextNode.keys[nextVNodeHash]
 		if len(keyHashMap) == 0 {
-			fmt.Println("No keys found in the next vnode to remap.")
+			r.logf("No keys found in the next vnode to remap.\n")
 			return nil
 		}
 
-		fmt.Printf("%d keys found in the next vnode to check for remapping.\n", len(keyHashMap))
+		r.logf("%d keys found in the next vnode to check for remapping.\n", len(keyHashMap))
 
 		// Iterate over the keys and check if they belong in the new vnode's hash range
 		for key, hashValue := range keyHashMap {
-			if r.shouldMove(hashValue, newVNodeHash, nextVNodeHash) {
-				fmt.Printf("Key %s with hash %d is less than vnode %d, remapping from %d.\n", key, *hashValue, newVNodeHash, nextVNodeHash)
-				r.moveKey(key, hashValue, nextNode, nextVNodeHash, newNode, newVNodeHash)
+			if inArc(hashValue, prevVNodeHash, newVNodeHash) && r.pinAllows(key, r, newNode) {
+				r.logf("Key %s with hash %d is in the arc of vnode %d, remapping from %d.\n", key, hashValue, newVNodeHash, nextVNodeHash)
+				if err := r.moveKey(key, hashValue, nextNode, nextVNodeHash, newNode, newVNodeHash); err != nil {
+					return err
+				}
 			}
 		}
 
 	case *Ring:
 		// If the next node is a subring, we need to handle the keys within that subring
-		nextNode.remapSubringKeys(r.level, newNode, newVNodeHash, nextVNodeHash)
-		return nil
+		return nextNode.remapSubringKeys(r.level, newNode, newVNodeHash, prevVNodeHash)
 	default:
-		return errors.New("next node is not valid for remapping")
+		// Custom members keep the keys they hold
+		return nil
 	}
 	return nil
 }
 
-// remaps keys within subrings
-func (r *Ring) remapSubringKeys(level int, newNode *Node, newVNodeHash, nextVNodeHash uint32) error {
+// remaps keys within subrings that fall in the arc (prevVNodeHash, newVNodeHash] on the given level
+// (assuming the parent's mutex is already locked)
+func (r *Ring) remapSubringKeys(level int, newNode *Node, newVNodeHash, prevVNodeHash uint32) error {
+	r.Lock()
+	defer r.Unlock()
+
+	// The ring the keys are remapped into
+	dest := r
+	for dest.level > level && dest.parent != nil {
+		dest = dest.parent
+	}
+
 	// Iterate through the subring's members
 	for _, member := range r.members {
 		// Check if this is a deeper ring or a node
@@ -569,61 +1262,59 @@ func (r *Ring) remapSubringKeys(level int, newNode *Node, newVNodeHash, nextVNod
 				// For each key in the vnode's key map
 				for key := range keyHashMap {
 					// Hash the key at the current level
-					hashAtNewNodeLevel := hash(key, level)
-
-					if r.shouldMove(&hashAtNewNodeLevel, newVNodeHash, nextVNodeHash) {
-						fmt.Printf("Key %s with hash %d is less than vnode %d, remapping from %d.\n", key, hashAtNewNodeLevel, newVNodeHash, nextVNodeHash)
-						r.moveKey(key, &hashAtNewNodeLevel, node, vNodeHash, newNode, newVNodeHash)
+					hashAtNewNodeLevel := r.config.keyHash(key, level)
+
+					if inArc(hashAtNewNodeLevel, prevVNodeHash, newVNodeHash) && r.pinAllows(key, dest, newNode) {
+						r.logf("Key %s with hash %d is in the arc of vnode %d, remapping from subring %s.\n", key, hashAtNewNodeLevel, newVNodeHash, r.id)
+						if err := r.moveKey(key, hashAtNewNodeLevel, node, vNodeHash, newNode, newVNodeHash); err != nil {
+							return err
+						}
+						r.index.set(key, newNode, dest)
 					}
 				}
 			}
 		case *Ring:
-			// Recursively go deeper into the subring, passing the same nextVNodeHash
-			err := node.remapSubringKeys(level, newNode, newVNodeHash, nextVNodeHash)
+			// Recursively go deeper into the subring, passing the same arc
+			err := node.remapSubringKeys(level, newNode, newVNodeHash, prevVNodeHash)
 			if err != nil {
 				return err
 			}
 		default:
-			return errors.New("invalid member found in subring")
+			// Custom members keep the keys they hold
 		}
 	}
 
 	return nil
 }
 
-// moves a key from one node to another.
-func (r *Ring) moveKey(key string, keyHash *uint32, oldNode *Node, oldVNodeHash uint32, newNode *Node, newVNodeHash uint32) {
-	remapped++
+// moves a key from one node to another. A key failing its checksum is left where it is.
+func (r *Ring) moveKey(key string, keyHash uint32, oldNode *Node, oldVNodeHash uint32, newNode *Node, newVNodeHash uint32) error {
+	if !r.checksumMatches(oldNode, key, oldNode.keys[oldVNodeHash][key]) {
+		return ErrChecksumMismatch
+	}
+	r.stats.remapped.add(1)
 	// Move the key from nextNode to NewNode
-	delete(oldNode.keys[oldVNodeHash], key) // Remove from old vnode
+	delete(oldNode.writable(oldVNodeHash), key) // Remove from old vnode
 	if newNode.keys[newVNodeHash] == nil {
-		newNode.keys[newVNodeHash] = make(map[string]*uint32)
+		newNode.keys[newVNodeHash] = make(keySet)
 	}
-	newNode.keys[newVNodeHash][key] = keyHash // Add to new vnode
-	oldNode.load--                            // Decrement load of old node
-	newNode.load++                            // Increment load of new node
-	fmt.Printf("Key %s remapped from vnode %d to vnode %d\n", key, oldVNodeHash, newVNodeHash)
+	newNode.writable(newVNodeHash)[key] = keyHash // Add to new vnode
+	r.index.set(key, newNode, r)
+	newNode.setCost(key, oldNode.clearCost(key)) // Carry the key's load over to the new node
+	r.carryChecksum(key, keyHash, oldNode, newNode)
+	r.joins.record(key, oldNode)
+	r.emitKeyMoved(key, oldNode, newNode)
+	r.logf("Key %s remapped from vnode %d to vnode %d\n", key, oldVNodeHash, newVNodeHash)
+	return nil
 }
 
-// Determines if a key should move.
-func (r *Ring) shouldMove(keyHash *uint32, newVNodeHash uint32, nextVNodeHash uint32) bool {
-	// Wraparound case: newVNodeHash is larger than nextVNodeHash
-	if nextVNodeHash < newVNodeHash {
-		// Move the key from the smallest hash if its less newVNodeHash or before nextVNodeHash (wraparound)
-		if *keyHash <= newVNodeHash && *keyHash > nextVNodeHash {
-			return true
-		}
-	} else {
-		// Regular case
-		if *keyHash <= newVNodeHash {
-			return true
-			// if new hash is smallest, then take all the keys greater
-		} else if *keyHash > newVNodeHash && *keyHash > nextVNodeHash {
-			return true
-		}
+// inArc reports whether a hash falls in the arc (from, to], which wraps around the top of the hash space
+// when from >= to. An arc starting and ending on the same vnode covers the whole circle.
+func inArc(keyHash, from, to uint32) bool {
+	if from < to {
+		return keyHash > from && keyHash <= to
 	}
-
-	return false
+	return keyHash > from || keyHash <= to
 }
 
 // Determines if a ring should collapse.
@@ -639,45 +1330,3 @@ func (r *Ring) shouldCollapse() bool {
 	}
 	return false // More than 2 members or root; no collapse
 }
-
-func (r *Ring) ParallelGossip(message string, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	// 1. Gossip to Parent if exists
-	if r.parent != nil {
-		fmt.Printf("Ring %s propagating message to parent %s.\n", r.id, r.parent.id)
-		go r.parent.ReceiveMessage(message, wg)
-	}
-
-	// 2. Disseminate to Children in Parallel
-	var childWg sync.WaitGroup
-	for _, member := range r.members {
-		switch member := member.(type) {
-		case *Node:
-			fmt.Printf("Node %s receiving message in ring %s.\n", member.id, r.id)
-			go func(node *Node) {
-				defer childWg.Done()
-				node.ReceiveMessage(message)
-			}(member)
-			childWg.Add(1)
-		case *Ring:
-			fmt.Printf("Subring %s receiving message in ring %s.\n", member.id, r.id)
-			go member.ParallelGossip(message, &childWg)
-			childWg.Add(1)
-		}
-	}
-
-	// Wait for all child propagations to finish
-	childWg.Wait()
-}
-
-// Helper function to receive a message in a Ring or Node
-func (r *Ring) ReceiveMessage(message string, wg *sync.WaitGroup) {
-	fmt.Printf("Ring %s received message: %s.\n", r.id, message)
-	wg.Add(1)
-	go r.ParallelGossip(message, wg)
-}
-
-func (n *Node) ReceiveMessage(message string) {
-	fmt.Printf("Node %s received message: %s.\n", n.id, message)
-}
@@ -1,23 +1,65 @@
 package ringtree
 
 import (
-	"log"
 	"math"
-	"os"
-	"runtime"
-	"runtime/pprof"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var numNodes int = 0 // tracks total number of keys
-var numKeys int = 0  // tracks total number of nodes
+// Stats tracks the counters and operation timings of a single ring tree.
+type Stats struct {
+	numNodes       counter                    // tracks total number of nodes
+	numKeys        counter                    // tracks total number of keys
+	remaps         []map[int]int              // aggregates instantaneous remapping operations [actual:expected]
+	remapped       counter                    // tracks the number of keys being remapped in the current operation
+	remappedTotal  counter                    // tracks the number of keys remapped by completed operations
+	operationTimes map[string][]time.Duration // Tracks elapsed times for each operation
+	corrupted      counter                    // tracks keys that failed checksum verification
+	window         statWindow                 // recent remaps, splits and operation durations, for windowed stats
+	histograms     map[latencyKey]*histogram  // operation durations by operation and level, for percentiles
+	mu             sync.Mutex                 // guards timings, remaps and samples, which concurrent lookups also write
+}
+
+// counter is a statistic read without the tree's writer lock, so it is updated atomically.
+type counter struct {
+	atomic.Int64
+}
+
+// add adds n to the counter.
+func (c *counter) add(n int) {
+	c.Add(int64(n))
+}
+
+// get returns the value of the counter.
+func (c *counter) get() int {
+	return int(c.Load())
+}
+
+func newStats() *Stats {
+	return &Stats{operationTimes: make(map[string][]time.Duration)}
+}
+
+// Stats returns the statistics of the ring tree.
+func (r *Ring) Stats() *Stats {
+	return r.stats
+}
+
+// Nodes returns the number of physical nodes in the tree.
+func (s *Stats) Nodes() int {
+	return s.numNodes.get()
+}
 
-var remaps []map[int]int // aggregates instantaneous remapping operations [actual:expected]
-var remapped int = 0     // tracks the number of keys being remapped in the current operation
+// Keys returns the number of keys in the tree.
+func (s *Stats) Keys() int {
+	return s.numKeys.get()
+}
 
-var timerStatus = sync.Map{}                          // Tracks active timers to avoid double logging
-var operationTimes = make(map[string][]time.Duration) // Tracks elapsed times for each operation
+// ChecksumFailures returns the number of keys that failed checksum verification.
+func (s *Stats) ChecksumFailures() int {
+	return s.corrupted.get()
+}
 
 // Helper function to compute the sum of a slice of integers.
 func sum(loads []int) int {
@@ -46,38 +88,39 @@ type LevelInfo struct {
 	RingCount int // The number of subrings at this level
 }
 
-func timeTrack(start time.Time, operation string, message string) {
-	elapsed := time.Since(start)
-	log.Printf("%s took %s %s.", operation, elapsed, message)
-
-	// Track elapsed time for stats
-	if operationTimes[operation] == nil {
-		operationTimes[operation] = make([]time.Duration, 0)
-	}
-	operationTimes[operation] = append(operationTimes[operation], elapsed)
+func (r *Ring) timeTrack(start time.Time, operation string, message string) {
+	r.timeTrackAt(start, operation, r.level, message)
 }
 
-func memoryProfile(filename string) {
-	f, err := os.Create(filename)
-	if err != nil {
-		log.Fatal("could not create memory profile: ", err)
+// timeTrackAt records the duration of an operation that completed on the given level.
+func (r *Ring) timeTrackAt(start time.Time, operation string, level int, message string) {
+	elapsed := time.Since(start)
+	if r.logging() {
+		r.logf("%s took %s %s.\n", operation, elapsed, message)
 	}
-	defer f.Close()
+	r.logSlow(operation, level, elapsed, message)
 
-	if err := pprof.WriteHeapProfile(f); err != nil {
-		log.Fatal("could not write memory profile: ", err)
+	// Track elapsed time for stats
+	s := r.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.operationTimes[operation] == nil {
+		s.operationTimes[operation] = make([]time.Duration, 0)
 	}
-}
-
-func logMemoryUsage(operation string) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	log.Printf("%s: Alloc = %v KB, TotalAlloc = %v KB, Sys = %v KB, NumGC = %v\n",
-		operation, m.Alloc/1024, m.TotalAlloc/1024, m.Sys/1024, m.NumGC)
+	s.operationTimes[operation] = append(s.operationTimes[operation], elapsed)
+	s.window.operation(start.Add(elapsed), operation, elapsed)
+	s.recordLatency(operation, level, elapsed)
 }
 
 // Recursively calculates the depth of the hierarchy.
 func (r *Ring) GetDepth() int {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.hierarchyDepth()
+}
+
+// hierarchyDepth calculates the depth of the hierarchy (assuming the tree's writer lock is held).
+func (r *Ring) hierarchyDepth() int {
 	var getDepth func(*Ring, int) int
 	getDepth = func(ring *Ring, depth int) int {
 		maxDepth := depth
@@ -96,6 +139,13 @@ func (r *Ring) GetDepth() int {
 
 // GetLoads calculates the total load and individual node loads within a ring (excluding subrings).
 func (r *Ring) GetLoads() (int, []int) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.ringLoads()
+}
+
+// ringLoads calculates the loads of the ring's own nodes (assuming the tree's writer lock is held).
+func (r *Ring) ringLoads() (int, []int) {
 	total := 0
 	var loads []int
 
@@ -110,6 +160,14 @@ func (r *Ring) GetLoads() (int, []int) {
 
 // Collects load statistics for all rings and subrings (run this on R0).
 func (r *Ring) GetTotalLoads() []RingInfo {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.totalLoads()
+}
+
+// totalLoads collects the load statistics of the ring and all of its subrings (assuming the tree's writer
+// lock is held).
+func (r *Ring) totalLoads() []RingInfo {
 	var result []RingInfo
 
 	// Helper function to recursively gather loads.
@@ -147,12 +205,24 @@ func (r *Ring) GetTotalLoads() []RingInfo {
 
 // Collects variance and standard deviation across the entire system.
 func (r *Ring) GetSystemVariance() ([]int, float64, float64, float64) {
+	r.writer.Lock()
+	allLoads := r.systemLoads()
+	r.writer.Unlock()
+
+	// Calculate and return variance and standard deviation.
+	mean, variance, stdDev := calculateStats(allLoads)
+	return allLoads, mean, variance, stdDev
+}
+
+// systemLoads gathers the loads of every physical node in the ring and its subrings (assuming the tree's
+// writer lock is held).
+func (r *Ring) systemLoads() []int {
 	var allLoads []int
 
 	// Helper function to gather all node loads.
 	var gatherAllLoads func(*Ring)
 	gatherAllLoads = func(ring *Ring) {
-		_, loads := ring.GetLoads() // Use GetLoads to extract node loads.
+		_, loads := ring.ringLoads()
 		allLoads = append(allLoads, loads...)
 
 		for _, member := range ring.members {
@@ -163,14 +233,60 @@ func (r *Ring) GetSystemVariance() ([]int, float64, float64, float64) {
 	}
 
 	gatherAllLoads(r) // Start from the top ring.
+	return allLoads
+}
 
-	// Calculate and return variance and standard deviation.
-	mean, variance, stdDev := calculateStats(allLoads)
-	return allLoads, mean, variance, stdDev
+// Imbalance characterizes the skew of a set of loads independently of their scale, so rings and levels
+// of different sizes compare directly.
+type Imbalance struct {
+	PeakToMean float64 `json:"peak_to_mean"` // Highest load over the mean load; 1 when perfectly even
+	Gini       float64 `json:"gini"`         // Gini coefficient; 0 when perfectly even, near 1 when one load holds all
+	Jain       float64 `json:"jain"`         // Jain's fairness index; 1 when perfectly even, 1/n when one load holds all
+}
+
+// GetImbalance computes the imbalance of the physical node loads across the entire system, and of the node
+// loads at each level.
+func (r *Ring) GetImbalance() (Imbalance, map[int]Imbalance) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.imbalance()
+}
+
+// imbalance computes the system and per-level imbalance (assuming the tree's writer lock is held).
+func (r *Ring) imbalance() (Imbalance, map[int]Imbalance) {
+	var allLoads []int
+	levelLoads := make(map[int][]int)
+
+	var gatherLoads func(*Ring, int)
+	gatherLoads = func(ring *Ring, level int) {
+		_, loads := ring.ringLoads()
+		allLoads = append(allLoads, loads...)
+		levelLoads[level] = append(levelLoads[level], loads...)
+		for _, member := range ring.members {
+			if subring, ok := member.(*Ring); ok {
+				gatherLoads(subring, level+1)
+			}
+		}
+	}
+	gatherLoads(r, 0)
+
+	levels := make(map[int]Imbalance, len(levelLoads))
+	for level, loads := range levelLoads {
+		levels[level] = calculateImbalance(loads)
+	}
+	return calculateImbalance(allLoads), levels
 }
 
 // GetHierarchyInfo calculates the depth of the hierarchy, the number of nodes, and the number of rings at each level.
 func (r *Ring) GetHierarchyInfo() (int, map[int]LevelInfo, int, int) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.hierarchyInfo()
+}
+
+// hierarchyInfo gathers the depth and the per-level counts of the hierarchy (assuming the tree's writer lock
+// is held).
+func (r *Ring) hierarchyInfo() (int, map[int]LevelInfo, int, int) {
 	levelInfo := make(map[int]LevelInfo)
 	maxDepth := 0 // Track the maximum depth dynamically.
 
@@ -212,13 +328,16 @@ func (r *Ring) GetHierarchyInfo() (int, map[int]LevelInfo, int, int) {
 	gatherLevelInfo(r, 0)
 
 	// Calculate total nodes and keys.
-	return maxDepth, levelInfo, numKeys, numNodes
+	return maxDepth, levelInfo, r.stats.numKeys.get(), r.stats.numNodes.get()
 }
 
-// Extracts remap statistics.
-func GetRemapStats() ([]map[int]int, int, float64, float64) {
+// RemapStats extracts remap statistics.
+func (s *Stats) RemapStats() ([]map[int]int, int, float64, float64) {
 	totalRemapped, totalExpected, validEntries := 0, 0, 0
 
+	s.mu.Lock()
+	remaps := s.remaps
+	s.mu.Unlock()
 	for _, remap := range remaps {
 		for actual, expected := range remap {
 			if actual == 0 {
@@ -236,11 +355,13 @@ func GetRemapStats() ([]map[int]int, int, float64, float64) {
 	return remaps, totalRemapped, averageRemapped, averageRatio
 }
 
-// Time Complexity
-func GetTimeStats() map[string]map[string]float64 {
+// TimeStats reports the mean, variance and standard deviation of each operation's duration.
+func (s *Stats) TimeStats() map[string]map[string]float64 {
 	stats := make(map[string]map[string]float64)
 
-	for operation, times := range operationTimes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for operation, times := range s.operationTimes {
 		if len(times) == 0 {
 			continue // Skip empty operations
 		}
@@ -265,13 +386,20 @@ func GetTimeStats() map[string]map[string]float64 {
 }
 
 // Appends remap complexity data to the remaps slice.
-func calculateRemapComplexity() {
-	if numNodes == 0 {
-		numNodes = 1
+func (s *Stats) calculateRemapComplexity() {
+	nodes := s.numNodes.get()
+	if nodes == 0 {
+		nodes = 1
+	}
+	expectedRemaps := s.numKeys.get() / nodes
+	remapped := int(s.remapped.Swap(0))
+	s.remappedTotal.add(remapped)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remaps = append(s.remaps, map[int]int{remapped: expectedRemaps})
+	if remapped > 0 {
+		s.window.remapped(time.Now(), remapped)
 	}
-	expectedRemaps := numKeys / numNodes
-	remaps = append(remaps, map[int]int{remapped: expectedRemaps})
-	remapped = 0
 }
 
 // Utility function to calculate mean, variance, and standard deviation.
@@ -320,3 +448,28 @@ func calculateStatsFloat64(values []float64) (float64, float64, float64) {
 
 	return mean, variance, stdDev
 }
+
+// calculateImbalance computes the peak-to-mean ratio, Gini coefficient and Jain's fairness index of the
+// loads. Empty or all-zero loads count as perfectly even.
+func calculateImbalance(loads []int) Imbalance {
+	total := sum(loads)
+	if len(loads) == 0 || total == 0 {
+		return Imbalance{PeakToMean: 1, Gini: 0, Jain: 1}
+	}
+	n := float64(len(loads))
+	sorted := append([]int(nil), loads...)
+	sort.Ints(sorted)
+
+	// Gini over the ascending loads: sum of (2i - n - 1) * x_i, over n * sum of x.
+	var weighted, squares float64
+	for i, load := range sorted {
+		weighted += float64(2*(i+1)-len(sorted)-1) * float64(load)
+		squares += float64(load) * float64(load)
+	}
+	mean := float64(total) / n
+	return Imbalance{
+		PeakToMean: float64(sorted[len(sorted)-1]) / mean,
+		Gini:       weighted / (n * float64(total)),
+		Jain:       float64(total) * float64(total) / (n * squares),
+	}
+}
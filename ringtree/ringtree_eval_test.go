@@ -0,0 +1,48 @@
+package ringtree
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestCalculateImbalance(t *testing.T) {
+	tests := []struct {
+		name  string
+		loads []int
+		want  Imbalance
+	}{
+		{"empty", nil, Imbalance{PeakToMean: 1, Gini: 0, Jain: 1}},
+		{"idle", []int{0, 0, 0}, Imbalance{PeakToMean: 1, Gini: 0, Jain: 1}},
+		{"even", []int{5, 5, 5, 5}, Imbalance{PeakToMean: 1, Gini: 0, Jain: 1}},
+		{"one holds all", []int{0, 0, 0, 8}, Imbalance{PeakToMean: 4, Gini: 0.75, Jain: 0.25}},
+		{"skewed", []int{1, 2, 3, 4}, Imbalance{PeakToMean: 1.6, Gini: 0.25, Jain: 100.0 / 120}},
+	}
+	for _, tt := range tests {
+		got := calculateImbalance(tt.loads)
+		if math.Abs(got.PeakToMean-tt.want.PeakToMean) > 1e-9 || math.Abs(got.Gini-tt.want.Gini) > 1e-9 || math.Abs(got.Jain-tt.want.Jain) > 1e-9 {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetImbalance(t *testing.T) {
+	rt := New(3)
+	rt.InsertNode(NewNode("A", 5))
+	rt.InsertNode(NewNode("B", 1000))
+	for i := 0; i < 200; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	system, levels := rt.GetImbalance()
+	depth := rt.GetDepth()
+	if depth == 0 {
+		t.Fatal("expected node A to split into a subring")
+	}
+	if len(levels) != depth+1 {
+		t.Fatalf("got imbalance for %d levels, want %d", len(levels), depth+1)
+	}
+	if system.PeakToMean < 1 || system.Gini < 0 || system.Gini >= 1 || system.Jain <= 0 || system.Jain > 1 {
+		t.Errorf("system imbalance %+v out of range", system)
+	}
+}
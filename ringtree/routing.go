@@ -0,0 +1,238 @@
+package ringtree
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrStaleRoutingTable is returned by ApplyDelta for a change it cannot replay from its event alone. The
+// caller should take a fresh RoutingTable from the ring.
+var ErrStaleRoutingTable = errors.New("routing table cannot follow the change, take a fresh one")
+
+// RoutingTable is an immutable copy of the tree's routing state for clients that route keys locally. It
+// answers Owner and Replicas without taking any lock and follows topology changes by replaying the ring's
+// Watch events through ApplyDelta. Keys are routed as of the pins taken with the table.
+type RoutingTable struct {
+	config *Config
+	root   string
+	rings  map[string]*routingRing
+	pins   map[string]string
+}
+
+// routingRing is the routing state of one ring. It is shared between tables and never modified once built.
+type routingRing struct {
+	id       string
+	level    int
+	vNodes   []VNode         // In hash order
+	subrings map[string]bool // Members that are subrings
+}
+
+// RoutingTable returns a routing table of the whole tree, built from the rings' published snapshots.
+func (r *Ring) RoutingTable() *RoutingTable {
+	root := r.root()
+	t := &RoutingTable{config: root.config, root: root.id, rings: make(map[string]*routingRing), pins: make(map[string]string)}
+	queue := []*Ring{root}
+	for len(queue) > 0 {
+		ring := queue[0]
+		queue = queue[1:]
+		rr := &routingRing{id: ring.id, level: ring.level, subrings: make(map[string]bool)}
+		if snapshot := ring.snapshot.Load(); snapshot != nil {
+			rr.vNodes = snapshot.vNodes
+			for id, member := range snapshot.members {
+				if subring, ok := member.(*Ring); ok {
+					rr.subrings[id] = true
+					queue = append(queue, subring)
+				}
+			}
+		}
+		t.rings[ring.id] = rr
+	}
+
+	root.pins.RLock()
+	for key, p := range root.pins.pins {
+		t.pins[key] = p.target
+	}
+	root.pins.RUnlock()
+	return t
+}
+
+// Owner returns the ID of the member a key is routed to: a physical node, or a custom member.
+func (t *RoutingTable) Owner(key string) (string, error) {
+	ring, vNodeIdx, err := t.route(key)
+	if err != nil {
+		return "", err
+	}
+	if vNodeIdx < 0 {
+		return t.pins[key], nil
+	}
+	return ring.vNodes[vNodeIdx].nodeID, nil
+}
+
+// Replicas returns the IDs of the members holding a key in replicated mode: its owner followed by the next
+// distinct members clockwise on the owner's ring.
+func (t *RoutingTable) Replicas(key string) ([]string, error) {
+	ring, vNodeIdx, err := t.route(key)
+	if err != nil {
+		return nil, err
+	}
+	if vNodeIdx < 0 {
+		return []string{t.pins[key]}, nil
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	n := t.config.replication()
+	for i := 0; i < len(ring.vNodes) && len(ids) < n; i++ {
+		id := ring.vNodes[(vNodeIdx+i)%len(ring.vNodes)].nodeID
+		if seen[id] || ring.subrings[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// route descends from the root to the ring the key ends on and returns the index of the vnode owning it
+// there, or -1 for a key pinned to a node.
+func (t *RoutingTable) route(key string) (*routingRing, int, error) {
+	ring := t.rings[t.root]
+	if target, pinned := t.pins[key]; pinned {
+		if _, isRing := t.rings[target]; !isRing {
+			return ring, -1, nil
+		}
+		ring = t.rings[target]
+	}
+	for {
+		if len(ring.vNodes) == 0 {
+			return nil, 0, ErrRingEmpty
+		}
+		keyHash := t.config.keyHash(key, ring.level)
+		idx := sort.Search(len(ring.vNodes), func(i int) bool {
+			return ring.vNodes[i].hash >= keyHash
+		})
+		if idx == len(ring.vNodes) {
+			idx = 0
+		}
+		id := ring.vNodes[idx].nodeID
+		if !ring.subrings[id] {
+			return ring, idx, nil
+		}
+		if ring = t.rings[id]; ring == nil {
+			return nil, 0, ErrStaleRoutingTable
+		}
+	}
+}
+
+// ApplyDelta returns a table with the topology changes of a batch of Watch events applied, leaving t as it
+// is. Node joins and removals, splits and collapses are replayed; node merges, arcs moved by Rebalance,
+// vnodes added or removed on their own and joins whose vnodes SpreadTolerance or WarmUp adjusted cannot
+// be, and return ErrStaleRoutingTable.
+func (t *RoutingTable) ApplyDelta(events []Event) (*RoutingTable, error) {
+	next := &RoutingTable{config: t.config, root: t.root, rings: make(map[string]*routingRing, len(t.rings)), pins: t.pins}
+	for id, ring := range t.rings {
+		next.rings[id] = ring
+	}
+	edited := make(map[string]bool)
+
+	for _, event := range events {
+		switch event.Type {
+		case NodeAdded:
+			if t.config.SpreadTolerance > 0 || t.config.WarmUp > 0 {
+				// The node joined with only part of its vnodes
+				return nil, ErrStaleRoutingTable
+			}
+			ring := next.edit(edited, event.RingID, event.Level)
+			if ring.owns(event.NodeID) {
+				continue
+			}
+			ring.vNodes = append(ring.vNodes, t.config.vNodes(event.NodeID)...)
+			sort.Slice(ring.vNodes, func(i, j int) bool {
+				return ring.vNodes[i].hash < ring.vNodes[j].hash
+			})
+		case NodeRemoved:
+			ring := next.edit(edited, event.RingID, event.Level)
+			vNodes := ring.vNodes[:0]
+			for _, vNode := range ring.vNodes {
+				if vNode.nodeID != event.NodeID {
+					vNodes = append(vNodes, vNode)
+				}
+			}
+			ring.vNodes = vNodes
+		case SubringCreated:
+			ring := next.edit(edited, event.RingID, event.Level-1)
+			if !ring.owns(event.NodeID) {
+				// A vnode split names the subring after the node and the vnode it takes over
+				sep := strings.LastIndex(event.NodeID, "/")
+				vNodeHash, err := strconv.ParseUint(event.NodeID[sep+1:], 10, 32)
+				if sep < 0 || err != nil {
+					return nil, ErrStaleRoutingTable
+				}
+				for i := range ring.vNodes {
+					if ring.vNodes[i].hash == uint32(vNodeHash) {
+						ring.vNodes[i].nodeID = event.NodeID
+					}
+				}
+			}
+			ring.subrings[event.NodeID] = true
+			next.edit(edited, event.NodeID, event.Level)
+		case SubringCollapsed:
+			ring := next.edit(edited, event.RingID, event.Level-1)
+			delete(ring.subrings, event.NodeID)
+			next.drop(event.NodeID)
+		case NodesMerged, TreesMerged, VNodesChanged:
+			return nil, ErrStaleRoutingTable
+		case Rebalanced:
+			// Nodes moved between subrings arrive as removals and joins; only a moved arc is lost
+			if next.rings[event.RingID] != nil && next.rings[event.RingID].subrings[event.NodeID] {
+				return nil, ErrStaleRoutingTable
+			}
+		}
+	}
+	return next, nil
+}
+
+// edit returns a private copy of a ring of the table, creating the ring if the table has not seen it yet.
+// Rings already in edited were copied earlier in the same delta and are returned as they are.
+func (t *RoutingTable) edit(edited map[string]bool, id string, level int) *routingRing {
+	ring, ok := t.rings[id]
+	if ok && edited[id] {
+		return ring
+	}
+	edited[id] = true
+	if !ok {
+		ring = &routingRing{id: id, level: level, subrings: make(map[string]bool)}
+		t.rings[id] = ring
+		return ring
+	}
+	copied := &routingRing{id: id, level: ring.level, vNodes: append([]VNode(nil), ring.vNodes...), subrings: make(map[string]bool, len(ring.subrings))}
+	for sub := range ring.subrings {
+		copied.subrings[sub] = true
+	}
+	t.rings[id] = copied
+	return copied
+}
+
+// drop removes a ring and every ring nested below it from the table.
+func (t *RoutingTable) drop(id string) {
+	ring, ok := t.rings[id]
+	if !ok {
+		return
+	}
+	delete(t.rings, id)
+	for sub := range ring.subrings {
+		t.drop(sub)
+	}
+}
+
+// owns reports whether a member has vnodes on the ring.
+func (r *routingRing) owns(id string) bool {
+	for _, vNode := range r.vNodes {
+		if vNode.nodeID == id {
+			return true
+		}
+	}
+	return false
+}
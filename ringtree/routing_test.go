@@ -0,0 +1,115 @@
+package ringtree
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// checkRoutes compares the owner of every key in a routing table against the ring.
+func checkRoutes(t *testing.T, rt *Ring, table *RoutingTable, keys []string) {
+	for _, key := range keys {
+		want, err := rt.Lookup(key)
+		if err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+		if got, err := table.Owner(key); err != nil || got != want {
+			t.Errorf("expected key %s routed to %s, got %s (%v)", key, want, got, err)
+		}
+	}
+}
+
+func TestRoutingTable(t *testing.T) {
+	rt := New(3, WithReplication(2))
+	rt.InsertNode(NewNode("A", 50))
+	rt.InsertNode(NewNode("B", 50))
+	var keys []string
+	for i := 0; i < 400; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		rt.InsertKey(keys[i])
+	}
+	table := rt.RoutingTable()
+	checkRoutes(t, rt, table, keys)
+
+	replicas, err := table.Replicas(keys[0])
+	if err != nil {
+		t.Fatalf("expected replicas, got error: %v", err)
+	}
+	want, _ := rt.Replicas(keys[0])
+	if !reflect.DeepEqual(replicas, want) {
+		t.Errorf("expected replicas %v, got %v", want, replicas)
+	}
+}
+
+func TestRoutingTableApplyDelta(t *testing.T) {
+	rt := New(3)
+	rt.InsertNode(NewNode("A", 50))
+	table := rt.RoutingTable()
+	events, cancel := rt.Watch(1000)
+	defer cancel()
+
+	// Joins, splits and collapses replayed from the Watch stream keep the table in step
+	var keys []string
+	for i := 0; i < 400; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		rt.InsertKey(keys[i])
+	}
+	rt.InsertNode(NewNode("B", 50))
+	for i := 0; i < 300; i++ {
+		rt.RemoveKey(keys[i])
+	}
+	keys = keys[300:]
+
+	before := table
+	for len(events) > 0 {
+		var err error
+		if table, err = table.ApplyDelta(<-events); err != nil {
+			t.Fatalf("expected delta to apply, got error: %v", err)
+		}
+	}
+	if rt.GetDepth() == 0 {
+		t.Fatalf("expected the tree to have split")
+	}
+	checkRoutes(t, rt, table, keys)
+
+	// The original table is unchanged
+	if owner, _ := before.Owner(keys[0]); owner != "A" {
+		t.Errorf("expected the original table to route to A, got %s", owner)
+	}
+}
+
+func TestRoutingTableStale(t *testing.T) {
+	rt := New(3)
+	rt.InsertNode(NewNode("A", 50))
+	rt.InsertNode(NewNode("B", 50))
+	table := rt.RoutingTable()
+	events, cancel := rt.Watch(10)
+	defer cancel()
+
+	rt.MergeNodes("A", "B")
+	if _, err := table.ApplyDelta(<-events); err != ErrStaleRoutingTable {
+		t.Errorf("expected ErrStaleRoutingTable after a merge, got %v", err)
+	}
+}
+
+func TestRoutingTableWarmUp(t *testing.T) {
+	rt := New(3, WithWarmUp(0.25, time.Hour))
+	rt.InsertNode(NewNode("A", 50))
+	table := rt.RoutingTable()
+	events, cancel := rt.Watch(10)
+	defer cancel()
+
+	// B joins with only part of its vnodes, which the join event does not carry
+	rt.InsertNode(NewNode("B", 50))
+	if _, err := table.ApplyDelta(<-events); err != ErrStaleRoutingTable {
+		t.Errorf("expected ErrStaleRoutingTable after a warming join, got %v", err)
+	}
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, "key-"+strconv.Itoa(i))
+		rt.InsertKey(keys[i])
+	}
+	checkRoutes(t, rt, rt.RoutingTable(), keys)
+}
@@ -0,0 +1,40 @@
+package ringtree
+
+import "encoding/binary"
+
+// LevelSalt returns the bytes appended to a key before hashing it on the given level.
+type LevelSalt func(level int) []byte
+
+// DefaultLevelSalt appends the level as a little-endian uint32, matching the vnode hash.
+func DefaultLevelSalt(level int) []byte {
+	levelBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(levelBytes, uint32(level))
+	return levelBytes
+}
+
+// NoLevelSalt hashes keys identically on every level.
+func NoLevelSalt(level int) []byte {
+	return nil
+}
+
+// WithLevelSalt sets how keys are salted per level before hashing. Vnode placement is unaffected.
+func WithLevelSalt(salt LevelSalt) Option {
+	return func(c *Config) {
+		c.LevelSalt = salt
+	}
+}
+
+// keyHash returns the position of a key on a ring of the given level.
+func (c *Config) keyHash(key string, level int) uint32 {
+	if c.KeyExtractor != nil {
+		// The keyspace salt is kept outside the extracted part, so keyspaces stay apart
+		prefix, rest := splitKeyspace(key)
+		key = prefix + c.KeyExtractor(rest)
+	}
+	if c.LevelSalt == nil {
+		return hash(key, level)
+	}
+	k := hashers.Get().(*keyHasher)
+	defer hashers.Put(k)
+	return k.sum(key, c.LevelSalt(level))
+}
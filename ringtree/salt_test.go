@@ -0,0 +1,32 @@
+package ringtree
+
+import "testing"
+
+func TestLevelSalt(t *testing.T) {
+	defaultConfig := defaultConfig(2)
+	if defaultConfig.keyHash("key", 3) != hash("key", 3) {
+		t.Errorf("expected the default salt to match the level hash")
+	}
+
+	noSalt := defaultConfig
+	WithLevelSalt(NoLevelSalt)(noSalt)
+	if noSalt.keyHash("key", 0) != noSalt.keyHash("key", 5) {
+		t.Errorf("expected unsalted keys to hash identically on every level")
+	}
+
+	rt := New(2, WithLevelSalt(func(level int) []byte { return []byte{byte(level), 0x5a} }))
+	rt.InsertNode(NewNode("", 5))
+	var keys []string
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	for _, key := range keys {
+		if _, err := rt.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package ringtree
+
+import (
+	"math"
+	"sort"
+)
+
+// scaleModel estimates the keys on every vnode of a tree as nodes are removed from it one by one, without
+// touching the tree.
+type scaleModel struct {
+	nodes []*scaleNode // Nodes still in the model
+}
+
+// scaleRing is a ring of the model.
+type scaleRing struct {
+	vNodes  []VNode               // Vnodes of the ring in hash order
+	members map[string]*scaleRing // Subrings of the ring by ID
+	nodes   map[string]*scaleNode // Nodes of the ring by ID
+	custom  map[string]bool       // Custom members of the ring, which take keys out of the tree
+	removed map[string]bool       // Nodes removed from the ring by the model
+}
+
+// scaleNode is a node of the model.
+type scaleNode struct {
+	id     string
+	ring   *scaleRing
+	keys   map[uint32]float64 // Estimated keys of each vnode
+	pinned bool               // Keys are pinned to the node, so it cannot be removed
+}
+
+// scaleFlow is an estimated number of keys moving onto a vnode.
+type scaleFlow struct {
+	node      *scaleNode
+	vNodeHash uint32
+	keys      float64
+}
+
+// total returns the estimated keys of the node.
+func (n *scaleNode) total() float64 {
+	total := 0.0
+	for _, keys := range n.keys {
+		total += keys
+	}
+	return total
+}
+
+// newScaleModel builds a model of the ring and its subrings (assuming the tree's writer lock is held).
+func (r *Ring) newScaleModel() *scaleModel {
+	m := &scaleModel{}
+	m.addRing(r)
+	return m
+}
+
+// addRing adds a ring and its subrings to the model and returns the ring's model.
+func (m *scaleModel) addRing(r *Ring) *scaleRing {
+	r.RLock()
+	defer r.RUnlock()
+	ring := &scaleRing{
+		vNodes:  circleVNodes(r.circle),
+		members: make(map[string]*scaleRing),
+		nodes:   make(map[string]*scaleNode),
+		custom:  make(map[string]bool),
+		removed: make(map[string]bool),
+	}
+	for id, member := range r.members {
+		switch member := member.(type) {
+		case *Node:
+			node := &scaleNode{id: id, ring: ring, keys: make(map[uint32]float64, len(member.keys)), pinned: r.pins.targeted(id)}
+			for vNodeHash, keys := range member.keys {
+				node.keys[vNodeHash] = float64(len(keys))
+			}
+			ring.nodes[id] = node
+			m.nodes = append(m.nodes, node)
+		case *Ring:
+			ring.members[id] = m.addRing(member)
+		default:
+			ring.custom[id] = true
+		}
+	}
+	return ring
+}
+
+// size returns the members left on the ring.
+func (r *scaleRing) size() int {
+	return len(r.members) + len(r.nodes) - len(r.removed) + len(r.custom)
+}
+
+// leaves returns the nodes left on the ring and its subrings.
+func (r *scaleRing) leaves() []*scaleNode {
+	var nodes []*scaleNode
+	for id, node := range r.nodes {
+		if !r.removed[id] {
+			nodes = append(nodes, node)
+		}
+	}
+	for _, subring := range r.members {
+		nodes = append(nodes, subring.leaves()...)
+	}
+	return nodes
+}
+
+// removable reports whether the node can be removed without emptying its ring, which for a subring would
+// collapse it into a new node rather than shrink the tree.
+func (n *scaleNode) removable() bool {
+	return !n.pinned && n.ring.size() >= 2
+}
+
+// flows returns where the keys of a removed node go: each vnode's keys to the next vnode clockwise held by
+// another member, like removeNode. Keys handed to a subring are spread evenly over its nodes, as hashing
+// spreads them on average, and keys handed to a custom member leave the tree.
+func (n *scaleNode) flows() []scaleFlow {
+	ring := n.ring
+	var flows []scaleFlow
+	for i, vNode := range ring.vNodes {
+		keys := n.keys[vNode.hash]
+		if vNode.nodeID != n.id || keys == 0 {
+			continue
+		}
+		for j := 1; j < len(ring.vNodes); j++ {
+			next := ring.vNodes[(i+j)%len(ring.vNodes)]
+			if next.nodeID == n.id || ring.removed[next.nodeID] {
+				continue
+			}
+			if node, ok := ring.nodes[next.nodeID]; ok {
+				flows = append(flows, scaleFlow{node: node, vNodeHash: next.hash, keys: keys})
+			} else if subring, ok := ring.members[next.nodeID]; ok {
+				leaves := subring.leaves()
+				for _, leaf := range leaves {
+					if len(leaf.keys) == 0 {
+						continue
+					}
+					share := keys / float64(len(leaves)) / float64(len(leaf.keys))
+					for vNodeHash := range leaf.keys {
+						flows = append(flows, scaleFlow{node: leaf, vNodeHash: vNodeHash, keys: share})
+					}
+				}
+			}
+			break
+		}
+	}
+	return flows
+}
+
+// deviation returns the standard deviation of the keys per node once a node is removed and its keys have
+// flowed to the given vnodes.
+func (m *scaleModel) deviation(removed *scaleNode, flows []scaleFlow) float64 {
+	gained := make(map[*scaleNode]float64)
+	for _, flow := range flows {
+		gained[flow.node] += flow.keys
+	}
+	var totals []float64
+	sum := 0.0
+	for _, node := range m.nodes {
+		if node != removed {
+			total := node.total() + gained[node]
+			totals = append(totals, total)
+			sum += total
+		}
+	}
+	if len(totals) == 0 {
+		return 0
+	}
+	mean := sum / float64(len(totals))
+	variance := 0.0
+	for _, total := range totals {
+		variance += (total - mean) * (total - mean)
+	}
+	return math.Sqrt(variance / float64(len(totals)))
+}
+
+// remove takes a node out of the model and moves its keys along the given flows.
+func (m *scaleModel) remove(removed *scaleNode, flows []scaleFlow) {
+	for _, flow := range flows {
+		flow.node.keys[flow.vNodeHash] += flow.keys
+	}
+	removed.ring.removed[removed.id] = true
+	for i, node := range m.nodes {
+		if node == removed {
+			m.nodes = append(m.nodes[:i], m.nodes[i+1:]...)
+			break
+		}
+	}
+}
+
+// PlanScaleDown picks n nodes to remove from the tree, in the order to remove them, and estimates the keys
+// the removals move. Nodes are picked one at a time, each minimizing the keys it moves plus the standard
+// deviation of keys per node left behind, so a light node next to an already heavy one may lose to a
+// slightly heavier node whose keys land evenly. Removing a node moves its keys to the next vnodes clockwise
+// on its ring, and keys it received from earlier picks move again. Nodes holding pinned keys and the last
+// node of a ring are never picked, so fewer than n nodes are returned if no more can be removed. The tree
+// is not changed; ScaleDown carries a plan out.
+func (r *Ring) PlanScaleDown(n int) (victims []string, movedKeys int) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.planScaleDown(n)
+}
+
+// planScaleDown picks the nodes to remove like PlanScaleDown (assuming the tree's writer lock is held).
+func (r *Ring) planScaleDown(n int) ([]string, int) {
+	model := r.root().newScaleModel()
+	// Visited in ID order, so ties break the same way each run
+	sort.Slice(model.nodes, func(i, j int) bool { return model.nodes[i].id < model.nodes[j].id })
+
+	var victims []string
+	moved := 0.0
+	for len(victims) < n {
+		var best *scaleNode
+		var bestFlows []scaleFlow
+		bestCost := math.Inf(1)
+		for _, node := range model.nodes {
+			if !node.removable() {
+				continue
+			}
+			flows := node.flows()
+			if cost := node.total() + model.deviation(node, flows); cost < bestCost {
+				best, bestFlows, bestCost = node, flows, cost
+			}
+		}
+		if best == nil {
+			break
+		}
+		moved += best.total()
+		victims = append(victims, best.id)
+		model.remove(best, bestFlows)
+	}
+	return victims, int(math.Round(moved))
+}
+
+// ScaleDown removes n nodes picked by PlanScaleDown through the drain path: every picked node is set to
+// Draining first, so writes made while the others are removed avoid all of them, then each is removed in
+// turn and its keys move to the next vnodes clockwise. It returns the nodes removed. If a removal fails the
+// nodes not yet removed are left Draining.
+func (r *Ring) ScaleDown(n int) ([]string, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	victims, _ := r.planScaleDown(n)
+	for _, id := range victims {
+		if err := r.setNodeStateOp(id, Draining); err != nil {
+			return nil, err
+		}
+	}
+	for i, id := range victims {
+		node, ring := r.findMember(id)
+		if node == nil {
+			return victims[:i], ErrNodeNotFound
+		}
+		r.beginOp()
+		if err := r.logOp(Op{Type: OpRemoveNode, Node: id}, ring.removeNode(node)); err != nil {
+			return victims[:i], err
+		}
+	}
+	return victims, nil
+}
@@ -0,0 +1,80 @@
+package ringtree
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestPlanScaleDown(t *testing.T) {
+	rt, _ := buildTree(t, 8, []string{"A", "B", "C", "D", "E", "F"}, 20000, 2000)
+	// Pin a key to the lightest node, which may then not be picked
+	lightest := rt.NodeIDs()[0]
+	for _, id := range rt.NodeIDs() {
+		if node, _ := rt.Node(id); node.Load() < mustNode(t, rt, lightest).Load() {
+			lightest = id
+		}
+	}
+	pinned, _ := rt.KeysForNode(lightest)
+	if err := rt.PinKey(pinned[0], lightest); err != nil {
+		t.Fatalf("expected the key to be pinned, got error: %v", err)
+	}
+
+	victims, moved := rt.PlanScaleDown(2)
+	checkNum(len(victims), 2, t)
+	checkNum(rt.Size(), 6, t)
+	for _, id := range victims {
+		if id == lightest {
+			t.Errorf("expected node %s holding a pinned key not to be picked", id)
+		}
+	}
+	// The plan moves fewer keys than removing the two heaviest nodes would
+	loads := make([]int, 0, 6)
+	for _, id := range rt.NodeIDs() {
+		loads = append(loads, mustNode(t, rt, id).Load())
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(loads)))
+	if moved == 0 || moved >= loads[0]+loads[1] {
+		t.Errorf("expected between 0 and %d keys to move, got %d", loads[0]+loads[1], moved)
+	}
+
+	// On a single ring the estimate is exact, counting keys moved twice by consecutive removals twice
+	remapped := rt.Counters().Remapped
+	removed, err := rt.ScaleDown(2)
+	if err != nil {
+		t.Fatalf("expected the nodes to be removed, got error: %v", err)
+	}
+	checkNum(len(removed), 2, t)
+	for i := range victims {
+		if removed[i] != victims[i] {
+			t.Errorf("expected ScaleDown to remove the planned nodes %v, got %v", victims, removed)
+		}
+	}
+	checkNum(rt.Size(), 4, t)
+	checkValid(rt, t)
+	checkNum(rt.Counters().Remapped-remapped, moved, t)
+}
+
+func TestPlanScaleDownLimits(t *testing.T) {
+	rt, _ := buildTree(t, 8, []string{"A", "B", "C"}, 3000, 300)
+	// The root keeps one node
+	victims, _ := rt.PlanScaleDown(10)
+	checkNum(len(victims), 2, t)
+
+	// The last node of a subring is never picked, since removing it would only collapse the subring
+	deep, keys := buildTree(t, 3, []string{"A", "B", "C", "D", "E", "F", "G"}, 10000, 1000)
+	nodes := deep.Stats().Nodes()
+	removed, err := deep.ScaleDown(nodes)
+	if err != nil {
+		t.Fatalf("expected the nodes to be removed, got error: %v", err)
+	}
+	checkNum(deep.Stats().Nodes(), nodes-len(removed), t)
+	checkValid(deep, t)
+	for _, key := range keys {
+		if _, err := deep.Lookup(key); err != nil {
+			t.Fatalf("expected key %s to be found after scaling down, got error: %v", key, err)
+		}
+	}
+	if victims, _ := deep.PlanScaleDown(1); len(victims) != 0 {
+		t.Errorf("expected no node left to remove, got %v", victims)
+	}
+}
@@ -0,0 +1,174 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrShadowStale is returned by Promote when the live tree's topology changed after its shadow was taken.
+var ErrShadowStale = errors.New("tree changed since the shadow was taken")
+
+// Shadow is a copy of a tree on which proposed changes, such as adding nodes or changing thresholds, are
+// applied and evaluated with the usual stats without touching the live tree. Every logged operation applied
+// to the copy is recorded, and Promote replays them on the live tree once the plan is accepted.
+type Shadow struct {
+	*Ring // The copy, on which changes are applied and evaluated
+
+	live     *Ring
+	epoch    uint64 // Topology epoch of the live tree when the shadow was taken
+	plan     *shadowPlan
+	promoted bool
+}
+
+// shadowPlan records the operations applied to a shadow, as its WAL.
+type shadowPlan struct {
+	ops []Op
+	sync.Mutex
+}
+
+// Append records an operation.
+func (p *shadowPlan) Append(op Op) error {
+	p.Lock()
+	defer p.Unlock()
+	p.ops = append(p.ops, op)
+	return nil
+}
+
+// Shadow returns a copy of the whole tree on which proposed changes can be tried out. Rings and nodes are
+// copied, while key sets are shared copy-on-write with the live tree, as iterators share them: whichever
+// tree next writes a vnode's keys copies that set first, so taking a shadow costs one map entry per vnode
+// rather than per key. Key costs and checksums, kept with LoadFunc and Checksums, are copied.
+// The shadow records its operations in its plan instead of the live tree's log, takes no checkpoints, and
+// does not see the live tree's watchers, leases or prepared node additions. Trees with custom members cannot
+// be shadowed.
+func (r *Ring) Shadow() (*Shadow, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	root := r.root()
+	r.settleRemaps()
+
+	plan := &shadowPlan{}
+	config := *r.config
+	config.WAL = plan
+	config.RandSource = nil // The live tree's source is not safe to share; replayed IDs keep the trees in step
+	shadow := newRoot(&config, root.maxCount)
+	if err := shadow.copyRing(root); err != nil {
+		return nil, err
+	}
+	root.pins.RLock()
+	for key, p := range root.pins.pins {
+		shadow.pins.set(key, p.target, p.sticky)
+	}
+	root.pins.RUnlock()
+	shadow.wal.seq = r.wal.seq
+	epoch := r.Epoch()
+	shadow.hub.epoch = epoch
+	return &Shadow{Ring: shadow, live: root, epoch: epoch, plan: plan}, nil
+}
+
+// copyRing fills an empty ring with copies of the members of another ring and everything below them.
+func (r *Ring) copyRing(from *Ring) error {
+	from.RLock()
+	defer from.RUnlock()
+	r.Lock()
+	defer r.Unlock()
+	defer r.publish()
+
+	r.level, r.maxCount, r.high, r.low, r.policy = from.level, from.maxCount, from.high, from.low, from.policy
+	r.circle.InsertBatch(circleVNodes(from.circle))
+	for id, member := range from.members {
+		switch member := member.(type) {
+		case *Node:
+			node := member.copyNode()
+			for _, keys := range node.keys {
+				if r.index != nil {
+					for key := range keys {
+						r.index.set(key, node, r)
+					}
+				}
+				r.stats.numKeys.add(len(keys))
+			}
+			r.members[id] = node
+			r.stats.numNodes.add(1)
+		case *Ring:
+			subring := newRing(r.config, r, id, member.level, member.maxCount)
+			if err := subring.copyRing(member); err != nil {
+				return err
+			}
+			r.members[id] = subring
+		default:
+			return ErrCustomMember
+		}
+	}
+	return nil
+}
+
+// copyNode returns a copy of the node sharing its key sets, which both nodes copy before they next write
+// them (assuming the tree's writer lock and the node's ring's mutex are held).
+func (n *Node) copyNode() *Node {
+	node := NewNode(n.id, n.threshold)
+	node.base, node.state, node.load, node.changedAt, node.locality = n.base, n.state, n.load, n.changedAt, n.locality
+	node.warmingAt = n.warmingAt
+	for _, w := range n.maintenance {
+		node.maintenance = append(node.maintenance, maintenanceWindow{start: w.start, end: w.end})
+	}
+	if n.shared == nil {
+		n.shared = make(map[uint32]bool, len(n.keys))
+	}
+	node.shared = make(map[uint32]bool, len(n.keys))
+	for vNodeHash, keys := range n.keys {
+		node.keys[vNodeHash] = keys
+		n.shared[vNodeHash] = true
+		node.shared[vNodeHash] = true
+	}
+	if len(n.costs) > 0 {
+		node.costs = make(map[string]int, len(n.costs))
+		for key, cost := range n.costs {
+			node.costs[key] = cost
+		}
+	}
+	if n.checksums != nil {
+		node.checksums = make(map[string]uint32, len(n.checksums))
+		for key, sum := range n.checksums {
+			node.checksums[key] = sum
+		}
+	}
+	return node
+}
+
+// Plan returns the operations applied to the shadow so far, which Promote replays on the live tree.
+func (s *Shadow) Plan() []Op {
+	s.plan.Lock()
+	defer s.plan.Unlock()
+	return append([]Op(nil), s.plan.ops...)
+}
+
+// Promote replays the operations applied to the shadow on the live tree, in order, with the node IDs the
+// shadow generated, so the live tree takes the shape evaluated on the shadow. Each operation is logged to
+// the live tree's WAL as it is applied. The live tree's writer lock is held from the epoch check to the last
+// operation, so no other change lands in between. It returns ErrShadowStale without changing anything if
+// the live tree's topology changed since the shadow was taken; keys written to the live tree meanwhile do
+// not stop a promotion, but may place differently than on the shadow.
+//
+// If an operation fails, the operations before it stay applied to the live tree and in its log, leaving it
+// part of the way to the shadow's shape; the error names the failed operation, and the shadow is not marked
+// promoted. A shadow can be promoted once.
+func (s *Shadow) Promote() error {
+	live := s.live
+	live.writer.Lock()
+	defer live.writer.Unlock()
+	if s.promoted {
+		return errors.New("shadow was already promoted")
+	}
+	if live.Epoch() != s.epoch {
+		return ErrShadowStale
+	}
+	for _, op := range s.Plan() {
+		if err := live.applyOp(op); err != nil && !op.Failed {
+			return fmt.Errorf("error promoting operation %d (%s): %w", op.Seq, op.Type, err)
+		}
+	}
+	s.promoted = true
+	return nil
+}
@@ -0,0 +1,153 @@
+package ringtree
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B", "C"}, 10000, 1000)
+	before := owners(rt, keys)
+	epoch := rt.Epoch()
+
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatalf("expected a shadow, got error: %v", err)
+	}
+	for _, id := range []string{"D", "E", "F"} {
+		if err := shadow.InsertNode(NewNode(id, 10000)); err != nil {
+			t.Fatalf("expected node %s to be added to the shadow, got error: %v", id, err)
+		}
+	}
+	if err := shadow.SetNodeThreshold("A", 500); err != nil {
+		t.Fatalf("expected the threshold to be set, got error: %v", err)
+	}
+	if _, err := shadow.Split("B"); err != nil {
+		t.Fatalf("expected node B to be split on the shadow, got error: %v", err)
+	}
+	checkValid(shadow.Ring, t)
+	checkNum(len(shadow.Plan()), 5, t)
+
+	// The change is evaluated on the shadow while the live tree is untouched
+	report := shadow.Report()
+	checkNum(report.Keys, 1000, t)
+	if report.Depth != 1 {
+		t.Errorf("expected the shadow to be one level deep, got %d", report.Depth)
+	}
+	checkNum(rt.Size(), 3, t)
+	checkNum(rt.Stats().Nodes(), 3, t)
+	checkNum(mustNode(t, rt, "A").Threshold(), 10000, t)
+	if rt.Epoch() != epoch {
+		t.Errorf("expected the live tree's epoch to stay at %d, got %d", epoch, rt.Epoch())
+	}
+	for key, owner := range owners(rt, keys) {
+		if owner != before[key] {
+			t.Fatalf("expected key %s to stay on %s in the live tree, got %s", key, before[key], owner)
+		}
+	}
+
+	// Promoting gives the live tree the shadow's shape, down to the IDs of the nodes the split seeded
+	if err := shadow.Promote(); err != nil {
+		t.Fatalf("expected the shadow to be promoted, got error: %v", err)
+	}
+	checkValid(rt, t)
+	live, planned := rt.NodeIDs(), shadow.NodeIDs()
+	sort.Strings(live)
+	sort.Strings(planned)
+	if len(live) != len(planned) {
+		t.Fatalf("expected the live tree's nodes %v to match the shadow's %v", live, planned)
+	}
+	for i := range live {
+		if live[i] != planned[i] {
+			t.Fatalf("expected the live tree's nodes %v to match the shadow's %v", live, planned)
+		}
+	}
+	checkNum(mustNode(t, rt, "A").Threshold(), 500, t)
+	promoted := owners(rt, keys)
+	for key, owner := range owners(shadow.Ring, keys) {
+		if promoted[key] != owner {
+			t.Errorf("expected key %s on %s as on the shadow, got %s", key, owner, promoted[key])
+		}
+	}
+	if err := shadow.Promote(); err == nil {
+		t.Errorf("expected a second promotion to fail")
+	}
+}
+
+func TestShadowStale(t *testing.T) {
+	rt, _ := buildTree(t, 8, []string{"A", "B"}, 1000, 100)
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatalf("expected a shadow, got error: %v", err)
+	}
+	shadow.InsertNode(NewNode("C", 1000))
+	rt.InsertNode(NewNode("D", 1000))
+	if err := shadow.Promote(); err != ErrShadowStale {
+		t.Errorf("expected ErrShadowStale, got %v", err)
+	}
+	checkNum(rt.Size(), 3, t)
+	if _, err := rt.Node("C"); err == nil {
+		t.Errorf("expected a stale shadow's node not to reach the live tree")
+	}
+}
+
+func TestShadowSharesKeySets(t *testing.T) {
+	rt, keys := buildTree(t, 8, []string{"A", "B"}, 2000, 200)
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatalf("expected a shadow, got error: %v", err)
+	}
+
+	// Taking the shadow copies no key set
+	a, copied := mustNode(t, rt, "A"), mustNode(t, shadow.Ring, "A")
+	for vNodeHash, keys := range a.keys {
+		if reflect.ValueOf(keys).Pointer() != reflect.ValueOf(copied.keys[vNodeHash]).Pointer() {
+			t.Fatalf("expected vnode %d's key set to be shared with the shadow", vNodeHash)
+		}
+	}
+
+	// Writes to either tree copy the set first and stay out of the other
+	if err := shadow.RemoveKey(keys[0]); err != nil {
+		t.Fatalf("expected key %s to be removed from the shadow, got error: %v", keys[0], err)
+	}
+	if err := rt.InsertKey("live-only"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := rt.Lookup(keys[0]); err != nil {
+		t.Errorf("expected key %s to stay in the live tree, got error: %v", keys[0], err)
+	}
+	if _, err := shadow.Lookup("live-only"); err == nil {
+		t.Errorf("expected a key written to the live tree not to reach the shadow")
+	}
+	checkNum(shadow.Stats().Keys(), 199, t)
+	checkNum(rt.Stats().Keys(), 201, t)
+	checkValid(rt, t)
+	checkValid(shadow.Ring, t)
+}
+
+func TestShadowPromoteFailure(t *testing.T) {
+	rt, _ := buildTree(t, 8, []string{"A", "B"}, 1000, 100)
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatalf("expected a shadow, got error: %v", err)
+	}
+	shadow.InsertNode(NewNode("C", 1000))
+	shadow.InsertKey("taken")
+	rt.InsertKey("taken")
+
+	// The node is added before the key collides, and the shadow is not marked promoted
+	if err := shadow.Promote(); !errors.Is(err, ErrKeyExists) {
+		t.Fatalf("expected the colliding key to stop the promotion, got %v", err)
+	}
+	if _, err := rt.Node("C"); err != nil {
+		t.Errorf("expected the operations before the failure to stay applied")
+	}
+	if shadow.promoted {
+		t.Errorf("expected a failed promotion not to mark the shadow promoted")
+	}
+	if err := shadow.Promote(); err != ErrShadowStale {
+		t.Errorf("expected a retry to find the live tree changed, got %v", err)
+	}
+}
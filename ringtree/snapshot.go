@@ -0,0 +1,106 @@
+package ringtree
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrUnverifiedOwner is returned by Lookup with lock-free reads, along with the key's owner from the
+// published snapshot, when the ring holding the key is being changed, so whether the owner holds the key
+// cannot be checked without waiting for the change.
+var ErrUnverifiedOwner = errors.New("owner read from a snapshot while its ring is changing, key not checked")
+
+// circleSnapshot is an immutable copy of a ring's circle and members, published after every membership
+// change so reads can route without taking the ring's lock.
+type circleSnapshot struct {
+	vNodes  []VNode
+	members map[string]Member
+}
+
+// publish replaces the ring's snapshot with its current circle and members (assuming mutex is already
+// locked).
+func (r *Ring) publish() {
+	members := make(map[string]Member, len(r.members))
+	for id, member := range r.members {
+		members[id] = member
+	}
+	snapshot := &circleSnapshot{vNodes: circleVNodes(r.circle), members: members}
+	r.snapshot.Store(snapshot)
+	r.history.record(r, snapshot)
+}
+
+// find returns the first vnode at or after keyHash, wrapping around to the first vnode.
+func (s *circleSnapshot) find(keyHash uint32) (uint32, string) {
+	if len(s.vNodes) == 0 {
+		return 0, ""
+	}
+	idx := sort.Search(len(s.vNodes), func(i int) bool {
+		return s.vNodes[i].hash >= keyHash
+	})
+	if idx == len(s.vNodes) {
+		idx = 0
+	}
+	return s.vNodes[idx].hash, s.vNodes[idx].nodeID
+}
+
+// snapshotOwner routes a key through the published snapshots without blocking. It reports busy when a
+// ring on the key's path is locked for a membership change, in which case the returned owner is the one
+// from before the change. Pinned keys are not placed by hash, so no owner is returned for them.
+func (r *Ring) snapshotOwner(key string) (string, bool) {
+	_, _, member, busy := r.snapshotRoute(key)
+	if member == nil {
+		return "", false
+	}
+	return member.ID(), busy
+}
+
+// snapshotRoute routes a key through the published snapshots without blocking, returning the ring it ends
+// on, the vnode owning it there and that vnode's member, which is nil for a pinned key or a ring without a
+// snapshot. busy reports whether a ring on the way was locked for a membership change.
+func (r *Ring) snapshotRoute(key string) (ring *Ring, vNodeHash uint32, member Member, busy bool) {
+	if _, pinned := r.pins.get(key); pinned {
+		return nil, 0, nil, false
+	}
+	for ring = r; ; {
+		if ring.TryRLock() {
+			ring.RUnlock()
+		} else {
+			busy = true
+		}
+
+		snapshot := ring.snapshot.Load()
+		if snapshot == nil {
+			return nil, 0, nil, false
+		}
+		var memberID string
+		vNodeHash, memberID = snapshot.find(ring.config.keyHash(key, ring.level))
+		member = snapshot.members[memberID]
+		subring, ok := member.(*Ring)
+		if !ok {
+			return ring, vNodeHash, member, busy
+		}
+		ring = subring
+	}
+}
+
+// lookupSnapshot answers a lookup from the published snapshots when a membership change on the key's path
+// would block it, and reports whether it answered. The owner's hold of the key is checked if the ring
+// holding it can be read without waiting; if not, the owner is returned with ErrUnverifiedOwner. Keys the
+// check does not find, or finds on a node that cannot serve them, are left to the locking path.
+func (r *Ring) lookupSnapshot(key string) (string, bool, error) {
+	ring, vNodeHash, member, busy := r.snapshotRoute(key)
+	node, ok := member.(*Node)
+	if !busy || !ok {
+		return "", false, nil
+	}
+	if !ring.TryRLock() {
+		return node.id, true, ErrUnverifiedOwner
+	}
+	_, held := node.keys[vNodeHash][key]
+	serves := node.servesReads()
+	ring.RUnlock()
+	if !held || !serves || r.config.Checksums {
+		return "", false, nil
+	}
+	return node.id, true, nil
+}
@@ -0,0 +1,104 @@
+package ringtree
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRouting(t *testing.T) {
+	rt := New(2, WithLockFreeReads(true))
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	// Snapshots follow splits into subrings
+	for _, key := range keys {
+		owner, err := rt.Lookup(key)
+		if err != nil {
+			t.Fatalf("expected key %s to be found, got error: %v", key, err)
+		}
+		if snapshotOwner, busy := rt.snapshotOwner(key); busy || snapshotOwner != owner {
+			t.Errorf("expected snapshot owner %s for key %s, got %s (busy: %v)", owner, key, snapshotOwner, busy)
+		}
+	}
+}
+
+func TestLockFreeLookup(t *testing.T) {
+	rt := New(4, WithLockFreeReads(true))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	rt.InsertKey("key")
+	owner, _ := rt.Lookup("key")
+
+	// A lookup during a membership change answers from the snapshot instead of blocking
+	rt.Lock()
+	defer rt.Unlock()
+	result := make(chan string, 1)
+	go func() {
+		id, _ := rt.Lookup("key")
+		result <- id
+	}()
+	select {
+	case id := <-result:
+		if id != owner {
+			t.Errorf("expected snapshot owner %s, got %s", owner, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected lookup not to block on the ring lock")
+	}
+}
+
+func TestLockFreeLookupChecksKey(t *testing.T) {
+	rt := New(2, WithLockFreeReads(true))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	rt.InsertKey("key")
+	owner, _ := rt.Lookup("key")
+
+	// The owner's own ring is changing, so a missing key cannot be told apart from a held one
+	rt.Lock()
+	result := make(chan error, 1)
+	go func() {
+		_, err := rt.Lookup("missing")
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrUnverifiedOwner) {
+			t.Errorf("expected ErrUnverifiedOwner for a missing key, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected lookup not to block on the ring lock")
+	}
+	rt.Unlock()
+
+	// With only a ring above the owner's changing, the key is checked on its node
+	if _, err := rt.Split(owner); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := rt.Lookup("key")
+	rt.Lock()
+	defer rt.Unlock()
+	go func() {
+		id, err := rt.Lookup("key")
+		if id != want {
+			err = errors.New("got owner " + id + ", want " + want)
+		}
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("expected the key to be found on its node, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected lookup not to block on the ring lock")
+	}
+}
@@ -0,0 +1,108 @@
+package ringtree
+
+import "errors"
+
+// ErrMaxDepth is returned when a split would nest a subring below the configured max depth.
+var ErrMaxDepth = errors.New("subring would exceed the max depth")
+
+// Split replaces a physical node anywhere in the tree with a subring and moves its keys into it, regardless
+// of the node's load. The subring takes the node's ID.
+func (r *Ring) Split(nodeID string) (*Ring, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.splitOp(nodeID)
+}
+
+// splitOp splits a node as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) splitOp(nodeID string) (*Ring, error) {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return nil, ErrNodeNotFound
+	}
+	if r.config.MaxDepth > 0 && ring.level >= r.config.MaxDepth {
+		return nil, ErrMaxDepth
+	}
+	r.beginOp()
+	subring, err := ring.splitNode(node, 0)
+	return subring, r.logOp(Op{Type: OpSplit, Node: nodeID}, err)
+}
+
+// Collapse replaces a subring anywhere in the tree, with any nested subrings, by a single node with the
+// subring's ID and reinserts its keys into the parent ring. The node gets the largest threshold of the
+// nodes it replaces.
+func (r *Ring) Collapse(subringID string) (*Node, error) {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.collapseOp(subringID)
+}
+
+// collapseOp collapses a subring as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) collapseOp(subringID string) (*Node, error) {
+	member, _ := r.findTarget(subringID)
+	subring, ok := member.(*Ring)
+	if !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	threshold, pinned := 1, false
+	subring.RLock()
+	subring.forEachNode(func(node *Node) {
+		if node.threshold > threshold {
+			threshold = node.threshold
+		}
+		pinned = pinned || r.pins.targeted(node.id)
+	})
+	subring.RUnlock()
+	if pinned {
+		return nil, ErrNodePinned
+	}
+	r.beginOp()
+	node, err := subring.collapseRing(threshold)
+	return node, r.logOp(Op{Type: OpCollapse, Node: subringID}, err)
+}
+
+// WithAutoSplit makes InsertNode on a full ring split the ring's most loaded member into a subring and place
+// the new node there, so scaling out stays a single call.
+func WithAutoSplit(enabled bool) Option {
+	return func(c *Config) {
+		c.AutoSplit = enabled
+	}
+}
+
+// insertNodeSplitting places a node below a full ring: in its most loaded subring, or in a subring replacing
+// its most loaded node, seeded with one fresh node beside the new one (assuming the tree's writer lock is
+// held).
+func (r *Ring) insertNodeSplitting(node *Node) error {
+	var heaviest Member
+	load := -1
+	r.RLock()
+	for _, member := range r.members {
+		if member.Kind() == KindCustom {
+			continue
+		}
+		if stats := member.MemberStats(); stats.Load > load {
+			heaviest, load = member, stats.Load
+		}
+	}
+	r.RUnlock()
+
+	switch heaviest := heaviest.(type) {
+	case *Ring:
+		err := heaviest.insertNode(node)
+		if err == ErrRingAtCapacity {
+			return heaviest.insertNodeSplitting(node)
+		}
+		return err
+	case *Node:
+		if r.config.MaxDepth > 0 && r.level >= r.config.MaxDepth {
+			return ErrRingAtCapacity
+		}
+		r.logf("Splitting node %s to make room for node %s.\n", heaviest.id, node.id)
+		_, err := r.replaceNode(heaviest, func(subring *Ring) (int, int) {
+			return 1, subring.config.childThreshold(subring.level, heaviest.threshold)
+		}, node)
+		return err
+	default:
+		return ErrRingAtCapacity
+	}
+}
@@ -0,0 +1,117 @@
+package ringtree
+
+import "testing"
+
+func TestSplitAndCollapse(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+	lookupAll := func() {
+		t.Helper()
+		for _, key := range keys {
+			if _, err := rt.Lookup(key); err != nil {
+				t.Fatalf("expected key %s to be found, got error: %v", key, err)
+			}
+		}
+		checkNum(rt.Stats().Keys(), len(keys), t)
+		checkNum(rt.MemberStats().Keys, len(keys), t)
+	}
+
+	// Split A, then split one of its children, nesting two levels deep
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+	if _, ok := rt.members["A"].(*Ring); !ok || subring.id != "A" {
+		t.Fatalf("expected subring A to replace node A")
+	}
+	var child string
+	for id := range subring.members {
+		child = id
+	}
+	if _, err := rt.Split(child); err != nil {
+		t.Fatalf("expected nested node to be split, got error: %v", err)
+	}
+	checkNum(rt.GetDepth(), 2, t)
+	lookupAll()
+
+	// Collapsing A folds the nested subring back in as well
+	node, err := rt.Collapse("A")
+	if err != nil {
+		t.Fatalf("expected subring to be collapsed, got error: %v", err)
+	}
+	if node.id != "A" || rt.members["A"] != node {
+		t.Fatalf("expected node A to replace subring A")
+	}
+	checkNum(rt.GetDepth(), 0, t)
+	checkNum(rt.Stats().Nodes(), 2, t)
+	lookupAll()
+}
+
+func TestSplitAndCollapseErrors(t *testing.T) {
+	rt := New(2, WithMaxDepth(1))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+
+	if _, err := rt.Split("missing"); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+	if _, err := rt.Collapse("A"); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound for a node, got %v", err)
+	}
+
+	subring, err := rt.Split("A")
+	if err != nil {
+		t.Fatalf("expected node to be split, got error: %v", err)
+	}
+	for id := range subring.members {
+		if _, err := rt.Split(id); err != ErrMaxDepth {
+			t.Errorf("expected ErrMaxDepth, got %v", err)
+		}
+	}
+}
+
+func TestAutoSplitOnJoin(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	if err := rt.InsertNode(NewNode("C", 100)); err != ErrRingAtCapacity {
+		t.Fatalf("expected ErrRingAtCapacity without auto-split, got %v", err)
+	}
+
+	rt = New(2, WithAutoSplit(true))
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	var keys []string
+	for i := 0; i < 100; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		rt.InsertKey(key)
+	}
+
+	// Every join past capacity lands in a subring and leaves all keys reachable
+	for _, id := range []string{"C", "D", "E", "F", "G"} {
+		if err := rt.InsertNode(NewNode(id, 100)); err != nil {
+			t.Fatalf("expected node %s to be inserted, got error: %v", id, err)
+		}
+		if node, ring := rt.findMember(id); node == nil || ring == rt {
+			t.Fatalf("expected node %s to be placed in a subring", id)
+		}
+		checkNum(len(rt.LookupMany(keys)), len(keys), t)
+	}
+	checkNum(rt.Stats().Keys(), len(keys), t)
+	checkNum(rt.MemberStats().Nodes, rt.Stats().Nodes(), t)
+
+	if err := rt.InsertNode(NewNode("C", 100)); err != ErrNodeExists {
+		t.Errorf("expected ErrNodeExists for a node already in a subring, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package ringtree
+
+import (
+	"math"
+)
+
+// maxSpreadAdjustments bounds the number of vnodes added or removed when enforcing a node's arc share.
+const maxSpreadAdjustments = 64
+
+// circleVNodes returns the vnodes of a circle in hash order (an array circle must already be sorted).
+func circleVNodes(c Circle) []VNode {
+	var vNodes []VNode
+	switch circle := c.(type) {
+	case *RBTreeCircle:
+		circle.TraverseWhile(func(n *redBlackNode) bool {
+			vNodes = append(vNodes, VNode{hash: n.key, nodeID: n.value})
+			return true
+		})
+	case *ArrayCircle:
+		vNodes = append(vNodes, circle.vNodes...)
+	case *AdaptiveCircle:
+		vNodes = circleVNodes(circle.Circle)
+	}
+	return vNodes
+}
+
+// arcShares returns, for each vnode owned by nodeID, the length of the arc it owns, and the node's total
+// share of the circle as a fraction (assuming mutex is already locked).
+func (r *Ring) arcShares(nodeID string) (map[uint32]uint64, float64) {
+	vNodes := circleVNodes(r.circle)
+	arcs := make(map[uint32]uint64)
+	var total uint64
+	for i, vNode := range vNodes {
+		if vNode.nodeID != nodeID {
+			continue
+		}
+		prev := vNodes[(i+len(vNodes)-1)%len(vNodes)].hash
+		arc := uint64(vNode.hash - prev) // Wraps around the top of the hash space
+		if len(vNodes) == 1 {
+			arc = math.MaxUint32 + 1
+		}
+		arcs[vNode.hash] = arc
+		total += arc
+	}
+	return arcs, float64(total) / (math.MaxUint32 + 1)
+}
+
+// ArcShare returns the fraction of the ring's hash space owned by a member.
+func (r *Ring) ArcShare(memberID string) float64 {
+	r.RLock()
+	defer r.RUnlock()
+	_, share := r.arcShares(memberID)
+	return share
+}
+
+// OwnedRanges returns the hash ranges owned by a member as (start, end] pairs, one per vnode in hash order.
+// A range whose start is not below its end wraps around the top of the hash space.
+func (r *Ring) OwnedRanges(memberID string) [][2]uint32 {
+	r.RLock()
+	defer r.RUnlock()
+	var ranges [][2]uint32
+	for _, vNode := range circleVNodes(r.circle) {
+		if vNode.nodeID == memberID {
+			prev, _ := r.circle.FindPrevClosest(vNode.hash)
+			ranges = append(ranges, [2]uint32{prev, vNode.hash})
+		}
+	}
+	return ranges
+}
+
+// enforceSpread adds or removes salted vnodes until the node's arc share is within the configured
+// tolerance of an even share of the ring (assuming mutex is already locked).
+func (r *Ring) enforceSpread(node *Node) error {
+	tolerance := r.config.SpreadTolerance
+	if tolerance <= 0 || len(r.members) < 2 || node.warming() {
+		return nil // A warming node's share is kept low on purpose
+	}
+	expected := 1 / float64(len(r.members))
+	low, high := expected*(1-tolerance), expected*(1+tolerance)
+
+	next := r.config.Replicas // Index used to salt the next extra vnode
+	for i := 0; i < maxSpreadAdjustments; i++ {
+		arcs, share := r.arcShares(node.id)
+		switch {
+		case share < low:
+			// Add a salted vnode to claim more of the ring
+			vNodeHash := hash(node.id, next)
+			next++
+			if _, exists := node.keys[vNodeHash]; exists || !r.circle.Insert(vNodeHash, node.id) {
+				continue
+			}
+			r.circle.Sort()
+			node.keys[vNodeHash] = make(keySet)
+			if err := r.remapKeys(node, vNodeHash); err != nil {
+				return err
+			}
+		case share > high && len(arcs) > 1:
+			// Drop the vnode owning the largest arc
+			var largest uint32
+			var largestArc uint64
+			for vNodeHash, arc := range arcs {
+				if arc > largestArc || (arc == largestArc && vNodeHash < largest) {
+					largest, largestArc = vNodeHash, arc
+				}
+			}
+			if !r.removeVNode(node, largest) {
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+	r.logf("Node %s arc share still outside tolerance after %d adjustments.\n", node.id, maxSpreadAdjustments)
+	return nil
+}
+
+// removeVNode removes one vnode of a node, moving its keys to the next vnode. Vnodes followed by a subring
+// are kept (assuming mutex is already locked).
+func (r *Ring) removeVNode(node *Node, vNodeHash uint32) bool {
+	nextVNodeHash, nextID := r.circle.FindNextClosest(vNodeHash)
+	next, ok := r.members[nextID].(*Node)
+	if !ok {
+		return false
+	}
+	for key, keyHash := range node.keys[vNodeHash] {
+		if !r.verifyKey(node, key, keyHash) {
+			return false // Keep a vnode holding a corrupted key
+		}
+	}
+	for key, keyHash := range node.keys[vNodeHash] {
+		if !r.pinAllows(key, r, next) {
+			// Keep keys pinned to the node on one of its other vnodes
+			for other := range node.keys {
+				if other != vNodeHash {
+					r.moveKey(key, keyHash, node, vNodeHash, node, other)
+					break
+				}
+			}
+			continue
+		}
+		r.moveKey(key, keyHash, node, vNodeHash, next, nextVNodeHash)
+	}
+	delete(node.keys, vNodeHash)
+	r.circle.Delete(vNodeHash)
+	r.circle.Sort()
+	return true
+}
@@ -0,0 +1,65 @@
+package ringtree
+
+import "testing"
+
+func TestSpreadTolerance(t *testing.T) {
+	tolerance := 0.2
+	rt := New(10, WithSpreadTolerance(tolerance))
+	var nodes []*Node
+	for i := 0; i < 5; i++ {
+		node := NewNode("", 1000)
+		nodes = append(nodes, node)
+		rt.InsertNode(node)
+	}
+
+	// The last node to join is brought within tolerance of an even share
+	last := nodes[len(nodes)-1]
+	share := rt.ArcShare(last.id)
+	expected := 1 / float64(len(nodes))
+	if share < expected*(1-tolerance) || share > expected*(1+tolerance) {
+		t.Errorf("expected arc share within %.2f of %.3f, got %.3f", tolerance, expected, share)
+	}
+	checkNum(rt.circle.Size(), func() int {
+		total := 0
+		for _, node := range nodes {
+			total += len(node.keys)
+		}
+		return total
+	}(), t)
+
+	total := 0.0
+	for _, node := range nodes {
+		total += rt.ArcShare(node.id)
+	}
+	if total < 0.999 || total > 1.001 {
+		t.Errorf("expected arc shares to sum to 1, got %.4f", total)
+	}
+}
+
+func TestOwnedRanges(t *testing.T) {
+	rt := New(4)
+	rt.InsertNode(NewNode("A", 100))
+	rt.InsertNode(NewNode("B", 100))
+	for i := 0; i < 200; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	// Every key stored on A falls inside one of A's ranges
+	ranges := rt.OwnedRanges("A")
+	checkNum(len(ranges), rt.config.Replicas, t)
+	node := rt.members["A"].(*Node)
+	for vNodeHash, keys := range node.keys {
+		for key, keyHash := range keys {
+			owned := false
+			for _, arc := range ranges {
+				if arc[1] == vNodeHash && inArc(keyHash, arc[0], arc[1]) {
+					owned = true
+				}
+			}
+			if !owned {
+				t.Errorf("expected key %s to fall in the range of vnode %d", key, vNodeHash)
+			}
+		}
+	}
+}
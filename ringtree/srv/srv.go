@@ -0,0 +1,200 @@
+// Package srv keeps the nodes of a ring tree in sync with the targets of a DNS SRV record, refreshed
+// periodically. Each target is a node named host:port. New targets are inserted, targets that disappear are
+// drained, and drained nodes are removed once they stay missing for a grace period.
+package srv
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+	"github.com/kagwave/ring-tree/ringtree/membership"
+)
+
+// Resolver looks up SRV records. *net.Resolver implements it.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Diff is the change one refresh made to the ring's nodes.
+type Diff struct {
+	Added    []string // New targets inserted as nodes
+	Drained  []string // Missing targets whose nodes were drained
+	Restored []string // Drained targets back in the record, set Up again
+	Removed  []string // Targets missing for the grace period, removed from the ring
+}
+
+// Empty reports whether the refresh changed nothing.
+func (d Diff) Empty() bool {
+	return len(d.Added)+len(d.Drained)+len(d.Restored)+len(d.Removed) == 0
+}
+
+// config holds the settings of a Refresher.
+type config struct {
+	resolver    Resolver
+	interval    time.Duration
+	jitter      float64
+	removeAfter time.Duration
+	membership  []membership.Option
+	hooks       []func(Diff)
+}
+
+// Option configures a Refresher.
+type Option func(*config)
+
+// WithResolver looks records up with resolver instead of net.DefaultResolver.
+func WithResolver(resolver Resolver) Option {
+	return func(c *config) {
+		c.resolver = resolver
+	}
+}
+
+// WithInterval refreshes every d on average. Defaults to 30 seconds.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithJitter spreads refreshes by up to fraction of the interval either way, so routers started together
+// do not query DNS in lockstep. Defaults to 0.1.
+func WithJitter(fraction float64) Option {
+	return func(c *config) {
+		if fraction >= 0 && fraction < 1 {
+			c.jitter = fraction
+		}
+	}
+}
+
+// WithRemoveAfter removes a drained node once its target has been missing for d. Zero, the default, keeps
+// drained nodes until their target returns or they are removed by hand.
+func WithRemoveAfter(d time.Duration) Option {
+	return func(c *config) {
+		c.removeAfter = d
+	}
+}
+
+// WithMembership configures how targets become nodes, such as the threshold of each node.
+func WithMembership(opts ...membership.Option) Option {
+	return func(c *config) {
+		c.membership = append(c.membership, opts...)
+	}
+}
+
+// OnDiff calls fn after every refresh that changed the ring's nodes.
+func OnDiff(fn func(Diff)) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, fn)
+	}
+}
+
+// Refresher applies the targets of an SRV record to a ring tree.
+type Refresher struct {
+	name    string
+	config  config
+	sync    *membership.Sync
+	missing map[string]time.Time // Drained targets, by the time they went missing
+	mu      sync.Mutex
+}
+
+// New returns a refresher maintaining the nodes of ring from the SRV record name, such as
+// "_ringtree._tcp.example.com".
+func New(ring *ringtree.Ring, name string, opts ...Option) *Refresher {
+	r := &Refresher{
+		name:    name,
+		config:  config{resolver: net.DefaultResolver, interval: 30 * time.Second, jitter: 0.1},
+		missing: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(&r.config)
+	}
+	r.sync = membership.New(ring, r.config.membership...)
+	return r
+}
+
+// Run refreshes until ctx is done. A failed lookup leaves the nodes as they are until the next refresh.
+func (r *Refresher) Run(ctx context.Context) error {
+	for {
+		r.Refresh(ctx)
+		timer := time.NewTimer(r.next())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Refresh looks the record up once and brings the ring's nodes in line with its targets.
+func (r *Refresher) Refresh(ctx context.Context) (Diff, error) {
+	_, records, err := r.config.resolver.LookupSRV(ctx, "", "", r.name)
+	if err != nil {
+		return Diff{}, err
+	}
+	targets := make(map[string]bool, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		targets[net.JoinHostPort(host, strconv.Itoa(int(record.Port)))] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var diff Diff
+	now := time.Now()
+	known := make(map[string]bool)
+	for _, peer := range r.sync.Peers() {
+		known[peer] = true
+	}
+	for target := range targets {
+		switch _, drained := r.missing[target]; {
+		case drained:
+			r.sync.SetState(target, ringtree.Up)
+			delete(r.missing, target)
+			diff.Restored = append(diff.Restored, target)
+		case !known[target]:
+			r.sync.JoinMeta(target, membership.Meta{})
+			diff.Added = append(diff.Added, target)
+		}
+	}
+	for peer := range known {
+		if targets[peer] {
+			continue
+		}
+		since, drained := r.missing[peer]
+		switch {
+		case !drained:
+			r.sync.SetState(peer, ringtree.Draining)
+			r.missing[peer] = now
+			diff.Drained = append(diff.Drained, peer)
+		case r.config.removeAfter > 0 && now.Sub(since) >= r.config.removeAfter:
+			r.sync.Leave(peer)
+			delete(r.missing, peer)
+			diff.Removed = append(diff.Removed, peer)
+		}
+	}
+
+	for _, list := range [][]string{diff.Added, diff.Drained, diff.Restored, diff.Removed} {
+		sort.Strings(list)
+	}
+	if !diff.Empty() {
+		for _, hook := range r.config.hooks {
+			hook(diff)
+		}
+	}
+	return diff, nil
+}
+
+// next returns the delay until the next refresh, with jitter applied.
+func (r *Refresher) next() time.Duration {
+	spread := float64(r.config.interval) * r.config.jitter
+	return r.config.interval + time.Duration((rand.Float64()*2-1)*spread)
+}
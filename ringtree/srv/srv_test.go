@@ -0,0 +1,65 @@
+package srv
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// records is a Resolver serving a fixed set of SRV targets.
+type records []*net.SRV
+
+func (r *records) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return name, *r, nil
+}
+
+func TestRefresh(t *testing.T) {
+	ring := ringtree.New(8)
+	resolver := &records{{Target: "a.example.com.", Port: 7000}, {Target: "b.example.com.", Port: 7000}}
+	var diffs []Diff
+	r := New(ring, "_ringtree._tcp.example.com", WithResolver(resolver), WithRemoveAfter(time.Nanosecond), OnDiff(func(d Diff) {
+		diffs = append(diffs, d)
+	}))
+	ctx := context.Background()
+
+	diff, err := r.Refresh(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.example.com:7000", "b.example.com:7000"}; !reflect.DeepEqual(diff.Added, want) {
+		t.Fatalf("got added %v, want %v", diff.Added, want)
+	}
+
+	*resolver = (*resolver)[:1]
+	if diff, _ := r.Refresh(ctx); !reflect.DeepEqual(diff.Drained, []string{"b.example.com:7000"}) {
+		t.Errorf("got drained %v, want b.example.com:7000", diff.Drained)
+	}
+	member, _ := ring.Member("b.example.com:7000")
+	if member.(*ringtree.Node).State() != ringtree.Draining {
+		t.Errorf("missing target: got state %v, want Draining", member.(*ringtree.Node).State())
+	}
+
+	*resolver = append(*resolver, &net.SRV{Target: "b.example.com.", Port: 7000})
+	if diff, _ := r.Refresh(ctx); !reflect.DeepEqual(diff.Restored, []string{"b.example.com:7000"}) {
+		t.Errorf("got restored %v, want b.example.com:7000", diff.Restored)
+	}
+	if diff, _ := r.Refresh(ctx); !diff.Empty() {
+		t.Errorf("got %+v from an unchanged record, want no change", diff)
+	}
+
+	*resolver = (*resolver)[:1]
+	r.Refresh(ctx)
+	if diff, _ := r.Refresh(ctx); !reflect.DeepEqual(diff.Removed, []string{"b.example.com:7000"}) {
+		t.Errorf("got removed %v, want b.example.com:7000 after the grace period", diff.Removed)
+	}
+	if _, ok := ring.Member("b.example.com:7000"); ok {
+		t.Error("removed target still in the ring")
+	}
+	if len(diffs) != 5 {
+		t.Errorf("hook saw %d diffs, want 5", len(diffs))
+	}
+}
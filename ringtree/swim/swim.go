@@ -0,0 +1,272 @@
+// Package swim detects failed ring nodes with the probing scheme of the SWIM protocol. Every protocol period
+// one node is probed directly; if it does not answer within the probe timeout, a few other nodes are asked
+// to probe it on the detector's behalf. A node no probe reaches is marked Suspect, so new writes avoid it,
+// and Down once it stays unreachable for the suspicion timeout. A node that answers again is set back Up.
+//
+// Only nodes that are Up, or that the detector itself marked, are ever changed, so nodes drained by an
+// operator or a maintenance window keep their state.
+package swim
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// Prober reaches ring nodes. The detector only needs a probe to return nil once the node acknowledged it.
+type Prober interface {
+	// Ping probes a node directly.
+	Ping(ctx context.Context, nodeID string) error
+	// PingReq asks relay to probe target and reports whether target acknowledged.
+	PingReq(ctx context.Context, relay, target string) error
+}
+
+// config holds the settings of a Detector.
+type config struct {
+	self           string
+	interval       time.Duration
+	timeout        time.Duration
+	indirect       int
+	suspectTimeout time.Duration
+	onChange       func(nodeID string, state ringtree.NodeState)
+}
+
+// Option configures a Detector.
+type Option func(*config)
+
+// WithSelf names the node the detector runs on, which it never probes.
+func WithSelf(nodeID string) Option {
+	return func(c *config) {
+		c.self = nodeID
+	}
+}
+
+// WithInterval sets the protocol period, in which one node is probed. Defaults to one second.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.interval = d
+		}
+	}
+}
+
+// WithProbeTimeout bounds the direct probe, and separately the indirect probes, of a node. Defaults to
+// 500ms.
+func WithProbeTimeout(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.timeout = d
+		}
+	}
+}
+
+// WithIndirectProbes sets how many other nodes are asked to probe a node that missed its direct probe.
+// Defaults to 3.
+func WithIndirectProbes(k int) Option {
+	return func(c *config) {
+		if k >= 0 {
+			c.indirect = k
+		}
+	}
+}
+
+// WithSuspectTimeout sets how long a node stays Suspect before it is confirmed Down. Defaults to five
+// seconds.
+func WithSuspectTimeout(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.suspectTimeout = d
+		}
+	}
+}
+
+// OnChange calls fn whenever the detector changes the state of a node.
+func OnChange(fn func(nodeID string, state ringtree.NodeState)) Option {
+	return func(c *config) {
+		c.onChange = fn
+	}
+}
+
+// Detector probes the nodes of a ring tree and feeds their health into its routing.
+type Detector struct {
+	ring   *ringtree.Ring
+	prober Prober
+	config config
+	queue  []string             // Nodes left to probe in the current round, in random order
+	marked map[string]time.Time // Nodes the detector marked Suspect or Down, by when they were suspected
+	rand   *rand.Rand
+	mu     sync.Mutex
+}
+
+// New returns a detector probing the nodes of ring through prober.
+func New(ring *ringtree.Ring, prober Prober, opts ...Option) *Detector {
+	d := &Detector{
+		ring:   ring,
+		prober: prober,
+		config: config{interval: time.Second, timeout: 500 * time.Millisecond, indirect: 3, suspectTimeout: 5 * time.Second},
+		marked: make(map[string]time.Time),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(&d.config)
+	}
+	return d
+}
+
+// Run runs a protocol period every interval until ctx is done.
+func (d *Detector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.Tick(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Tick runs one protocol period: it probes the next node of the round and confirms suspicions that timed
+// out. Every node is probed once per round, in an order shuffled each round.
+func (d *Detector) Tick(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(time.Now())
+
+	target, ok := d.next()
+	if !ok {
+		return
+	}
+	if d.probe(ctx, target) {
+		if _, suspected := d.marked[target]; suspected {
+			delete(d.marked, target)
+			d.set(target, ringtree.Up)
+		}
+		return
+	}
+	if _, suspected := d.marked[target]; suspected {
+		return
+	}
+	if node, err := d.ring.Node(target); err != nil || node.State() != ringtree.Up {
+		return
+	}
+	d.marked[target] = time.Now()
+	d.set(target, ringtree.Suspect)
+}
+
+// Suspects returns, in sorted order, the nodes the detector has marked Suspect or Down.
+func (d *Detector) Suspects() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]string, 0, len(d.marked))
+	for id := range d.marked {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// next returns the node to probe, starting a new round when the last one is done.
+func (d *Detector) next() (string, bool) {
+	for {
+		if len(d.queue) == 0 {
+			d.queue = d.peers()
+			if len(d.queue) == 0 {
+				return "", false
+			}
+			d.rand.Shuffle(len(d.queue), func(i, j int) {
+				d.queue[i], d.queue[j] = d.queue[j], d.queue[i]
+			})
+		}
+		target := d.queue[0]
+		d.queue = d.queue[1:]
+		if _, err := d.ring.Node(target); err == nil {
+			return target, true
+		}
+	}
+}
+
+// probe pings a node directly, then through up to k relays, and reports whether it acknowledged.
+func (d *Detector) probe(ctx context.Context, target string) bool {
+	direct, cancel := context.WithTimeout(ctx, d.config.timeout)
+	err := d.prober.Ping(direct, target)
+	cancel()
+	if err == nil {
+		return true
+	}
+
+	var relays []string
+	for _, id := range d.peers() {
+		if _, suspected := d.marked[id]; id != target && !suspected {
+			relays = append(relays, id)
+		}
+	}
+	d.rand.Shuffle(len(relays), func(i, j int) {
+		relays[i], relays[j] = relays[j], relays[i]
+	})
+	if len(relays) > d.config.indirect {
+		relays = relays[:d.config.indirect]
+	}
+	if len(relays) == 0 {
+		return false
+	}
+
+	indirect, cancel := context.WithTimeout(ctx, d.config.timeout)
+	defer cancel()
+	acks := make(chan error, len(relays))
+	for _, relay := range relays {
+		go func(relay string) {
+			acks <- d.prober.PingReq(indirect, relay, target)
+		}(relay)
+	}
+	for range relays {
+		if <-acks == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// expire confirms as Down the suspects whose suspicion timed out.
+func (d *Detector) expire(now time.Time) {
+	for id, since := range d.marked {
+		if now.Sub(since) < d.config.suspectTimeout {
+			continue
+		}
+		node, err := d.ring.Node(id)
+		if errors.Is(err, ringtree.ErrNodeNotFound) {
+			delete(d.marked, id)
+			continue
+		}
+		if err == nil && node.State() == ringtree.Suspect {
+			d.set(id, ringtree.Down)
+		}
+	}
+}
+
+// peers returns the nodes to probe: every node of the tree but the detector's own.
+func (d *Detector) peers() []string {
+	var ids []string
+	for _, id := range d.ring.NodeIDs() {
+		if id != d.config.self {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// set changes the state of a node and reports it.
+func (d *Detector) set(nodeID string, state ringtree.NodeState) {
+	if err := d.ring.SetNodeState(nodeID, state); err != nil {
+		return
+	}
+	if d.config.onChange != nil {
+		d.config.onChange(nodeID, state)
+	}
+}
@@ -0,0 +1,100 @@
+package swim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// network is a Prober over nodes that are either reachable or not, with some links cut.
+type network struct {
+	down map[string]bool
+	cut  map[string]bool // Targets the detector cannot reach directly
+	sync.Mutex
+}
+
+var errTimeout = errors.New("probe timed out")
+
+func (n *network) Ping(ctx context.Context, nodeID string) error {
+	n.Lock()
+	defer n.Unlock()
+	if n.down[nodeID] || n.cut[nodeID] {
+		return errTimeout
+	}
+	return nil
+}
+
+func (n *network) PingReq(ctx context.Context, relay, target string) error {
+	n.Lock()
+	defer n.Unlock()
+	if n.down[relay] || n.down[target] {
+		return errTimeout
+	}
+	return nil
+}
+
+func (n *network) set(nodeID string, down bool) {
+	n.Lock()
+	defer n.Unlock()
+	n.down[nodeID] = down
+}
+
+func TestDetector(t *testing.T) {
+	ring := ringtree.New(8)
+	for _, id := range []string{"self", "a", "b", "c"} {
+		ring.InsertNode(ringtree.NewNode(id, 100))
+	}
+	net := &network{down: map[string]bool{}, cut: map[string]bool{"c": true}}
+	d := New(ring, net, WithSelf("self"), WithSuspectTimeout(50*time.Millisecond))
+	ctx := context.Background()
+	state := func(id string) ringtree.NodeState {
+		node, err := ring.Node(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node.State()
+	}
+	// Any six periods cover a whole round of the three peers
+	round := func() {
+		for i := 0; i < 6; i++ {
+			d.Tick(ctx)
+		}
+	}
+
+	round()
+	if state("c") != ringtree.Up {
+		t.Errorf("node c reachable through relays: got state %v, want Up", state("c"))
+	}
+
+	net.set("b", true)
+	round()
+	if state("b") != ringtree.Suspect {
+		t.Fatalf("unreachable node b: got state %v, want Suspect", state("b"))
+	}
+	time.Sleep(60 * time.Millisecond)
+	d.Tick(ctx)
+	if state("b") != ringtree.Down {
+		t.Errorf("node b past the suspicion timeout: got state %v, want Down", state("b"))
+	}
+	if got := d.Suspects(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("got suspects %v, want [b]", got)
+	}
+
+	net.set("b", false)
+	round()
+	if state("b") != ringtree.Up || len(d.Suspects()) != 0 {
+		t.Errorf("node b answering again: got state %v and suspects %v, want Up and none", state("b"), d.Suspects())
+	}
+
+	ring.SetNodeState("a", ringtree.Draining)
+	net.set("a", true)
+	round()
+	if state("a") != ringtree.Draining {
+		t.Errorf("drained node a: got state %v, want it left Draining", state("a"))
+	}
+}
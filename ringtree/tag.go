@@ -0,0 +1,30 @@
+package ringtree
+
+import "strings"
+
+// KeyExtractor returns the part of a key that is hashed to place it. Keys with the same extracted part land
+// on the same node at every level.
+type KeyExtractor func(key string) string
+
+// HashTag extracts a Redis Cluster style hash tag: the text between the first '{' and the next '}', so
+// "user:{42}:profile" and "user:{42}:cart" both hash on "42". Keys without a non-empty tag are hashed whole.
+func HashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// WithKeyExtractor hashes the part of each key returned by fn instead of the whole key, for example
+// WithKeyExtractor(HashTag) to co-locate keys sharing a hash tag. Keys sharing a tag cannot be spread by a
+// split, so a tag heavier than a node's threshold should be bounded with WithMaxDepth.
+func WithKeyExtractor(fn KeyExtractor) Option {
+	return func(c *Config) {
+		c.KeyExtractor = fn
+	}
+}
@@ -0,0 +1,54 @@
+package ringtree
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashTag(t *testing.T) {
+	cases := map[string]string{
+		"user:{42}:profile": "42",
+		"{42}":              "42",
+		"user:{}:profile":   "user:{}:profile",
+		"user:{42:profile":  "user:{42:profile",
+		"user:42:profile":   "user:42:profile",
+		"a{b}{c}":           "b",
+		"a}{b}":             "b",
+	}
+	for key, tag := range cases {
+		if got := HashTag(key); got != tag {
+			t.Errorf("expected tag %q for key %q, got %q", tag, key, got)
+		}
+	}
+}
+
+func TestHashTagColocation(t *testing.T) {
+	rt := New(2, WithKeyExtractor(HashTag))
+	rt.InsertNode(NewNode("", 10))
+	rt.InsertNode(NewNode("", 10))
+
+	// Enough users to split nodes, so co-location must hold on every level
+	for i := 0; i < 100; i++ {
+		for _, field := range []string{"profile", "cart"} {
+			key := "user:{" + strconv.Itoa(i) + "}:" + field
+			if err := rt.InsertKey(key); err != nil {
+				t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+			}
+		}
+	}
+	if rt.GetDepth() < 1 {
+		t.Fatalf("expected the tree to have split")
+	}
+
+	for i := 0; i < 100; i++ {
+		user := "user:{" + strconv.Itoa(i) + "}:"
+		profile, err := rt.Lookup(user + "profile")
+		if err != nil {
+			t.Fatalf("expected key to be found, got error: %v", err)
+		}
+		cart, _ := rt.Lookup(user + "cart")
+		if profile != cart {
+			t.Errorf("expected keys of user %d on the same node, got %s and %s", i, profile, cart)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package ringtree
+
+// Attribute is a key-value pair recorded on a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is an operation being traced.
+type Span interface {
+	// SetAttributes records attributes on the span.
+	SetAttributes(attrs ...Attribute)
+	// End finishes the span, recording the operation's error if it failed.
+	End(err error)
+}
+
+// Tracer starts spans for ring operations. It has the shape of an OpenTelemetry tracer, which an adapter
+// wraps as follows:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//	type otelSpan struct {
+//		ctx  context.Context
+//		span trace.Span
+//	}
+//
+//	func (t otelTracer) Start(parent ringtree.Span, name string, attrs ...ringtree.Attribute) ringtree.Span {
+//		ctx := context.Background()
+//		if p, ok := parent.(otelSpan); ok {
+//			ctx = p.ctx
+//		}
+//		ctx, span := t.tracer.Start(ctx, name)
+//		s := otelSpan{ctx, span}
+//		s.SetAttributes(attrs...)
+//		return s
+//	}
+//
+//	func (s otelSpan) SetAttributes(attrs ...ringtree.Attribute) {
+//		for _, a := range attrs {
+//			s.span.SetAttributes(attribute.String(a.Key, fmt.Sprint(a.Value)))
+//		}
+//	}
+//
+//	func (s otelSpan) End(err error) {
+//		if err != nil {
+//			s.span.RecordError(err)
+//			s.span.SetStatus(codes.Error, err.Error())
+//		}
+//		s.span.End()
+//	}
+type Tracer interface {
+	// Start starts a span, as a child of parent unless parent is nil.
+	Start(parent Span, name string, attrs ...Attribute) Span
+}
+
+// Span attribute keys.
+const (
+	AttrRingID   = "ringtree.ring.id"
+	AttrLevel    = "ringtree.level"
+	AttrKey      = "ringtree.key"
+	AttrNodeID   = "ringtree.node.id"
+	AttrRemapped = "ringtree.keys.remapped"
+)
+
+// traceState holds the span of the mutation in progress, which spans of nested operations such as splits
+// are children of (guarded by the tree's writer lock).
+type traceState struct {
+	current Span
+}
+
+// WithTracer traces InsertKey, RemoveKey, Lookup, InsertNode, and every split and collapse, recording the
+// ring ID, level and keys remapped on each span. Splits and collapses triggered by another operation are
+// traced as its children.
+func WithTracer(t Tracer) Option {
+	return func(c *Config) {
+		c.Tracer = t
+	}
+}
+
+// opSpan is the span of a traced operation. A nil opSpan traces nothing.
+type opSpan struct {
+	ring     *Ring
+	span     Span
+	parent   Span // Span the operation is nested in, restored as the current span when it ends
+	remapped int  // Keys remapped by the tree before the operation started
+	moved    int  // Keys moved by a split or collapse, which Stats does not count as remaps
+}
+
+// traceOp starts a span for a mutation on the ring, nested in the mutation in progress (assuming the tree's
+// writer lock is held).
+func (r *Ring) traceOp(name string, attrs ...Attribute) *opSpan {
+	t := r.config.Tracer
+	if t == nil {
+		return nil
+	}
+	s := &opSpan{ring: r, parent: r.trace.current, remapped: r.stats.remappedTotal.get() + r.stats.remapped.get()}
+	s.span = t.Start(s.parent, name, append(attrs, Attribute{AttrRingID, r.id}, Attribute{AttrLevel, r.level})...)
+	r.trace.current = s.span
+	return s
+}
+
+// traceRead starts a span for a read, which may run alongside mutations and so is never nested.
+func (r *Ring) traceRead(name string, attrs ...Attribute) Span {
+	t := r.config.Tracer
+	if t == nil {
+		return nil
+	}
+	return t.Start(nil, name, append(attrs, Attribute{AttrRingID, r.id}, Attribute{AttrLevel, r.level})...)
+}
+
+// tracing reports whether the operation is traced.
+func (s *opSpan) tracing() bool {
+	return s != nil
+}
+
+// end records the keys the operation remapped and ends its span.
+func (s *opSpan) end(err error) {
+	if s == nil {
+		return
+	}
+	stats := s.ring.stats
+	s.span.SetAttributes(Attribute{AttrRemapped, stats.remappedTotal.get() + stats.remapped.get() - s.remapped + s.moved})
+	s.ring.trace.current = s.parent
+	s.span.End(err)
+}
+
+// endRead ends the span of a traced read.
+func endRead(span Span, err error) {
+	if span != nil {
+		span.End(err)
+	}
+}
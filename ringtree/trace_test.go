@@ -0,0 +1,89 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// recordedSpan is a span kept by recordingTracer.
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]interface{}
+	err    error
+	ended  bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.err, s.ended = err, true
+}
+
+// recordingTracer keeps every span it starts.
+type recordingTracer struct {
+	spans []*recordedSpan
+	sync.Mutex
+}
+
+func (t *recordingTracer) Start(parent Span, name string, attrs ...Attribute) Span {
+	t.Lock()
+	defer t.Unlock()
+	s := &recordedSpan{name: name, attrs: make(map[string]interface{})}
+	if parent != nil {
+		s.parent = parent.(*recordedSpan)
+	}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return s
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	rt := New(2, WithTracer(tracer))
+	rt.InsertNode(NewNode("A", 10))
+	rt.InsertNode(NewNode("B", 10))
+	for i := 0; i < 60; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	rt.Lookup("key-1")
+	rt.RemoveKey("missing")
+
+	var splits, lookups int
+	for _, s := range tracer.spans {
+		if !s.ended {
+			t.Fatalf("span %s was not ended", s.name)
+		}
+		switch s.name {
+		case "splitNode":
+			splits++
+			if s.parent == nil || s.parent.name != "InsertKey" {
+				t.Fatal("expected a split triggered by a key to be a child of its InsertKey span")
+			}
+			if s.attrs[AttrRemapped].(int) == 0 {
+				t.Fatal("expected a split to record the keys it remapped")
+			}
+		case "Lookup":
+			lookups++
+			if s.parent != nil || s.attrs[AttrKey] != "key-1" {
+				t.Fatal("expected a root Lookup span carrying the key")
+			}
+		case "RemoveKey":
+			if !errors.Is(s.err, ErrKeyNotFound) {
+				t.Fatalf("got error %v on the RemoveKey span, want ErrKeyNotFound", s.err)
+			}
+		}
+		if _, ok := s.attrs[AttrLevel]; !ok {
+			t.Fatalf("span %s has no level attribute", s.name)
+		}
+	}
+	if splits == 0 || lookups != 1 {
+		t.Fatalf("got %d split and %d lookup spans, want splits and one lookup", splits, lookups)
+	}
+}
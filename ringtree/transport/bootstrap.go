@@ -0,0 +1,129 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// TransferMessage carries keys a joining node took over; it is answered with the values the store holds.
+const TransferMessage = "ring.transfer"
+
+// defaultTransferBatch is the number of keys fetched per transfer request when no batch size is given.
+const defaultTransferBatch = 256
+
+// Store holds the values of the keys a process serves. Serve answers transfers from it, and a Bootstrapper
+// puts the values a joining node fetches into it.
+type Store interface {
+	// Get returns the values of the keys held, leaving out the keys that are not.
+	Get(keys []string) (map[string][]byte, error)
+	// Put stores values fetched from another node.
+	Put(values map[string][]byte) error
+}
+
+// Progress is how far a bootstrap got, reported after every batch of keys fetched.
+type Progress struct {
+	Node    string // Joining node
+	From    string // Node the last batch was fetched from
+	Fetched int    // Keys fetched so far
+	Total   int    // Keys to fetch
+}
+
+// Done reports whether every key was fetched.
+func (p Progress) Done() bool {
+	return p.Fetched == p.Total
+}
+
+// Bootstrapper joins nodes to a tree and brings them up once they hold the values of their keys.
+type Bootstrapper struct {
+	ring      *ringtree.Ring
+	transport Transport
+	store     Store
+	batchSize int
+	resolve   func(nodeID string) (string, error)
+}
+
+// NewBootstrapper returns a bootstrapper putting fetched values into store, reaching each node at the
+// address resolve returns for it. A nil resolve uses node IDs as addresses, and a batch size of 0 fetches
+// 256 keys per request.
+func NewBootstrapper(ring *ringtree.Ring, t Transport, store Store, batchSize int, resolve func(nodeID string) (string, error)) *Bootstrapper {
+	if batchSize < 1 {
+		batchSize = defaultTransferBatch
+	}
+	if resolve == nil {
+		resolve = func(nodeID string) (string, error) {
+			return nodeID, nil
+		}
+	}
+	return &Bootstrapper{ring: ring, transport: t, store: store, batchSize: batchSize, resolve: resolve}
+}
+
+// Join bootstraps a node and then activates it. The node is inserted Joining, taking over its key ranges
+// while their reads still go to the replicas; the keys it took over are then fetched in batches from the
+// nodes that held them and put into the store, calling progress, if not nil, after each batch. Only once
+// every transfer is done is the node set Up. If a fetch fails the node is left Joining and the error is
+// returned; removing the node and joining it again starts over.
+func (b *Bootstrapper) Join(ctx context.Context, node *ringtree.Node, progress func(Progress)) error {
+	transfers, err := b.ring.JoinNode(node)
+	if err != nil {
+		return err
+	}
+	p := Progress{Node: node.ID()}
+	for _, transfer := range transfers {
+		p.Total += len(transfer.Keys)
+	}
+	for _, transfer := range transfers {
+		for start := 0; start < len(transfer.Keys); start += b.batchSize {
+			batch := transfer.Keys[start:min(start+b.batchSize, len(transfer.Keys))]
+			if err := b.fetch(ctx, transfer.From, batch); err != nil {
+				return fmt.Errorf("bootstrapping node %s from %s: %w", node.ID(), transfer.From, err)
+			}
+			p.From = transfer.From
+			p.Fetched += len(batch)
+			if progress != nil {
+				progress(p)
+			}
+		}
+	}
+	return b.ring.SetNodeState(node.ID(), ringtree.Up)
+}
+
+// fetch requests the values of a batch of keys from a node and puts them into the store.
+func (b *Bootstrapper) fetch(ctx context.Context, nodeID string, keys []string) error {
+	addr, err := b.resolve(nodeID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	reply, err := b.transport.Request(ctx, addr, ringtree.Message{Type: TransferMessage, Payload: payload})
+	if err != nil {
+		return err
+	}
+	var values map[string][]byte
+	if err := json.Unmarshal(reply.Payload, &values); err != nil {
+		return err
+	}
+	return b.store.Put(values)
+}
+
+// answerTransfer answers a transfer request with the values the store holds.
+func answerTransfer(store Store, msg ringtree.Message) (ringtree.Message, error) {
+	var keys []string
+	if err := json.Unmarshal(msg.Payload, &keys); err != nil {
+		return ringtree.Message{}, err
+	}
+	values, err := store.Get(keys)
+	if err != nil {
+		return ringtree.Message{}, err
+	}
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return ringtree.Message{}, err
+	}
+	return ringtree.Message{Type: TransferMessage, Payload: payload}, nil
+}
@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// mapStore is a Store holding values in memory.
+type mapStore struct {
+	values map[string][]byte
+	mu     sync.Mutex
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{values: make(map[string][]byte)}
+}
+
+func (s *mapStore) Get(keys []string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := s.values[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (s *mapStore) Put(values map[string][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, value := range values {
+		s.values[key] = value
+	}
+	return nil
+}
+
+func TestBootstrapper(t *testing.T) {
+	network := NewNetwork()
+	ring := ringtree.New(5, ringtree.WithReplication(2))
+	stores := make(map[string]*mapStore)
+	for _, id := range []string{"A", "B", "C", "D"} {
+		tr, err := network.NewMem(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tr.Close()
+		stores[id] = newMapStore()
+		Serve(tr, ring, nil, nil, stores[id])
+		if id != "D" {
+			ring.InsertNode(ringtree.NewNode(id, 1000))
+		}
+	}
+	// Each process holds the values of the keys its node owns
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		ring.InsertKey(key)
+		owner, err := ring.Lookup(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[owner].values[key] = []byte("value of " + key)
+	}
+
+	var reports []Progress
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	node := ringtree.NewNode("D", 1000)
+	client, err := network.NewMem("D-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBootstrapper(ring, client, stores["D"], 16, nil)
+	if err := b.Join(ctx, node, func(p Progress) { reports = append(reports, p) }); err != nil {
+		t.Fatal(err)
+	}
+	if node.State() != ringtree.Up {
+		t.Fatalf("expected D to be Up after its bootstrap, got %v", node.State())
+	}
+	if len(reports) < 2 || !reports[len(reports)-1].Done() {
+		t.Fatalf("expected progress reported per batch ending done, got %+v", reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Fetched <= reports[i-1].Fetched || reports[i].Fetched-reports[i-1].Fetched > 16 {
+			t.Fatalf("expected progress to grow by at most a batch, got %+v", reports)
+		}
+	}
+
+	// D holds the value of every key it owns now
+	total := 0
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if owner, _ := ring.Lookup(key); owner == "D" {
+			total++
+			if string(stores["D"].values[key]) != "value of "+key {
+				t.Errorf("expected D to hold the value of %s", key)
+			}
+		}
+	}
+	if total == 0 || total != reports[len(reports)-1].Total {
+		t.Errorf("expected %d keys fetched, got %+v", total, reports[len(reports)-1])
+	}
+}
+
+func TestBootstrapperFailure(t *testing.T) {
+	network := NewNetwork()
+	ring := ringtree.New(5)
+	ring.InsertNode(ringtree.NewNode("A", 1000))
+	for i := 0; i < 100; i++ {
+		ring.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	client, err := network.NewMem("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A is not served, so the transfer from it fails and E stays Joining
+	node := ringtree.NewNode("E", 1000)
+	b := NewBootstrapper(ring, client, newMapStore(), 0, nil)
+	if err := b.Join(context.Background(), node, nil); err == nil {
+		t.Fatal("expected the bootstrap to fail")
+	}
+	if node.State() != ringtree.Joining {
+		t.Errorf("expected E to stay Joining, got %v", node.State())
+	}
+}
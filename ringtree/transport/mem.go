@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// ErrClosed is returned when sending through a closed transport.
+var ErrClosed = errors.New("transport closed")
+
+// Network connects in-memory transports within a process, for tests and simulations.
+type Network struct {
+	transports map[string]*Mem
+	sync.RWMutex
+}
+
+// NewNetwork returns an empty in-memory network.
+func NewNetwork() *Network {
+	return &Network{transports: make(map[string]*Mem)}
+}
+
+// Mem is a transport attached to an in-memory network.
+type Mem struct {
+	network *Network
+	addr    string
+	handler handlerSlot
+}
+
+// NewMem attaches a transport at addr to the network.
+func (n *Network) NewMem(addr string) (*Mem, error) {
+	n.Lock()
+	defer n.Unlock()
+	if _, ok := n.transports[addr]; ok {
+		return nil, errors.New("address already in use")
+	}
+	t := &Mem{network: n, addr: addr}
+	n.transports[addr] = t
+	return t, nil
+}
+
+// Addr returns the address of the transport on its network.
+func (t *Mem) Addr() string {
+	return t.addr
+}
+
+// Send delivers a message to the transport at an address without waiting for it to be handled.
+func (t *Mem) Send(ctx context.Context, to string, msg ringtree.Message) error {
+	h, err := t.peer(to)
+	if err != nil {
+		return err
+	}
+	go h(msg)
+	return nil
+}
+
+// Request delivers a message to the transport at an address and returns its handler's reply.
+func (t *Mem) Request(ctx context.Context, to string, msg ringtree.Message) (ringtree.Message, error) {
+	h, err := t.peer(to)
+	if err != nil {
+		return ringtree.Message{}, err
+	}
+	done := make(chan frame, 1)
+	go func() {
+		done <- answer(h, frame{Kind: frameRequest, Msg: msg})
+	}()
+	select {
+	case reply := <-done:
+		return reply.result()
+	case <-ctx.Done():
+		return ringtree.Message{}, ctx.Err()
+	}
+}
+
+// peer returns the handler of the transport at an address.
+func (t *Mem) peer(addr string) (Handler, error) {
+	t.network.RLock()
+	defer t.network.RUnlock()
+	if t.network.transports[t.addr] != t {
+		return nil, ErrClosed
+	}
+	peer, ok := t.network.transports[addr]
+	if !ok {
+		return nil, errors.New("no transport at " + addr)
+	}
+	h := peer.handler.get()
+	if h == nil {
+		return nil, ErrNoHandler
+	}
+	return h, nil
+}
+
+// Listen starts handing messages sent to the transport to h.
+func (t *Mem) Listen(h Handler) {
+	t.handler.set(h)
+}
+
+// Close detaches the transport from its network.
+func (t *Mem) Close() error {
+	t.network.Lock()
+	defer t.network.Unlock()
+	if t.network.transports[t.addr] == t {
+		delete(t.network.transports, t.addr)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// maxFrame is the largest frame a TCP transport accepts, bounding what a peer can make it allocate.
+const maxFrame = 64 << 20
+
+// TCP is a transport opening a connection per message and exchanging length-prefixed frames, for messages
+// too large for a datagram such as anti-entropy deltas.
+type TCP struct {
+	listener net.Listener
+	handler  handlerSlot
+	dialer   net.Dialer
+}
+
+// ListenTCP returns a TCP transport listening on addr, such as ":7946" or "127.0.0.1:0".
+func ListenTCP(addr string) (*TCP, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	t := &TCP{listener: listener}
+	go t.accept()
+	return t, nil
+}
+
+// Addr returns the local address of the transport.
+func (t *TCP) Addr() string {
+	return t.listener.Addr().String()
+}
+
+// Send sends a message over a new connection.
+func (t *TCP) Send(ctx context.Context, to string, msg ringtree.Message) error {
+	conn, err := t.dial(ctx, to)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return writeFrame(conn, frame{Kind: frameSend, Msg: msg})
+}
+
+// Request sends a message over a new connection and reads the reply from it.
+func (t *TCP) Request(ctx context.Context, to string, msg ringtree.Message) (ringtree.Message, error) {
+	conn, err := t.dial(ctx, to)
+	if err != nil {
+		return ringtree.Message{}, err
+	}
+	defer conn.Close()
+	if err := writeFrame(conn, frame{Kind: frameRequest, Msg: msg}); err != nil {
+		return ringtree.Message{}, err
+	}
+	f, err := readFrame(bufio.NewReader(conn))
+	if err != nil {
+		return ringtree.Message{}, err
+	}
+	return f.result()
+}
+
+// dial connects to an address, bounding the connection by the context's deadline.
+func (t *TCP) dial(ctx context.Context, to string) (net.Conn, error) {
+	conn, err := t.dialer.DialContext(ctx, "tcp", to)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// accept serves incoming connections until the listener is closed.
+func (t *TCP) accept() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go t.serve(conn)
+	}
+}
+
+// serve handles the frame sent over a connection.
+func (t *TCP) serve(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Minute))
+	f, err := readFrame(bufio.NewReader(conn))
+	if err != nil {
+		return
+	}
+	if f.Kind == frameRequest {
+		writeFrame(conn, answer(t.handler.get(), f))
+		return
+	}
+	if h := t.handler.get(); h != nil {
+		h(f.Msg)
+	}
+}
+
+// Listen starts handing received messages to h.
+func (t *TCP) Listen(h Handler) {
+	t.handler.set(h)
+}
+
+// Close stops accepting connections.
+func (t *TCP) Close() error {
+	return t.listener.Close()
+}
+
+// writeFrame writes a frame prefixed with its length.
+func writeFrame(w io.Writer, f frame) error {
+	data, err := encode(f)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err = w.Write(buf)
+	return err
+}
+
+// readFrame reads a length-prefixed frame.
+func readFrame(r io.Reader) (frame, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return frame{}, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrame {
+		return frame{}, errors.New("frame too large")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return frame{}, err
+	}
+	return decode(data)
+}
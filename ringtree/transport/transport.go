@@ -0,0 +1,258 @@
+// Package transport carries ring tree gossip between processes. A Transport sends messages one way or as
+// requests awaiting a reply, and hands incoming messages to a Handler; Network.NewMem, ListenUDP and
+// ListenTCP provide in-memory, UDP and TCP transports.
+//
+// On top of any transport, Serve answers gossip, anti-entropy, failure detector and transfer messages for a
+// tree, Gossiper spreads a tree's gossip to other processes, Replica reaches another process's tree for
+// Ring.SyncWith, Prober probes nodes for the swim failure detector, and Bootstrapper joins nodes that fetch
+// their keys from the nodes that held them before they are set Up.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// Message types of the failure detector probes.
+const (
+	PingMessage    = "swim.ping"     // Answered with an ack
+	PingReqMessage = "swim.ping-req" // Carries a node ID to probe; answered with an ack once the node acknowledged
+	AckMessage     = "swim.ack"
+)
+
+// ErrNoHandler is returned when a message reaches a transport nobody listens on.
+var ErrNoHandler = errors.New("no handler listening")
+
+// Handler handles a message received by a transport. The reply is returned to the sender of a request and
+// dropped for a one-way message.
+type Handler func(msg ringtree.Message) (ringtree.Message, error)
+
+// Transport sends messages to the transports of other processes, identified by address.
+type Transport interface {
+	// Addr returns the address other transports reach this one at.
+	Addr() string
+	// Send delivers a message one way.
+	Send(ctx context.Context, to string, msg ringtree.Message) error
+	// Request delivers a message and waits for the handler's reply.
+	Request(ctx context.Context, to string, msg ringtree.Message) (ringtree.Message, error)
+	// Listen starts handing received messages to h.
+	Listen(h Handler)
+	// Close stops the transport.
+	Close() error
+}
+
+// frame is the encoded form of a message on the wire.
+type frame struct {
+	Kind  byte             `json:"k"` // frameSend, frameRequest or frameReply
+	ID    uint64           `json:"id,omitempty"`
+	Msg   ringtree.Message `json:"m"`
+	Error string           `json:"e,omitempty"`
+}
+
+const (
+	frameSend byte = iota
+	frameRequest
+	frameReply
+)
+
+// answer runs a handler for a request frame and returns the reply frame.
+func answer(h Handler, f frame) frame {
+	reply := frame{Kind: frameReply, ID: f.ID}
+	if h == nil {
+		reply.Error = ErrNoHandler.Error()
+		return reply
+	}
+	msg, err := h(f.Msg)
+	if err != nil {
+		reply.Error = err.Error()
+	}
+	reply.Msg = msg
+	return reply
+}
+
+// result returns the message of a reply frame, or the error the remote handler returned.
+func (f frame) result() (ringtree.Message, error) {
+	if f.Error != "" {
+		return ringtree.Message{}, errors.New(f.Error)
+	}
+	return f.Msg, nil
+}
+
+// handlerSlot holds the handler of a transport, which Listen may set after messages start arriving.
+type handlerSlot struct {
+	h Handler
+	sync.RWMutex
+}
+
+func (s *handlerSlot) set(h Handler) {
+	s.Lock()
+	s.h = h
+	s.Unlock()
+}
+
+func (s *handlerSlot) get() Handler {
+	s.RLock()
+	defer s.RUnlock()
+	return s.h
+}
+
+// Serve answers the messages a tree's peers send over t: anti-entropy digests and pulls are answered by the
+// ring, probes are acknowledged, indirect probes are relayed through prober, transfers of joining nodes are
+// answered from store, and any other message is gossip, spread through the tree and on to the gossiper's
+// peers. prober, gossiper and store may be nil.
+func Serve(t Transport, ring *ringtree.Ring, prober *Prober, gossiper *Gossiper, store Store) {
+	t.Listen(func(msg ringtree.Message) (ringtree.Message, error) {
+		switch msg.Type {
+		case ringtree.DigestMessage, ringtree.PullMessage:
+			return ring.Exchange(msg)
+		case PingMessage:
+			return ringtree.Message{Type: AckMessage}, nil
+		case PingReqMessage:
+			if prober == nil {
+				return ringtree.Message{}, ErrNoHandler
+			}
+			if err := prober.Ping(context.Background(), string(msg.Payload)); err != nil {
+				return ringtree.Message{}, err
+			}
+			return ringtree.Message{Type: AckMessage}, nil
+		case TransferMessage:
+			if store == nil {
+				return ringtree.Message{}, ErrNoHandler
+			}
+			return answerTransfer(store, msg)
+		default:
+			if ring.ReceiveMessage(msg) && gossiper != nil {
+				msg.TTL--
+				gossiper.forward(msg)
+			}
+			return ringtree.Message{}, nil
+		}
+	})
+}
+
+// Gossiper spreads a tree's gossip messages to the processes holding its other replicas.
+type Gossiper struct {
+	ring      *ringtree.Ring
+	transport Transport
+	peers     []string
+	fanout    int
+	mu        sync.Mutex
+}
+
+// NewGossiper returns a gossiper sending each message to fanout randomly chosen peers, or to all of them if
+// fanout is 0. Pass it to Serve so messages received from peers are forwarded in turn.
+func NewGossiper(ring *ringtree.Ring, t Transport, fanout int, peers ...string) *Gossiper {
+	return &Gossiper{ring: ring, transport: t, peers: peers, fanout: fanout}
+}
+
+// SetPeers replaces the addresses messages are sent to.
+func (g *Gossiper) SetPeers(peers ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.peers = peers
+}
+
+// Gossip spreads a message through the local tree, sends it on to the peers and returns its ID.
+func (g *Gossiper) Gossip(msgType string, payload []byte) string {
+	msg := g.ring.NewMessage(msgType, payload)
+	if g.ring.ReceiveMessage(msg) {
+		msg.TTL--
+		g.forward(msg)
+	}
+	return msg.ID()
+}
+
+// forward sends a message to the chosen peers without waiting for them.
+func (g *Gossiper) forward(msg ringtree.Message) {
+	if msg.TTL <= 0 {
+		return
+	}
+	g.mu.Lock()
+	peers := append([]string(nil), g.peers...)
+	g.mu.Unlock()
+	if g.fanout > 0 && g.fanout < len(peers) {
+		rand.Shuffle(len(peers), func(i, j int) {
+			peers[i], peers[j] = peers[j], peers[i]
+		})
+		peers = peers[:g.fanout]
+	}
+	for _, peer := range peers {
+		go g.transport.Send(context.Background(), peer, msg)
+	}
+}
+
+// Replica is a tree replica in another process, reached through a transport.
+type Replica struct {
+	transport Transport
+	addr      string
+}
+
+// NewReplica returns the replica served at addr.
+func NewReplica(t Transport, addr string) *Replica {
+	return &Replica{transport: t, addr: addr}
+}
+
+// Exchange sends an anti-entropy message to the replica and returns its answer.
+func (r *Replica) Exchange(msg ringtree.Message) (ringtree.Message, error) {
+	return r.transport.Request(context.Background(), r.addr, msg)
+}
+
+// Prober probes ring nodes over a transport for the swim failure detector.
+type Prober struct {
+	transport Transport
+	resolve   func(nodeID string) (string, error)
+}
+
+// NewProber returns a prober reaching each node at the address resolve returns for it. A nil resolve uses
+// node IDs as addresses.
+func NewProber(t Transport, resolve func(nodeID string) (string, error)) *Prober {
+	if resolve == nil {
+		resolve = func(nodeID string) (string, error) {
+			return nodeID, nil
+		}
+	}
+	return &Prober{transport: t, resolve: resolve}
+}
+
+// Ping probes a node directly.
+func (p *Prober) Ping(ctx context.Context, nodeID string) error {
+	return p.request(ctx, nodeID, ringtree.Message{Type: PingMessage})
+}
+
+// PingReq asks relay to probe target.
+func (p *Prober) PingReq(ctx context.Context, relay, target string) error {
+	return p.request(ctx, relay, ringtree.Message{Type: PingReqMessage, Payload: []byte(target)})
+}
+
+// request sends a probe to a node and checks it was acknowledged.
+func (p *Prober) request(ctx context.Context, nodeID string, msg ringtree.Message) error {
+	addr, err := p.resolve(nodeID)
+	if err != nil {
+		return err
+	}
+	reply, err := p.transport.Request(ctx, addr, msg)
+	if err != nil {
+		return err
+	}
+	if reply.Type != AckMessage {
+		return errors.New("probe not acknowledged")
+	}
+	return nil
+}
+
+// encode returns the wire form of a frame.
+func encode(f frame) ([]byte, error) {
+	return json.Marshal(f)
+}
+
+// decode parses the wire form of a frame.
+func decode(data []byte) (frame, error) {
+	var f frame
+	err := json.Unmarshal(data, &f)
+	return f, err
+}
@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// replicas returns two trees serving over transports t1 and t2, the second restored from a snapshot of the
+// first and then changed.
+func replicas(t *testing.T, t1, t2 Transport) (*ringtree.Ring, *ringtree.Ring) {
+	a := ringtree.New(4)
+	a.InsertNode(ringtree.NewNode("A", 1000))
+	a.InsertNode(ringtree.NewNode("B", 1000))
+	for i := 0; i < 100; i++ {
+		a.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	var buf bytes.Buffer
+	if err := a.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ringtree.ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.InsertNode(ringtree.NewNode("C", 1000)); err != nil {
+		t.Fatal(err)
+	}
+	Serve(t1, a, nil, nil, nil)
+	Serve(t2, b, nil, nil, nil)
+	return a, b
+}
+
+func TestTransports(t *testing.T) {
+	network := NewNetwork()
+	for name, listen := range map[string]func() (Transport, error){
+		"mem": func() (Transport, error) {
+			return network.NewMem(fmt.Sprintf("mem-%d", time.Now().UnixNano()))
+		},
+		"udp": func() (Transport, error) { return ListenUDP("127.0.0.1:0") },
+		"tcp": func() (Transport, error) { return ListenTCP("127.0.0.1:0") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			t1, err := listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer t1.Close()
+			t2, err := listen()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer t2.Close()
+
+			a, b := replicas(t, t1, t2)
+			pulled, err := a.SyncWith(NewReplica(t1, t2.Addr()))
+			if err != nil || !pulled {
+				t.Fatalf("got pulled %v (%v), want the delta pulled over the transport", pulled, err)
+			}
+			if !a.Digest().Equal(b.Digest()) {
+				t.Fatal("expected equal digests after the pull")
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			prober := NewProber(t1, nil)
+			if err := prober.Ping(ctx, t2.Addr()); err != nil {
+				t.Fatalf("ping failed: %v", err)
+			}
+			if err := prober.PingReq(ctx, t2.Addr(), t1.Addr()); err == nil {
+				t.Fatal("expected an indirect probe through a peer without a prober to fail")
+			}
+		})
+	}
+}
+
+func TestGossiper(t *testing.T) {
+	network := NewNetwork()
+	var rings []*ringtree.Ring
+	var addrs []string
+	var transports []*Mem
+	for i := 0; i < 3; i++ {
+		ring := ringtree.New(4)
+		ring.InsertNode(ringtree.NewNode(fmt.Sprintf("%d", i), 100))
+		tr, err := network.NewMem(fmt.Sprintf("proc-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rings = append(rings, ring)
+		addrs = append(addrs, tr.Addr())
+		transports = append(transports, tr)
+	}
+	var gossipers []*Gossiper
+	for i, ring := range rings {
+		g := NewGossiper(ring, transports[i], 0, addrs...)
+		Serve(transports[i], ring, nil, g, nil)
+		gossipers = append(gossipers, g)
+	}
+
+	id := gossipers[0].Gossip("greeting", []byte("hi"))
+	deadline := time.Now().Add(time.Second)
+	for i, ring := range rings {
+		node, err := ring.Node(fmt.Sprintf("%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for node.LastMessage().ID() != id {
+			if time.Now().After(deadline) {
+				t.Fatalf("message %s did not reach node %d", id, i)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
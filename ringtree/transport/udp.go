@@ -0,0 +1,139 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	ringtree "github.com/kagwave/ring-tree/ringtree"
+)
+
+// maxDatagram is the largest frame a UDP transport sends.
+const maxDatagram = 64 * 1024
+
+// ErrTooLarge is returned when a message does not fit in a datagram.
+var ErrTooLarge = errors.New("message too large for a datagram")
+
+// UDP is a transport exchanging one datagram per message. Delivery is not guaranteed: a lost request or
+// reply surfaces as the request's context running out.
+type UDP struct {
+	conn    *net.UDPConn
+	handler handlerSlot
+	nextID  atomic.Uint64
+	pending map[uint64]chan frame
+	mu      sync.Mutex
+}
+
+// ListenUDP returns a UDP transport bound to addr, such as ":7946" or "127.0.0.1:0".
+func ListenUDP(addr string) (*UDP, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	t := &UDP{conn: conn, pending: make(map[uint64]chan frame)}
+	go t.read()
+	return t, nil
+}
+
+// Addr returns the local address of the transport.
+func (t *UDP) Addr() string {
+	return t.conn.LocalAddr().String()
+}
+
+// Send sends a message in a datagram.
+func (t *UDP) Send(ctx context.Context, to string, msg ringtree.Message) error {
+	return t.write(to, frame{Kind: frameSend, Msg: msg})
+}
+
+// Request sends a message in a datagram and waits for the reply datagram.
+func (t *UDP) Request(ctx context.Context, to string, msg ringtree.Message) (ringtree.Message, error) {
+	id := t.nextID.Add(1)
+	reply := make(chan frame, 1)
+	t.mu.Lock()
+	t.pending[id] = reply
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(to, frame{Kind: frameRequest, ID: id, Msg: msg}); err != nil {
+		return ringtree.Message{}, err
+	}
+	select {
+	case f := <-reply:
+		return f.result()
+	case <-ctx.Done():
+		return ringtree.Message{}, ctx.Err()
+	}
+}
+
+// write encodes a frame into a datagram to an address.
+func (t *UDP) write(to string, f frame) error {
+	addr, err := net.ResolveUDPAddr("udp", to)
+	if err != nil {
+		return err
+	}
+	data, err := encode(f)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxDatagram {
+		return ErrTooLarge
+	}
+	_, err = t.conn.WriteToUDP(data, addr)
+	return err
+}
+
+// read receives datagrams until the connection is closed, handing requests to the handler and replies to
+// the requests waiting for them.
+func (t *UDP) read() {
+	buf := make([]byte, maxDatagram)
+	for {
+		n, from, err := t.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		f, err := decode(buf[:n])
+		if err != nil {
+			continue
+		}
+		switch f.Kind {
+		case frameReply:
+			t.mu.Lock()
+			reply, ok := t.pending[f.ID]
+			t.mu.Unlock()
+			if ok {
+				reply <- f
+			}
+		case frameRequest:
+			go func() {
+				_ = t.write(from.String(), answer(t.handler.get(), f))
+			}()
+		default:
+			if h := t.handler.get(); h != nil {
+				go h(f.Msg)
+			}
+		}
+	}
+}
+
+// Listen starts handing received messages to h.
+func (t *UDP) Listen(h Handler) {
+	t.handler.set(h)
+}
+
+// Close closes the socket.
+func (t *UDP) Close() error {
+	return t.conn.Close()
+}
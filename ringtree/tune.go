@@ -0,0 +1,143 @@
+package ringtree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// ErrTuneTargets is returned by Tune when no candidate meets the targets.
+var ErrTuneTargets = errors.New("no candidate meets the tuning targets")
+
+// TuneTargets describes what Tune aims for and the grid it searches. Zero fields take the defaults noted.
+type TuneTargets struct {
+	Nodes    int     // Physical nodes the simulated tree grows to before the sample is inserted (10)
+	MaxCount int     // Members per ring of the simulated tree (7)
+	MaxCV    float64 // Highest standard deviation of the node loads over the mean load (0.25)
+	MaxDepth int     // Deepest level of subrings allowed after the sample is inserted (0 allows any)
+	Seed     int64   // Seed of the generated node IDs (1, so tuning gives the same result on every run)
+
+	Replicas      []int // Vnodes per node to try (10, 20, 40, 80 and 160)
+	Thresholds    []int // Node thresholds τ to try (1, 2 and 4 times the mean keys per node)
+	BranchFactors []int // Branch factors to try (1 and 2)
+}
+
+// TuneTrial is the outcome of one simulation of the grid.
+type TuneTrial struct {
+	Replicas     int
+	Threshold    int
+	BranchFactor int
+	CV           float64 // Standard deviation of the node loads over the mean load
+	Depth        int     // Deepest level of subrings
+	Nodes        int     // Physical nodes, including those seeded by splits
+}
+
+// meets reports whether the trial meets the targets.
+func (t TuneTrial) meets(targets TuneTargets) bool {
+	return t.CV <= targets.MaxCV && (targets.MaxDepth == 0 || t.Depth <= targets.MaxDepth)
+}
+
+// Tuning is the recommendation of Tune, with every trial it ran.
+type Tuning struct {
+	TuneTrial
+	Trials []TuneTrial // Every trial run, best first
+}
+
+// Options returns the options that apply the recommended replica count and branch factor. The threshold is
+// set on each node, as NewNode's threshold.
+func (t Tuning) Options() []Option {
+	return []Option{WithReplicas(t.Replicas), WithBranchFactor(t.BranchFactor)}
+}
+
+// Tune recommends a replica count, node threshold and branch factor for a workload, replacing trial and
+// error with short seeded simulations. For every combination on the grid it grows a tree to the target
+// node count, inserts the sample and measures the spread of the node loads and the depth the splits
+// reached. Of the combinations meeting the targets it recommends the one with the fewest vnodes, which are
+// the memory and lookup cost, breaking ties by the lowest spread. If none meets them it returns the lowest
+// spread with an error wrapping ErrTuneTargets.
+func Tune(sample []string, targets TuneTargets) (Tuning, error) {
+	targets = targets.withDefaults(len(sample))
+	var tuning Tuning
+	for _, replicas := range targets.Replicas {
+		for _, threshold := range targets.Thresholds {
+			for _, factor := range targets.BranchFactors {
+				trial, err := runTuneTrial(sample, targets, replicas, threshold, factor)
+				if err != nil {
+					return Tuning{}, err
+				}
+				tuning.Trials = append(tuning.Trials, trial)
+			}
+		}
+	}
+
+	sort.SliceStable(tuning.Trials, func(i, j int) bool {
+		a, b := tuning.Trials[i], tuning.Trials[j]
+		if a.meets(targets) != b.meets(targets) {
+			return a.meets(targets)
+		}
+		if a.meets(targets) && a.Replicas != b.Replicas {
+			return a.Replicas < b.Replicas
+		}
+		return a.CV < b.CV
+	})
+	tuning.TuneTrial = tuning.Trials[0]
+	if !tuning.meets(targets) {
+		return tuning, fmt.Errorf("%w: lowest stddev/mean %.3f at depth %d", ErrTuneTargets, tuning.CV, tuning.Depth)
+	}
+	return tuning, nil
+}
+
+// runTuneTrial simulates one combination of the grid.
+func runTuneTrial(sample []string, targets TuneTargets, replicas, threshold, factor int) (TuneTrial, error) {
+	rt := New(targets.MaxCount, WithReplicas(replicas), WithBranchFactor(factor), WithRandSource(rand.NewSource(targets.Seed)))
+	for i := 0; rt.stats.numNodes.get() < targets.Nodes; i++ {
+		if err := growTree(rt, "node"+strconv.Itoa(i), threshold); err != nil {
+			return TuneTrial{}, err
+		}
+	}
+	for _, key := range sample {
+		if err := rt.InsertKey(key); err != nil && !errors.Is(err, ErrKeyExists) {
+			return TuneTrial{}, err
+		}
+	}
+
+	rt.writer.Lock()
+	defer rt.writer.Unlock()
+	loads := rt.systemLoads()
+	sort.Ints(loads) // Summed in a fixed order, so a seed reproduces the trial exactly
+	mean, _, stdDev := calculateStats(loads)
+	trial := TuneTrial{Replicas: replicas, Threshold: threshold, BranchFactor: factor, Depth: rt.hierarchyDepth(), Nodes: len(loads)}
+	if mean > 0 {
+		trial.CV = stdDev / mean
+	}
+	return trial, nil
+}
+
+// withDefaults fills in the zero fields of the targets for a sample of the given size.
+func (t TuneTargets) withDefaults(keys int) TuneTargets {
+	if t.Nodes < 1 {
+		t.Nodes = 10
+	}
+	if t.MaxCount < 2 {
+		t.MaxCount = 7
+	}
+	if t.MaxCV <= 0 {
+		t.MaxCV = 0.25
+	}
+	if t.Seed == 0 {
+		t.Seed = 1
+	}
+	if len(t.Replicas) == 0 {
+		t.Replicas = []int{10, 20, 40, 80, 160}
+	}
+	if len(t.Thresholds) == 0 {
+		mean := max(keys/t.Nodes, 1)
+		t.Thresholds = []int{mean, 2 * mean, 4 * mean}
+	}
+	if len(t.BranchFactors) == 0 {
+		t.BranchFactors = []int{1, 2}
+	}
+	return t
+}
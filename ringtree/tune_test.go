@@ -0,0 +1,45 @@
+package ringtree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTune(t *testing.T) {
+	var sample []string
+	for i := 0; i < 4000; i++ {
+		key, _ := GenerateRandomString(20)
+		sample = append(sample, key)
+	}
+	targets := TuneTargets{Nodes: 8, MaxCV: 0.3, MaxDepth: 1, Replicas: []int{5, 40, 160}, BranchFactors: []int{1}}
+	tuning, err := Tune(sample, targets)
+	if err != nil {
+		t.Fatalf("expected a candidate to meet the targets, got error: %v", err)
+	}
+	if len(tuning.Trials) != 9 {
+		t.Errorf("expected 9 trials, got %d", len(tuning.Trials))
+	}
+	if tuning.CV > 0.3 || tuning.Depth > 1 {
+		t.Errorf("expected the recommendation to meet the targets, got %+v", tuning.TuneTrial)
+	}
+	// No candidate meeting the targets uses fewer vnodes
+	for _, trial := range tuning.Trials {
+		if trial.CV <= 0.3 && trial.Depth <= 1 && trial.Replicas < tuning.Replicas {
+			t.Errorf("expected the fewest vnodes meeting the targets, got %+v over %+v", tuning.TuneTrial, trial)
+		}
+	}
+	again, _ := Tune(sample, targets)
+	if !reflect.DeepEqual(again, tuning) {
+		t.Errorf("expected tuning to be reproducible, got %+v and %+v", tuning.TuneTrial, again.TuneTrial)
+	}
+
+	rt := New(8, tuning.Options()...)
+	if rt.config.Replicas != tuning.Replicas || rt.config.BranchFactor != tuning.BranchFactor {
+		t.Errorf("expected the options to apply %+v, got %+v", tuning.TuneTrial, rt.config)
+	}
+
+	if _, err := Tune(sample, TuneTargets{Nodes: 8, MaxCV: 0.001, Replicas: []int{5}}); !errors.Is(err, ErrTuneTargets) {
+		t.Errorf("expected ErrTuneTargets for an unreachable spread, got %v", err)
+	}
+}
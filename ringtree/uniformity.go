@@ -0,0 +1,166 @@
+package ringtree
+
+import (
+	"math"
+	"sort"
+)
+
+// UniformityTest holds a chi-square and a Kolmogorov–Smirnov test of a set of loads against the uniform
+// expectation that every member holds the same share of the keys. Small p-values reject uniformity.
+type UniformityTest struct {
+	Members          int     `json:"members"`            // Loads tested
+	Keys             int     `json:"keys"`               // Sum of the loads
+	ChiSquare        float64 `json:"chi_square"`         // Pearson's statistic, sum of (observed-expected)²/expected
+	DegreesOfFreedom int     `json:"degrees_of_freedom"` // Members less one
+	ChiSquareP       float64 `json:"chi_square_p"`       // Probability of a statistic at least as large under uniformity
+	KS               float64 `json:"ks"`                 // Largest gap between the cumulative load shares and the uniform shares
+	KSP              float64 `json:"ks_p"`               // Probability of a gap at least as large under uniformity
+}
+
+// RingUniformity is the uniformity test of the members of one ring, subrings counted by their total load.
+type RingUniformity struct {
+	ID    string `json:"id"`
+	Level int    `json:"level"`
+	UniformityTest
+}
+
+// UniformityReport holds the uniformity tests of the physical node loads across the tree, and of the member
+// loads of every ring.
+type UniformityReport struct {
+	System UniformityTest   `json:"system"`
+	Rings  []RingUniformity `json:"rings"`
+}
+
+// EvaluateUniformity tests whether keys are spread evenly: across all physical nodes of the tree, and
+// across the members of each ring. Loads are taken in member ID order, so the Kolmogorov–Smirnov statistic
+// is reproducible. It waits for a mutation in progress to finish.
+func EvaluateUniformity(r *Ring) UniformityReport {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	var report UniformityReport
+	var nodeLoads []int
+	var walk func(ring *Ring) int
+	walk = func(ring *Ring) int {
+		ids := make([]string, 0, len(ring.members))
+		for id := range ring.members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		var loads []int
+		for _, id := range ids {
+			switch member := ring.members[id].(type) {
+			case *Node:
+				loads = append(loads, member.load)
+				nodeLoads = append(nodeLoads, member.load)
+			case *Ring:
+				loads = append(loads, walk(member))
+			}
+		}
+		report.Rings = append(report.Rings, RingUniformity{ID: ring.id, Level: ring.level, UniformityTest: testUniformity(loads)})
+		return sum(loads)
+	}
+	walk(r.root())
+
+	report.System = testUniformity(nodeLoads)
+	sort.Slice(report.Rings, func(i, j int) bool {
+		if report.Rings[i].Level != report.Rings[j].Level {
+			return report.Rings[i].Level < report.Rings[j].Level
+		}
+		return report.Rings[i].ID < report.Rings[j].ID
+	})
+	return report
+}
+
+// testUniformity runs the chi-square and Kolmogorov–Smirnov tests on the loads. With fewer than two loads,
+// or no keys, there is nothing to reject and both p-values are 1.
+func testUniformity(loads []int) UniformityTest {
+	test := UniformityTest{Members: len(loads), Keys: sum(loads), ChiSquareP: 1, KSP: 1}
+	if test.Members < 2 || test.Keys == 0 {
+		return test
+	}
+	n, total := float64(test.Members), float64(test.Keys)
+	expected := total / n
+
+	for _, load := range loads {
+		diff := float64(load) - expected
+		test.ChiSquare += diff * diff / expected
+	}
+	test.DegreesOfFreedom = test.Members - 1
+	test.ChiSquareP = gammaQ(float64(test.DegreesOfFreedom)/2, test.ChiSquare/2)
+
+	// The cumulative share of the keys held by the first i members, against the i/n of a uniform spread.
+	cumulative := 0
+	for i, load := range loads {
+		cumulative += load
+		test.KS = math.Max(test.KS, math.Abs(float64(cumulative)/total-float64(i+1)/n))
+	}
+	root := math.Sqrt(total)
+	test.KSP = kolmogorovQ((root + 0.12 + 0.11/root) * test.KS)
+	return test
+}
+
+// gammaQ returns the regularized upper incomplete gamma function Q(a, x), the tail probability of a
+// chi-square statistic 2x with 2a degrees of freedom.
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+	if x < a+1 {
+		// Series for P(a, x), which converges quickly below the mean.
+		term, total := 1/a, 1/a
+		for n := 1.0; n < 1000; n++ {
+			term *= x / (a + n)
+			total += term
+			if term < total*1e-15 {
+				break
+			}
+		}
+		return math.Max(0, 1-total*prefix)
+	}
+	// Lentz's continued fraction for Q(a, x).
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Min(1, prefix*h)
+}
+
+// kolmogorovQ returns the tail probability of the Kolmogorov distribution at lambda.
+func kolmogorovQ(lambda float64) float64 {
+	if lambda < 0.3 {
+		return 1
+	}
+	var total float64
+	sign := 1.0
+	for j := 1.0; j <= 100; j++ {
+		term := sign * math.Exp(-2*j*j*lambda*lambda)
+		total += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, 2*total))
+}
@@ -0,0 +1,68 @@
+package ringtree
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestGammaQ(t *testing.T) {
+	tests := []struct {
+		a, x, want float64
+	}{
+		{0.5, 3.841459 / 2, 0.05}, // chi-square 95th percentile, 1 degree of freedom
+		{5, 18.307038 / 2, 0.05},  // 10 degrees of freedom
+		{1, 1, math.Exp(-1)},
+		{1, 0, 1},
+		{50, 200, 0},
+	}
+	for _, tt := range tests {
+		if got := gammaQ(tt.a, tt.x); math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("gammaQ(%v, %v) = %v, want %v", tt.a, tt.x, got, tt.want)
+		}
+	}
+}
+
+func TestKolmogorovQ(t *testing.T) {
+	if got := kolmogorovQ(1.3581); math.Abs(got-0.05) > 1e-4 {
+		t.Errorf("kolmogorovQ(1.3581) = %v, want 0.05", got)
+	}
+	if got := kolmogorovQ(0.1); got != 1 {
+		t.Errorf("kolmogorovQ(0.1) = %v, want 1", got)
+	}
+}
+
+func TestTestUniformity(t *testing.T) {
+	even := testUniformity([]int{100, 100, 100, 100})
+	if even.ChiSquare != 0 || even.ChiSquareP != 1 || even.KS != 0 || even.KSP != 1 {
+		t.Errorf("even loads: got %+v, want no deviation", even)
+	}
+	skewed := testUniformity([]int{10, 20, 30, 340})
+	if skewed.DegreesOfFreedom != 3 || skewed.ChiSquareP > 1e-6 || skewed.KSP > 1e-6 {
+		t.Errorf("skewed loads: got %+v, want uniformity rejected", skewed)
+	}
+	if single := testUniformity([]int{7}); single.ChiSquareP != 1 || single.KSP != 1 {
+		t.Errorf("single load: got %+v, want p-values of 1", single)
+	}
+}
+
+func TestEvaluateUniformity(t *testing.T) {
+	rt := New(4)
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 40))
+	}
+	for i := 0; i < 300; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	report := EvaluateUniformity(rt)
+	if report.System.Members != rt.Stats().Nodes() || report.System.Keys != 300 {
+		t.Errorf("got %d nodes and %d keys tested, want %d and 300", report.System.Members, report.System.Keys, rt.Stats().Nodes())
+	}
+	if len(report.Rings) != len(rt.GetTotalLoads()) || report.Rings[0].Level != 0 {
+		t.Errorf("got %d rings, want %d with the root first", len(report.Rings), len(rt.GetTotalLoads()))
+	}
+	if again := EvaluateUniformity(rt); again.System != report.System {
+		t.Errorf("got %+v then %+v, want a reproducible report", report.System, again.System)
+	}
+}
@@ -0,0 +1,128 @@
+package ringtree
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Validate checks the structural invariants of the whole tree and returns every violation found, each
+// wrapping ErrInvariantViolated; a consistent tree returns nil. It checks that:
+//
+//   - every key is stored on the node it routes to, unless it is pinned or a node join deferred its remap,
+//     and under the hash of its key at the level of its ring
+//   - every node's load matches the cost of the keys it holds
+//   - every vnode on a circle references a member of its ring, and every vnode a node holds keys under is
+//     on the circle and owned by that node
+//   - every subring is filed under its ID, points back to its parent and sits one level below it
+//   - every red-black circle keeps its keys in order with valid colouring and black heights
+//
+// Validate waits for a mutation in progress to finish, and is meant for tests and debugging: it routes every
+// key in the tree.
+func (r *Ring) Validate() []error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	root := r.root()
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvariantViolated}, args...)...))
+	}
+
+	var visit func(ring *Ring)
+	visit = func(ring *Ring) {
+		if rb, ok := ring.circle.(*RBTreeCircle); ok {
+			if err := rb.tree.validate(); err != nil {
+				fail("ring %s: circle: %v", ring.id, err)
+			}
+		}
+		vnodes := ring.circle.Range(0, math.MaxUint32)
+		if len(vnodes) != ring.circle.Size() {
+			fail("ring %s: circle reports %d vnodes but holds %d", ring.id, ring.circle.Size(), len(vnodes))
+		}
+		owners := make(map[uint32]string, len(vnodes))
+		for _, vnode := range vnodes {
+			owners[vnode.hash] = vnode.nodeID
+			if ring.members[vnode.nodeID] == nil {
+				fail("ring %s: vnode %d references missing member %s", ring.id, vnode.hash, vnode.nodeID)
+			}
+		}
+
+		// Members are visited in ID order, so the violations come back in the same order each run
+		ids := make([]string, 0, len(ring.members))
+		for id := range ring.members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			switch member := ring.members[id].(type) {
+			case *Node:
+				if member.id != id {
+					fail("ring %s: node %s is filed under %s", ring.id, member.id, id)
+				}
+				errs = append(errs, ring.validateNode(root, member, owners)...)
+			case *Ring:
+				if member.id != id {
+					fail("ring %s: subring %s is filed under %s", ring.id, member.id, id)
+				}
+				if member.parent != ring {
+					fail("ring %s: subring %s has the wrong parent", ring.id, member.id)
+				}
+				if member.level != ring.level+1 {
+					fail("ring %s: subring %s is on level %d, want %d", ring.id, member.id, member.level, ring.level+1)
+				}
+				visit(member)
+			}
+		}
+	}
+	visit(root)
+	return errs
+}
+
+// validateNode checks the vnodes, keys and load of a node on the ring, whose circle maps each vnode to
+// its owner in owners (assuming the tree's writer lock is held).
+func (r *Ring) validateNode(root *Ring, node *Node, owners map[uint32]string) []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: ring %s: node %s: "+format, append([]any{ErrInvariantViolated, r.id, node.id}, args...)...))
+	}
+
+	load := 0
+	for vNodeHash, keys := range node.keys {
+		if owner, ok := owners[vNodeHash]; !ok {
+			if len(keys) > 0 {
+				fail("holds %d keys under vnode %d, which is not on the circle", len(keys), vNodeHash)
+			}
+		} else if owner != node.id {
+			fail("holds vnode %d, which the circle gives to %s", vNodeHash, owner)
+		}
+		for key, keyHash := range keys {
+			load += node.cost(key)
+			if keyHash != r.config.keyHash(key, r.level) {
+				fail("key %s is stored under the wrong hash", key)
+			}
+			if _, pinned := r.pins.get(key); pinned {
+				continue
+			}
+			if remap, deferred := r.remaps.get(key); deferred && remap.from == node {
+				continue
+			}
+			// A key written while its owner was draining stays on the node the write was routed to
+			owner, _, _, _, err := root.routeKey(key, false)
+			if err == nil && owner == node {
+				continue
+			}
+			if target, _, _, _, err := root.routeKey(key, true); err == nil && target == node {
+				continue
+			}
+			if owner == nil {
+				fail("key %s does not route to a node: %v", key, err)
+			} else {
+				fail("key %s routes to node %s", key, owner.id)
+			}
+		}
+	}
+	if load != node.load {
+		fail("load is %d but its keys cost %d", node.load, load)
+	}
+	return errs
+}
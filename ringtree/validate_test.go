@@ -0,0 +1,113 @@
+package ringtree
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithArrayCircle(true)}, {WithSiblingMerge(true)}, {WithRemapBudget(5)}} {
+		rt := New(4, opts...)
+		rt.InsertNode(NewNode("", 20))
+		rng := rand.New(rand.NewSource(1))
+		var keys []string
+		for i := 0; i < 3000; i++ {
+			switch x := rng.Intn(100); {
+			case x < 70 || len(keys) == 0:
+				key, _ := GenerateRandomString(20)
+				if err := rt.InsertKey(key); err != nil {
+					t.Fatal(err)
+				}
+				keys = append(keys, key)
+			case x < 96:
+				j := rng.Intn(len(keys))
+				if err := rt.RemoveKey(keys[j]); err != nil {
+					t.Fatal(err)
+				}
+				keys = append(keys[:j], keys[j+1:]...)
+			case x < 98:
+				if err := growTree(rt, NodeID(rng), 20); err != nil {
+					t.Fatal(err)
+				}
+			default:
+				if rt.Stats().Nodes() > 2 {
+					if err := removeRandomNode(rt, rng); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		}
+		if errs := rt.Validate(); len(errs) > 0 {
+			t.Errorf("expected a consistent tree, got %d violations: %v", len(errs), errs)
+		}
+	}
+}
+
+// expectViolation checks that the tree reports exactly one violation, mentioning want.
+func expectViolation(t *testing.T, rt *Ring, want string) {
+	t.Helper()
+	errs := rt.Validate()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrInvariantViolated) || !strings.Contains(errs[0].Error(), want) {
+		t.Errorf("expected one violation mentioning %q, got %v", want, errs)
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	build := func() (*Ring, *Node, *Node) {
+		rt := New(5)
+		a, b := NewNode("a", 100), NewNode("b", 100)
+		rt.InsertNode(a)
+		rt.InsertNode(b)
+		for i := 0; i < 50; i++ {
+			key, _ := GenerateRandomString(20)
+			rt.InsertKey(key)
+		}
+		if errs := rt.Validate(); len(errs) > 0 {
+			t.Fatalf("expected a consistent tree, got %v", errs)
+		}
+		return rt, a, b
+	}
+
+	rt, a, _ := build()
+	a.load++
+	expectViolation(t, rt, "load is")
+
+	// Move one key from a onto b
+	rt, a, b := build()
+	var moving string
+	for _, keys := range a.keys {
+		for key := range keys {
+			moving = key
+		}
+	}
+	node, _, vNodeHash, _, _ := rt.FindNode(moving)
+	for other := range b.keys {
+		b.keys[other][moving] = node.keys[vNodeHash][moving]
+		break
+	}
+	delete(node.keys[vNodeHash], moving)
+	a.load--
+	b.load++
+	expectViolation(t, rt, "routes to node a")
+
+	// A vnode just past one of a's takes over no keys
+	rt, a, _ = build()
+	var free uint32
+	for vNodeHash := range a.keys {
+		free = vNodeHash + 1
+	}
+	rt.circle.Insert(free, "ghost")
+	expectViolation(t, rt, "missing member ghost")
+	rt.circle.Delete(free)
+
+	rt.members["sub"] = newRing(rt.config, nil, "sub", 1, 5)
+	rt.circle.Insert(free, "sub")
+	expectViolation(t, rt, "wrong parent")
+	delete(rt.members, "sub")
+	rt.circle.Delete(free)
+
+	rt.circle.(*RBTreeCircle).tree.root.red = true
+	expectViolation(t, rt, "root")
+}
@@ -0,0 +1,231 @@
+package ringtree
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	defaultVNodeRounds = 3 // Rounds a node must stay past the factor when none are configured
+	maxVNodeScale      = 4 // Most vnodes the controller gives a node, as a multiple of Replicas
+)
+
+// WithAdaptiveVNodes makes AdjustVNodes correct nodes whose load stays more than factor times their ring's
+// mean, or below the mean over factor, for rounds consecutive rounds: an overloaded node gives up a vnode
+// and an underloaded one gains a vnode. Factors of 1 or below disable the controller; rounds below 1 fall
+// back to the default.
+func WithAdaptiveVNodes(factor float64, rounds int) Option {
+	return func(c *Config) {
+		c.VNodeFactor = factor
+		c.VNodeRounds = rounds
+	}
+}
+
+// vNodeRounds returns the configured number of rounds.
+func (c *Config) vNodeRounds() int {
+	if c.VNodeRounds < 1 {
+		return defaultVNodeRounds
+	}
+	return c.VNodeRounds
+}
+
+// AdjustVNodes runs one round of the vnode controller over the tree. Each physical node that is Up is
+// compared with the mean load of the nodes on its ring; a node that has stayed above the mean by the
+// configured factor for the configured number of rounds gives up the vnode holding the most load, and one
+// that has stayed below it gains a vnode, which takes over part of a neighbour's arc. One vnode changes per
+// node per streak, so the correction is gradual and much lighter than a split. Nodes keep between one and
+// four times Replicas vnodes. It returns the number of vnodes added or removed.
+func (r *Ring) AdjustVNodes() (int, error) {
+	if r.config.VNodeFactor <= 1 {
+		return 0, nil
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.settleRemaps()
+
+	var changes []Op
+	var visit func(ring *Ring)
+	visit = func(ring *Ring) {
+		ring.Lock()
+		changes = append(changes, ring.vNodeChanges()...)
+		ring.Unlock()
+		for _, subring := range ring.subrings() {
+			visit(subring)
+		}
+	}
+	visit(r.root())
+
+	changed := 0
+	for _, op := range changes {
+		r.beginOp()
+		var err error
+		if op.Type == OpAddVNode {
+			err = r.addVNode(op.Node)
+		} else {
+			err = r.removeVNodeOf(op.Node)
+		}
+		if err := r.logOp(op, err); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// vNodeChanges advances the streak of every node of the ring and returns the vnode changes of the nodes
+// whose streak is complete (assuming mutex is already locked).
+func (r *Ring) vNodeChanges() []Op {
+	var nodes []*Node
+	total := 0
+	for _, member := range r.members {
+		if node, ok := member.(*Node); ok {
+			nodes = append(nodes, node)
+			total += node.load
+		}
+	}
+	if len(nodes) < 2 || total == 0 {
+		return nil
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].id < nodes[j].id })
+	mean := float64(total) / float64(len(nodes))
+	factor, rounds := r.config.VNodeFactor, r.config.vNodeRounds()
+
+	var changes []Op
+	now := time.Now()
+	for _, node := range nodes {
+		load := float64(node.load)
+		switch {
+		case node.stateAt(now) != Up || node.warming():
+			node.vNodeStreak = 0
+		case load > mean*factor:
+			node.vNodeStreak = max(node.vNodeStreak, 0) + 1
+		case load < mean/factor:
+			node.vNodeStreak = min(node.vNodeStreak, 0) - 1
+		default:
+			node.vNodeStreak = 0
+		}
+		switch {
+		case node.vNodeStreak >= rounds && len(node.keys) > 1:
+			changes = append(changes, Op{Type: OpRemoveVNode, Node: node.id})
+			node.vNodeStreak = 0
+		case node.vNodeStreak <= -rounds && len(node.keys) < maxVNodeScale*r.config.Replicas:
+			changes = append(changes, Op{Type: OpAddVNode, Node: node.id})
+			node.vNodeStreak = 0
+		}
+	}
+	return changes
+}
+
+// AddVNode gives a physical node one more vnode, which takes over the keys on its arc. The vnode is placed
+// at the node's first salted position not in use, so replaying the change places it in the same spot.
+func (r *Ring) AddVNode(nodeID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.addVNodeOp(nodeID)
+}
+
+// addVNodeOp gives a node one more vnode as one logged operation (assuming the tree's writer lock is held).
+func (r *Ring) addVNodeOp(nodeID string) error {
+	r.settleRemaps()
+	r.beginOp()
+	return r.logOp(Op{Type: OpAddVNode, Node: nodeID}, r.addVNode(nodeID))
+}
+
+// addVNode gives a node one more vnode (assuming the tree's writer lock is held).
+func (r *Ring) addVNode(nodeID string) error {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	ring.Lock()
+	defer ring.Unlock()
+	defer ring.publish()
+	for i := 0; ; i++ {
+		vNodeHash := hash(node.id, i)
+		if _, exists := node.keys[vNodeHash]; exists || !ring.circle.Insert(vNodeHash, node.id) {
+			continue
+		}
+		ring.circle.Sort()
+		node.keys[vNodeHash] = make(keySet)
+		if err := ring.remapKeys(node, vNodeHash); err != nil {
+			return err
+		}
+		r.logf("Added vnode %d to node %s.\n", vNodeHash, node.id)
+		ring.emit(Event{Type: VNodesChanged, RingID: ring.id, NodeID: node.id, Level: ring.level})
+		return nil
+	}
+}
+
+// RemoveVNode takes one vnode from a physical node, the one holding the most load, and moves its keys to
+// the next vnode clockwise. A node keeps at least one vnode.
+func (r *Ring) RemoveVNode(nodeID string) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	return r.removeVNodeOp(nodeID)
+}
+
+// removeVNodeOp takes one vnode from a node as one logged operation (assuming the tree's writer lock is
+// held).
+func (r *Ring) removeVNodeOp(nodeID string) error {
+	r.settleRemaps()
+	r.beginOp()
+	return r.logOp(Op{Type: OpRemoveVNode, Node: nodeID}, r.removeVNodeOf(nodeID))
+}
+
+// removeVNodeOf takes the most loaded vnode from a node (assuming the tree's writer lock is held).
+func (r *Ring) removeVNodeOf(nodeID string) error {
+	node, ring := r.findMember(nodeID)
+	if node == nil {
+		return ErrNodeNotFound
+	}
+	ring.Lock()
+	defer ring.Unlock()
+	defer ring.publish()
+	if len(node.keys) < 2 {
+		return errors.New("node has a single vnode")
+	}
+
+	// Most loaded first, ties by hash, so replaying the change removes the same vnode
+	loads := make(map[uint32]int, len(node.keys))
+	vNodeHashes := make([]uint32, 0, len(node.keys))
+	for vNodeHash, keys := range node.keys {
+		for key := range keys {
+			loads[vNodeHash] += node.cost(key)
+		}
+		vNodeHashes = append(vNodeHashes, vNodeHash)
+	}
+	sort.Slice(vNodeHashes, func(i, j int) bool {
+		a, b := vNodeHashes[i], vNodeHashes[j]
+		return loads[a] > loads[b] || loads[a] == loads[b] && a < b
+	})
+	for _, vNodeHash := range vNodeHashes {
+		// Vnodes followed by a subring are kept
+		if ring.removeVNode(node, vNodeHash) {
+			r.logf("Removed vnode %d from node %s.\n", vNodeHash, node.id)
+			ring.emit(Event{Type: VNodesChanged, RingID: ring.id, NodeID: node.id, Level: ring.level})
+			return nil
+		}
+	}
+	return errors.New("every vnode of the node is followed by a subring")
+}
+
+// RunAdaptiveVNodes runs a round of the vnode controller every interval until ctx is done. Failed rounds
+// are logged and retried on the next tick.
+func (r *Ring) RunAdaptiveVNodes(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if changed, err := r.AdjustVNodes(); err != nil {
+				r.logf("Vnode controller round failed: %v.\n", err)
+			} else if changed > 0 {
+				r.logf("Vnode controller changed %d vnodes.\n", changed)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
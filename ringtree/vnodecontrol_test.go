@@ -0,0 +1,101 @@
+package ringtree
+
+import "testing"
+
+func TestAdjustVNodes(t *testing.T) {
+	rt := New(8, WithAdaptiveVNodes(1.3, 2))
+	for _, id := range []string{"A", "B", "C", "D"} {
+		rt.InsertNode(NewNode(id, 100000))
+	}
+	// Node A claims twice its share of the ring and B half of it
+	for i := 0; i < defaultReplicas; i++ {
+		if err := rt.AddVNode("A"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < defaultReplicas/2; i++ {
+		if err := rt.RemoveVNode("B"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 4000; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+	a, b := mustNode(t, rt, "A"), mustNode(t, rt, "B")
+	checkNum(len(a.keys), 2*defaultReplicas, t)
+	checkNum(len(b.keys), defaultReplicas/2, t)
+	events, cancel := rt.Watch(64)
+	defer cancel()
+
+	// The first round only starts the streaks
+	if changed, err := rt.AdjustVNodes(); err != nil || changed != 0 {
+		t.Fatalf("expected no change on the first round, got %d (%v)", changed, err)
+	}
+	changed, err := rt.AdjustVNodes()
+	if err != nil || changed != 2 {
+		t.Fatalf("expected A and B to change a vnode each on the second round, got %d (%v)", changed, err)
+	}
+	checkNum(len(a.keys), 2*defaultReplicas-1, t)
+	checkNum(len(b.keys), defaultReplicas/2+1, t)
+	var types []EventType
+	for _, batch := range [][]Event{<-events, <-events} {
+		for _, event := range batch {
+			types = append(types, event.Type)
+		}
+	}
+	if len(types) != 2 || types[0] != VNodesChanged || types[1] != VNodesChanged {
+		t.Errorf("expected two VNodesChanged events, got %v", types)
+	}
+
+	before := float64(a.load) / float64(b.load)
+	for i := 0; i < 40; i++ {
+		if _, err := rt.AdjustVNodes(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if after := float64(a.load) / float64(b.load); after >= before {
+		t.Errorf("expected the gap between A and B to narrow, got %.2f, then %.2f", before, after)
+	}
+	checkNum(rt.stats.numKeys.get(), 4000, t)
+	checkValid(rt, t)
+}
+
+func TestAdjustVNodesReplay(t *testing.T) {
+	rt := New(8, WithAdaptiveVNodes(1.2, 1))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 100000))
+	}
+	for i := 0; i < defaultReplicas; i++ {
+		rt.AddVNode("A")
+	}
+	for i := 0; i < 2000; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	// Rounds tried on a shadow are logged as vnode changes, which replay the same way on the live tree
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := shadow.AdjustVNodes(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(shadow.Plan()) == 0 {
+		t.Fatal("expected the rounds to change vnodes")
+	}
+	if err := shadow.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if d := Diff(shadow.Ring, rt); !d.Unchanged() {
+		t.Errorf("expected the live tree to match the shadow, got %+v", d)
+	}
+	checkNum(len(mustNode(t, rt, "A").keys), len(mustNode(t, shadow.Ring, "A").keys), t)
+
+	if err := New(4).RemoveVNode("A"); err != ErrNodeNotFound {
+		t.Errorf("expected ErrNodeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package ringtree
+
+import "sort"
+
+// VNodeLoad is the number of keys held by one virtual node.
+type VNodeLoad struct {
+	Hash uint32 `json:"hash"` // Position of the vnode on its ring
+	Keys int    `json:"keys"` // Keys placed on the vnode
+}
+
+// NodeVNodes reports how the keys of one physical node are spread over its virtual nodes.
+type NodeVNodes struct {
+	NodeID    string      `json:"node"`
+	RingID    string      `json:"ring"`
+	Level     int         `json:"level"`
+	Keys      int         `json:"keys"`
+	Mean      float64     `json:"mean"`      // Mean keys per vnode
+	Stdev     float64     `json:"stdev"`     // Standard deviation of the keys per vnode
+	Imbalance Imbalance   `json:"imbalance"` // Skew of the keys over the vnodes
+	Hottest   []VNodeLoad `json:"hottest"`   // Vnodes holding the most keys, most first
+	Coldest   []VNodeLoad `json:"coldest"`   // Vnodes holding the fewest keys, fewest first
+}
+
+// VNodeLoads returns the key count of every vnode of the node, in ring order (assuming its ring's mutex
+// is already locked).
+func (n *Node) VNodeLoads() []VNodeLoad {
+	loads := make([]VNodeLoad, 0, len(n.keys))
+	for vNodeHash, keys := range n.keys {
+		loads = append(loads, VNodeLoad{Hash: vNodeHash, Keys: len(keys)})
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Hash < loads[j].Hash })
+	return loads
+}
+
+// VNodeReport reports the spread of keys over the vnodes of every physical node, with the top hottest and
+// coldest vnodes of each. Nodes are listed by level, then by ID. Clumped hashes show up as a hot vnode far
+// above the mean; a high Gini coefficient across the board suggests raising the vnode count.
+func (r *Ring) VNodeReport(top int) []NodeVNodes {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+
+	var report []NodeVNodes
+	var walk func(ring *Ring)
+	walk = func(ring *Ring) {
+		ring.RLock()
+		for _, member := range ring.members {
+			if node, ok := member.(*Node); ok {
+				report = append(report, node.vnodeReport(ring, top))
+			}
+		}
+		ring.RUnlock()
+		for _, subring := range ring.subrings() {
+			walk(subring)
+		}
+	}
+	walk(r.root())
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Level != report[j].Level {
+			return report[i].Level < report[j].Level
+		}
+		return report[i].NodeID < report[j].NodeID
+	})
+	return report
+}
+
+// vnodeReport summarizes the vnode loads of the node (assuming its ring's mutex is already locked).
+func (n *Node) vnodeReport(ring *Ring, top int) NodeVNodes {
+	vnodes := n.VNodeLoads()
+	counts := make([]int, len(vnodes))
+	for i, vnode := range vnodes {
+		counts[i] = vnode.Keys
+	}
+	mean, _, stdev := calculateStats(counts)
+	report := NodeVNodes{NodeID: n.id, RingID: ring.id, Level: ring.level, Keys: sum(counts), Mean: mean, Stdev: stdev, Imbalance: calculateImbalance(counts)}
+
+	// Ties keep ring order, so the report is stable between runs.
+	sort.SliceStable(vnodes, func(i, j int) bool { return vnodes[i].Keys > vnodes[j].Keys })
+	if top > len(vnodes) {
+		top = len(vnodes)
+	}
+	report.Hottest = append([]VNodeLoad(nil), vnodes[:top]...)
+	for i := len(vnodes) - 1; i >= len(vnodes)-top; i-- {
+		report.Coldest = append(report.Coldest, vnodes[i])
+	}
+	return report
+}
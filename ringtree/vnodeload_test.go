@@ -0,0 +1,44 @@
+package ringtree
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVNodeReport(t *testing.T) {
+	rt := New(4, WithReplicas(8))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+	for i := 0; i < 400; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	report := rt.VNodeReport(3)
+	if len(report) != 2 || report[0].NodeID != "A" || report[1].NodeID != "B" {
+		t.Fatalf("got %+v, want nodes A and B", report)
+	}
+	total := 0
+	for _, node := range report {
+		total += node.Keys
+		if len(node.Hottest) != 3 || len(node.Coldest) != 3 {
+			t.Fatalf("node %s: got %d hottest and %d coldest vnodes, want 3 each", node.NodeID, len(node.Hottest), len(node.Coldest))
+		}
+		if node.Hottest[0].Keys < node.Hottest[2].Keys || node.Coldest[0].Keys > node.Coldest[2].Keys {
+			t.Errorf("node %s: hottest %v or coldest %v out of order", node.NodeID, node.Hottest, node.Coldest)
+		}
+		if node.Hottest[0].Keys < node.Coldest[0].Keys || float64(node.Hottest[0].Keys) < node.Mean {
+			t.Errorf("node %s: hottest vnode %v below coldest %v or the mean %.2f", node.NodeID, node.Hottest[0], node.Coldest[0], node.Mean)
+		}
+	}
+	if total != 400 {
+		t.Errorf("got %d keys over all vnodes, want 400", total)
+	}
+
+	node, _ := rt.findMember("A")
+	if loads := node.VNodeLoads(); len(loads) != 8 {
+		t.Errorf("got %d vnode loads for A, want 8", len(loads))
+	}
+	if wide := rt.VNodeReport(100); len(wide[0].Hottest) != 8 {
+		t.Errorf("got %d hottest vnodes with a large top, want all 8", len(wide[0].Hottest))
+	}
+}
@@ -0,0 +1,307 @@
+package ringtree
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrWAL is returned, wrapping the cause, when an operation was applied but could not be logged.
+var ErrWAL = errors.New("error appending to write-ahead log")
+
+// OpType identifies the operation recorded by a log entry.
+type OpType string
+
+const (
+	OpInsertKey    OpType = "insert-key"
+	OpRemoveKey    OpType = "remove-key"
+	OpInsertNode   OpType = "insert-node"
+	OpRemoveNode   OpType = "remove-node"
+	OpSplit        OpType = "split"
+	OpCollapse     OpType = "collapse"
+	OpSetThreshold OpType = "set-threshold"
+	OpAddVNode     OpType = "add-vnode"
+	OpRemoveVNode  OpType = "remove-vnode"
+	OpRemoveKeys   OpType = "remove-keys"
+	OpInsertKeys   OpType = "insert-keys"
+	OpLoadKeys     OpType = "load-keys"
+	OpInsertNodes  OpType = "insert-nodes"
+	OpMergeNodes   OpType = "merge-nodes"
+	OpRebalance    OpType = "rebalance"
+	OpSetState     OpType = "set-state"
+	OpMaintenance  OpType = "maintenance"
+	OpPinKey       OpType = "pin-key"
+	OpMoveKey      OpType = "move-key"
+	OpUnpinKey     OpType = "unpin-key"
+)
+
+// Op is one entry of the write-ahead log.
+type Op struct {
+	Seq        uint64        `json:"seq"`                  // Position of the operation in the tree's history
+	Type       OpType        `json:"type"`                 // Operation applied
+	Ring       string        `json:"ring"`                 // Ring the operation was called on
+	Key        string        `json:"key,omitempty"`        // Key inserted, removed, pinned or moved
+	Keys       []string      `json:"keys,omitempty"`       // Keys inserted or removed together
+	Value      []byte        `json:"value,omitempty"`      // Value the key's load was measured from
+	Node       string        `json:"node,omitempty"`       // Node inserted, removed, split or a key is pinned to, or subring collapsed
+	Threshold  int           `json:"threshold,omitempty"`  // Threshold of an inserted node
+	State      NodeState     `json:"state,omitempty"`      // State set on a node, or of an inserted node
+	Start      *time.Time    `json:"start,omitempty"`      // Start of a maintenance window
+	Duration   time.Duration `json:"duration,omitempty"`   // Length of a maintenance window
+	Nodes      []string      `json:"nodes,omitempty"`      // Nodes inserted together, or merged
+	Thresholds []int         `json:"thresholds,omitempty"` // Thresholds of the nodes inserted together
+	IDs        []string      `json:"ids,omitempty"`        // IDs of the nodes the operation created on its own
+	Failed     bool          `json:"failed,omitempty"`     // Whether the operation failed after changing the tree
+}
+
+// WAL is an append-only log of ring operations. Append must not return before the entry is durable.
+type WAL interface {
+	Append(op Op) error
+}
+
+// walState numbers logged operations and records the IDs of nodes created by splits and overflowing keys,
+// which are random, so a replay recreates the same nodes (guarded by the tree's writer lock).
+type walState struct {
+	seq       uint64
+	generated []string // IDs created by the current operation
+	applied   bool     // Whether the current batch operation changed the tree before it failed
+	replay    []string // IDs to hand out instead of random ones during a replay
+	replaying bool
+	ids       *rand.Rand // Source of generated IDs when RandSource is set
+
+	dir        string    // Directory of the snapshot and log of a tree opened with Open
+	sinceCheck int       // Operations logged since the last checkpoint
+	checked    time.Time // Time of the last checkpoint
+}
+
+// WithWAL appends the operations that change the tree's nodes and keys to w: single and batch key inserts
+// and removals, node inserts and removals (including batched joins, prepared additions and quorum
+// removals), splits, collapses, merges, rebalancing, threshold changes, vnode adjustments, node states,
+// maintenance windows, pins and key moves. Each is logged after it is applied and before it returns, so an
+// operation that returned is in the log. Topology freezes and custom members are not logged.
+func WithWAL(w WAL) Option {
+	return func(c *Config) {
+		c.WAL = w
+	}
+}
+
+// newNodeID returns the ID of a node the tree creates on its own (assuming the tree's writer lock is held).
+func (r *Ring) newNodeID() string {
+	s := r.wal
+	var id string
+	if len(s.replay) > 0 {
+		id, s.replay = s.replay[0], s.replay[1:]
+	} else {
+		id = NodeID(s.ids)
+	}
+	s.generated = append(s.generated, id)
+	return id
+}
+
+// beginOp starts recording the node IDs an operation creates (assuming the tree's writer lock is held).
+func (r *Ring) beginOp() {
+	r.wal.generated = r.wal.generated[:0]
+	r.wal.applied = false
+}
+
+// logOp appends an applied operation to the configured WAL and returns the operation's error. A failed
+// operation is only logged if it created nodes or applied part of a batch, and so may have changed the tree
+// (assuming the tree's writer lock is held).
+func (r *Ring) logOp(op Op, err error) error {
+	s := r.wal
+	if r.config.WAL == nil || s.replaying || (err != nil && len(s.generated) == 0 && !s.applied) {
+		return err
+	}
+	s.seq++
+	op.Seq, op.Ring = s.seq, r.id
+	op.IDs = append([]string(nil), s.generated...)
+	op.Failed = err != nil
+	if appendErr := r.config.WAL.Append(op); appendErr != nil {
+		if err == nil {
+			err = fmt.Errorf("%w: %v", ErrWAL, appendErr)
+		}
+		return err
+	}
+	s.sinceCheck++
+	if r.checkpointDue() {
+		if cerr := r.checkpoint(); cerr != nil {
+			r.logf("Checkpoint failed: %v.\n", cerr)
+		}
+	}
+	return err
+}
+
+// ReplayWAL applies the operations of a log written by a tree with the same options, reconstructing its
+// state. Operations already included in the tree, such as those before the snapshot it was restored from,
+// are skipped, as are operations that failed when they were logged and fail again; any other failure stops
+// the replay. A final entry cut short by a crash is ignored.
+func (r *Ring) ReplayWAL(rd io.Reader) error {
+	br := bufio.NewReader(rd)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// An entry without its newline was torn by a crash while being appended
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var op Op
+		if err := json.Unmarshal(line, &op); err != nil {
+			return fmt.Errorf("error decoding log entry: %v", err)
+		}
+		if err := r.apply(op); err != nil && !op.Failed {
+			return fmt.Errorf("error replaying operation %d (%s): %w", op.Seq, op.Type, err)
+		}
+	}
+}
+
+// apply applies a logged operation, handing out the node IDs it created when it was logged.
+func (r *Ring) apply(op Op) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	s := r.wal
+	if op.Seq <= s.seq {
+		return nil
+	}
+	s.replaying, s.seq = true, op.Seq
+	defer func() {
+		s.replaying = false
+	}()
+	return r.applyOp(op)
+}
+
+// applyOp applies an operation to the ring it was called on, handing out the node IDs it created when it
+// was logged. The operation is logged again unless a replay is in progress (assuming the tree's writer lock
+// is held).
+func (r *Ring) applyOp(op Op) error {
+	s := r.wal
+	s.replay = op.IDs
+	defer func() {
+		s.replay = nil
+	}()
+
+	ring := r.root().findRing(op.Ring)
+	if ring == nil {
+		return fmt.Errorf("operation on unknown ring %s", op.Ring)
+	}
+	var err error
+	switch op.Type {
+	case OpInsertKey:
+		err = ring.insertKeyOp(op.Key, op.Value)
+	case OpRemoveKey:
+		err = ring.removeKeyOp(op.Key)
+	case OpInsertNode:
+		node := NewNode(op.Node, op.Threshold)
+		node.state = op.State
+		err = ring.insertNodeOp(node)
+	case OpRemoveNode:
+		err = ring.removeNodeOp(op.Node)
+	case OpSplit:
+		_, err = ring.splitOp(op.Node)
+	case OpCollapse:
+		_, err = ring.collapseOp(op.Node)
+	case OpSetThreshold:
+		err = ring.setNodeThresholdOp(op.Node, op.Threshold)
+	case OpAddVNode:
+		err = ring.addVNodeOp(op.Node)
+	case OpRemoveVNode:
+		err = ring.removeVNodeOp(op.Node)
+	case OpRemoveKeys:
+		var results []KeyResult
+		results, err = ring.removeKeys(op.Keys)
+		for _, result := range results {
+			if err == nil {
+				err = result.Err
+			}
+		}
+	case OpInsertKeys:
+		err = ring.insertKeysOp(op.Keys)
+	case OpLoadKeys:
+		err = ring.loadPartitionOp(op.Keys)
+	case OpInsertNodes:
+		if len(op.Thresholds) != len(op.Nodes) {
+			return fmt.Errorf("got %d thresholds for %d nodes", len(op.Thresholds), len(op.Nodes))
+		}
+		nodes := make([]*Node, len(op.Nodes))
+		for i, id := range op.Nodes {
+			nodes[i] = NewNode(id, op.Thresholds[i])
+		}
+		err = ring.insertNodesOp(nodes)
+	case OpMergeNodes:
+		if len(op.Nodes) != 2 {
+			return fmt.Errorf("got %d nodes to merge, want 2", len(op.Nodes))
+		}
+		err = ring.mergeNodesOp(op.Nodes[0], op.Nodes[1])
+	case OpRebalance:
+		err = ring.rebalanceOp()
+	case OpSetState:
+		err = ring.setNodeStateOp(op.Node, op.State)
+	case OpMaintenance:
+		if op.Start == nil {
+			return errors.New("maintenance window without a start")
+		}
+		if !op.Start.Add(op.Duration).After(time.Now()) {
+			return nil // The window is over, and the keys written past the node have moved back
+		}
+		err = ring.scheduleMaintenanceOp(op.Node, *op.Start, op.Duration)
+	case OpPinKey:
+		err = ring.pinKeyOp(op.Key, op.Node)
+	case OpMoveKey:
+		err = ring.moveKeyOp(op.Key, op.Node)
+	case OpUnpinKey:
+		err = ring.unpinKeyOp(op.Key)
+	default:
+		err = fmt.Errorf("unknown operation type %q", op.Type)
+	}
+	return err
+}
+
+// FileWAL is a WAL appending one JSON line per operation to a file, synced after every append.
+type FileWAL struct {
+	file *os.File
+	sync.Mutex
+}
+
+// OpenWAL opens or creates the log file at path for appending.
+func OpenWAL(path string) (*FileWAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWAL{file: file}, nil
+}
+
+// Append writes an operation to the file and syncs it to disk.
+func (w *FileWAL) Append(op Op) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	w.Lock()
+	defer w.Unlock()
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Truncate empties the log file, once a checkpoint has made its entries redundant.
+func (w *FileWAL) Truncate() error {
+	w.Lock()
+	defer w.Unlock()
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// Close closes the log file.
+func (w *FileWAL) Close() error {
+	return w.file.Close()
+}
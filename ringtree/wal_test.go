@@ -0,0 +1,286 @@
+package ringtree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReplayWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring.wal")
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := New(4, WithWAL(wal))
+	rt.InsertNode(NewNode("A", 20))
+	rt.InsertNode(NewNode("B", 20))
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range keys[:50] {
+		if err := rt.RemoveKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := rt.NodeIDs()
+	if len(ids) < 3 {
+		t.Fatalf("got %d nodes, want overflowing keys to have added nodes", len(ids))
+	}
+	if _, err := rt.Split(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.Collapse(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.RemoveNodeByID(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.SetNodeThreshold(ids[2], 40); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.RemoveKey("missing"); err == nil {
+		t.Fatal("expected removing a missing key to fail")
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	replayed := New(4)
+	if err := replayed.ReplayWAL(f); err != nil {
+		t.Fatal(err)
+	}
+	if !replayed.Digest().Equal(rt.Digest()) {
+		t.Fatal("expected the replayed tree to have the same topology")
+	}
+	for _, key := range keys {
+		want, wantErr := rt.Lookup(key)
+		got, err := replayed.Lookup(key)
+		if got != want || (err == nil) != (wantErr == nil) {
+			t.Fatalf("%s: got %q (%v) after replay, want %q (%v)", key, got, err, want, wantErr)
+		}
+	}
+	if node, err := replayed.Node(ids[2]); err != nil || node.Threshold() != 40 {
+		t.Fatalf("expected node %s to have threshold 40 after replay", ids[2])
+	}
+	if replayed.wal.seq != rt.wal.seq {
+		t.Fatalf("got sequence %d after replay, want %d", replayed.wal.seq, rt.wal.seq)
+	}
+}
+
+// bufferWAL is a WAL appending one JSON line per operation to a buffer.
+type bufferWAL struct {
+	bytes.Buffer
+}
+
+func (w *bufferWAL) Append(op Op) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	w.Write(append(data, '\n'))
+	return nil
+}
+
+func TestReplayWALEveryMutation(t *testing.T) {
+	keys := func(prefix string, n int) []string {
+		var keys []string
+		for i := 0; i < n; i++ {
+			keys = append(keys, fmt.Sprintf("%s-%d", prefix, i))
+		}
+		return keys
+	}
+	tests := []struct {
+		name   string
+		opts   []Option
+		mutate func(t *testing.T, rt *Ring)
+	}{
+		{"insert-keys", nil, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 300)...); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"load-keys", nil, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 100)...); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.LoadKeys(keys("load", 200), 4); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"insert-nodes", []Option{WithBatchWindow(time.Hour)}, func(t *testing.T, rt *Ring) {
+			for _, id := range []string{"C", "D"} {
+				if err := rt.EnqueueNode(NewNode(id, 40)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := rt.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.InsertKeys(keys("key", 100)...); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"prepare", nil, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 30)...); err != nil {
+				t.Fatal(err)
+			}
+			pending, err := rt.PrepareInsertNode(NewNode("C", 40))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pending.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"auto-remove", []Option{WithRemovalQuorum(1, ObserverFunc(func(string) bool { return true }))}, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 30)...); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.AutoRemoveNode("B"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"lease-expiry", []Option{WithLease(time.Hour, time.Minute)}, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 30)...); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.Heartbeat("B"); err != nil {
+				t.Fatal(err)
+			}
+			expires, _ := rt.Lease("B")
+			rt.checkLeases(expires)
+			rt.checkLeases(expires.Add(time.Minute))
+			if _, err := rt.Node("B"); !errors.Is(err, ErrNodeNotFound) {
+				t.Fatal("expected node B to be drained after the grace period")
+			}
+		}},
+		{"merge", nil, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 30)...); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.MergeNodes("A", "B"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"remove-keys", nil, func(t *testing.T, rt *Ring) {
+			if err := rt.InsertKeys(keys("key", 200)...); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rt.RemoveKeysByPrefix("key-1"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"rebalance", []Option{WithBranchFactor(3), WithSeedNodes(3), WithRebalanceSkew(1.1), WithMinDwell(time.Hour)}, func(t *testing.T, rt *Ring) {
+			for _, id := range []string{"A", "B"} {
+				if _, err := rt.Split(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, key := range keys("key", 300) {
+				if err := rt.InsertKey(key); err != nil {
+					t.Fatal(err)
+				}
+				if foundWithin(t, rt, key, "B") {
+					rt.RemoveKey(key)
+				}
+			}
+			if err := rt.Rebalance(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wal := &bufferWAL{}
+			rt := New(4, append([]Option{WithWAL(wal)}, tt.opts...)...)
+			rt.InsertNode(NewNode("A", 40))
+			rt.InsertNode(NewNode("B", 40))
+			tt.mutate(t, rt)
+
+			replayed := New(4, tt.opts...)
+			if err := replayed.ReplayWAL(&wal.Buffer); err != nil {
+				t.Fatal(err)
+			}
+			if !replayed.Digest().Equal(rt.Digest()) {
+				t.Fatal("expected the replayed tree to have the same topology")
+			}
+			checkNum(replayed.Stats().Keys(), rt.Stats().Keys(), t)
+			checkNum(replayed.Stats().Nodes(), rt.Stats().Nodes(), t)
+			rt.AllKeys()(func(key string) bool {
+				want, _ := rt.Lookup(key)
+				if got, err := replayed.Lookup(key); got != want {
+					t.Fatalf("%s: got %q (%v) after replay, want %q", key, got, err, want)
+				}
+				return true
+			})
+			checkValid(replayed, t)
+		})
+	}
+}
+
+func TestReplayWALStatesAndPins(t *testing.T) {
+	wal := &bufferWAL{}
+	rt := New(4, WithWAL(wal))
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 1000))
+	}
+	for i := 0; i < 100; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+	steps := []func() error{
+		func() error { return rt.SetNodeState("A", Draining) },
+		func() error { return rt.ScheduleMaintenance("B", time.Now(), time.Hour) },
+		func() error { return rt.PinKey("key-1", "C") },
+		func() error { return rt.PinKey("key-2", "C") },
+		func() error { return rt.UnpinKey("key-2") },
+		func() error { return rt.MoveKey("key-3", "C") },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+	// Writes made now avoid the draining and maintained nodes, as they must after a replay
+	for i := 100; i < 200; i++ {
+		rt.InsertKey(fmt.Sprintf("key-%d", i))
+	}
+
+	replayed := New(4)
+	if err := replayed.ReplayWAL(&wal.Buffer); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"A", "B", "C"} {
+		if got, want := mustNode(t, replayed, id).State(), mustNode(t, rt, id).State(); got != want {
+			t.Errorf("node %s: got state %v after replay, want %v", id, got, want)
+		}
+	}
+	for _, key := range []string{"key-1", "key-2", "key-3"} {
+		want, _ := rt.pins.get(key)
+		if got, _ := replayed.pins.get(key); got != want {
+			t.Errorf("%s: got pin %+v after replay, want %+v", key, got, want)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		want, _ := rt.Lookup(key)
+		if got, err := replayed.Lookup(key); got != want {
+			t.Fatalf("%s: got %q (%v) after replay, want %q", key, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ringtree
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// SkipRing is returned by a WalkFunc visiting a ring to skip the ring's members. It is not returned by Walk.
+var SkipRing = errors.New("skip this ring")
+
+// SkipAll is returned by a WalkFunc to stop the walk without an error. Visits already running finish.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// WalkFunc is called by Walk for each physical node, and with WalkRings for each ring, of the tree. ring is
+// the ring holding the member, or nil for the ring Walk was called on.
+type WalkFunc func(ctx context.Context, member Member, ring *Ring) error
+
+type walkConfig struct {
+	parallelism int
+	rings       bool
+}
+
+// WalkOption configures Walk.
+type WalkOption func(*walkConfig)
+
+// WithWalkParallelism visits up to n members at once. With the default of one, members are visited in
+// order: a ring before its members, and the members of a ring by ID.
+func WithWalkParallelism(n int) WalkOption {
+	return func(c *walkConfig) {
+		if n > 0 {
+			c.parallelism = n
+		}
+	}
+}
+
+// WithWalkRings also calls the WalkFunc for every ring, starting with the one Walk was called on, before
+// its members. Returning SkipRing from such a call skips the ring's members.
+func WithWalkRings(enabled bool) WalkOption {
+	return func(c *walkConfig) {
+		c.rings = enabled
+	}
+}
+
+// Walk calls fn for every physical node below the ring, and with WithWalkRings for every ring, and returns
+// once every call has returned. Custom members are not visited. The first error fn returns, or the
+// context's error once it is done, cancels the context passed to fn, stops further visits and is returned;
+// SkipAll stops the walk and makes Walk return nil.
+//
+// Walk takes no lock while fn runs, so fn may read and mutate the tree. Each ring's members are read as the
+// walk reaches the ring, so members added behind the walk are missed.
+func (r *Ring) Walk(ctx context.Context, fn WalkFunc, opts ...WalkOption) error {
+	cfg := walkConfig{parallelism: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &walker{fn: fn, cfg: cfg, sem: make(chan struct{}, cfg.parallelism), cancel: cancel}
+
+	if cfg.parallelism == 1 {
+		w.walk(ctx, r, nil)
+	} else {
+		w.wg.Add(1)
+		go w.visit(ctx, r, nil)
+		w.wg.Wait()
+	}
+	if errors.Is(w.err, SkipAll) {
+		return nil
+	}
+	return w.err
+}
+
+// walker holds the state of one walk.
+type walker struct {
+	fn     WalkFunc
+	cfg    walkConfig
+	sem    chan struct{} // Holds a token for each visit running
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error // First error, which ends the walk
+}
+
+// fail records the error ending the walk, unless one was recorded already.
+func (w *walker) fail(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+	w.cancel()
+}
+
+// call runs fn on a member, and reports whether the walk should descend into it.
+func (w *walker) call(ctx context.Context, member Member, ring *Ring) bool {
+	if err := ctx.Err(); err != nil {
+		w.fail(err)
+		return false
+	}
+	switch err := w.fn(ctx, member, ring); {
+	case err == nil:
+		return true
+	case errors.Is(err, SkipRing):
+		return false
+	default:
+		w.fail(err)
+		return false
+	}
+}
+
+// walk visits a member and everything below it in order.
+func (w *walker) walk(ctx context.Context, member Member, ring *Ring) {
+	sub, isRing := member.(*Ring)
+	if !isRing {
+		w.call(ctx, member, ring)
+		return
+	}
+	if w.cfg.rings && !w.call(ctx, sub, ring) {
+		return
+	}
+	for _, m := range sub.sortedMembers() {
+		if err := ctx.Err(); err != nil {
+			w.fail(err)
+			return
+		}
+		w.walk(ctx, m, sub)
+	}
+}
+
+// visit visits a member once a token is free, then starts a visit of each of a ring's members.
+func (w *walker) visit(ctx context.Context, member Member, ring *Ring) {
+	defer w.wg.Done()
+	select {
+	case w.sem <- struct{}{}:
+	case <-ctx.Done():
+		w.fail(ctx.Err())
+		return
+	}
+	sub, isRing := member.(*Ring)
+	descend := false
+	if !isRing {
+		w.call(ctx, member, ring)
+	} else {
+		descend = !w.cfg.rings || w.call(ctx, sub, ring)
+	}
+	<-w.sem
+	if !descend {
+		return
+	}
+	for _, m := range sub.sortedMembers() {
+		w.wg.Add(1)
+		go w.visit(ctx, m, sub)
+	}
+}
+
+// sortedMembers returns the nodes and subrings of the ring in ID order.
+func (r *Ring) sortedMembers() []Member {
+	r.RLock()
+	defer r.RUnlock()
+	members := make([]Member, 0, len(r.members))
+	for _, member := range r.members {
+		switch member.(type) {
+		case *Node, *Ring:
+			members = append(members, member)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID() < members[j].ID() })
+	return members
+}
@@ -0,0 +1,134 @@
+package ringtree
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWalk(t *testing.T) {
+	rt, _ := buildTree(t, 3, []string{"A"}, 5, 200)
+	if rt.GetDepth() < 1 {
+		t.Fatal("expected the tree to have subrings")
+	}
+	ids := rt.NodeIDs()
+	var visited []string
+	rings := 0
+	err := rt.Walk(context.Background(), func(_ context.Context, member Member, ring *Ring) error {
+		switch member := member.(type) {
+		case *Node:
+			if ring.members[member.id] != member {
+				t.Errorf("node %s visited with the wrong ring %s", member.id, ring.id)
+			}
+			visited = append(visited, member.id)
+		case *Ring:
+			if member.parent != ring {
+				t.Errorf("ring %s visited with the wrong parent", member.id)
+			}
+			rings++
+		}
+		return nil
+	}, WithWalkRings(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(visited)
+	if len(visited) != len(ids) {
+		t.Errorf("visited %d nodes, want %d", len(visited), len(ids))
+	}
+	if want := rt.Counters().Rings; rings != want {
+		t.Errorf("visited %d rings, want %d", rings, want)
+	}
+}
+
+func TestWalkParallel(t *testing.T) {
+	rt, _ := buildTree(t, 3, []string{"A"}, 5, 200)
+	if rt.GetDepth() < 1 {
+		t.Fatal("expected the tree to have subrings")
+	}
+	ids := rt.NodeIDs()
+	var visited, running, peak atomic.Int64
+	err := rt.Walk(context.Background(), func(_ context.Context, member Member, _ *Ring) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		if _, ok := member.(*Node); ok {
+			visited.Add(1)
+		}
+		return nil
+	}, WithWalkParallelism(3), WithWalkRings(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(visited.Load()) != len(ids) {
+		t.Errorf("visited %d nodes, want %d", visited.Load(), len(ids))
+	}
+	if peak.Load() > 3 {
+		t.Errorf("ran %d visits at once, want at most 3", peak.Load())
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	rt, _ := buildTree(t, 3, []string{"A"}, 5, 200)
+	if rt.GetDepth() < 1 {
+		t.Fatal("expected the tree to have subrings")
+	}
+	onRoot := 0
+	for _, member := range rt.members {
+		if _, ok := member.(*Node); ok {
+			onRoot++
+		}
+	}
+	for _, parallelism := range []int{1, 4} {
+		// SkipRing on every subring leaves only the root's nodes
+		var mu sync.Mutex
+		var visited []string
+		err := rt.Walk(context.Background(), func(_ context.Context, member Member, ring *Ring) error {
+			if member.Kind() == KindRing && ring != nil {
+				return SkipRing
+			}
+			if member.Kind() == KindNode {
+				mu.Lock()
+				visited = append(visited, member.ID())
+				mu.Unlock()
+			}
+			return nil
+		}, WithWalkRings(true), WithWalkParallelism(parallelism))
+		if err != nil || len(visited) != onRoot {
+			t.Errorf("parallelism %d: got %v after visiting %d nodes, want the root's %d", parallelism, err, len(visited), onRoot)
+		}
+
+		var calls atomic.Int64
+		err = rt.Walk(context.Background(), func(context.Context, Member, *Ring) error {
+			calls.Add(1)
+			return SkipAll
+		}, WithWalkParallelism(parallelism))
+		if err != nil || int(calls.Load()) > parallelism {
+			t.Errorf("parallelism %d: SkipAll returned %v after %d calls", parallelism, err, calls.Load())
+		}
+
+		boom := errors.New("boom")
+		err = rt.Walk(context.Background(), func(context.Context, Member, *Ring) error {
+			return boom
+		}, WithWalkParallelism(parallelism))
+		if !errors.Is(err, boom) {
+			t.Errorf("parallelism %d: got %v, want the visit's error", parallelism, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = rt.Walk(ctx, func(context.Context, Member, *Ring) error {
+			t.Error("unexpected visit after the context was cancelled")
+			return nil
+		}, WithWalkParallelism(parallelism))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("parallelism %d: got %v, want context.Canceled", parallelism, err)
+		}
+	}
+}
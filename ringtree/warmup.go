@@ -0,0 +1,108 @@
+package ringtree
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// WithWarmUp makes nodes inserted with InsertNode, JoinNode, EnqueueNode or PrepareInsertNode start with
+// fraction of their vnodes, at least one, so a node whose cache is cold takes only part of its share of the
+// keys and their traffic. The node gains the rest of its vnodes evenly over d as AdvanceWarmUp runs. A d
+// of 0 disables the ramp.
+func WithWarmUp(fraction float64, d time.Duration) Option {
+	return func(c *Config) {
+		c.WarmUpFraction = fraction
+		c.WarmUp = d
+	}
+}
+
+// warmUpVNodes returns the vnodes a node should have once it has warmed up for elapsed: its starting share
+// of Replicas, growing evenly to all of them over WarmUp.
+func (c *Config) warmUpVNodes(elapsed time.Duration) int {
+	start := min(max(int(math.Ceil(c.WarmUpFraction*float64(c.Replicas))), 1), c.Replicas)
+	if elapsed >= c.WarmUp {
+		return c.Replicas
+	}
+	return start + int(float64(c.Replicas-start)*float64(elapsed)/float64(c.WarmUp))
+}
+
+// startWarmUp starts the ramp of a node about to be inserted, if WarmUp is set.
+func (c *Config) startWarmUp(node *Node) {
+	if c.WarmUp > 0 {
+		node.warmingAt = time.Now()
+	}
+}
+
+// warming reports whether the node is still ramping up to all of its vnodes.
+func (n *Node) warming() bool {
+	return !n.warmingAt.IsZero()
+}
+
+// AdvanceWarmUp gives every warming node the vnodes its ramp has reached by now, each taking over the keys
+// on its arc. Vnodes are added at the node's salted positions in order, so a warmed node ends up where an
+// insert without the ramp would have placed it, and each addition is logged to replay the same way. It
+// returns the number of vnodes added.
+func (r *Ring) AdvanceWarmUp() (int, error) {
+	if r.config.WarmUp <= 0 {
+		return 0, nil
+	}
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	r.settleRemaps()
+
+	var warming []string
+	root := r.root()
+	root.Lock()
+	root.forEachNode(func(node *Node) {
+		if node.warming() {
+			warming = append(warming, node.id)
+		}
+	})
+	root.Unlock()
+	sort.Strings(warming)
+
+	added := 0
+	now := time.Now()
+	for _, nodeID := range warming {
+		node, ring := r.findMember(nodeID)
+		if node == nil {
+			continue
+		}
+		ring.RLock()
+		target, vNodes := r.config.warmUpVNodes(now.Sub(node.warmingAt)), len(node.keys)
+		ring.RUnlock()
+		for ; vNodes < target; vNodes++ {
+			r.beginOp()
+			if err := r.logOp(Op{Type: OpAddVNode, Node: nodeID}, r.addVNode(nodeID)); err != nil {
+				return added, err
+			}
+			added++
+		}
+		if target == r.config.Replicas {
+			ring.Lock()
+			node.warmingAt = time.Time{}
+			ring.Unlock()
+			r.logf("Node %s finished warming up.\n", nodeID)
+		}
+	}
+	return added, nil
+}
+
+// RunWarmUp advances the warm-up ramp every interval until ctx is done. Failed steps are logged and retried
+// on the next tick.
+func (r *Ring) RunWarmUp(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.AdvanceWarmUp(); err != nil {
+				r.logf("Warm-up step failed: %v.\n", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ringtree
+
+import (
+	"testing"
+	"time"
+)
+
+// backdate moves the start of a node's warm-up ramp back by d.
+func backdate(t *testing.T, rt *Ring, nodeID string, d time.Duration) {
+	node := mustNode(t, rt, nodeID)
+	node.warmingAt = node.warmingAt.Add(-d)
+}
+
+func TestWarmUp(t *testing.T) {
+	rt := New(8, WithWarmUp(0.25, time.Hour))
+	cold := New(8)
+	start := rt.config.warmUpVNodes(0)
+	checkNum(start, (defaultReplicas+3)/4, t)
+	for _, id := range []string{"A", "B", "C"} {
+		rt.InsertNode(NewNode(id, 100000))
+		cold.InsertNode(NewNode(id, 100000))
+		checkNum(len(mustNode(t, rt, id).keys), start, t)
+		backdate(t, rt, id, time.Hour)
+	}
+	added, err := rt.AdvanceWarmUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNum(added, 3*(defaultReplicas-start), t)
+	for i := 0; i < 3000; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+		cold.InsertKey(key)
+	}
+
+	// A new node starts with a quarter of its vnodes and reaches about half of them halfway through
+	rt.InsertNode(NewNode("D", 100000))
+	cold.InsertNode(NewNode("D", 100000))
+	d, a := mustNode(t, rt, "D"), mustNode(t, rt, "A")
+	checkNum(len(d.keys), start, t)
+	if d.load*2 > a.load {
+		t.Errorf("expected warming D to take well under A's share, got %d and %d keys", d.load, a.load)
+	}
+	backdate(t, rt, "D", 30*time.Minute)
+	if _, err := rt.AdvanceWarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	if halfway := rt.config.warmUpVNodes(30 * time.Minute); len(d.keys) < halfway || len(d.keys) == defaultReplicas {
+		t.Errorf("expected D to have about %d vnodes halfway, got %d", halfway, len(d.keys))
+	}
+	if !d.warming() {
+		t.Error("expected D to still be warming")
+	}
+
+	// Once warm, D holds what it would have held had it been inserted without the ramp
+	backdate(t, rt, "D", time.Hour)
+	if _, err := rt.AdvanceWarmUp(); err != nil {
+		t.Fatal(err)
+	}
+	checkNum(len(d.keys), defaultReplicas, t)
+	if d.warming() {
+		t.Error("expected D to be warm")
+	}
+	if diff := Diff(rt, cold); !diff.Unchanged() {
+		t.Errorf("expected the warmed tree to match one without warm-up, got %+v", diff)
+	}
+	checkNum(rt.stats.numKeys.get(), 3000, t)
+	checkValid(rt, t)
+}
+
+func TestWarmUpReplay(t *testing.T) {
+	rt := New(8, WithWarmUp(0.5, time.Hour))
+	for _, id := range []string{"A", "B"} {
+		rt.InsertNode(NewNode(id, 100000))
+	}
+	for i := 0; i < 1000; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	// Steps of the ramp taken on a shadow are logged as vnode additions, which replay on the live tree
+	shadow, err := rt.Shadow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backdate(t, shadow.Ring, "A", time.Hour)
+	if added, err := shadow.AdvanceWarmUp(); err != nil || added == 0 {
+		t.Fatalf("expected the shadow to add vnodes, got %d (%v)", added, err)
+	}
+	if err := shadow.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(shadow.Ring, rt); !diff.Unchanged() {
+		t.Errorf("expected the live tree to match the shadow, got %+v", diff)
+	}
+	checkNum(len(mustNode(t, rt, "A").keys), defaultReplicas, t)
+
+	if added, err := New(4).AdvanceWarmUp(); err != nil || added != 0 {
+		t.Errorf("expected no warm-up without the option, got %d (%v)", added, err)
+	}
+}
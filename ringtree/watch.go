@@ -0,0 +1,202 @@
+package ringtree
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of topology change carried by an Event.
+type EventType int
+
+const (
+	NodeAdded        EventType = iota // A physical node joined a ring
+	NodeRemoved                       // A physical node left a ring
+	SubringCreated                    // An overloaded node was split into a subring
+	SubringCollapsed                  // A subring was collapsed back into a node
+	NodeStateChanged                  // A node changed health state
+	NodesMerged                       // A node was merged into an adjacent sibling
+	ReplicaPromoted                   // A replica took over the arcs of a failed primary
+	ReplicaDemoted                    // A promoted replica handed arcs back to a recovered primary
+	KeyMoved                          // A sampled key moved between nodes
+	Rebalanced                        // Load moved sideways from a subring to a sibling
+	TreesMerged                       // Another tree was merged into this one
+	VNodesChanged                     // A node gained or gave up a vnode
+)
+
+// String returns a readable name for the event type.
+func (t EventType) String() string {
+	switch t {
+	case NodeAdded:
+		return "NodeAdded"
+	case NodeRemoved:
+		return "NodeRemoved"
+	case SubringCreated:
+		return "SubringCreated"
+	case SubringCollapsed:
+		return "SubringCollapsed"
+	case NodeStateChanged:
+		return "NodeStateChanged"
+	case NodesMerged:
+		return "NodesMerged"
+	case ReplicaPromoted:
+		return "ReplicaPromoted"
+	case ReplicaDemoted:
+		return "ReplicaDemoted"
+	case KeyMoved:
+		return "KeyMoved"
+	case Rebalanced:
+		return "Rebalanced"
+	case TreesMerged:
+		return "TreesMerged"
+	case VNodesChanged:
+		return "VNodesChanged"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event describes a single topology change in the ring tree.
+type Event struct {
+	Type     EventType // Kind of change
+	RingID   string    // Ring the change happened on
+	NodeID   string    // Node (or subring) affected by the change
+	Level    int       // Level of the affected member
+	Remapped int       // Keys remapped by the change; for KeyMoved, moves since the previous sample
+	Key      string    // Key that moved, for KeyMoved events
+	From     string    // Node the key moved from, for KeyMoved events
+	Time     time.Time // When the change was applied
+}
+
+// watchHub fans topology events out to Watch subscribers, grouping events emitted inside a batch.
+type watchHub struct {
+	mu          sync.Mutex
+	subscribers map[int]chan []Event
+	nextID      int
+	depth       int     // Nesting depth of open batches
+	pending     []Event // Events collected while a batch is open
+	keyMoves    int     // Exact number of key moves, sampled or not
+	unsampled   int     // Key moves since the last sampled KeyMoved event
+	epoch       uint64  // Topology changes applied to the tree
+	history     *historyState
+}
+
+func newWatchHub() *watchHub {
+	return &watchHub{subscribers: make(map[int]chan []Event)}
+}
+
+// Watch subscribes to topology changes. Events are delivered in batches; a batch is dropped for a
+// subscriber whose buffer is full. The returned function cancels the subscription.
+func (r *Ring) Watch(buffer int) (<-chan []Event, func()) {
+	h := r.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	ch := make(chan []Event, buffer)
+	h.subscribers[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers, id)
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// begin opens a batch; events emitted until the matching end are delivered together.
+func (h *watchHub) begin() {
+	h.mu.Lock()
+	h.depth++
+	h.mu.Unlock()
+}
+
+// end closes a batch and delivers its events once the outermost batch is closed.
+func (h *watchHub) end() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.depth--
+	if h.depth > 0 {
+		return
+	}
+	h.history.sealBatch(h.epoch)
+	if len(h.pending) == 0 {
+		return
+	}
+	h.deliver(h.pending)
+	h.pending = nil
+}
+
+// publish queues the event in the open batch, or delivers it immediately.
+func (h *watchHub) publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if event.Type != KeyMoved {
+		h.epoch++
+	}
+	if h.depth > 0 {
+		h.pending = append(h.pending, event)
+		return
+	}
+	h.deliver([]Event{event})
+}
+
+// deliver sends a batch to every subscriber without blocking (assuming mutex is already locked).
+func (h *watchHub) deliver(batch []Event) {
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- batch:
+		default:
+		}
+	}
+}
+
+// emit stamps and publishes an event to the tree's subscribers.
+func (r *Ring) emit(event Event) {
+	if r.hub == nil {
+		return
+	}
+	event.Time = time.Now()
+	r.hub.publish(event)
+}
+
+// emitKeyMoved publishes one KeyMoved event for every KeyEventSampling key moves. Each sampled event
+// carries the exact number of moves it stands for, so subscribers can keep precise totals.
+func (r *Ring) emitKeyMoved(key string, from *Node, to *Node) {
+	rate := r.config.KeyEventSampling
+	if r.hub == nil || rate <= 0 {
+		return
+	}
+	h := r.hub
+	h.mu.Lock()
+	h.keyMoves++
+	h.unsampled++
+	if h.keyMoves%rate != 0 {
+		h.mu.Unlock()
+		return
+	}
+	count := h.unsampled
+	h.unsampled = 0
+	h.mu.Unlock()
+
+	r.emit(Event{Type: KeyMoved, RingID: r.id, NodeID: to.id, Level: r.level, Remapped: count, Key: key, From: from.id})
+}
+
+// Epoch returns the topology epoch of the tree: the number of topology changes applied to it, or to the
+// replica it last pulled its topology from.
+func (r *Ring) Epoch() uint64 {
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	return r.hub.epoch
+}
+
+// KeyMoves returns the exact number of key moves observed while key events are enabled.
+func (r *Ring) KeyMoves() int {
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	return r.hub.keyMoves
+}
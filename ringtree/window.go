@@ -0,0 +1,158 @@
+package ringtree
+
+import "time"
+
+// Windows over which recent statistics are commonly reported. Any window up to statRetention can be used.
+const (
+	LastMinute    = time.Minute
+	Last5Minutes  = 5 * time.Minute
+	LastHour      = time.Hour
+	statRetention = LastHour
+)
+
+// statBucketWidth is the span of activity each bucket of windowed statistics aggregates. Windows are
+// resolved to whole buckets, and an hour of them is kept however many operations run.
+const (
+	statBucketWidth = time.Second
+	statBuckets     = int64(statRetention / statBucketWidth)
+)
+
+// statBucket aggregates the remaps, splits and operation durations of one second.
+type statBucket struct {
+	second int64 // Unix second the bucket covers; a bucket of an older second is stale
+	remaps int
+	splits int
+	ops    map[string]latencyTotals
+}
+
+// latencyTotals accumulates the durations of one operation within a bucket.
+type latencyTotals struct {
+	count int
+	total time.Duration
+	max   time.Duration
+}
+
+// statWindow is a ring buffer of per-second buckets covering the retention period.
+type statWindow struct {
+	buckets []statBucket // Allocated on the first record
+}
+
+// WindowStats summarizes the activity of a ring tree over a recent window.
+type WindowStats struct {
+	Window     time.Duration
+	Remaps     int                     // Keys remapped within the window
+	Splits     int                     // Subrings created within the window
+	Operations map[string]LatencyStats // Latency of each operation within the window
+}
+
+// LatencyStats summarizes the durations of one operation.
+type LatencyStats struct {
+	Count int
+	Mean  time.Duration
+	Max   time.Duration
+}
+
+// bucket returns the bucket covering at, recycling the slot of a second that left the retention period, or
+// nil if at is itself older than that.
+func (w *statWindow) bucket(at time.Time) *statBucket {
+	if w.buckets == nil {
+		w.buckets = make([]statBucket, statBuckets)
+	}
+	second := at.Unix()
+	b := &w.buckets[(second%statBuckets+statBuckets)%statBuckets]
+	if b.second > second {
+		return nil
+	}
+	if b.second < second {
+		*b = statBucket{second: second}
+	}
+	return b
+}
+
+// remapped counts keys remapped at the given time.
+func (w *statWindow) remapped(at time.Time, n int) {
+	if b := w.bucket(at); b != nil {
+		b.remaps += n
+	}
+}
+
+// split counts a subring created at the given time.
+func (w *statWindow) split(at time.Time) {
+	if b := w.bucket(at); b != nil {
+		b.splits++
+	}
+}
+
+// operation adds the duration of an operation that completed at the given time.
+func (w *statWindow) operation(at time.Time, op string, elapsed time.Duration) {
+	b := w.bucket(at)
+	if b == nil {
+		return
+	}
+	if b.ops == nil {
+		b.ops = make(map[string]latencyTotals)
+	}
+	totals := b.ops[op]
+	totals.count++
+	totals.total += elapsed
+	if elapsed > totals.max {
+		totals.max = elapsed
+	}
+	b.ops[op] = totals
+}
+
+// recordSplit counts a subring creation.
+func (s *Stats) recordSplit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.window.split(time.Now())
+}
+
+// Window returns the remaps, splits and operation latencies of the last d, which is capped at one hour. The
+// window is resolved to whole seconds.
+func (s *Stats) Window(d time.Duration) WindowStats {
+	if d > statRetention {
+		d = statRetention
+	}
+	since := time.Now().Add(-d).Unix()
+	stats := WindowStats{Window: d, Operations: make(map[string]LatencyStats)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totals := make(map[string]time.Duration)
+	for _, b := range s.window.buckets {
+		if b.second < since {
+			continue
+		}
+		stats.Remaps += b.remaps
+		stats.Splits += b.splits
+		for op, t := range b.ops {
+			latency := stats.Operations[op]
+			latency.Count += t.count
+			if t.max > latency.Max {
+				latency.Max = t.max
+			}
+			totals[op] += t.total
+			stats.Operations[op] = latency
+		}
+	}
+	for op, latency := range stats.Operations {
+		latency.Mean = totals[op] / time.Duration(latency.Count)
+		stats.Operations[op] = latency
+	}
+	return stats
+}
+
+// Reset clears the cumulative and windowed counters, operation timings and latency histograms, starting a new
+// measurement window. Node and key counts describe the current tree and are kept.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remaps = nil
+	s.remapped.Store(0)
+	s.operationTimes = make(map[string][]time.Duration)
+	s.corrupted.Store(0)
+	s.window = statWindow{}
+	s.histograms = nil
+}
@@ -0,0 +1,51 @@
+package ringtree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowStats(t *testing.T) {
+	rt := New(2)
+	rt.InsertNode(NewNode("A", 5))
+	rt.InsertNode(NewNode("B", 5))
+	for i := 0; i < 30; i++ {
+		key, _ := GenerateRandomString(20)
+		rt.InsertKey(key)
+	}
+
+	stats := rt.Stats()
+	recent := stats.Window(LastMinute)
+	if recent.Splits == 0 {
+		t.Errorf("expected splits in the last minute")
+	}
+	if recent.Operations["InsertKey"].Count == 0 {
+		t.Errorf("expected InsertKey latencies in the last minute")
+	}
+
+	// Activity older than the window is excluded, and older than the retention period is dropped
+	stats.window.split(time.Now().Add(-10 * time.Minute))
+	checkNum(stats.Window(Last5Minutes).Splits, recent.Splits, t)
+	checkNum(stats.Window(LastHour).Splits, recent.Splits+1, t)
+
+	remaps := stats.Window(LastHour).Remaps
+	stats.window.remapped(time.Now().Add(-2*time.Hour), 7)
+	stats.window.remapped(time.Now(), 3)
+	checkNum(stats.Window(LastHour).Remaps, remaps+3, t)
+
+	// Memory is bounded by the buckets, however many operations complete
+	now := time.Now()
+	for i := 0; i < 100000; i++ {
+		stats.window.operation(now, "Lookup", time.Microsecond)
+	}
+	checkNum(len(stats.window.buckets), int(statBuckets), t)
+	checkNum(stats.Window(LastMinute).Operations["Lookup"].Count, 100000, t)
+
+	stats.Reset()
+	recent = stats.Window(LastHour)
+	checkNum(recent.Splits, 0, t)
+	checkNum(recent.Remaps, 0, t)
+	checkNum(len(recent.Operations), 0, t)
+	checkNum(len(stats.TimeStats()), 0, t)
+	checkNum(stats.Keys(), 30, t)
+}
@@ -0,0 +1,185 @@
+// Package workload generates keys for simulations, benchmarks and tests of a ring tree: uniformly random
+// keys, Zipfian keys drawn from a fixed key space, keys with a hot prefix, and monotonically increasing keys.
+//
+// Generators are fed into a tree with Insert and Lookup. The package does not depend on the ring tree, so
+// the tree's own tests can use it; *ringtree.Ring satisfies Target.
+package workload
+
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Generator produces a stream of keys. Generators are safe for concurrent use.
+type Generator interface {
+	Next() string
+}
+
+// Target is a key store a workload is fed into.
+type Target interface {
+	InsertKey(key string) error
+	Lookup(key string) (string, error)
+}
+
+// Insert feeds n generated keys into the target and returns the keys inserted, in order. Keys the
+// generator repeats within the call are skipped, so Zipfian and hotspot workloads insert each key once.
+func Insert(t Target, g Generator, n int) ([]string, error) {
+	seen := make(map[string]bool, n)
+	keys := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		key := g.Next()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := t.InsertKey(key); err != nil {
+			return keys, fmt.Errorf("inserting key %s: %w", key, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Lookup looks up n generated keys in the target and returns the number of lookups served by each owner.
+func Lookup(t Target, g Generator, n int) (map[string]int, error) {
+	owners := make(map[string]int)
+	for i := 0; i < n; i++ {
+		key := g.Next()
+		owner, err := t.Lookup(key)
+		if err != nil {
+			return owners, fmt.Errorf("looking up key %s: %w", key, err)
+		}
+		owners[owner]++
+	}
+	return owners, nil
+}
+
+// source is a random source shared by the calls of one generator.
+type source struct {
+	mu  sync.Mutex
+	rng *rand.Rand // Nil draws from crypto/rand
+}
+
+// read fills b with random bytes.
+func (s *source) read(b []byte) {
+	if s.rng == nil {
+		crand.Read(b)
+		return
+	}
+	s.mu.Lock()
+	s.rng.Read(b)
+	s.mu.Unlock()
+}
+
+// float64 returns a random number in [0, 1).
+func (s *source) float64() float64 {
+	if s.rng == nil {
+		var b [8]byte
+		crand.Read(b[:])
+		return float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Float64()
+}
+
+// uniform generates uniformly random keys.
+type uniform struct {
+	src    *source
+	length int
+}
+
+// Uniform returns a generator of uniformly random keys: length random bytes, base64 encoded. With a nil
+// rng the bytes come from crypto/rand; pass a seeded rng for reproducible keys.
+func Uniform(rng *rand.Rand, length int) Generator {
+	return &uniform{src: &source{rng: rng}, length: length}
+}
+
+func (g *uniform) Next() string {
+	b := make([]byte, g.length)
+	g.src.read(b)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// zipf draws keys from a fixed key space with Zipfian popularity.
+type zipf struct {
+	src    *source
+	prefix string
+	cdf    []float64 // Cumulative probability of the keys, most popular first
+}
+
+// Zipf returns a generator drawing from the n keys prefix0 to prefix(n-1), where the key of rank k is
+// drawn with probability proportional to 1/(k+1)^s. An s of 0 is uniform; around 1 is typical of caches and
+// web traffic. Unlike math/rand's Zipf, any s >= 0 is accepted. It panics if n is not positive.
+func Zipf(rng *rand.Rand, prefix string, s float64, n int) Generator {
+	if n < 1 {
+		panic("workload: Zipf key space must be positive")
+	}
+	cdf := make([]float64, n)
+	total := 0.0
+	for k := range cdf {
+		total += 1 / math.Pow(float64(k+1), s)
+		cdf[k] = total
+	}
+	for k := range cdf {
+		cdf[k] /= total
+	}
+	return &zipf{src: &source{rng: rng}, prefix: prefix, cdf: cdf}
+}
+
+func (g *zipf) Next() string {
+	u := g.src.float64()
+	k := sort.SearchFloat64s(g.cdf, u)
+	if k == len(g.cdf) {
+		k--
+	}
+	return fmt.Sprintf("%s%d", g.prefix, k)
+}
+
+// hotspot prefixes a share of the keys of another generator.
+type hotspot struct {
+	src      *source
+	prefix   string
+	fraction float64
+	base     Generator
+}
+
+// Hotspot returns a generator that takes its keys from base, and gives a fraction of them the hot prefix,
+// as when one tenant or table dominates the traffic.
+func Hotspot(rng *rand.Rand, prefix string, fraction float64, base Generator) Generator {
+	return &hotspot{src: &source{rng: rng}, prefix: prefix, fraction: fraction, base: base}
+}
+
+func (g *hotspot) Next() string {
+	if g.src.float64() < g.fraction {
+		return g.prefix + g.base.Next()
+	}
+	return g.base.Next()
+}
+
+// sequential generates monotonically increasing keys.
+type sequential struct {
+	mu     sync.Mutex
+	prefix string
+	next   uint64
+}
+
+// Sequential returns a generator of increasing keys prefix followed by a zero-padded counter from start,
+// as written by time-ordered or auto-increment IDs. The keys also sort in the order generated.
+func Sequential(prefix string, start uint64) Generator {
+	return &sequential{prefix: prefix, next: start}
+}
+
+func (g *sequential) Next() string {
+	g.mu.Lock()
+	n := g.next
+	g.next++
+	g.mu.Unlock()
+	return fmt.Sprintf("%s%020d", g.prefix, n)
+}
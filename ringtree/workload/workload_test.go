@@ -0,0 +1,104 @@
+package workload
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// store is a map-backed Target.
+type store map[string]bool
+
+func (s store) InsertKey(key string) error {
+	if s[key] {
+		return errors.New("key exists")
+	}
+	s[key] = true
+	return nil
+}
+
+func (s store) Lookup(key string) (string, error) {
+	if !s[key] {
+		return "", errors.New("key not found")
+	}
+	return key[:1], nil
+}
+
+func TestUniform(t *testing.T) {
+	a, b := Uniform(rand.New(rand.NewSource(1)), 20), Uniform(rand.New(rand.NewSource(1)), 20)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Next(), b.Next(); x != y || len(x) != 28 {
+			t.Fatalf("got %q and %q from equally seeded generators, want the same 28-character key", x, y)
+		}
+	}
+	random := Uniform(nil, 20)
+	if random.Next() == random.Next() {
+		t.Error("expected distinct keys from crypto/rand")
+	}
+}
+
+func TestZipf(t *testing.T) {
+	g := Zipf(rand.New(rand.NewSource(1)), "k", 1.2, 1000)
+	counts := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		counts[g.Next()]++
+	}
+	if counts["k0"] < counts["k1"] || counts["k1"] < counts["k9"] || counts["k0"] < 20000/10 {
+		t.Errorf("got counts k0=%d k1=%d k9=%d, want popularity falling with rank", counts["k0"], counts["k1"], counts["k9"])
+	}
+
+	flat := Zipf(rand.New(rand.NewSource(1)), "k", 0, 4)
+	counts = make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[flat.Next()]++
+	}
+	for key, count := range counts {
+		if count < 800 || count > 1200 {
+			t.Errorf("s=0: got %d draws of %s, want about 1000", count, key)
+		}
+	}
+}
+
+func TestHotspot(t *testing.T) {
+	g := Hotspot(rand.New(rand.NewSource(1)), "hot/", 0.3, Sequential("k", 0))
+	hot := 0
+	for i := 0; i < 10000; i++ {
+		if strings.HasPrefix(g.Next(), "hot/") {
+			hot++
+		}
+	}
+	if hot < 2700 || hot > 3300 {
+		t.Errorf("got %d hot keys of 10000, want about 3000", hot)
+	}
+}
+
+func TestSequential(t *testing.T) {
+	g := Sequential("k", 98)
+	keys := []string{g.Next(), g.Next(), g.Next()}
+	if !sort.StringsAreSorted(keys) || keys[0] != "k00000000000000000098" {
+		t.Errorf("got %v, want increasing zero-padded keys from 98", keys)
+	}
+}
+
+func TestInsertAndLookup(t *testing.T) {
+	s := make(store)
+	keys, err := Insert(s, Zipf(rand.New(rand.NewSource(1)), "k", 1, 50), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != len(s) || len(keys) > 50 {
+		t.Errorf("inserted %d keys into a store of %d, want each of at most 50 keys once", len(keys), len(s))
+	}
+	owners, err := Lookup(s, Zipf(rand.New(rand.NewSource(1)), "k", 1, 50), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners["k"] != 500 {
+		t.Errorf("got owners %v, want 500 lookups served by k", owners)
+	}
+	if _, err := Lookup(s, Sequential("x", 0), 1); err == nil {
+		t.Error("expected a failed lookup to be reported")
+	}
+}
@@ -0,0 +1,33 @@
+package ringtree
+
+import "runtime"
+
+// defaultCheckpointInterval is the number of keys a split moves between lock releases by default.
+const defaultCheckpointInterval = 10000
+
+// yield releases the ring's lock so waiting lookups can run, then reacquires it (assuming mutex is already
+// locked).
+func (r *Ring) yield() {
+	r.Unlock()
+	runtime.Gosched()
+	r.Lock()
+}
+
+// migratingKey finds a key that a split into this ring or one of its ancestors has not moved yet. It returns
+// the node being split, the ring whose lock guards it, and the vnode holding the key.
+func (r *Ring) migratingKey(key string) (*Node, *Ring, uint32) {
+	for ring := r; ring != nil && ring.parent != nil; ring = ring.parent {
+		guard := ring.parent
+		guard.RLock()
+		if node := ring.migrating; node != nil {
+			for vNodeHash, keys := range node.keys {
+				if _, exists := keys[key]; exists {
+					guard.RUnlock()
+					return node, guard, vNodeHash
+				}
+			}
+		}
+		guard.RUnlock()
+	}
+	return nil, nil, 0
+}
@@ -0,0 +1,60 @@
+package ringtree
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSplitYieldsToLookups(t *testing.T) {
+	rt := New(2, WithCheckpointInterval(1))
+	rt.InsertNode(NewNode("A", 1000))
+	rt.InsertNode(NewNode("B", 1000))
+
+	var keys []string
+	for i := 0; i < 500; i++ {
+		key, _ := GenerateRandomString(20)
+		keys = append(keys, key)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+	}
+
+	// Look keys up continuously while further inserts split a node
+	var missed, lookups int64
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := rt.Lookup(keys[i%len(keys)]); err != nil {
+				atomic.AddInt64(&missed, 1)
+			}
+			atomic.AddInt64(&lookups, 1)
+			time.Sleep(time.Microsecond)
+		}
+	}()
+
+	inserted := len(keys)
+	for !rt.hasSubrings() {
+		key, _ := GenerateRandomString(20)
+		if err := rt.InsertKey(key); err != nil {
+			t.Fatalf("expected key %s to be inserted, got error: %v", key, err)
+		}
+		inserted++
+	}
+	close(done)
+	wg.Wait()
+
+	if missed > 0 {
+		t.Errorf("expected every lookup during the split to succeed, %d of %d missed", missed, lookups)
+	}
+	checkNum(rt.Stats().Keys(), inserted, t)
+}